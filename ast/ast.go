@@ -2,12 +2,16 @@ package ast
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/toyaAoi/sild/token"
 )
 
 type Node interface {
 	String() string
+	Pos() token.Position // position of the first character of the node
+	End() token.Position // position just past the last character of the node
 }
 
 type Statement interface {
@@ -20,23 +24,138 @@ type Expression interface {
 	expressionNode()
 }
 
+// TypeExpr is a type annotation.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
 type Program struct {
 	Statements []Statement
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if n := len(p.Statements); n > 0 {
+		return p.Statements[n-1].End()
+	}
+	return token.Position{}
+}
+
+// Loc records the source span of nodes whose extent is not implied by the
+// tokens they hold.
+type Loc struct {
+	StartPos token.Position
+	EndPos   token.Position
+}
+
+func (l Loc) Pos() token.Position { return l.StartPos }
+func (l Loc) End() token.Position { return l.EndPos }
+
+// CommentGroup is a sequence of comments with no tokens between them.
+type CommentGroup struct {
+	List []token.Comment
+}
+
+// Text returns the text of the comments without the comment markers, one
+// line per line of the comments. The leading '*' of the lines of a block
+// comment, as in JSDoc comments, is removed along with the space after it,
+// and the blank lines at the start and end of block comments are dropped.
+func (g *CommentGroup) Text() []string {
+	if g == nil {
+		return nil
+	}
+
+	var lines []string
+	for _, c := range g.List {
+		if text, ok := strings.CutPrefix(c.Text, "//"); ok {
+			lines = append(lines, strings.TrimRight(strings.TrimPrefix(text, " "), " \t"))
+			continue
+		}
+
+		text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+		if IsDocComment(c) {
+			text = text[1:]
+		}
+		var block []string
+		for line := range strings.Lines(text) {
+			line = strings.TrimLeft(line, " \t")
+			if rest, ok := strings.CutPrefix(line, "*"); ok {
+				line = strings.TrimPrefix(rest, " ")
+			}
+			block = append(block, strings.TrimRight(line, " \t\r\n"))
+		}
+		for len(block) > 0 && block[0] == "" {
+			block = block[1:]
+		}
+		for len(block) > 0 && block[len(block)-1] == "" {
+			block = block[:len(block)-1]
+		}
+		lines = append(lines, block...)
+	}
+	return lines
+}
+
+// IsDocComment reports whether c is a JSDoc comment, a block comment
+// starting with "/**".
+func IsDocComment(c token.Comment) bool {
+	return strings.HasPrefix(c.Text, "/**") && c.Text != "/**/"
+}
+
+// Trivia holds the comments attached to a statement or member, which don't
+// change its meaning.
+type Trivia struct {
+	Leading  *CommentGroup // comments on the lines before the node
+	Trailing *CommentGroup // comments after the node on its last line
+}
+
+func (t *Trivia) Comments() *Trivia { return t }
+
+// Commented is implemented by the nodes comments are attached to: statements
+// and the members of classes and object types.
+type Commented interface {
+	Node
+	Comments() *Trivia
+}
+
+// BadStatement stands in for a statement that could not be parsed. It spans
+// the tokens the parser skipped while recovering from the error.
+type BadStatement struct {
+	Loc
+	Trivia
+}
+
+func (b *BadStatement) statementNode() {}
+func (b *BadStatement) String() string {
+	return "<bad statement>"
+}
+
 type Identifier struct {
 	Token token.Token
 }
 
-func (i *Identifier) expressionNode() {}
+func (i *Identifier) expressionNode()     {}
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+func (i *Identifier) End() token.Position { return i.Token.End }
 func (i *Identifier) String() string {
 	return i.Token.Literal
 }
 
+// VariableDeclaration declares a variable. Type is nil if the type is to be
+// inferred from Expr, and Expr is nil if the variable isn't initialized.
 type VariableDeclaration struct {
-	Name string
-	Type string
-	Expr Expression
+	Loc
+	Trivia
+	Keyword token.Token // let, const or var
+	Name    string
+	Type    TypeExpr
+	Expr    Expression
 }
 
 func (v *VariableDeclaration) statementNode() {}
@@ -44,7 +163,62 @@ func (v *VariableDeclaration) String() string {
 	if v == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("name: %q, type: %q, value: %q", v.Name, v.Type, v.Expr.String())
+	value := ""
+	if v.Expr != nil {
+		value = v.Expr.String()
+	}
+	return fmt.Sprintf("name: %q, type: %q, value: %q", v.Name, typeString(v.Type), value)
+}
+
+// VarDeclarations returns the var declarations among stmts and the
+// statements nested in them, which are scoped to the enclosing function
+// rather than to the block they appear in. Declarations in nested functions
+// and classes are not included.
+func VarDeclarations(stmts []Statement) []*VariableDeclaration {
+	var decls []*VariableDeclaration
+	var walk func(stmt Statement)
+	walk = func(stmt Statement) {
+		switch s := stmt.(type) {
+		case *VariableDeclaration:
+			if s.Keyword.Type == token.VAR {
+				decls = append(decls, s)
+			}
+		case *BlockStatement:
+			for _, stmt := range s.Statements {
+				walk(stmt)
+			}
+		case *IfStatement:
+			walk(s.Consequence)
+			if s.Alternative != nil {
+				walk(s.Alternative)
+			}
+		case *WhileStatement:
+			walk(s.Body)
+		case *DoWhileStatement:
+			walk(s.Body)
+		case *ForStatement:
+			if s.Init != nil {
+				walk(s.Init)
+			}
+			walk(s.Body)
+		case *ForOfStatement:
+			walk(s.Body)
+		case *ForInStatement:
+			walk(s.Body)
+		case *LabeledStatement:
+			walk(s.Body)
+		case *SwitchStatement:
+			for _, clause := range s.Cases {
+				for _, stmt := range clause.Body {
+					walk(stmt)
+				}
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		walk(stmt)
+	}
+	return decls
 }
 
 type BinaryExpression struct {
@@ -53,7 +227,9 @@ type BinaryExpression struct {
 	Right    Expression
 }
 
-func (b *BinaryExpression) expressionNode() {}
+func (b *BinaryExpression) expressionNode()     {}
+func (b *BinaryExpression) Pos() token.Position { return b.Left.Pos() }
+func (b *BinaryExpression) End() token.Position { return b.Right.End() }
 func (b *BinaryExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", b.Left.String(), b.Operator.Literal, b.Right.String())
 }
@@ -62,40 +238,114 @@ type NumberLiteral struct {
 	Token token.Token
 }
 
-func (s *NumberLiteral) expressionNode() {}
+func (s *NumberLiteral) expressionNode()     {}
+func (s *NumberLiteral) Pos() token.Position { return s.Token.Pos }
+func (s *NumberLiteral) End() token.Position { return s.Token.End }
 func (s *NumberLiteral) String() string {
 	return s.Token.Literal
 }
 
+// BigIntLiteral is an integer literal with an n suffix, such as 123n. The
+// literal of its token includes the suffix.
+type BigIntLiteral struct {
+	Token token.Token
+}
+
+func (b *BigIntLiteral) expressionNode()     {}
+func (b *BigIntLiteral) Pos() token.Position { return b.Token.Pos }
+func (b *BigIntLiteral) End() token.Position { return b.Token.End }
+func (b *BigIntLiteral) String() string {
+	return b.Token.Literal
+}
+
 type StringLiteral struct {
 	Token token.Token
 }
 
-func (s *StringLiteral) expressionNode() {}
+func (s *StringLiteral) expressionNode()     {}
+func (s *StringLiteral) Pos() token.Position { return s.Token.Pos }
+func (s *StringLiteral) End() token.Position { return s.Token.End }
 func (s *StringLiteral) String() string {
 	return s.Token.Literal
 }
 
+// TemplateLiteral is a template literal with substitutions, such as
+// `${a} + ${b}`. Quasis holds the text around the substitutions, so it has
+// one more element than Expressions.
+type TemplateLiteral struct {
+	Loc
+	Quasis      []string
+	Expressions []Expression
+}
+
+func (t *TemplateLiteral) expressionNode() {}
+func (t *TemplateLiteral) String() string {
+	var b strings.Builder
+	b.WriteString("`")
+	for i, quasi := range t.Quasis {
+		if i > 0 {
+			fmt.Fprintf(&b, "${%s}", t.Expressions[i-1].String())
+		}
+		b.WriteString(quasi)
+	}
+	b.WriteString("`")
+	return b.String()
+}
+
 type BooleanLiteral struct {
 	Token token.Token
 }
 
-func (b *BooleanLiteral) expressionNode() {}
+func (b *BooleanLiteral) expressionNode()     {}
+func (b *BooleanLiteral) Pos() token.Position { return b.Token.Pos }
+func (b *BooleanLiteral) End() token.Position { return b.Token.End }
 func (b *BooleanLiteral) String() string {
 	return b.Token.Literal
 }
 
+// NullLiteral is null or undefined, told apart by the type of its token.
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) expressionNode()     {}
+func (n *NullLiteral) Pos() token.Position { return n.Token.Pos }
+func (n *NullLiteral) End() token.Position { return n.Token.End }
+func (n *NullLiteral) String() string {
+	return n.Token.Literal
+}
+
 type UnaryExpression struct {
 	Operator token.Token
 	Right    Expression
 }
 
-func (u *UnaryExpression) expressionNode() {}
+func (u *UnaryExpression) expressionNode()     {}
+func (u *UnaryExpression) Pos() token.Position { return u.Operator.Pos }
+func (u *UnaryExpression) End() token.Position { return u.Right.End() }
 func (u *UnaryExpression) String() string {
+	if u.Operator.Type == token.TYPEOF {
+		return fmt.Sprintf("typeof %s", u.Right.String())
+	}
 	return fmt.Sprintf("%s%s", u.Operator.Literal, u.Right.String())
 }
 
+// NonNullExpression is a non-null assertion such as x!, which asserts that
+// x is neither null nor undefined.
+type NonNullExpression struct {
+	Expression Expression
+	Token      token.Token // the '!'
+}
+
+func (n *NonNullExpression) expressionNode()     {}
+func (n *NonNullExpression) Pos() token.Position { return n.Expression.Pos() }
+func (n *NonNullExpression) End() token.Position { return n.Token.End }
+func (n *NonNullExpression) String() string {
+	return n.Expression.String() + "!"
+}
+
 type ParenthesizedExpression struct {
+	Loc
 	Expression Expression
 }
 
@@ -105,15 +355,25 @@ func (p *ParenthesizedExpression) String() string {
 }
 
 type FunctionParam struct {
-	Type token.Token
+	Type TypeExpr
 	Name token.Token
 }
 
+func (fp *FunctionParam) Pos() token.Position { return fp.Name.Pos }
+func (fp *FunctionParam) End() token.Position {
+	if fp.Type == nil {
+		return fp.Name.End
+	}
+	return fp.Type.End()
+}
+
 func (fp *FunctionParam) String() string {
-	return fmt.Sprintf("%s %s", fp.Name.Literal, mapType(fp.Type.Literal))
+	return fmt.Sprintf("%s %s", fp.Name.Literal, mapType(fp.Type))
 }
 
 type ReturnStatement struct {
+	Loc
+	Trivia
 	Token token.Token
 	Value Expression
 }
@@ -123,14 +383,37 @@ func (r *ReturnStatement) String() string {
 	if r == nil {
 		return "<nil>"
 	}
+	if r.Value == nil {
+		return "return"
+	}
 	return "return " + r.Value.String()
 }
 
+// ThrowStatement throws Value, which ends the program since there is no
+// try statement to catch it.
+type ThrowStatement struct {
+	Loc
+	Trivia
+	Token token.Token
+	Value Expression
+}
+
+func (t *ThrowStatement) statementNode() {}
+func (t *ThrowStatement) String() string {
+	if t == nil {
+		return "<nil>"
+	}
+	return "throw " + t.Value.String()
+}
+
 type FunctionDeclaration struct {
-    Name       token.Token
-    Params     []FunctionParam
-    Body       []Statement
-    ReturnType token.Token
+	Loc
+	Trivia
+	Name       token.Token
+	TypeParams []*TypeParam
+	Params     []FunctionParam
+	Body       []Statement
+	ReturnType TypeExpr
 }
 
 func (f *FunctionDeclaration) statementNode() {}
@@ -149,19 +432,88 @@ func (f *FunctionDeclaration) String() string {
 		body = append(body, stmt.String())
 	}
 
-	return fmt.Sprintf("name: %q, params: %q, body: %q, return type: %q", f.Name.Literal, params, body, f.ReturnType.Literal)
+	return fmt.Sprintf("name: %q, params: %q, body: %q, return type: %q", f.Name.Literal+typeParamsString(f.TypeParams), params, body, typeString(f.ReturnType))
+}
+
+// FunctionExpression is an anonymous function expression or an arrow
+// function. Parameters of arrow functions and function expressions may omit
+// their type, which is then taken from the context, and ReturnType is nil if
+// it is to be inferred. A concise arrow function has an Expr in place of a
+// Body.
+type FunctionExpression struct {
+	Loc
+	Params     []FunctionParam
+	ReturnType TypeExpr
+	Body       []Statement
+	Expr       Expression
+	Arrow      bool
+}
+
+func (f *FunctionExpression) expressionNode() {}
+func (f *FunctionExpression) String() string {
+	params := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		params[i] = paramString(param)
+	}
+
+	var ret string
+	if f.ReturnType != nil {
+		ret = ": " + f.ReturnType.String()
+	}
+
+	var body string
+	if f.Expr != nil {
+		body = f.Expr.String()
+	} else {
+		stmts := make([]string, len(f.Body))
+		for i, stmt := range f.Body {
+			stmts[i] = stmt.String()
+		}
+		body = "{ " + strings.Join(stmts, "; ") + " }"
+	}
+
+	if f.Arrow {
+		return fmt.Sprintf("(%s)%s => %s", strings.Join(params, ", "), ret, body)
+	}
+	return fmt.Sprintf("function (%s)%s %s", strings.Join(params, ", "), ret, body)
+}
+
+func paramString(param FunctionParam) string {
+	if param.Type == nil {
+		return param.Name.Literal
+	}
+	return param.Name.Literal + ": " + param.Type.String()
+}
+
+// FunctionType is the type of a function, written as (x: number) => string.
+type FunctionType struct {
+	Loc
+	Params     []FunctionParam
+	ReturnType TypeExpr
 }
 
-func mapType(tsType string) string {
+func (f *FunctionType) typeNode() {}
+func (f *FunctionType) String() string {
+	params := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		params[i] = paramString(param)
+	}
+	return fmt.Sprintf("(%s) => %s", strings.Join(params, ", "), f.ReturnType.String())
+}
+
+func mapType(t TypeExpr) string {
+	if elem := ElementType(t); elem != nil {
+		return "[]" + mapType(elem)
+	}
 
 	typeMap := map[string]string{
-		"number":  "int",
+		"number":  "float64",
 		"string":  "string",
 		"boolean": "bool",
 		"void":    "",
 	}
 
-	if goType, exists := typeMap[tsType]; exists {
+	if goType, exists := typeMap[typeString(t)]; exists {
 		return goType
 	}
 
@@ -172,25 +524,718 @@ type VariableExpression struct {
 	Token token.Token
 }
 
-func (v *VariableExpression) expressionNode() {}
+func (v *VariableExpression) expressionNode()     {}
+func (v *VariableExpression) Pos() token.Position { return v.Token.Pos }
+func (v *VariableExpression) End() token.Position { return v.Token.End }
 func (v *VariableExpression) String() string {
 	return v.Token.Literal
 }
 
+// FunctionCallExpression is a call. TypeArgs are the explicit type
+// arguments of a call of a generic function, as in first<number>(xs).
+// Optional is set for an optional call such as f?.(x).
 type FunctionCallExpression struct {
-	Token token.Token
-	Args  []Expression
+	Loc
+	Callee   Expression
+	TypeArgs []TypeExpr
+	Args     []Expression
+	Optional bool
 }
 
 func (f *FunctionCallExpression) expressionNode() {}
 func (f *FunctionCallExpression) String() string {
 	args := ""
-	for i,a := range f.Args {
-		if (i > 0) {
-			args+= ", "
+	for i, a := range f.Args {
+		if i > 0 {
+			args += ", "
 		}
 		args += a.String()
 	}
 
-	return fmt.Sprintf("%s(%s)", f.Token.Literal, args)
-}
\ No newline at end of file
+	optional := ""
+	if f.Optional {
+		optional = "?."
+	}
+	if _, ok := f.Callee.(*FunctionExpression); ok {
+		return fmt.Sprintf("(%s)%s%s(%s)", f.Callee.String(), optional, typeArgsString(f.TypeArgs), args)
+	}
+	return fmt.Sprintf("%s%s%s(%s)", f.Callee.String(), optional, typeArgsString(f.TypeArgs), args)
+}
+
+type BlockStatement struct {
+	Loc
+	Trivia
+	Statements []Statement
+}
+
+func (b *BlockStatement) statementNode() {}
+func (b *BlockStatement) String() string {
+	var stmts []string
+	for _, stmt := range b.Statements {
+		stmts = append(stmts, stmt.String())
+	}
+	return fmt.Sprintf("{ %s }", strings.Join(stmts, "; "))
+}
+
+// IfStatement is an if statement. Consequence and Alternative are either a
+// *BlockStatement or a single statement; Alternative is nil without an else
+// branch and an *IfStatement for an else if.
+type IfStatement struct {
+	Loc
+	Trivia
+	Token       token.Token
+	Condition   Expression
+	Consequence Statement
+	Alternative Statement
+}
+
+func (i *IfStatement) statementNode() {}
+func (i *IfStatement) String() string {
+	s := fmt.Sprintf("if %s %s", i.Condition.String(), i.Consequence.String())
+	if i.Alternative != nil {
+		s += " else " + i.Alternative.String()
+	}
+	return s
+}
+
+type ExpressionStatement struct {
+	Loc
+	Trivia
+	Expression Expression
+}
+
+func (e *ExpressionStatement) statementNode() {}
+func (e *ExpressionStatement) String() string {
+	return e.Expression.String()
+}
+
+// AssignmentStatement assigns Value to Target, using either '=' or one of
+// the compound assignment operators such as '+='.
+type AssignmentStatement struct {
+	Loc
+	Trivia
+	Target   Expression
+	Operator token.Token
+	Value    Expression
+}
+
+func (a *AssignmentStatement) statementNode() {}
+func (a *AssignmentStatement) String() string {
+	return fmt.Sprintf("%s %s %s", a.Target.String(), a.Operator.Literal, a.Value.String())
+}
+
+// IncDecStatement is a '++' or '--' applied to Target as a statement.
+type IncDecStatement struct {
+	Loc
+	Trivia
+	Target   Expression
+	Operator token.Token
+	Prefix   bool
+}
+
+func (i *IncDecStatement) statementNode() {}
+func (i *IncDecStatement) String() string {
+	if i.Prefix {
+		return i.Operator.Literal + i.Target.String()
+	}
+	return i.Target.String() + i.Operator.Literal
+}
+
+type WhileStatement struct {
+	Loc
+	Trivia
+	Token     token.Token
+	Condition Expression
+	Body      Statement
+}
+
+func (w *WhileStatement) statementNode() {}
+func (w *WhileStatement) String() string {
+	return fmt.Sprintf("while %s %s", w.Condition.String(), w.Body.String())
+}
+
+type DoWhileStatement struct {
+	Loc
+	Trivia
+	Token     token.Token
+	Body      Statement
+	Condition Expression
+}
+
+func (d *DoWhileStatement) statementNode() {}
+func (d *DoWhileStatement) String() string {
+	return fmt.Sprintf("do %s while %s", d.Body.String(), d.Condition.String())
+}
+
+// ForStatement is a C-style for loop. Init, Condition and Update are nil
+// when omitted.
+type ForStatement struct {
+	Loc
+	Trivia
+	Token     token.Token
+	Init      Statement
+	Condition Expression
+	Update    Statement
+	Body      Statement
+}
+
+func (f *ForStatement) statementNode() {}
+func (f *ForStatement) String() string {
+	var init, cond, update string
+	if f.Init != nil {
+		init = f.Init.String()
+	}
+	if f.Condition != nil {
+		cond = f.Condition.String()
+	}
+	if f.Update != nil {
+		update = f.Update.String()
+	}
+	return fmt.Sprintf("for (%s; %s; %s) %s", init, cond, update, f.Body.String())
+}
+
+// BranchStatement is a break or continue, with an optional label.
+type BranchStatement struct {
+	Loc
+	Trivia
+	Token token.Token
+	Label *Identifier
+}
+
+func (b *BranchStatement) statementNode() {}
+func (b *BranchStatement) String() string {
+	if b.Label != nil {
+		return b.Token.Literal + " " + b.Label.String()
+	}
+	return b.Token.Literal
+}
+
+type LabeledStatement struct {
+	Loc
+	Trivia
+	Label *Identifier
+	Body  Statement
+}
+
+func (l *LabeledStatement) statementNode() {}
+func (l *LabeledStatement) String() string {
+	return fmt.Sprintf("%s: %s", l.Label.String(), l.Body.String())
+}
+
+// ForOfStatement is a for...of loop over the values of Iterable.
+type ForOfStatement struct {
+	Loc
+	Trivia
+	Token    token.Token
+	Keyword  token.Token // let, const or var
+	Variable *Identifier
+	Iterable Expression
+	Body     Statement
+}
+
+func (f *ForOfStatement) statementNode() {}
+func (f *ForOfStatement) String() string {
+	return fmt.Sprintf("for (%s %s of %s) %s", f.Keyword.Literal, f.Variable.String(), f.Iterable.String(), f.Body.String())
+}
+
+// ForInStatement is a for...in loop over the keys of Object.
+type ForInStatement struct {
+	Loc
+	Trivia
+	Token    token.Token
+	Keyword  token.Token // let, const or var
+	Variable *Identifier
+	Object   Expression
+	Body     Statement
+}
+
+func (f *ForInStatement) statementNode() {}
+func (f *ForInStatement) String() string {
+	return fmt.Sprintf("for (%s %s in %s) %s", f.Keyword.Literal, f.Variable.String(), f.Object.String(), f.Body.String())
+}
+
+// SwitchStatement is a switch statement. Cases are in source order, so the
+// default clause, if there is one, may be anywhere among them.
+type SwitchStatement struct {
+	Loc
+	Trivia
+	Token        token.Token
+	Discriminant Expression
+	Cases        []*CaseClause
+}
+
+func (s *SwitchStatement) statementNode() {}
+func (s *SwitchStatement) String() string {
+	var cases []string
+	for _, c := range s.Cases {
+		cases = append(cases, c.String())
+	}
+	return fmt.Sprintf("switch (%s) { %s }", s.Discriminant.String(), strings.Join(cases, " "))
+}
+
+// CaseClause is a case of a switch statement, or its default clause if Test
+// is nil.
+type CaseClause struct {
+	Loc
+	Test Expression
+	Body []Statement
+}
+
+func (c *CaseClause) String() string {
+	var stmts []string
+	for _, stmt := range c.Body {
+		stmts = append(stmts, stmt.String())
+	}
+	label := "default:"
+	if c.Test != nil {
+		label = fmt.Sprintf("case %s:", c.Test.String())
+	}
+	if len(stmts) == 0 {
+		return label
+	}
+	return fmt.Sprintf("%s %s;", label, strings.Join(stmts, "; "))
+}
+
+// TypeReference names a type, such as number or Array<string>.
+type TypeReference struct {
+	Loc
+	Name token.Token
+	Args []TypeExpr
+}
+
+func (t *TypeReference) typeNode() {}
+func (t *TypeReference) String() string {
+	if len(t.Args) == 0 {
+		return t.Name.Literal
+	}
+
+	var args []string
+	for _, arg := range t.Args {
+		args = append(args, arg.String())
+	}
+	return fmt.Sprintf("%s<%s>", t.Name.Literal, strings.Join(args, ", "))
+}
+
+// ArrayType is an array type written as Elem[].
+type ArrayType struct {
+	Loc
+	Elem TypeExpr
+}
+
+func (a *ArrayType) typeNode() {}
+func (a *ArrayType) String() string {
+	switch a.Elem.(type) {
+	case *FunctionType, *UnionType:
+		return "(" + a.Elem.String() + ")[]"
+	}
+	return a.Elem.String() + "[]"
+}
+
+// UnionType is a union of types such as string | number. Types holds two
+// or more types, none of which is itself a union.
+type UnionType struct {
+	Loc
+	Types []TypeExpr
+}
+
+func (u *UnionType) typeNode() {}
+func (u *UnionType) String() string {
+	types := make([]string, len(u.Types))
+	for i, t := range u.Types {
+		types[i] = t.String()
+		if _, ok := t.(*FunctionType); ok {
+			types[i] = "(" + types[i] + ")"
+		}
+	}
+	return strings.Join(types, " | ")
+}
+
+// LiteralType is a string literal type such as "circle", which only the
+// string itself belongs to.
+type LiteralType struct {
+	Token token.Token
+}
+
+func (l *LiteralType) typeNode()           {}
+func (l *LiteralType) Pos() token.Position { return l.Token.Pos }
+func (l *LiteralType) End() token.Position { return l.Token.End }
+func (l *LiteralType) String() string {
+	return strconv.Quote(l.Token.Literal)
+}
+
+// ElementType returns the element type of an array type, whether written as
+// T[] or Array<T>, and nil for any other type.
+func ElementType(t TypeExpr) TypeExpr {
+	switch t := t.(type) {
+	case *ArrayType:
+		return t.Elem
+	case *TypeReference:
+		if t.Name.Literal == "Array" && len(t.Args) == 1 {
+			return t.Args[0]
+		}
+	}
+	return nil
+}
+
+func typeString(t TypeExpr) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+type ArrayLiteral struct {
+	Loc
+	Elements []Expression
+}
+
+func (a *ArrayLiteral) expressionNode() {}
+func (a *ArrayLiteral) String() string {
+	var elems []string
+	for _, elem := range a.Elements {
+		elems = append(elems, elem.String())
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elems, ", "))
+}
+
+// IndexExpression is an element access such as xs[0], or xs?.[0] if
+// Optional is set.
+type IndexExpression struct {
+	Loc
+	Left     Expression
+	Index    Expression
+	Optional bool
+}
+
+func (i *IndexExpression) expressionNode() {}
+func (i *IndexExpression) String() string {
+	if i.Optional {
+		return fmt.Sprintf("%s?.[%s]", i.Left.String(), i.Index.String())
+	}
+	return fmt.Sprintf("%s[%s]", i.Left.String(), i.Index.String())
+}
+
+// MemberExpression is a property access such as xs.length. An optional one,
+// such as p?.name, evaluates to undefined if the object is null or
+// undefined, and so does the rest of the chain of accesses and calls it
+// starts.
+type MemberExpression struct {
+	Object   Expression
+	Property *Identifier
+	Optional bool
+}
+
+func (m *MemberExpression) expressionNode()     {}
+func (m *MemberExpression) Pos() token.Position { return m.Object.Pos() }
+func (m *MemberExpression) End() token.Position { return m.Property.End() }
+func (m *MemberExpression) String() string {
+	if m.Optional {
+		return fmt.Sprintf("%s?.%s", m.Object.String(), m.Property.String())
+	}
+	return fmt.Sprintf("%s.%s", m.Object.String(), m.Property.String())
+}
+
+// IsOptionalChain reports whether expr is an optional property access,
+// element access or call, or follows one in the same chain, so that it
+// evaluates to undefined when an optional link is null or undefined.
+func IsOptionalChain(expr Expression) bool {
+	for {
+		switch e := expr.(type) {
+		case *MemberExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Object
+		case *IndexExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Left
+		case *FunctionCallExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Callee
+		case *NonNullExpression:
+			expr = e.Expression
+		default:
+			return false
+		}
+	}
+}
+
+// PropertySignature declares a property of an object type, such as
+// "x?: number".
+type PropertySignature struct {
+	Trivia
+	Name     token.Token
+	Optional bool
+	Type     TypeExpr
+}
+
+func (p *PropertySignature) Pos() token.Position { return p.Name.Pos }
+func (p *PropertySignature) End() token.Position { return p.Type.End() }
+func (p *PropertySignature) String() string {
+	if p.Optional {
+		return fmt.Sprintf("%s?: %s", p.Name.Literal, p.Type.String())
+	}
+	return fmt.Sprintf("%s: %s", p.Name.Literal, p.Type.String())
+}
+
+// ObjectType is an object type literal such as { x: number; y: number }.
+type ObjectType struct {
+	Loc
+	Members []*PropertySignature
+}
+
+func (o *ObjectType) typeNode() {}
+func (o *ObjectType) String() string {
+	var members []string
+	for _, m := range o.Members {
+		members = append(members, m.String())
+	}
+	if len(members) == 0 {
+		return "{}"
+	}
+	return fmt.Sprintf("{ %s }", strings.Join(members, "; "))
+}
+
+// Member returns the member of o called name, or nil if there is none.
+func (o *ObjectType) Member(name string) *PropertySignature {
+	for _, m := range o.Members {
+		if m.Name.Literal == name {
+			return m
+		}
+	}
+	return nil
+}
+
+type InterfaceDeclaration struct {
+	Loc
+	Trivia
+	Name       token.Token
+	TypeParams []*TypeParam
+	Type       *ObjectType
+}
+
+func (i *InterfaceDeclaration) statementNode() {}
+func (i *InterfaceDeclaration) String() string {
+	return fmt.Sprintf("interface %s%s %s", i.Name.Literal, typeParamsString(i.TypeParams), i.Type.String())
+}
+
+// TypeAliasDeclaration gives a name to a type, as in "type Point = { ... }".
+type TypeAliasDeclaration struct {
+	Loc
+	Trivia
+	Name       token.Token
+	TypeParams []*TypeParam
+	Type       TypeExpr
+}
+
+func (t *TypeAliasDeclaration) statementNode() {}
+func (t *TypeAliasDeclaration) String() string {
+	return fmt.Sprintf("type %s%s = %s", t.Name.Literal, typeParamsString(t.TypeParams), t.Type.String())
+}
+
+// Property is a key-value pair of an object literal. Shorthand properties
+// like { x } have a Value referring to the variable of the same name.
+type Property struct {
+	Key   token.Token
+	Value Expression
+}
+
+func (p *Property) Pos() token.Position { return p.Key.Pos }
+func (p *Property) End() token.Position { return p.Value.End() }
+func (p *Property) String() string {
+	return fmt.Sprintf("%s: %s", p.Key.Literal, p.Value.String())
+}
+
+type ObjectLiteral struct {
+	Loc
+	Properties []*Property
+}
+
+func (o *ObjectLiteral) expressionNode() {}
+func (o *ObjectLiteral) String() string {
+	var props []string
+	for _, p := range o.Properties {
+		props = append(props, p.String())
+	}
+	if len(props) == 0 {
+		return "{}"
+	}
+	return fmt.Sprintf("{ %s }", strings.Join(props, ", "))
+}
+
+// Modifiers are the modifiers of a class member. Access is "public",
+// "private" or "protected", or empty if the member has no access modifier,
+// which makes it public.
+type Modifiers struct {
+	Access   string
+	Static   bool
+	Readonly bool
+}
+
+func (m Modifiers) String() string {
+	var mods []string
+	if m.Access != "" {
+		mods = append(mods, m.Access)
+	}
+	if m.Static {
+		mods = append(mods, "static")
+	}
+	if m.Readonly {
+		mods = append(mods, "readonly")
+	}
+	if len(mods) == 0 {
+		return ""
+	}
+	return strings.Join(mods, " ") + " "
+}
+
+// IsPublic reports whether the member is visible outside of its class.
+func (m Modifiers) IsPublic() bool {
+	return m.Access == "" || m.Access == "public"
+}
+
+type FieldDeclaration struct {
+	Loc
+	Trivia
+	Modifiers
+	Name     token.Token
+	Optional bool
+	Type     TypeExpr
+	Value    Expression
+}
+
+func (f *FieldDeclaration) String() string {
+	optional := ""
+	if f.Optional {
+		optional = "?"
+	}
+	if f.Value == nil {
+		return fmt.Sprintf("%s%s%s: %s", f.Modifiers.String(), f.Name.Literal, optional, f.Type.String())
+	}
+	return fmt.Sprintf("%s%s%s: %s = %s", f.Modifiers.String(), f.Name.Literal, optional, f.Type.String(), f.Value.String())
+}
+
+// MethodDeclaration is a method or constructor of a class. Constructors
+// have no return type and no type parameters.
+type MethodDeclaration struct {
+	Loc
+	Trivia
+	Modifiers
+	Name       token.Token
+	TypeParams []*TypeParam
+	Params     []FunctionParam
+	Body       []Statement
+	ReturnType TypeExpr
+}
+
+func (m *MethodDeclaration) String() string {
+	var params []string
+	for _, p := range m.Params {
+		params = append(params, fmt.Sprintf("%s: %s", p.Name.Literal, p.Type.String()))
+	}
+
+	ret := ""
+	if m.ReturnType != nil {
+		ret = ": " + m.ReturnType.String()
+	}
+
+	return fmt.Sprintf("%s%s%s(%s)%s %s", m.Modifiers.String(), m.Name.Literal, typeParamsString(m.TypeParams), strings.Join(params, ", "), ret, (&BlockStatement{Statements: m.Body}).String())
+}
+
+// ClassDeclaration is a class. Extends is nil if the class has no base
+// class.
+type ClassDeclaration struct {
+	Loc
+	Trivia
+	Name        token.Token
+	TypeParams  []*TypeParam
+	Extends     *Identifier
+	Fields      []*FieldDeclaration
+	Constructor *MethodDeclaration
+	Methods     []*MethodDeclaration
+}
+
+func (c *ClassDeclaration) statementNode() {}
+func (c *ClassDeclaration) String() string {
+	var members []string
+	for _, f := range c.Fields {
+		members = append(members, f.String())
+	}
+	if c.Constructor != nil {
+		members = append(members, c.Constructor.String())
+	}
+	for _, m := range c.Methods {
+		members = append(members, m.String())
+	}
+	header := "class " + c.Name.Literal + typeParamsString(c.TypeParams)
+	if c.Extends != nil {
+		header += " extends " + c.Extends.String()
+	}
+	if len(members) == 0 {
+		return header + " {}"
+	}
+	return fmt.Sprintf("%s { %s }", header, strings.Join(members, "; "))
+}
+
+// Field returns the field of c called name, or nil if there is none.
+func (c *ClassDeclaration) Field(name string) *FieldDeclaration {
+	for _, f := range c.Fields {
+		if f.Name.Literal == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Method returns the method of c called name, or nil if there is none.
+func (c *ClassDeclaration) Method(name string) *MethodDeclaration {
+	for _, m := range c.Methods {
+		if m.Name.Literal == name {
+			return m
+		}
+	}
+	return nil
+}
+
+type ThisExpression struct {
+	Token token.Token
+}
+
+func (t *ThisExpression) expressionNode()     {}
+func (t *ThisExpression) Pos() token.Position { return t.Token.Pos }
+func (t *ThisExpression) End() token.Position { return t.Token.End }
+func (t *ThisExpression) String() string      { return "this" }
+
+// SuperExpression refers to the base class in super(...) calls and
+// super.method() calls.
+type SuperExpression struct {
+	Token token.Token
+}
+
+func (s *SuperExpression) expressionNode()     {}
+func (s *SuperExpression) Pos() token.Position { return s.Token.Pos }
+func (s *SuperExpression) End() token.Position { return s.Token.End }
+func (s *SuperExpression) String() string      { return "super" }
+
+// NewExpression creates an instance of a class, as in new Point(1, 2).
+// TypeArgs are the explicit type arguments of a generic class, as in
+// new Stack<number>().
+type NewExpression struct {
+	Loc
+	Class    *Identifier
+	TypeArgs []TypeExpr
+	Args     []Expression
+}
+
+func (n *NewExpression) expressionNode() {}
+func (n *NewExpression) String() string {
+	var args []string
+	for _, a := range n.Args {
+		args = append(args, a.String())
+	}
+	return fmt.Sprintf("new %s%s(%s)", n.Class.String(), typeArgsString(n.TypeArgs), strings.Join(args, ", "))
+}
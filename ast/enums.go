@@ -0,0 +1,190 @@
+package ast
+
+import (
+	"fmt"
+	"go/constant"
+	gotoken "go/token"
+	"strings"
+
+	"github.com/toyaAoi/sild/token"
+)
+
+// EnumMember is a member of an enum. Value is nil if the member takes the
+// value following that of the member before it.
+type EnumMember struct {
+	Trivia
+	Name  token.Token
+	Value Expression
+}
+
+func (m *EnumMember) Pos() token.Position { return m.Name.Pos }
+func (m *EnumMember) End() token.Position {
+	if m.Value != nil {
+		return m.Value.End()
+	}
+	return m.Name.End
+}
+func (m *EnumMember) String() string {
+	if m.Value == nil {
+		return m.Name.Literal
+	}
+	return fmt.Sprintf("%s = %s", m.Name.Literal, m.Value.String())
+}
+
+// EnumDeclaration is an enum, as in enum Color { Red, Green }. The members
+// of a const enum are inlined where they are used.
+type EnumDeclaration struct {
+	Loc
+	Trivia
+	Const   bool
+	Name    token.Token
+	Members []*EnumMember
+}
+
+func (e *EnumDeclaration) statementNode() {}
+func (e *EnumDeclaration) String() string {
+	var members []string
+	for _, m := range e.Members {
+		members = append(members, m.String())
+	}
+	header := "enum " + e.Name.Literal
+	if e.Const {
+		header = "const " + header
+	}
+	if len(members) == 0 {
+		return header + " {}"
+	}
+	return fmt.Sprintf("%s { %s }", header, strings.Join(members, ", "))
+}
+
+// Member returns the member of e called name, or nil if there is none.
+func (e *EnumDeclaration) Member(name string) *EnumMember {
+	for _, m := range e.Members {
+		if m.Name.Literal == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// EnumValues returns the values of the members of e, which are numbers or
+// strings. A member without an initializer takes the value of the member
+// before it plus one, or 0 if it is the first member. The value of a member
+// whose initializer isn't a constant expression, or that follows such a
+// member or a string member without an initializer of its own, is unknown.
+// Initializers may refer to the members before them by name, as in A * 2,
+// or through the enum, as in E.A.
+func EnumValues(e *EnumDeclaration) []constant.Value {
+	values := make([]constant.Value, len(e.Members))
+	known := map[string]constant.Value{}
+	for i, m := range e.Members {
+		switch {
+		case m.Value != nil:
+			values[i] = enumValue(e, m.Value, known)
+		case i == 0:
+			values[i] = constant.MakeInt64(0)
+		case values[i-1].Kind() == constant.Int || values[i-1].Kind() == constant.Float:
+			values[i] = constant.BinaryOp(values[i-1], gotoken.ADD, constant.MakeInt64(1))
+		default:
+			values[i] = constant.MakeUnknown()
+		}
+		known[m.Name.Literal] = values[i]
+	}
+	return values
+}
+
+// enumValue evaluates the initializer of an enum member with JavaScript
+// semantics: numbers are floats, stored as integers when they have no
+// fractional part, and + concatenates strings.
+func enumValue(e *EnumDeclaration, expr Expression, known map[string]constant.Value) constant.Value {
+	unknown := constant.MakeUnknown()
+
+	switch x := expr.(type) {
+	case *NumberLiteral:
+		lit := strings.ToLower(x.Token.Literal)
+		if v := constant.MakeFromLiteral(lit, gotoken.INT, 0); v.Kind() == constant.Int {
+			return v
+		}
+		return integral(constant.MakeFromLiteral(lit, gotoken.FLOAT, 0))
+	case *StringLiteral:
+		return constant.MakeString(x.Token.Literal)
+	case *ParenthesizedExpression:
+		return enumValue(e, x.Expression, known)
+	case *VariableExpression:
+		if v, ok := known[x.Token.Literal]; ok {
+			return v
+		}
+	case *MemberExpression:
+		if obj, ok := x.Object.(*VariableExpression); ok && obj.Token.Literal == e.Name.Literal && !x.Optional {
+			if v, ok := known[x.Property.String()]; ok {
+				return v
+			}
+		}
+	case *UnaryExpression:
+		v := enumValue(e, x.Right, known)
+		if !isNumberValue(v) {
+			return unknown
+		}
+		switch x.Operator.Type {
+		case token.MINUS:
+			return constant.UnaryOp(gotoken.SUB, v, 0)
+		case token.PLUS:
+			return v
+		}
+	case *BinaryExpression:
+		l, r := enumValue(e, x.Left, known), enumValue(e, x.Right, known)
+		if x.Operator.Type == token.PLUS && (l.Kind() == constant.String || r.Kind() == constant.String) {
+			if l.Kind() == constant.Unknown || r.Kind() == constant.Unknown {
+				return unknown
+			}
+			return constant.MakeString(valueString(l) + valueString(r))
+		}
+		if !isNumberValue(l) || !isNumberValue(r) {
+			return unknown
+		}
+		switch x.Operator.Type {
+		case token.PLUS:
+			return integral(constant.BinaryOp(l, gotoken.ADD, r))
+		case token.MINUS:
+			return integral(constant.BinaryOp(l, gotoken.SUB, r))
+		case token.MUL:
+			return integral(constant.BinaryOp(l, gotoken.MUL, r))
+		case token.DIV:
+			if constant.Sign(r) == 0 {
+				return unknown
+			}
+			return integral(constant.BinaryOp(constant.ToFloat(l), gotoken.QUO, constant.ToFloat(r)))
+		case token.MOD:
+			// Go only has remainders of integers
+			if l.Kind() == constant.Int && r.Kind() == constant.Int && constant.Sign(r) != 0 {
+				return constant.BinaryOp(l, gotoken.REM, r)
+			}
+		}
+	}
+	return unknown
+}
+
+func isNumberValue(v constant.Value) bool {
+	return v.Kind() == constant.Int || v.Kind() == constant.Float
+}
+
+// integral returns v as an integer if it is a number without a fractional
+// part.
+func integral(v constant.Value) constant.Value {
+	if i := constant.ToInt(v); i.Kind() == constant.Int {
+		return i
+	}
+	return v
+}
+
+// valueString converts an enum value to a string the way JavaScript does.
+func valueString(v constant.Value) string {
+	switch v.Kind() {
+	case constant.String:
+		return constant.StringVal(v)
+	case constant.Int:
+		return v.ExactString()
+	}
+	f, _ := constant.Float64Val(v)
+	return fmt.Sprint(f)
+}
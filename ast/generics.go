@@ -0,0 +1,234 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toyaAoi/sild/token"
+)
+
+// TypeParam is a type parameter of a generic function, class, interface or
+// type alias, as in <T extends Comparable<T>>. Constraint is nil if the
+// parameter has no extends clause.
+type TypeParam struct {
+	Name       token.Token
+	Constraint TypeExpr
+}
+
+func (t *TypeParam) Pos() token.Position { return t.Name.Pos }
+func (t *TypeParam) End() token.Position {
+	if t.Constraint == nil {
+		return t.Name.End
+	}
+	return t.Constraint.End()
+}
+
+func (t *TypeParam) String() string {
+	if t.Constraint == nil {
+		return t.Name.Literal
+	}
+	return t.Name.Literal + " extends " + t.Constraint.String()
+}
+
+// typeParamsString formats a type parameter list, or returns "" if there is
+// none.
+func typeParamsString(params []*TypeParam) string {
+	if len(params) == 0 {
+		return ""
+	}
+	list := make([]string, len(params))
+	for i, p := range params {
+		list[i] = p.String()
+	}
+	return "<" + strings.Join(list, ", ") + ">"
+}
+
+// typeArgsString formats a type argument list, or returns "" if there is
+// none.
+func typeArgsString(args []TypeExpr) string {
+	if len(args) == 0 {
+		return ""
+	}
+	list := make([]string, len(args))
+	for i, a := range args {
+		list[i] = a.String()
+	}
+	return fmt.Sprintf("<%s>", strings.Join(list, ", "))
+}
+
+// TypeArgs maps the names of params to the corresponding types of args.
+// Parameters without an argument are left out; a nil argument stands for
+// an unknown type.
+func TypeArgs(params []*TypeParam, args []TypeExpr) map[string]TypeExpr {
+	m := make(map[string]TypeExpr, len(params))
+	for i, p := range params {
+		if i < len(args) {
+			m[p.Name.Literal] = args[i]
+		}
+	}
+	return m
+}
+
+// Substitute returns t with the references to the type parameters in args
+// replaced by their type arguments. Parts of t that don't refer to any of
+// them are shared with t.
+func Substitute(t TypeExpr, args map[string]TypeExpr) TypeExpr {
+	if len(args) == 0 {
+		return t
+	}
+
+	switch typ := t.(type) {
+	case *TypeReference:
+		if len(typ.Args) == 0 {
+			if arg, ok := args[typ.Name.Literal]; ok {
+				return arg
+			}
+			return t
+		}
+		ref := *typ
+		ref.Args = make([]TypeExpr, len(typ.Args))
+		for i, a := range typ.Args {
+			ref.Args[i] = Substitute(a, args)
+		}
+		return &ref
+	case *ArrayType:
+		return &ArrayType{Loc: typ.Loc, Elem: Substitute(typ.Elem, args)}
+	case *FunctionType:
+		return &FunctionType{Loc: typ.Loc, Params: SubstituteParams(typ.Params, args), ReturnType: Substitute(typ.ReturnType, args)}
+	case *ObjectType:
+		obj := &ObjectType{Loc: typ.Loc, Members: make([]*PropertySignature, len(typ.Members))}
+		for i, m := range typ.Members {
+			member := *m
+			member.Type = Substitute(m.Type, args)
+			obj.Members[i] = &member
+		}
+		return obj
+	case *UnionType:
+		// type arguments that are unions themselves are flattened
+		union := &UnionType{Loc: typ.Loc}
+		for _, m := range typ.Types {
+			m = Substitute(m, args)
+			if u, ok := m.(*UnionType); ok {
+				union.Types = append(union.Types, u.Types...)
+			} else {
+				union.Types = append(union.Types, m)
+			}
+		}
+		return union
+	}
+	return t
+}
+
+// SubstituteParams returns params with their types substituted like
+// Substitute does.
+func SubstituteParams(params []FunctionParam, args map[string]TypeExpr) []FunctionParam {
+	if len(args) == 0 {
+		return params
+	}
+	subst := make([]FunctionParam, len(params))
+	for i, p := range params {
+		subst[i] = FunctionParam{Name: p.Name, Type: Substitute(p.Type, args)}
+	}
+	return subst
+}
+
+// Mentions reports whether t refers to the type called name.
+func Mentions(t TypeExpr, name string) bool {
+	switch typ := t.(type) {
+	case *TypeReference:
+		if typ.Name.Literal == name {
+			return true
+		}
+		for _, a := range typ.Args {
+			if Mentions(a, name) {
+				return true
+			}
+		}
+	case *ArrayType:
+		return Mentions(typ.Elem, name)
+	case *FunctionType:
+		for _, p := range typ.Params {
+			if Mentions(p.Type, name) {
+				return true
+			}
+		}
+		return Mentions(typ.ReturnType, name)
+	case *ObjectType:
+		for _, m := range typ.Members {
+			if Mentions(m.Type, name) {
+				return true
+			}
+		}
+	case *UnionType:
+		for _, m := range typ.Types {
+			if Mentions(m, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InferTypeArgs infers type arguments for the type parameters in args from
+// matching param, a type that may refer to them, against arg, the type of
+// the value passed for it. Type parameters get the first type they are
+// matched against; those already inferred are left alone. resolve expands
+// the names of arg that param doesn't match as they are, such as type
+// aliases.
+func InferTypeArgs(param, arg TypeExpr, args map[string]TypeExpr, params []*TypeParam, resolve func(TypeExpr) TypeExpr) {
+	if param == nil || arg == nil {
+		return
+	}
+
+	if ref, ok := param.(*TypeReference); ok && len(ref.Args) == 0 {
+		for _, p := range params {
+			if p.Name.Literal == ref.Name.Literal {
+				if _, ok := args[p.Name.Literal]; !ok {
+					args[p.Name.Literal] = arg
+				}
+				return
+			}
+		}
+	}
+
+	if elem := ElementType(param); elem != nil {
+		if argElem := ElementType(resolve(arg)); argElem != nil {
+			InferTypeArgs(elem, argElem, args, params, resolve)
+		}
+		return
+	}
+
+	switch p := param.(type) {
+	case *TypeReference:
+		if a, ok := arg.(*TypeReference); ok && a.Name.Literal == p.Name.Literal && len(a.Args) == len(p.Args) {
+			for i := range p.Args {
+				InferTypeArgs(p.Args[i], a.Args[i], args, params, resolve)
+			}
+			return
+		}
+		if rp := resolve(param); rp != param {
+			InferTypeArgs(rp, arg, args, params, resolve)
+		} else if a := resolve(arg); a != arg {
+			InferTypeArgs(param, a, args, params, resolve)
+		}
+	case *FunctionType:
+		a, ok := resolve(arg).(*FunctionType)
+		if !ok {
+			return
+		}
+		for i := range min(len(p.Params), len(a.Params)) {
+			InferTypeArgs(p.Params[i].Type, a.Params[i].Type, args, params, resolve)
+		}
+		InferTypeArgs(p.ReturnType, a.ReturnType, args, params, resolve)
+	case *ObjectType:
+		a, ok := resolve(arg).(*ObjectType)
+		if !ok {
+			return
+		}
+		for _, m := range p.Members {
+			if am := a.Member(m.Name.Literal); am != nil {
+				InferTypeArgs(m.Type, am.Type, args, params, resolve)
+			}
+		}
+	}
+}
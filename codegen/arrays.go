@@ -0,0 +1,206 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// generateArrayLiteral generates a pointer to a slice literal. The element
+// type comes from the expected type where there is one, and from the first
+// element otherwise.
+func (g *Generator) generateArrayLiteral(array *ast.ArrayLiteral, expected ast.TypeExpr) string {
+	elem := ast.ElementType(expected)
+	if elem == nil {
+		elem = ast.ElementType(g.typeOf(array))
+	}
+
+	elemType := "any"
+	if elem != nil {
+		elemType = g.goType(elem)
+	}
+
+	elems := make([]string, len(array.Elements))
+	for i, e := range array.Elements {
+		elems[i] = g.generateExpressionAs(e, elem)
+	}
+
+	return fmt.Sprintf("&[]%s{%s}", elemType, strings.Join(elems, ", "))
+}
+
+// generateArrayMethodCall lowers a call of an Array.prototype method to Go,
+// either inline or through a runtime helper. Methods that modify the array
+// or return it take a pointer to the slice, and the others the slice itself.
+// It reports false if the call can't be lowered, for instance because of an
+// unsupported number of arguments.
+func (g *Generator) generateArrayMethodCall(receiver ast.Expression, receiverType ast.TypeExpr, method string, callArgs []ast.Expression) (string, bool) {
+	elem := ast.ElementType(receiverType)
+	ptr := g.generateExpression(receiver)
+	xs := deref(ptr)
+
+	// the accumulator of reduce starts with the initial value, if any
+	acc := elem
+	if method == "reduce" && len(callArgs) == 2 {
+		acc = g.typeOf(callArgs[1])
+	}
+
+	// callbacks of the iteration methods taking the index of the element
+	// are called through the Indexed variants of the helpers
+	indexed := false
+	if len(callArgs) > 0 && method != "reduce" && method != "sort" {
+		if fn := g.funcType(g.typeOf(callArgs[0])); fn != nil && len(fn.Params) > 1 {
+			indexed = true
+		}
+	}
+	suffix := ""
+	if indexed {
+		suffix = "Indexed"
+	}
+
+	args := make([]string, len(callArgs))
+	for i, arg := range callArgs {
+		if callback := callbackType(method, elem, acc, indexed); callback != nil && i == 0 {
+			args[i] = g.generateExpressionAs(arg, callback)
+			continue
+		}
+		switch method {
+		case "push", "includes":
+			args[i] = g.generateExpressionAs(arg, elem)
+		case "indexOf":
+			if i == 0 {
+				args[i] = g.generateExpressionAs(arg, elem)
+			} else {
+				args[i] = g.generateIndex(arg)
+			}
+		case "slice":
+			args[i] = g.generateIndex(arg)
+		case "concat":
+			args[i] = g.generateExpressionAs(arg, receiverType)
+		default:
+			args[i] = g.generateExpression(arg)
+		}
+	}
+	argList := strings.Join(args, ", ")
+
+	switch {
+	case method == "push" && len(args) > 0:
+		g.useHelper("sildPush")
+		return fmt.Sprintf("sildPush(%s, %s)", ptr, argList), true
+	// pop and find return undefined as nil, through a pointer if the
+	// elements can't be nil
+	case method == "pop" && len(args) == 0 && g.nilable(elem):
+		g.useHelper("sildPop")
+		return fmt.Sprintf("sildPop(%s)", ptr), true
+	case method == "pop" && len(args) == 0:
+		g.useHelper("sildPopPtr")
+		return fmt.Sprintf("sildPopPtr(%s)", ptr), true
+	case method == "slice" && len(args) <= 2:
+		g.useHelper("sildSlice")
+		return helperCall("sildSlice", xs, args), true
+	case method == "concat":
+		g.useHelper("sildConcat")
+		return helperCall("sildConcat", ptr, args), true
+	case method == "indexOf" && len(args) == 1:
+		g.use("slices")
+		return fmt.Sprintf("slices.Index(%s, %s)", xs, argList), true
+	case method == "indexOf" && len(args) == 2:
+		g.useHelper("sildIndexOf")
+		return fmt.Sprintf("sildIndexOf(%s, %s)", xs, argList), true
+	// includes finds NaN, unlike indexOf
+	case method == "includes" && len(args) == 1 && isNumber(elem):
+		g.useHelper("sildIncludes")
+		return fmt.Sprintf("sildIncludes(%s, %s)", xs, argList), true
+	case method == "includes" && len(args) == 1 && g.goType(elem) == "any":
+		// Go doesn't infer any from the type of the element
+		g.useHelper("sildIncludes")
+		return fmt.Sprintf("sildIncludes[any](%s, %s)", xs, argList), true
+	case method == "includes" && len(args) == 1:
+		g.use("slices")
+		return fmt.Sprintf("slices.Contains(%s, %s)", xs, argList), true
+	case method == "join" && len(args) == 1 && g.isString(elem):
+		g.use("strings")
+		return fmt.Sprintf("strings.Join(%s, %s)", xs, argList), true
+	case method == "join" && len(args) <= 1:
+		g.useHelper("sildJoin")
+		return helperCall("sildJoin", xs, args), true
+	case method == "reverse" && len(args) == 0:
+		g.useHelper("sildReverse")
+		return fmt.Sprintf("sildReverse(%s)", ptr), true
+	case method == "map" && len(args) == 1:
+		g.useHelper("sildMap" + suffix)
+		return fmt.Sprintf("sildMap%s(%s, %s)", suffix, xs, argList), true
+	case method == "filter" && len(args) == 1:
+		g.useHelper("sildFilter" + suffix)
+		return fmt.Sprintf("sildFilter%s(%s, %s)", suffix, xs, argList), true
+	case method == "reduce" && len(args) == 1:
+		g.useHelper("sildReduceFirst")
+		return fmt.Sprintf("sildReduceFirst(%s, %s)", xs, argList), true
+	case method == "reduce" && len(args) == 2:
+		g.useHelper("sildReduce")
+		return fmt.Sprintf("sildReduce(%s, %s)", xs, argList), true
+	case method == "find" && len(args) == 1 && g.nilable(elem):
+		g.useHelper("sildFind" + suffix)
+		return fmt.Sprintf("sildFind%s(%s, %s)", suffix, xs, argList), true
+	case method == "find" && len(args) == 1:
+		g.useHelper("sildFindPtr" + suffix)
+		return fmt.Sprintf("sildFindPtr%s(%s, %s)", suffix, xs, argList), true
+	case method == "some" && len(args) == 1 && indexed:
+		g.useHelper("sildSomeIndexed")
+		return fmt.Sprintf("sildSomeIndexed(%s, %s)", xs, argList), true
+	case method == "some" && len(args) == 1:
+		g.use("slices")
+		return fmt.Sprintf("slices.ContainsFunc(%s, %s)", xs, argList), true
+	case method == "every" && len(args) == 1:
+		g.useHelper("sildEvery" + suffix)
+		return fmt.Sprintf("sildEvery%s(%s, %s)", suffix, xs, argList), true
+	case method == "forEach" && len(args) == 1:
+		g.useHelper("sildForEach" + suffix)
+		return fmt.Sprintf("sildForEach%s(%s, %s)", suffix, xs, argList), true
+	case method == "sort" && len(args) == 0:
+		g.useHelper("sildSort")
+		return fmt.Sprintf("sildSort(%s)", ptr), true
+	case method == "sort" && len(args) == 1:
+		g.useHelper("sildSortFunc")
+		return fmt.Sprintf("sildSortFunc(%s, %s)", ptr, argList), true
+	}
+
+	return "", false
+}
+
+// generateArrayMethodStatement lowers array method calls whose result is
+// unused to plain Go statements where that reads better than a helper call.
+func (g *Generator) generateArrayMethodStatement(receiver ast.Expression, method string, callArgs []ast.Expression) (string, bool) {
+	receiverType := g.typeOf(receiver)
+	if !isArray(receiverType) {
+		return "", false
+	}
+	xs := deref(g.generateExpression(receiver))
+
+	switch {
+	case method == "push" && len(callArgs) > 0:
+		args := make([]string, len(callArgs))
+		for i, arg := range callArgs {
+			args[i] = g.generateExpressionAs(arg, ast.ElementType(receiverType))
+		}
+		return fmt.Sprintf("%s = append(%s, %s)", xs, xs, strings.Join(args, ", ")), true
+	case method == "reverse" && len(callArgs) == 0:
+		g.use("slices")
+		return fmt.Sprintf("slices.Reverse(%s)", xs), true
+	}
+
+	return "", false
+}
+
+// deref returns the Go expression of the slice ptr, a pointer to one, points
+// to.
+func deref(ptr string) string {
+	if strings.HasPrefix(ptr, "&") {
+		return ptr[1:]
+	}
+	return "*" + ptr
+}
+
+func helperCall(name, receiver string, args []string) string {
+	return fmt.Sprintf("%s(%s)", name, strings.Join(append([]string{receiver}, args...), ", "))
+}
@@ -0,0 +1,92 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// bigIntMethods maps the arithmetic operators, and the assignment operators
+// combined with them, to the big.Int methods computing them. Quo and Rem
+// truncate towards zero like bigint division and remainder do.
+var bigIntMethods = map[token.TokenType]string{
+	token.PLUS:         "Add",
+	token.MINUS:        "Sub",
+	token.MUL:          "Mul",
+	token.DIV:          "Quo",
+	token.MOD:          "Rem",
+	token.PLUS_ASSIGN:  "Add",
+	token.MINUS_ASSIGN: "Sub",
+	token.MUL_ASSIGN:   "Mul",
+	token.DIV_ASSIGN:   "Quo",
+	token.MOD_ASSIGN:   "Rem",
+}
+
+func isBigInt(t ast.TypeExpr) bool {
+	return typeName(t) == "bigint"
+}
+
+// generateBigIntLiteral generates a bigint literal as a *big.Int. Every
+// *big.Int the generated code creates is treated as immutable, so that
+// values can be shared like bigints are.
+func (g *Generator) generateBigIntLiteral(lit *ast.BigIntLiteral) string {
+	g.use("math/big")
+	digits := strings.TrimSuffix(lit.Token.Literal, "n")
+	if _, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), 0, 64); err == nil {
+		return fmt.Sprintf("big.NewInt(%s)", digits)
+	}
+	g.useHelper("sildBigInt")
+	return fmt.Sprintf("sildBigInt(%s)", strconv.Quote(digits))
+}
+
+// generateBigIntArithmetic lowers an arithmetic operation on two bigints to
+// a big.Int method call, reporting false if e isn't one.
+func (g *Generator) generateBigIntArithmetic(e *ast.BinaryExpression) (string, bool) {
+	method, ok := bigIntMethods[e.Operator.Type]
+	if !ok || !isBigInt(g.typeOf(e.Left)) || !isBigInt(g.typeOf(e.Right)) {
+		return "", false
+	}
+	return fmt.Sprintf("new(big.Int).%s(%s, %s)", method, g.generateExpression(e.Left), g.generateExpression(e.Right)), true
+}
+
+// generateBigIntComparison lowers the comparison of a bigint with a bigint
+// or a number to a comparison of the result of Cmp with 0, reporting false
+// if e isn't one.
+func (g *Generator) generateBigIntComparison(e *ast.BinaryExpression) (string, bool) {
+	switch e.Operator.Type {
+	case token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL,
+		token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+	default:
+		return "", false
+	}
+
+	left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+	switch {
+	case isBigInt(left) && isBigInt(right):
+		return fmt.Sprintf("%s.Cmp(%s) %s 0", g.generateExpression(e.Left), g.generateExpression(e.Right), goOperator(e.Operator)), true
+	case isBigInt(left) && isNumber(right):
+		g.useHelper("sildCompareBig")
+		return fmt.Sprintf("sildCompareBig(%s, %s) %s 0", g.generateExpression(e.Left), g.generateNumber(e.Right, false), goOperator(e.Operator)), true
+	case isNumber(left) && isBigInt(right):
+		// compare the other way round
+		op := map[token.TokenType]string{token.LESS: ">", token.LESS_EQUAL: ">=", token.GREATER: "<", token.GREATER_EQUAL: "<="}[e.Operator.Type]
+		g.useHelper("sildCompareBig")
+		return fmt.Sprintf("sildCompareBig(%s, %s) %s 0", g.generateExpression(e.Right), g.generateNumber(e.Left, false), op), true
+	}
+	return "", false
+}
+
+// generateBigIntAssignment lowers an assignment combined with an arithmetic
+// operator, or an increment or decrement, of a bigint to an assignment of a
+// new *big.Int, reporting false if target isn't a bigint.
+func (g *Generator) generateBigIntAssignment(target ast.Expression, op token.TokenType, value string) (string, bool) {
+	method, ok := bigIntMethods[op]
+	if !ok || !isBigInt(g.typeOf(target)) {
+		return "", false
+	}
+	t := g.generateExpression(target)
+	return fmt.Sprintf("%s = new(big.Int).%s(%s, %s)", t, method, t, value), true
+}
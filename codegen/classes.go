@@ -0,0 +1,379 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// generateClassDeclaration lowers a class to a struct holding its instance
+// fields, package-level variables and functions for its static members, a
+// constructor function NewX returning a pointer to the struct, and methods
+// with pointer receivers. Private and protected members are unexported.
+func (g *Generator) generateClassDeclaration(class *ast.ClassDeclaration) string {
+	builder := strings.Builder{}
+
+	outer := g.class
+	g.class = class
+	defer func() { g.class = outer }()
+	outerParams := g.declareTypeParams(class.TypeParams)
+	defer func() { g.typeParams = outerParams }()
+
+	var fields []*ast.FieldDeclaration
+	for _, f := range class.Fields {
+		if !f.Static {
+			fields = append(fields, f)
+		}
+	}
+	builder.WriteString(g.generateMethodsInterface(class))
+	builder.WriteString(generateComments(class.Leading, g.goName(class.Name.Literal)))
+	builder.WriteString(fmt.Sprintf("type %s%s %s\n", g.goName(class.Name.Literal), g.generateTypeParams(class.TypeParams), g.classStructType(class, fields)))
+
+	for _, f := range class.Fields {
+		if !f.Static {
+			continue
+		}
+		name := g.staticName(class, f.Modifiers, f.Name.Literal)
+		decl := fmt.Sprintf("var %s %s", name, g.fieldDeclType(f))
+		if f.Value != nil {
+			decl += " = " + g.generateFieldValue(f.Value, f.Type, f.Optional)
+		}
+		builder.WriteString("\n" + generateComments(f.Leading, name) + withTrailingComment(decl, f.Trailing) + "\n")
+	}
+
+	builder.WriteString("\n" + g.generateConstructor(class, fields))
+
+	for _, m := range class.Methods {
+		var header, name string
+		if m.Static {
+			name = g.staticName(class, m.Modifiers, m.Name.Literal)
+			header = "func " + name
+		} else {
+			name = memberName(m.Modifiers, m.Name.Literal)
+			header = fmt.Sprintf("func (this *%s%s) %s", g.goName(class.Name.Literal), g.generateTypeArgs(class.TypeParams), name)
+		}
+		if len(m.TypeParams) > 0 {
+			g.errorf(m, "cannot translate generic method '%s' of '%s': Go methods can't have type parameters", m.Name.Literal, class.Name.Literal)
+		}
+
+		g.pushScope()
+		if !m.Static {
+			g.declare("this", classType(class))
+		}
+		method := g.generateFunction(header, m.Params, m.ReturnType, m.Body)
+		builder.WriteString("\n" + generateComments(m.Leading, name) + withTrailingComment(method, m.Trailing))
+		g.popScope()
+	}
+
+	return builder.String()
+}
+
+// classStructType generates the struct of a class, which embeds its base
+// class and holds the self field of its overridden methods, if any.
+func (g *Generator) classStructType(class *ast.ClassDeclaration, fields []*ast.FieldDeclaration) string {
+	var names, types []string
+	if base := g.bases[class]; base != nil {
+		names = append(names, "*"+g.goName(base.Name.Literal))
+		types = append(types, "")
+	}
+	if len(g.virtualMethods(class)) > 0 {
+		names = append(names, selfField(class))
+		types = append(types, g.methodsInterface(class))
+	}
+	// the fields of class follow the embedded base and self field
+	first := len(names)
+	for _, f := range fields {
+		names = append(names, memberName(f.Modifiers, f.Name.Literal))
+		types = append(types, g.fieldDeclType(f))
+	}
+	if len(names) == 0 {
+		return "struct{}"
+	}
+
+	nameWidth := 0
+	for i := range names {
+		if types[i] != "" {
+			nameWidth = max(nameWidth, len(names[i]))
+		}
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("struct {\n")
+	for i := range names {
+		if types[i] == "" {
+			builder.WriteString(indent + names[i] + "\n")
+			continue
+		}
+		if i < first {
+			builder.WriteString(fmt.Sprintf("%s%-*s %s\n", indent, nameWidth, names[i], types[i]))
+			continue
+		}
+		f := fields[i-first]
+		builder.WriteString(indentComments(f.Leading, names[i]))
+		builder.WriteString(withTrailingComment(fmt.Sprintf("%s%-*s %s", indent, nameWidth, names[i], types[i]), f.Trailing) + "\n")
+	}
+	builder.WriteString("}")
+
+	return builder.String()
+}
+
+func (g *Generator) fieldDeclType(f *ast.FieldDeclaration) string {
+	if f.Optional {
+		return g.goType(g.nullable(f.Type))
+	}
+	return g.goType(f.Type)
+}
+
+// generateConstructor generates the function creating an instance of class,
+// which initializes the fields that have an initializer before running the
+// body of the constructor, if the class has one. The embedded instance of
+// the base class is created by the super call of the body, or if the class
+// doesn't declare a constructor, by passing on the arguments.
+func (g *Generator) generateConstructor(class *ast.ClassDeclaration, fields []*ast.FieldDeclaration) string {
+	if g.hasSelf(class) {
+		return g.generateSelfConstructor(class)
+	}
+
+	var inits []string
+	for _, f := range fields {
+		if f.Value != nil {
+			inits = append(inits, fmt.Sprintf("%s: %s", memberName(f.Modifiers, f.Name.Literal), g.generateFieldValue(f.Value, f.Type, f.Optional)))
+		}
+	}
+
+	base := g.bases[class]
+	params := g.constructorParams(class)
+	var body []Statement
+	if class.Constructor != nil {
+		body = class.Constructor.Body
+	}
+
+	self := g.goName(class.Name.Literal) + g.generateTypeArgs(class.TypeParams)
+	prologue := []string{fmt.Sprintf("this := &%s{%s}", self, strings.Join(inits, ", "))}
+	switch {
+	case base == nil:
+		prologue = append(prologue, g.generateSelfAssignments(class)...)
+	case class.Constructor == nil:
+		args := make([]string, len(params))
+		for i, p := range params {
+			args[i] = g.goName(p.Name.Literal)
+		}
+		prologue = append(prologue, fmt.Sprintf("this.%s = %s(%s)", g.goName(base.Name.Literal), g.constructorName(base), strings.Join(args, ", ")))
+		prologue = append(prologue, g.generateSelfAssignments(class)...)
+	default:
+		supers := 0
+		for _, stmt := range body {
+			if isSuperCall(stmt) {
+				supers++
+			}
+		}
+		if supers != 1 {
+			g.errorf(class.Constructor, "constructors for derived classes must contain exactly one 'super' call as a statement of their body")
+		}
+	}
+
+	builder := strings.Builder{}
+	if class.Constructor != nil {
+		builder.WriteString(generateComments(class.Constructor.Leading, g.constructorName(class)))
+	}
+	builder.WriteString(fmt.Sprintf("func %s%s(%s) *%s {\n", g.constructorName(class), g.generateTypeParams(class.TypeParams), g.generateParams(params), self))
+	for _, stmt := range prologue {
+		builder.WriteString(indent + stmt + "\n")
+	}
+
+	g.pushScope()
+	g.declare("this", classType(class))
+	g.constructing = true
+	builder.WriteString(g.generateFunctionBody(params, nil, body))
+	g.constructing = false
+	g.popScope()
+
+	builder.WriteString(indent + "return this\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+func (g *Generator) constructorName(class *ast.ClassDeclaration) string {
+	name := g.goName(class.Name.Literal)
+	if isExported(name) {
+		return "New" + name
+	}
+	return "new" + fieldName(name)
+}
+
+// classType returns the type of the instances of class, which inside a
+// generic class are instances for its own type parameters.
+func classType(class *ast.ClassDeclaration) ast.TypeExpr {
+	ref := &ast.TypeReference{Name: token.Token{Type: token.IDENT, Literal: class.Name.Literal}}
+	for _, p := range class.TypeParams {
+		ref.Args = append(ref.Args, &ast.TypeReference{Name: p.Name})
+	}
+	return ref
+}
+
+// memberName returns the Go name of a class member, which is only exported
+// if the member is public.
+func memberName(mods ast.Modifiers, name string) string {
+	if mods.IsPublic() {
+		return fieldName(name)
+	}
+	return mangleName(unexportedName(name))
+}
+
+// staticName returns the name of the package-level variable or function a
+// static member is lowered to, which is prefixed by the name of its class.
+func (g *Generator) staticName(class *ast.ClassDeclaration, mods ast.Modifiers, name string) string {
+	className := g.goName(class.Name.Literal)
+	if mods.IsPublic() && isExported(className) {
+		return className + fieldName(name)
+	}
+	return unexportedName(className) + fieldName(name)
+}
+
+func unexportedName(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func isExported(name string) bool {
+	return unicode.IsUpper([]rune(name)[0])
+}
+
+// classOf returns the class t refers to, or nil if t isn't a class type.
+func (g *Generator) classOf(t ast.TypeExpr) *ast.ClassDeclaration {
+	if ref, ok := t.(*ast.TypeReference); ok && g.typeParam(t) == nil {
+		return g.classes[ref.Name.Literal]
+	}
+	return nil
+}
+
+// classMember is a member of a class accessed through a property access,
+// either on an instance or, for static members, on the class itself. owner
+// is the class declaring the member, which may be a base class of class,
+// and args are the type arguments of the instance of a generic class.
+type classMember struct {
+	class  *ast.ClassDeclaration
+	owner  *ast.ClassDeclaration
+	field  *ast.FieldDeclaration
+	method *ast.MethodDeclaration
+	args   map[string]ast.TypeExpr
+}
+
+func (m classMember) modifiers() ast.Modifiers {
+	if m.field != nil {
+		return m.field.Modifiers
+	}
+	return m.method.Modifiers
+}
+
+func (m classMember) name() string {
+	if m.field != nil {
+		return m.field.Name.Literal
+	}
+	return m.method.Name.Literal
+}
+
+func (m classMember) static() bool {
+	return m.modifiers().Static
+}
+
+// classMember resolves a property access to the class member it refers to.
+func (g *Generator) classMember(e *ast.MemberExpression) (classMember, bool) {
+	name := e.Property.String()
+
+	class := g.classOf(g.typeOf(e.Object))
+	static := false
+	if v, ok := e.Object.(*ast.VariableExpression); ok && g.lookup(v.Token.Literal) == nil {
+		if c, ok := g.classes[v.Token.Literal]; ok {
+			class = c
+			static = true
+		}
+	}
+	if class == nil {
+		return classMember{}, false
+	}
+
+	m := classMember{class: class}
+	if !static {
+		m.args = g.classArgs(g.typeOf(e.Object))
+	}
+	if f, owner := g.findField(class, name); f != nil {
+		m.field, m.owner = f, owner
+	} else if method, owner := g.findMethod(class, name); method != nil {
+		m.method, m.owner = method, owner
+	} else {
+		return classMember{}, false
+	}
+
+	if m.static() != static {
+		return classMember{}, false
+	}
+	return m, true
+}
+
+// generateClassMember generates an access to a class member, which for
+// static members doesn't involve the class at all. Overridden methods are
+// accessed through the self field of the class introducing them, unless
+// they are called through super.
+func (g *Generator) generateClassMember(e *ast.MemberExpression, m classMember) string {
+	if m.static() {
+		return g.staticName(m.owner, m.modifiers(), m.name())
+	}
+
+	object := g.generateExpression(e.Object)
+	name := memberName(m.modifiers(), m.name())
+
+	if _, isSuper := e.Object.(*ast.SuperExpression); m.method != nil && !isSuper {
+		if intro, ok := g.isVirtualCall(m.class, m.method); ok {
+			return fmt.Sprintf("%s.%s.%s", object, selfField(intro), name)
+		}
+	}
+
+	return fmt.Sprintf("%s.%s", object, name)
+}
+
+// generateSuper generates the embedded instance of the base class that
+// super refers to.
+func (g *Generator) generateSuper(e *ast.SuperExpression) string {
+	if g.class == nil || g.bases[g.class] == nil {
+		g.errorf(e, "'super' can only be referenced in a derived class")
+		return "super"
+	}
+	return "this." + g.goName(g.bases[g.class].Name.Literal)
+}
+
+// generateNewExpression generates a call of the constructor of a class. The
+// type arguments of a generic class are those of the expression, or of the
+// type expected if they are left to be inferred and it is an instance of
+// the class, or else those inferred from the arguments.
+func (g *Generator) generateNewExpression(e *ast.NewExpression, expected ast.TypeExpr) string {
+	class, ok := g.classes[e.Class.String()]
+	if !ok {
+		return fmt.Sprintf("New%s(%s)", e.Class.String(), g.generateArgs(nil, e.Args))
+	}
+	params := g.constructorParams(class)
+	if len(class.TypeParams) == 0 {
+		return fmt.Sprintf("%s(%s)", g.constructorName(class), g.generateArgs(params, e.Args))
+	}
+
+	bound := g.typeArgs[e]
+	typeArgs := g.generateCallTypeArgs(class.TypeParams, bound, params, e.Args)
+	return fmt.Sprintf("%s%s(%s)", g.constructorName(class), typeArgs, g.generateGenericArgs(params, bound, e.Args))
+}
+
+// generateUpcast generates expr, an instance of a subclass of the class
+// expected, as its embedded instance of that class. Overridden methods
+// called on it still dispatch to the subclass through its self fields.
+func (g *Generator) generateUpcast(expr ast.Expression, expected ast.TypeExpr) (string, bool) {
+	want := g.classOf(expected)
+	have := g.classOf(g.typeOf(expr))
+	if want == nil || have == nil || !g.isSubclass(have, want) {
+		return "", false
+	}
+	return g.generateExpression(expr) + "." + g.goName(want.Name.Literal), true
+}
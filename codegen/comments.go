@@ -0,0 +1,201 @@
+package codegen
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// generateCommented generates stmt preceded by the comments on the lines
+// before it and followed by those on its last line.
+func (g *Generator) generateCommented(stmt Statement) string {
+	code := g.generateStatement(stmt)
+	c, ok := stmt.(ast.Commented)
+	if !ok {
+		return code
+	}
+
+	leading := c.Comments().Leading
+	if _, ok := stmt.(*ast.ClassDeclaration); ok {
+		// written by generateClassDeclaration in front of the struct, which
+		// the interface of its overridden methods may precede
+		leading = nil
+	}
+	return generateComments(leading, g.declName(stmt)) + withTrailingComment(code, c.Comments().Trailing)
+}
+
+// declName returns the Go name of the function or type stmt declares, or ""
+// if it doesn't declare one.
+func (g *Generator) declName(stmt Statement) string {
+	switch s := stmt.(type) {
+	case *ast.FunctionDeclaration:
+		return g.goName(s.Name.Literal)
+	case *ast.InterfaceDeclaration:
+		return g.goName(s.Name.Literal)
+	case *ast.TypeAliasDeclaration:
+		return g.goName(s.Name.Literal)
+	case *ast.EnumDeclaration:
+		return g.goName(s.Name.Literal)
+	}
+	return ""
+}
+
+// generateComments generates the comments of group as Go line comments, each
+// followed by a newline, with blank lines between the comments kept. JSDoc comments are converted to Go doc comments for
+// the declaration called name, unless name is empty.
+func generateComments(group *ast.CommentGroup, name string) string {
+	if group == nil {
+		return ""
+	}
+
+	builder := strings.Builder{}
+	for i, c := range group.List {
+		// keep comments separated by blank lines apart, so that only the
+		// last ones become the doc comment of the declaration
+		if i > 0 && c.Pos.Line > group.List[i-1].End.Line+1 {
+			builder.WriteString("\n")
+		}
+		lines := (&ast.CommentGroup{List: []token.Comment{c}}).Text()
+		if ast.IsDocComment(c) {
+			lines = docLines(name, lines)
+		}
+		for _, line := range lines {
+			if line == "" {
+				builder.WriteString("//\n")
+				continue
+			}
+			builder.WriteString("// " + line + "\n")
+		}
+	}
+	return builder.String()
+}
+
+// indentComments is generateComments for the members of structs and
+// interfaces, indented one level.
+func indentComments(group *ast.CommentGroup, name string) string {
+	builder := strings.Builder{}
+	for line := range strings.Lines(generateComments(group, name)) {
+		if line != "\n" {
+			builder.WriteString(indent)
+		}
+		builder.WriteString(line)
+	}
+	return builder.String()
+}
+
+// withTrailingComment appends the comments of group to the last line of code
+// as a line comment.
+func withTrailingComment(code string, group *ast.CommentGroup) string {
+	text := strings.Join(group.Text(), " ")
+	if text == "" {
+		return code
+	}
+	trimmed := strings.TrimSuffix(code, "\n")
+	return trimmed + " // " + text + code[len(trimmed):]
+}
+
+// docLines converts the lines of a JSDoc comment to a Go doc comment. The
+// description starts with name, following the Go convention, @param tags
+// become a list of the parameters, and @returns and @deprecated tags become
+// paragraphs. Other tags are kept as they are.
+func docLines(name string, lines []string) []string {
+	i := 0
+	for i < len(lines) && !strings.HasPrefix(lines[i], "@") {
+		i++
+	}
+	desc := append([]string{}, lines[:i]...)
+	if name != "" && len(desc) > 0 {
+		desc[0] = docSentence(name, desc[0])
+	}
+
+	// each tag with the lines continuing it
+	var tags []string
+	for _, line := range lines[i:] {
+		if strings.HasPrefix(line, "@") || len(tags) == 0 {
+			tags = append(tags, line)
+			continue
+		}
+		tags[len(tags)-1] += " " + strings.TrimSpace(line)
+	}
+
+	var params, paragraphs []string
+	for _, tag := range tags {
+		name, text, _ := strings.Cut(tag, " ")
+		text = strings.TrimSpace(text)
+		switch name {
+		case "@param":
+			params = append(params, paramItem(text))
+		case "@returns", "@return":
+			if text != "" {
+				paragraphs = append(paragraphs, "Returns "+lowerFirst(text))
+			}
+		case "@deprecated":
+			paragraphs = append(paragraphs, strings.TrimSpace("Deprecated: "+text))
+		default:
+			paragraphs = append(paragraphs, tag)
+		}
+	}
+
+	out := desc
+	if len(params) > 0 {
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, params...)
+	}
+	for _, p := range paragraphs {
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// paramItem converts the text of a @param tag, such as "{number} [n=1] - the
+// count", to an item of a Go doc comment list.
+func paramItem(text string) string {
+	if strings.HasPrefix(text, "{") {
+		if end := strings.Index(text, "}"); end >= 0 {
+			text = strings.TrimSpace(text[end+1:])
+		}
+	}
+	param, desc, _ := strings.Cut(text, " ")
+	param = strings.Trim(param, "[]")
+	param, _, _ = strings.Cut(param, "=")
+	desc = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(desc), "-"))
+	if desc == "" {
+		return "  - " + mangleName(param)
+	}
+	return "  - " + mangleName(param) + ": " + desc
+}
+
+// docSentence makes the first line of a description start with name, as
+// Go doc comments do: "Returns the sum." becomes "add returns the sum.", and
+// "A point." becomes "Point is a point.".
+func docSentence(name, line string) string {
+	word, _, _ := strings.Cut(line, " ")
+	switch strings.TrimRight(word, ".,:;") {
+	case name:
+		return line
+	case "A", "An", "The":
+		return name + " is " + lowerFirst(line)
+	}
+	return name + " " + lowerFirst(line)
+}
+
+// lowerFirst lowercases the first letter of s, unless it starts an acronym
+// such as "HTTP".
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	first, size := utf8.DecodeRuneInString(s)
+	if next, _ := utf8.DecodeRuneInString(s[size:]); unicode.IsUpper(next) {
+		return s
+	}
+	return string(unicode.ToLower(first)) + s[size:]
+}
@@ -0,0 +1,49 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// isConsole reports whether expr refers to the builtin console object,
+// rather than to a variable, function or class of the same name.
+func (g *Generator) isConsole(expr ast.Expression) bool {
+	v, ok := expr.(*ast.VariableExpression)
+	if !ok || v.Token.Literal != "console" {
+		return false
+	}
+	return g.lookup("console") == nil && g.functions["console"] == nil && g.classes["console"] == nil
+}
+
+// generateConsoleLog lowers a call of console.log to fmt.Println, with the
+// arguments formatted like console.log formats them: strings as they are,
+// and other values the way util.inspect does.
+func (g *Generator) generateConsoleLog(args []ast.Expression) string {
+	g.use("fmt")
+	list := make([]string, len(args))
+	for i, arg := range args {
+		list[i] = g.generateLogged(arg)
+	}
+	return fmt.Sprintf("fmt.Println(%s)", strings.Join(list, ", "))
+}
+
+// generateLogged generates arg formatted for console.log. Primitives are
+// converted like in strings, except that bigints end in n, and other
+// values are formatted at run time.
+func (g *Generator) generateLogged(arg ast.Expression) string {
+	t := g.resolveType(g.typeOf(arg))
+	switch {
+	case isBigInt(t):
+		return g.generateString(arg) + ` + "n"`
+	case g.isString(t) || isNumber(t) || typeName(t) == "boolean" || g.isNumericEnum(t) || g.isStringEnum(t):
+		return g.generateString(arg)
+	}
+	null := "undefined"
+	if g.nilIsNull(t) {
+		null = "null"
+	}
+	g.useHelper("sildInspect")
+	return fmt.Sprintf("sildInspect(%s, %q)", g.generateExpression(arg), null)
+}
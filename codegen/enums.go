@@ -0,0 +1,290 @@
+package codegen
+
+import (
+	"fmt"
+	"go/constant"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// enum is an enum declared at the top level of the program, lowered to a
+// named Go type: int for enums of numbers and string for enums of strings.
+type enum struct {
+	decl   *ast.EnumDeclaration
+	values []constant.Value
+	kind   constant.Kind // Int or String, Unknown if the members mix them
+}
+
+func newEnum(decl *ast.EnumDeclaration) *enum {
+	e := &enum{decl: decl, values: ast.EnumValues(decl), kind: constant.Int}
+	strings, numbers := false, false
+	for _, v := range e.values {
+		switch v.Kind() {
+		case constant.String:
+			strings = true
+		case constant.Int, constant.Float:
+			numbers = true
+		}
+	}
+	switch {
+	case strings && numbers:
+		e.kind = constant.Unknown
+	case strings:
+		e.kind = constant.String
+	}
+	return e
+}
+
+// constName returns the name of the Go constant of member, prefixed with
+// the name of the enum as Go constants aren't scoped to their type.
+func (g *Generator) enumConst(e *enum, member string) string {
+	return g.goName(e.decl.Name.Literal) + fieldName(member)
+}
+
+// literal returns the Go constant of the value of member, typed as the enum.
+func (g *Generator) enumLiteral(e *enum, member string) string {
+	for i, m := range e.decl.Members {
+		if m.Name.Literal == member {
+			return fmt.Sprintf("%s(%s)", g.goName(e.decl.Name.Literal), valueLiteral(e.values[i]))
+		}
+	}
+	return ""
+}
+
+func valueLiteral(v constant.Value) string {
+	if v.Kind() == constant.String {
+		return strconv.Quote(constant.StringVal(v))
+	}
+	return v.ExactString()
+}
+
+// generateEnumDeclaration generates the Go type of an enum and, unless it
+// is a const enum whose members are inlined, a constant per member and a
+// String method. String returns the name of the member of a numeric enum
+// with a given value, which lowers the reverse mapping of TypeScript, and
+// the value itself for a string enum.
+func (g *Generator) generateEnumDeclaration(decl *ast.EnumDeclaration) string {
+	e := g.enums[decl.Name.Literal]
+	if e == nil || e.decl != decl {
+		g.errorf(decl, "cannot translate an enum declared inside a function")
+		return ""
+	}
+	if !g.checkEnum(e) {
+		return ""
+	}
+
+	name := g.goName(decl.Name.Literal)
+	underlying := "int"
+	if e.kind == constant.String {
+		underlying = "string"
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("type %s %s\n", name, underlying))
+	if decl.Const || len(decl.Members) == 0 {
+		return builder.String()
+	}
+
+	builder.WriteString("\nconst (\n")
+	builder.WriteString(g.generateEnumConsts(e))
+	builder.WriteString(")\n\n")
+
+	recv := receiverName(decl.Name.Literal)
+	if e.kind == constant.String {
+		builder.WriteString(fmt.Sprintf("func (%s %s) String() string { return string(%s) }\n", recv, name, recv))
+		return builder.String()
+	}
+
+	g.use("strconv")
+	builder.WriteString(fmt.Sprintf("func (%s %s) String() string {\n%sswitch %s {\n", recv, name, indent, recv))
+	for _, i := range reverseMapping(e.values) {
+		builder.WriteString(fmt.Sprintf("%scase %s:\n%s%sreturn %s\n", indent, g.enumConst(e, decl.Members[i].Name.Literal), indent, indent, strconv.Quote(decl.Members[i].Name.Literal)))
+	}
+	builder.WriteString(fmt.Sprintf("%s}\n%sreturn \"%s(\" + strconv.Itoa(int(%s)) + \")\"\n}\n", indent, indent, name, recv))
+	return builder.String()
+}
+
+// checkEnum reports the enums Go constants can't hold: those whose members
+// mix numbers and strings, and those with members whose values aren't
+// integer constants.
+func (g *Generator) checkEnum(e *enum) bool {
+	if e.kind == constant.Unknown {
+		g.errorf(e.decl, "cannot translate enum '%s' mixing numbers and strings", e.decl.Name.Literal)
+		return false
+	}
+	for i, v := range e.values {
+		m := e.decl.Members[i]
+		switch v.Kind() {
+		case constant.Unknown:
+			g.errorf(m, "cannot translate enum member '%s' whose value isn't a constant", m.Name.Literal)
+			return false
+		case constant.Float:
+			g.errorf(m, "cannot translate enum member '%s' whose value isn't an integer", m.Name.Literal)
+			return false
+		}
+	}
+	return true
+}
+
+// generateEnumConsts generates the constants of the members of an enum.
+// Members numbered consecutively are numbered with iota.
+func (g *Generator) generateEnumConsts(e *enum) string {
+	name := g.goName(e.decl.Name.Literal)
+	names := make([]string, len(e.decl.Members))
+	width := 0
+	for i, m := range e.decl.Members {
+		names[i] = g.enumConst(e, m.Name.Literal)
+		width = max(width, len(names[i]))
+	}
+
+	builder := strings.Builder{}
+	first, consecutive := iotaExpression(e.values)
+	for i, m := range e.decl.Members {
+		builder.WriteString(indentComments(m.Leading, names[i]))
+		var line string
+		switch {
+		case consecutive && i == 0:
+			line = fmt.Sprintf("%s %s = %s", names[i], name, first)
+		case consecutive:
+			line = names[i]
+		default:
+			line = fmt.Sprintf("%-*s %s = %s", width, names[i], name, valueLiteral(e.values[i]))
+		}
+		builder.WriteString(indent + withTrailingComment(line, m.Trailing) + "\n")
+	}
+	return builder.String()
+}
+
+// iotaExpression returns the expression of iota that numbers the members of
+// an enum with the given values, and false if they aren't consecutive
+// integers.
+func iotaExpression(values []constant.Value) (string, bool) {
+	if values[0].Kind() != constant.Int {
+		return "", false
+	}
+	first, _ := constant.Int64Val(values[0])
+	for i, v := range values {
+		n, exact := constant.Int64Val(v)
+		if !exact || n != first+int64(i) {
+			return "", false
+		}
+	}
+	switch {
+	case first > 0:
+		return fmt.Sprintf("iota + %d", first), true
+	case first < 0:
+		return fmt.Sprintf("iota - %d", -first), true
+	}
+	return "iota", true
+}
+
+// reverseMapping returns the indices of the members the values of a numeric
+// enum map back to, in the order of their values' first members. Like in
+// TypeScript, a value maps back to the last member that has it.
+func reverseMapping(values []constant.Value) []int {
+	var order []int
+	last := map[string]int{}
+	for i, v := range values {
+		key := v.ExactString()
+		if _, ok := last[key]; !ok {
+			order = append(order, i)
+		}
+		last[key] = i
+	}
+	for n, i := range order {
+		order[n] = last[values[i].ExactString()]
+	}
+	return order
+}
+
+// receiverName returns the name of the receiver of the methods of the Go
+// type name, its first letter in lower case.
+func receiverName(name string) string {
+	r := []rune(name)[0]
+	if !unicode.IsLetter(r) || r > unicode.MaxASCII {
+		return "e"
+	}
+	return string(unicode.ToLower(r))
+}
+
+// enumOf returns the enum t is the type of, or nil.
+func (g *Generator) enumOf(t ast.TypeExpr) *enum {
+	if t == nil || g.typeParam(t) != nil {
+		return nil
+	}
+	return g.enums[typeName(g.resolveType(t))]
+}
+
+func (g *Generator) isNumericEnum(t ast.TypeExpr) bool {
+	e := g.enumOf(t)
+	return e != nil && e.kind == constant.Int
+}
+
+func (g *Generator) isStringEnum(t ast.TypeExpr) bool {
+	e := g.enumOf(t)
+	return e != nil && e.kind == constant.String
+}
+
+// isNumberValue reports whether values of type t are numbers: t is number
+// or a numeric enum.
+func (g *Generator) isNumberValue(t ast.TypeExpr) bool {
+	return isNumber(t) || g.isNumericEnum(t)
+}
+
+// enumName returns the enum expr names, or nil if it doesn't name one.
+func (g *Generator) enumName(expr ast.Expression) *enum {
+	v, ok := expr.(*ast.VariableExpression)
+	if !ok || g.lookup(v.Token.Literal) != nil {
+		return nil
+	}
+	return g.enums[v.Token.Literal]
+}
+
+// enumMember returns the enum whose member expr refers to, as in Color.Red
+// or Color["Red"], and the name of the member, or nil if expr doesn't refer
+// to a member of an enum.
+func (g *Generator) enumMember(expr ast.Expression) (*enum, string) {
+	switch e := expr.(type) {
+	case *ast.MemberExpression:
+		if enum := g.enumName(e.Object); enum != nil {
+			return enum, e.Property.String()
+		}
+	case *ast.IndexExpression:
+		if key, ok := e.Index.(*ast.StringLiteral); ok {
+			if enum := g.enumName(e.Left); enum != nil {
+				return enum, key.Token.Literal
+			}
+		}
+	}
+	return nil, ""
+}
+
+// generateEnumMember generates a reference to a member of an enum: its
+// constant, or its value for a member of a const enum.
+func (g *Generator) generateEnumMember(e *enum, member string) string {
+	if e.decl.Const {
+		return g.enumLiteral(e, member)
+	}
+	return g.enumConst(e, member)
+}
+
+// generateNumberOperand generates an operand of an operation on numbers,
+// converting the value of a numeric enum to float64.
+func (g *Generator) generateNumberOperand(expr ast.Expression) string {
+	if g.isNumericEnum(g.typeOf(expr)) {
+		return conversion("float64", expr, g.generateExpression(expr))
+	}
+	return g.generateExpression(expr)
+}
+
+// generateReverseMapping generates an index into a numeric enum, as in
+// Color[0], which looks up the name of the member with the value of index.
+func (g *Generator) generateReverseMapping(e *enum, index ast.Expression) string {
+	if g.enumOf(g.typeOf(index)) == e {
+		return g.generateExpression(index) + ".String()"
+	}
+	return fmt.Sprintf("%s(%s).String()", g.goName(e.decl.Name.Literal), g.generateExpression(index))
+}
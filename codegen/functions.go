@@ -0,0 +1,167 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// generateFunctionExpression lowers an arrow function or function expression
+// to a Go function literal of the type functionType infers for it. Go
+// closures capture variables by reference like TypeScript's, so the body
+// needs no changes. Since Go only assigns a function to a variable of the
+// very same type, the literal takes the extra parameters expected ignores.
+func (g *Generator) generateFunctionExpression(fn *ast.FunctionExpression, expected ast.TypeExpr) string {
+	t := g.functionType(fn, expected)
+
+	params := t.Params
+	if want := g.funcType(expected); want != nil {
+		for _, p := range want.Params[min(len(params), len(want.Params)):] {
+			params = append(params, ast.FunctionParam{Type: p.Type})
+		}
+	}
+
+	body := fn.Body
+	switch {
+	case fn.Expr != nil && isVoid(t.ReturnType):
+		body = []Statement{&ast.ExpressionStatement{Expression: fn.Expr}}
+	case fn.Expr != nil:
+		body = []Statement{&ast.ReturnStatement{Value: fn.Expr}}
+	}
+
+	// a return in an arrow function inside a constructor returns from the
+	// arrow function
+	outer := g.constructing
+	g.constructing = false
+	defer func() { g.constructing = outer }()
+
+	builder := strings.Builder{}
+	builder.WriteString("func(" + g.generateParams(params) + ")")
+	if ret := g.goType(t.ReturnType); ret != "" {
+		builder.WriteString(" " + ret)
+	}
+	builder.WriteString(" {\n")
+	// the body is narrowed along with the function enclosing the literal,
+	// whose variables it may assign
+	builder.WriteString(g.generateScopedBody(params, t.ReturnType, body))
+	builder.WriteString("}")
+	return builder.String()
+}
+
+// functionType returns the type of an arrow function or function
+// expression. A function without a return type returns the type expected
+// returns, if it gives one, rather than the type the checker inferred from
+// its body, since Go only assigns a function to a variable of the very same
+// type.
+func (g *Generator) functionType(fn *ast.FunctionExpression, expected ast.TypeExpr) *ast.FunctionType {
+	t, ok := g.typeOf(fn).(*ast.FunctionType)
+	if !ok {
+		t = &ast.FunctionType{Params: fn.Params, ReturnType: fn.ReturnType}
+	}
+	if want := g.funcType(expected); fn.ReturnType == nil && want != nil && want.ReturnType != nil {
+		return &ast.FunctionType{Params: t.Params, ReturnType: want.ReturnType}
+	}
+	return t
+}
+
+// funcType returns the function type t is or names, or nil if t isn't a
+// function type.
+func (g *Generator) funcType(t ast.TypeExpr) *ast.FunctionType {
+	for range len(g.typeDecls) + len(g.typeParams) + 1 {
+		switch typ := t.(type) {
+		case *ast.FunctionType:
+			return typ
+		case *ast.TypeReference:
+			t = g.declaredType(typ)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// declaredFunctionType returns the type of a declared function.
+func declaredFunctionType(fn *ast.FunctionDeclaration) *ast.FunctionType {
+	return &ast.FunctionType{Params: fn.Params, ReturnType: fn.ReturnType}
+}
+
+// generateFunctionType generates the Go func type of a function type, which
+// only lists the types of the parameters.
+func (g *Generator) generateFunctionType(fn *ast.FunctionType) string {
+	params := make([]string, len(fn.Params))
+	for i, p := range fn.Params {
+		params[i] = g.goType(p.Type)
+	}
+	if ret := g.goType(fn.ReturnType); ret != "" {
+		return fmt.Sprintf("func(%s) %s", strings.Join(params, ", "), ret)
+	}
+	return fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+}
+
+// declareNestedFunctions declares variables for the functions declared in a
+// block, which Go only allows as function literals. The variables are
+// declared at the top of the block, so that the functions can call each
+// other and themselves, but only assigned where the functions are declared,
+// since they may use the variables declared before them.
+func (g *Generator) declareNestedFunctions(stmts []Statement) string {
+	var decls []string
+	for _, stmt := range stmts {
+		if fn, ok := stmt.(*ast.FunctionDeclaration); ok {
+			if len(fn.TypeParams) > 0 {
+				g.errorf(fn, "cannot translate generic function '%s' declared in a function: Go function literals can't have type parameters", fn.Name.Literal)
+			}
+			t := declaredFunctionType(fn)
+			g.declare(fn.Name.Literal, t)
+			decls = append(decls, fmt.Sprintf("var %s %s", g.goName(fn.Name.Literal), g.generateFunctionType(t)))
+			// Go rejects local variables that are never read
+			if !g.read[fn] {
+				decls = append(decls, "_ = "+g.goName(fn.Name.Literal))
+			}
+		}
+	}
+	return strings.Join(decls, "\n")
+}
+
+// generateNestedFunction assigns a function declared in a block to the
+// variable declareNestedFunctions declared for it.
+func (g *Generator) generateNestedFunction(fn *ast.FunctionDeclaration) string {
+	literal := &ast.FunctionExpression{Params: fn.Params, ReturnType: fn.ReturnType, Body: fn.Body}
+	g.types[literal] = declaredFunctionType(fn)
+	return fmt.Sprintf("%s = %s", g.goName(fn.Name.Literal), g.generateFunctionExpression(literal, nil))
+}
+
+func isVoid(t ast.TypeExpr) bool {
+	return typeName(t) == "void"
+}
+
+func functionParam(t ast.TypeExpr) ast.FunctionParam {
+	return ast.FunctionParam{Name: token.Token{Type: token.IDENT}, Type: t}
+}
+
+// callbackType returns the type of the function passed to an array method
+// on an array of elem, or nil if the method doesn't take one. The return
+// type is nil where it is inferred from the function, and acc is the type of
+// the accumulator of reduce. The parameters match those the runtime helpers
+// call the function with, including the index of the element if indexed.
+func callbackType(method string, elem, acc ast.TypeExpr, indexed bool) *ast.FunctionType {
+	params := []ast.FunctionParam{functionParam(elem)}
+	if indexed {
+		params = append(params, functionParam(primitiveType("number")))
+	}
+
+	switch method {
+	case "map":
+		return &ast.FunctionType{Params: params}
+	case "filter", "find", "some", "every":
+		return &ast.FunctionType{Params: params, ReturnType: primitiveType("boolean")}
+	case "forEach":
+		return &ast.FunctionType{Params: params, ReturnType: primitiveType("void")}
+	case "reduce":
+		return &ast.FunctionType{Params: []ast.FunctionParam{functionParam(acc), functionParam(elem)}, ReturnType: acc}
+	case "sort":
+		return &ast.FunctionType{Params: []ast.FunctionParam{functionParam(elem), functionParam(elem)}, ReturnType: primitiveType("number")}
+	}
+	return nil
+}
@@ -2,115 +2,1227 @@ package codegen
 
 import (
 	"fmt"
+	"go/constant"
+	"maps"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/diag"
 	"github.com/toyaAoi/sild/token"
+	"github.com/toyaAoi/sild/types"
 )
 
 type Program = ast.Program
 type Statement = ast.Statement
 
+const indent = "    "
+
 type Generator struct {
+	// NarrowIntegers lowers number variables that provably only hold
+	// integers to Go int instead of float64.
+	NarrowIntegers bool
+
 	output strings.Builder
+
+	// labels that are the target of a break or continue; Go rejects
+	// labels that are never used
+	usedLabels map[string]bool
+
+	imports map[string]bool
+	helpers map[string]bool
+	scope   *scope
+
+	// the types of the expressions of the program found by the type
+	// checker, with those of the expressions the generator rewrites, the
+	// types of the links of optional chains when they don't short-circuit,
+	// and the type arguments of generic calls
+	types    map[ast.Expression]ast.TypeExpr
+	chains   map[ast.Expression]ast.TypeExpr
+	typeArgs map[ast.Expression]map[string]ast.TypeExpr
+
+	// the types of the variables narrowed by conditions, the switch
+	// statements covering all the members of a union and the declared types
+	// of narrowed properties, as the type checker found them
+	narrowings map[ast.Node]map[string]ast.TypeExpr
+	exhaustive map[*ast.SwitchStatement]bool
+	declared   map[ast.Expression]ast.TypeExpr
+	// the declarations of the variables the program refers to, since Go
+	// rejects unused loop variables, and of those it reads, since Go also
+	// rejects local variables that are only assigned to
+	used map[ast.Node]bool
+	read map[ast.Node]bool
+
+	functions map[string]*ast.FunctionDeclaration
+	typeDecls map[string]ast.TypeExpr
+	classes   map[string]*ast.ClassDeclaration
+	enums     map[string]*enum
+	hierarchy
+	// names of the package-level declarations the generated code introduces
+	// for those of the program, such as constructors, which user
+	// identifiers are renamed to avoid
+	reserved map[string]bool
+	// top-level variables, declared at package level and assigned in main
+	globals map[*ast.VariableDeclaration]bool
+	// type parameters of the generic types declared in the program, the
+	// interfaces used as constraints, which are lowered to Go interfaces,
+	// and the type parameters in scope
+	generics    map[string][]*ast.TypeParam
+	constraints map[string]bool
+	typeParams  map[string]*ast.TypeParam
+	// the type aliases lowered to sealed interfaces, and their variants by
+	// object type
+	unions   map[string]*discriminatedUnion
+	variants map[*ast.ObjectType]*variant
+	// return type of the function being generated
+	returnType ast.TypeExpr
+	// the class whose constructor or methods are being generated, and
+	// whether the function being generated is its constructor
+	class        *ast.ClassDeclaration
+	constructing bool
+
+	// declarations of the variables that integer narrowing found may hold
+	// values that aren't integers, whether the last pass of the narrowing
+	// found new ones, and the sums assigned to narrowed variables in it
+	wide        map[*ast.VariableDeclaration]bool
+	widened     bool
+	sums        []narrowedSum
+	diagnostics []diag.Diagnostic
 }
 
 func New() *Generator {
 	return &Generator{}
 }
 
-func (g *Generator) Generate(p *Program) string {
+// Generate generates the Go code of a program the type checker found no
+// errors in, given the types it found.
+func (g *Generator) Generate(p *Program, info *types.Info) string {
 	g.output.Reset()
+	g.types = maps.Clone(info.Types)
+	g.chains = info.Chains
+	g.typeArgs = info.TypeArgs
+	g.narrowings = info.Narrowings
+	g.exhaustive = info.Exhaustive
+	g.declared = info.Declared
+	g.used = map[ast.Node]bool{}
+	g.read = map[ast.Node]bool{}
+	for v, sym := range info.Uses {
+		g.used[sym.Decl] = true
+		if !info.Assigned[v] {
+			g.read[sym.Decl] = true
+		}
+	}
+	g.usedLabels = map[string]bool{}
+	g.imports = map[string]bool{}
+	g.helpers = map[string]bool{}
+	g.functions = map[string]*ast.FunctionDeclaration{}
+	g.typeDecls = map[string]ast.TypeExpr{}
+	g.classes = map[string]*ast.ClassDeclaration{}
+	g.enums = map[string]*enum{}
+	g.generics = map[string][]*ast.TypeParam{}
+	g.constraints = map[string]bool{}
+	g.typeParams = nil
+	g.unions = map[string]*discriminatedUnion{}
+	g.variants = map[*ast.ObjectType]*variant{}
+	g.wide = map[*ast.VariableDeclaration]bool{}
+	g.reserved = nil
+	g.globals = map[*ast.VariableDeclaration]bool{}
+	g.diagnostics = nil
+	g.returnType = nil
+	g.scope = nil
+	g.pushScope()
 
-	g.output.WriteString("package main\n\n")
+	// top-level variables and functions are visible everywhere in TypeScript
+	for _, stmt := range p.Statements {
+		switch s := stmt.(type) {
+		case *ast.FunctionDeclaration:
+			g.functions[s.Name.Literal] = s
+		case *ast.InterfaceDeclaration:
+			g.typeDecls[s.Name.Literal] = s.Type
+			g.generics[s.Name.Literal] = s.TypeParams
+		case *ast.TypeAliasDeclaration:
+			g.typeDecls[s.Name.Literal] = s.Type
+			g.generics[s.Name.Literal] = s.TypeParams
+		case *ast.ClassDeclaration:
+			g.classes[s.Name.Literal] = s
+		case *ast.EnumDeclaration:
+			g.enums[s.Name.Literal] = newEnum(s)
+		}
+	}
+	g.collectConstraints(p.Statements)
+	g.collectUnions(p.Statements)
+	g.resolveHierarchy(p.Statements)
+	g.reserved = g.generatedNames()
+	for _, v := range g.variants {
+		if !v.inline {
+			// renamed if it takes a generated name
+			v.goName = g.goName(typeName(v.typ))
+		}
+	}
+	// the types inferred for top-level variables may depend on functions
+	for _, stmt := range p.Statements {
+		if v, ok := stmt.(*ast.VariableDeclaration); ok {
+			g.declare(v.Name, g.variableType(v))
+		}
+	}
 
+	decls := strings.Builder{}
+	for _, stmt := range p.Statements {
+		switch s := stmt.(type) {
+		case *ast.InterfaceDeclaration, *ast.TypeAliasDeclaration, *ast.ClassDeclaration, *ast.EnumDeclaration:
+			decls.WriteString(g.generateCommented(s) + "\n")
+		}
+	}
+	decls.WriteString(g.generatePackageVars(p.Statements))
 	for _, stmt := range p.Statements {
 		if _, ok := stmt.(*ast.FunctionDeclaration); ok {
-			g.output.WriteString(g.generateFunctionDeclaration(stmt.(*ast.FunctionDeclaration)) + "\n")
+			decls.WriteString(g.generateCommented(stmt) + "\n")
 		}
 	}
 
-	g.output.WriteString("func main() {\n")
+	decls.WriteString("func main() {\n")
 
+	var body []Statement
 	for _, stmt := range p.Statements {
-		if _, ok := stmt.(*ast.FunctionDeclaration); ok {
+		switch s := stmt.(type) {
+		case *ast.FunctionDeclaration, *ast.InterfaceDeclaration, *ast.TypeAliasDeclaration, *ast.ClassDeclaration, *ast.EnumDeclaration:
 			continue
+		case *ast.VariableDeclaration:
+			if !g.globals[s] {
+				// a constant, declared at package level
+				continue
+			}
 		}
 
-		g.output.WriteString("    " + g.generateStatement(stmt) + "\n")
+		body = append(body, stmt)
 	}
+	decls.WriteString(g.generateNarrowed(func() string {
+		return g.generateBlock(body)
+	}))
 
-	g.output.WriteString("}\n")
+	decls.WriteString("}\n")
+
+	g.output.WriteString("package main\n\n")
+	g.writeImports()
+	g.output.WriteString(decls.String())
+	g.writeHelpers(&g.output)
 
 	return g.output.String()
 }
 
+// Diagnostics returns the problems found while generating the last program,
+// such as TypeScript constructs that can't be faithfully translated to Go.
+func (g *Generator) Diagnostics() []diag.Diagnostic {
+	return g.diagnostics
+}
+
+func (g *Generator) errorf(node ast.Node, format string, args ...any) {
+	g.report(diag.Error, node, format, args...)
+}
+
+func (g *Generator) report(severity diag.Severity, node ast.Node, format string, args ...any) {
+	g.diagnostics = append(g.diagnostics, diag.Diagnostic{
+		Severity: severity,
+		Pos:      node.Pos(),
+		End:      node.End(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// use records that the generated code refers to the Go package path.
+func (g *Generator) use(path string) {
+	g.imports[path] = true
+}
+
+func (g *Generator) writeImports() {
+	if len(g.imports) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	g.output.WriteString("import (\n")
+	for _, path := range paths {
+		g.output.WriteString(indent + strconv.Quote(path) + "\n")
+	}
+	g.output.WriteString(")\n\n")
+}
+
+// generateBlock generates stmts one per line, indented one level deeper than
+// the enclosing braces, after the variables of the functions declared in the
+// block.
+func (g *Generator) generateBlock(stmts []Statement) string {
+	builder := strings.Builder{}
+
+	g.pushScope()
+	defer g.popScope()
+
+	codes := []string{g.declareNestedFunctions(stmts)}
+	for _, stmt := range stmts {
+		codes = append(codes, g.generateCommented(stmt))
+		// the rest of the block sees the variables narrowed by the branches
+		// of an if statement that complete normally, and the value assigned
+		// to a variable by an assignment or a declaration
+		switch stmt.(type) {
+		case *ast.IfStatement, *ast.AssignmentStatement, *ast.VariableDeclaration:
+			if n, ok := g.narrowings[stmt]; ok {
+				g.pushNarrowing(n, false)
+				defer g.popScope()
+			}
+		}
+	}
+
+	for _, code := range codes {
+		if code == "" {
+			continue
+		}
+		for _, line := range strings.Split(code, "\n") {
+			if line == "" {
+				builder.WriteString("\n")
+				continue
+			}
+			builder.WriteString(indent + line + "\n")
+		}
+	}
+
+	return builder.String()
+}
+
 func (g *Generator) generateStatement(stmt Statement) string {
 	switch s := stmt.(type) {
 	case *ast.VariableDeclaration:
-		return g.generateVariableDeclaration(s)
+		code := g.generateVariableDeclaration(s)
+		// var declarations are read where hoistVars declares them
+		if s.Keyword.Type != token.VAR && g.isUnread(s) {
+			code += "\n_ = " + g.goName(s.Name)
+		}
+		return code
 	case *ast.FunctionDeclaration:
 		return g.generateFunctionDeclaration(s)
 	case *ast.ReturnStatement:
 		return g.generateReturnStatement(s)
+	case *ast.ThrowStatement:
+		return g.generateThrowStatement(s)
+	case *ast.IfStatement:
+		return g.generateIfStatement(s)
+	case *ast.SwitchStatement:
+		return g.generateSwitchStatement(s)
+	case *ast.BlockStatement:
+		return "{\n" + g.generateBlock(s.Statements) + "}"
+	case *ast.ExpressionStatement:
+		return g.generateExpressionStatement(s)
+	case *ast.InterfaceDeclaration:
+		g.typeDecls[s.Name.Literal] = s.Type
+		g.generics[s.Name.Literal] = s.TypeParams
+		return g.generateTypeDeclaration(s.Name.Literal, s.TypeParams, s.Type)
+	case *ast.TypeAliasDeclaration:
+		g.typeDecls[s.Name.Literal] = s.Type
+		g.generics[s.Name.Literal] = s.TypeParams
+		return g.generateTypeDeclaration(s.Name.Literal, s.TypeParams, s.Type)
+	case *ast.ClassDeclaration:
+		g.classes[s.Name.Literal] = s
+		return g.generateClassDeclaration(s)
+	case *ast.EnumDeclaration:
+		return g.generateEnumDeclaration(s)
+	case *ast.AssignmentStatement:
+		return g.generateAssignmentStatement(s)
+	case *ast.IncDecStatement:
+		op := token.PLUS_ASSIGN
+		if s.Operator.Type == token.MINUS_MINUS {
+			op = token.MINUS_ASSIGN
+		}
+		g.checkNarrowedTarget(s.Target)
+		if code, ok := g.generateBigIntAssignment(s.Target, op, "big.NewInt(1)"); ok {
+			return code
+		}
+		if code, ok := g.generateNarrowedUpdate(s.Target, op, nil); ok {
+			return code
+		}
+		return g.generateExpression(s.Target) + s.Operator.Literal
+	case *ast.WhileStatement:
+		if isTrue(s.Condition) {
+			// Go only sees a loop without a condition as terminating
+			return fmt.Sprintf("for {\n%s}", g.generateBody(s.Body))
+		}
+		return fmt.Sprintf("for %s {\n%s}", g.generateCondition(s.Condition), g.generateBody(s.Body))
+	case *ast.DoWhileStatement:
+		return g.generateDoWhileStatement(s)
+	case *ast.ForStatement:
+		return g.generateForStatement(s)
+	case *ast.ForOfStatement:
+		return g.generateForOfStatement(s)
+	case *ast.ForInStatement:
+		return g.generateForInStatement(s)
+	case *ast.BranchStatement:
+		if s.Label != nil {
+			g.usedLabels[s.Label.String()] = true
+			return s.Token.Literal + " " + g.goName(s.Label.String())
+		}
+		return s.Token.Literal
+	case *ast.LabeledStatement:
+		body := g.generateStatement(s.Body)
+		if !g.usedLabels[s.Label.String()] {
+			return body
+		}
+		delete(g.usedLabels, s.Label.String())
+		return g.goName(s.Label.String()) + ":\n" + body
 	default:
 		return ""
 	}
 }
 
-func (g *Generator) generateVariableDeclaration(varDec *ast.VariableDeclaration) string {
-	if varDec.Type == "string" {
-		return fmt.Sprintf("%s := %q", varDec.Name, varDec.Expr.String())
+func (g *Generator) generateAssignmentStatement(stmt *ast.AssignmentStatement) string {
+	g.checkNarrowedTarget(stmt.Target)
+	if stmt.Operator.Type == token.NULLISH_ASSIGN {
+		return g.generateNullishAssignment(stmt)
 	}
-	return fmt.Sprintf("%s := %s", varDec.Name, varDec.Expr.String())
+	if stmt.Operator.Type != token.ASSIGN {
+		if code, ok := g.generateNarrowedUpdate(stmt.Target, stmt.Operator.Type, stmt.Value); ok {
+			return code
+		}
+	}
+	target := g.generateExpression(stmt.Target)
+	targetType := g.typeOf(stmt.Target)
+
+	var value string
+	switch v, _ := stmt.Target.(*ast.VariableExpression); {
+	case v != nil && g.lookupNarrowed(v.Token.Literal) != nil:
+		g.checkNarrowed(v.Token.Literal, stmt.Operator.Type, stmt.Value)
+		value = g.generateNumber(stmt.Value, true)
+	case isBigInt(targetType) && stmt.Operator.Type != token.ASSIGN:
+		if code, ok := g.generateBigIntAssignment(stmt.Target, stmt.Operator.Type, g.generateExpression(stmt.Value)); ok {
+			return code
+		}
+		value = g.generateExpression(stmt.Value)
+	case stmt.Operator.Type == token.PLUS_ASSIGN && g.isString(targetType):
+		value = g.generateString(stmt.Value)
+	case stmt.Operator.Type == token.MOD_ASSIGN && isNumber(targetType):
+		// Go's %= doesn't apply to floats
+		g.use("math")
+		return fmt.Sprintf("%s = math.Mod(%s, %s)", target, target, g.generateNumber(stmt.Value, false))
+	default:
+		value = g.generateExpressionAs(stmt.Value, targetType)
+	}
+	return fmt.Sprintf("%s %s %s", target, stmt.Operator.Literal, value)
 }
 
-func returnType(t token.TokenType) string {
-	switch t {
-	case token.TYPE_NUMBER:
-		return "int"
-	case token.TYPE_STRING:
-		return "string"
-	case token.TYPE_BOOLEAN:
-		return "bool"
-	case token.TYPE_VOID:
-		return ""
+func (g *Generator) generateExpressionStatement(stmt *ast.ExpressionStatement) string {
+	if ast.IsOptionalChain(stmt.Expression) {
+		return g.generateOptionalChainStatement(stmt.Expression)
+	}
+	if call, ok := stmt.Expression.(*ast.FunctionCallExpression); ok {
+		if member, ok := call.Callee.(*ast.MemberExpression); ok {
+			if s, ok := g.generateArrayMethodStatement(member.Object, member.Property.String(), call.Args); ok {
+				return s
+			}
+		}
+	}
+
+	expr := g.generateExpression(stmt.Expression)
+	if _, ok := stmt.Expression.(*ast.FunctionCallExpression); ok {
+		return expr
+	}
+	// Go rejects expression statements whose value is unused
+	return "_ = " + expr
+}
+
+// generateDoWhileStatement lowers a do-while loop to a for loop whose
+// condition is only evaluated after the first iteration. The condition sits
+// in the post statement so that continue still evaluates it.
+func (g *Generator) generateDoWhileStatement(stmt *ast.DoWhileStatement) string {
+	return fmt.Sprintf("for _do := true; _do; _do = %s {\n%s}", g.generateCondition(stmt.Condition), g.generateBody(stmt.Body))
+}
+
+func (g *Generator) generateForStatement(stmt *ast.ForStatement) string {
+	// the variables declared by the init statement are scoped to the loop
+	g.pushScope()
+	defer g.popScope()
+
+	var init, cond, update string
+	if stmt.Init != nil {
+		init = g.generateStatement(stmt.Init)
+	}
+	if stmt.Condition != nil && !isTrue(stmt.Condition) {
+		cond = g.generateCondition(stmt.Condition)
+	}
+	if stmt.Update != nil {
+		update = g.generateStatement(stmt.Update)
+	}
+
+	header := cond
+	if stmt.Init != nil || stmt.Update != nil {
+		header = fmt.Sprintf("%s; %s; %s", init, cond, update)
+	}
+
+	if header == "" {
+		return fmt.Sprintf("for {\n%s}", g.generateBody(stmt.Body))
+	}
+	return fmt.Sprintf("for %s {\n%s}", header, g.generateBody(stmt.Body))
+}
+
+// isTrue reports whether expr is the literal true, possibly parenthesized.
+func isTrue(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.BooleanLiteral:
+		return e.Token.Literal == "true"
+	case *ast.ParenthesizedExpression:
+		return isTrue(e.Expression)
+	}
+	return false
+}
+
+// generateForOfStatement lowers a for...of loop to a range loop. Strings are
+// iterated by code point like in TypeScript, with each one converted back to
+// a string. A loop whose variable is never used ranges without one. Arrays
+// other than literals are iterated by index instead, so that the loop also
+// visits the elements its body pushes, as it does in TypeScript.
+func (g *Generator) generateForOfStatement(stmt *ast.ForOfStatement) string {
+	name := stmt.Variable.String()
+	iterable := g.generateExpression(stmt.Iterable)
+	iterType := g.typeOf(stmt.Iterable)
+
+	g.pushScope()
+	defer g.popScope()
+
+	switch {
+	case !g.used[stmt] && g.isString(iterType):
+		g.declare(name, iterType)
+		return fmt.Sprintf("for range %s {\n%s}", iterable, g.generateBody(stmt.Body))
+	case g.isString(iterType):
+		g.declare(name, iterType)
+		return fmt.Sprintf("for _, _r := range %s {\n%s%s := string(_r)\n%s}", iterable, indent, g.goName(name), g.generateBody(stmt.Body))
+	}
+
+	if _, ok := stmt.Iterable.(*ast.ArrayLiteral); ok {
+		g.declare(name, ast.ElementType(iterType))
+		if !g.used[stmt] {
+			return fmt.Sprintf("for range %s {\n%s}", deref(iterable), g.generateBody(stmt.Body))
+		}
+		return fmt.Sprintf("for _, %s := range %s {\n%s}", g.goName(name), deref(iterable), g.generateBody(stmt.Body))
+	}
+
+	// the array is evaluated once, like in TypeScript
+	init := "_i := 0"
+	if v, ok := stmt.Iterable.(*ast.VariableExpression); !ok || iterable != g.goName(v.Token.Literal) {
+		init, iterable = "_v, _i := "+iterable+", 0", "_v"
+	}
+	loop := fmt.Sprintf("for %s; _i < len(%s); _i++ {\n", init, deref(iterable))
+	g.declare(name, ast.ElementType(iterType))
+	if !g.used[stmt] {
+		return loop + g.generateBody(stmt.Body) + "}"
+	}
+	return fmt.Sprintf("%s%s%s := (%s)[_i]\n%s}", loop, indent, g.goName(name), deref(iterable), g.generateBody(stmt.Body))
+}
+
+// generateForInStatement lowers a for...in loop to a range loop over the
+// keys of an object, array or string. The keys of an object are its
+// properties in declaration order. Keys are always strings in TypeScript, so
+// indices are converted, and strings are indexed by UTF-16 code unit. Like
+// for...of loops, a loop whose variable is never used ranges without one.
+func (g *Generator) generateForInStatement(stmt *ast.ForInStatement) string {
+	name := stmt.Variable.String()
+	object := g.generateExpression(stmt.Object)
+	objType := g.typeOf(stmt.Object)
+
+	g.pushScope()
+	defer g.popScope()
+	g.declare(name, primitiveType("string"))
+
+	switch {
+	case !g.used[stmt] && g.isString(objType):
+		g.use("unicode/utf16")
+		return fmt.Sprintf("for range utf16.Encode([]rune(%s)) {\n%s}", object, g.generateBody(stmt.Body))
+	case !g.used[stmt] && isArray(objType):
+		return fmt.Sprintf("for range %s {\n%s}", deref(object), g.generateBody(stmt.Body))
+	case g.isString(objType):
+		g.use("strconv")
+		g.use("unicode/utf16")
+		return fmt.Sprintf("for _i := range utf16.Encode([]rune(%s)) {\n%s%s := strconv.Itoa(_i)\n%s}", object, indent, g.goName(name), g.generateBody(stmt.Body))
+	case g.objectType(objType) != nil && hasOptional(g.objectType(objType)):
+		// only the optional properties the object has are known at run time
+		g.useHelper("sildPresentKeys")
+		if !g.used[stmt] {
+			return fmt.Sprintf("for range sildPresentKeys(%s) {\n%s}", object, g.generateBody(stmt.Body))
+		}
+		return fmt.Sprintf("for _, %s := range sildPresentKeys(%s) {\n%s}", g.goName(name), object, g.generateBody(stmt.Body))
+	case g.objectType(objType) != nil:
+		g.useHelper("sildKeys")
+		keys := append([]string{object}, propertyNames(g.objectType(objType))...)
+		if !g.used[stmt] {
+			return fmt.Sprintf("for range sildKeys(%s) {\n%s}", strings.Join(keys, ", "), g.generateBody(stmt.Body))
+		}
+		return fmt.Sprintf("for _, %s := range sildKeys(%s) {\n%s}", g.goName(name), strings.Join(keys, ", "), g.generateBody(stmt.Body))
+	case isArray(objType):
+		g.use("strconv")
+		return fmt.Sprintf("for _i := range %s {\n%s%s := strconv.Itoa(_i)\n%s}", deref(object), indent, g.goName(name), g.generateBody(stmt.Body))
 	default:
-		return ""
+		g.errorf(stmt.Object, "cannot translate a for...in loop over a value of type '%s': only plain objects, arrays and strings are supported", typeString(objType))
+		return fmt.Sprintf("for _, %s := range []string{} {\n%s}", g.goName(name), g.generateBody(stmt.Body))
 	}
 }
 
-func (g *Generator) generateFunctionDeclaration(fn *ast.FunctionDeclaration) string {
+// generateVariableDeclaration declares a variable with :=, or with var and
+// its zero value if it isn't initialized. Variables without a type
+// annotation get the type of their initializer. A const initialized with a
+// constant expression becomes a Go constant, and var declarations become
+// assignments to the variables hoisted by hoistVars.
+func (g *Generator) generateVariableDeclaration(varDec *ast.VariableDeclaration) string {
+	t := g.variableType(varDec)
+
+	switch {
+	case varDec.Keyword.Type == token.VAR || g.globals[varDec]:
+		if varDec.Expr == nil {
+			return ""
+		}
+		if g.lookupNarrowed(varDec.Name) != nil {
+			g.checkNarrowed(varDec.Name, token.ASSIGN, varDec.Expr)
+			return fmt.Sprintf("%s = %s", g.goName(varDec.Name), g.generateNumber(varDec.Expr, true))
+		}
+		return fmt.Sprintf("%s = %s", g.goName(varDec.Name), g.generateExpressionAs(varDec.Expr, g.lookup(varDec.Name)))
+	case varDec.Keyword.Type == token.CONST && g.isConstant(varDec.Expr) && !g.isUnion(t):
+		if varDec.Type != nil {
+			g.declareConst(varDec.Name, t, floatNum, varDec.Expr)
+			return fmt.Sprintf("const %s %s = %s", g.goName(varDec.Name), g.goType(t), g.generateExpressionAs(varDec.Expr, t))
+		}
+		kind := floatNum
+		if isNumber(t) {
+			kind = g.numKind(varDec.Expr)
+		}
+		g.declareConst(varDec.Name, t, kind, varDec.Expr)
+		return fmt.Sprintf("const %s = %s", g.goName(varDec.Name), g.generateExpression(varDec.Expr))
+	case g.narrows(varDec, t):
+		if varDec.Expr == nil {
+			g.declareInt(varDec, t)
+			return fmt.Sprintf("var %s int", varDec.Name)
+		}
+		if g.holdsInteger(varDec.Expr) {
+			value := g.generateNumber(varDec.Expr, true)
+			g.recordSum(varDec, token.ASSIGN, varDec.Expr)
+			g.declareInt(varDec, t)
+			return fmt.Sprintf("%s := %s", g.goName(varDec.Name), value)
+		}
+		g.widen(varDec)
+	}
+
+	if _, null := varDec.Expr.(*ast.NullLiteral); varDec.Expr == nil || null {
+		g.declare(varDec.Name, t)
+		return fmt.Sprintf("var %s %s", g.goName(varDec.Name), g.goType(t))
+	}
+	value := g.generateTypedExpressionAs(varDec.Expr, t)
+	g.declare(varDec.Name, t)
+	// := would give the variable the type of the value rather than the
+	// interface of the union, or the pointer of a nullable type
+	if g.isUnion(t) && g.goType(g.typeOf(varDec.Expr)) != g.goType(t) {
+		return fmt.Sprintf("var %s %s = %s", g.goName(varDec.Name), g.goType(t), value)
+	}
+	return fmt.Sprintf("%s := %s", g.goName(varDec.Name), value)
+}
+
+// isConstant reports whether Go can evaluate expr at compile time, so that
+// it can initialize a Go constant.
+func (g *Generator) isConstant(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral, *ast.StringLiteral, *ast.BooleanLiteral:
+		return true
+	case *ast.ParenthesizedExpression:
+		return g.isConstant(e.Expression)
+	case *ast.UnaryExpression:
+		if n, ok := g.foldNumber(e); ok && n.folded {
+			return n.kind() != floatNum
+		}
+		return g.isConstant(e.Right)
+	case *ast.VariableExpression:
+		return g.isConst(e.Token.Literal)
+	case *ast.BinaryExpression:
+		if n, ok := g.foldNumber(e); ok && n.folded {
+			return n.kind() != floatNum
+		}
+		// Go doesn't convert between the operands of constant expressions
+		if e.Operator.Type == token.NULLISH || !g.isConstant(e.Left) || !g.isConstant(e.Right) || typeName(g.typeOf(e.Left)) != typeName(g.typeOf(e.Right)) {
+			return false
+		}
+		// remainders that aren't integers are computed with math.Mod
+		return e.Operator.Type != token.MOD || g.binaryKind(e) != floatNum
+	}
+	return false
+}
+
+// constantValue returns the value of expr if it is a constant expression of
+// numbers, strings or booleans, or a member of an enum, reporting false if
+// it isn't one.
+func (g *Generator) constantValue(expr ast.Expression) (constValue, bool) {
+	if n, ok := g.foldNumber(expr); ok {
+		return n, true
+	}
+	if e, member := g.enumMember(expr); e != nil {
+		for i, m := range e.decl.Members {
+			if m.Name.Literal == member {
+				return constValue{exact: e.values[i]}, e.values[i].Kind() != constant.Unknown
+			}
+		}
+	}
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		return constValue{exact: constant.MakeString(e.Token.Literal)}, true
+	case *ast.BooleanLiteral:
+		return constValue{exact: constant.MakeBool(e.Token.Literal == "true")}, true
+	case *ast.ParenthesizedExpression:
+		return g.constantValue(e.Expression)
+	case *ast.VariableExpression:
+		if g.isConst(e.Token.Literal) {
+			return g.lookupValue(e.Token.Literal)
+		}
+	case *ast.BinaryExpression:
+		l, lok := g.constantValue(e.Left)
+		r, rok := g.constantValue(e.Right)
+		if e.Operator.Type == token.PLUS && lok && rok && l.exact.Kind() == constant.String && r.exact.Kind() == constant.String {
+			return constValue{exact: constant.MakeString(constant.StringVal(l.exact) + constant.StringVal(r.exact))}, true
+		}
+	}
+	return constValue{}, false
+}
+
+// hoistVars declares the variables of the var declarations in a function
+// body at its top, since TypeScript scopes them to the function rather than
+// to the block they appear in.
+func (g *Generator) hoistVars(body []Statement) string {
 	builder := strings.Builder{}
+	seen := map[string]bool{}
+	for _, v := range ast.VarDeclarations(body) {
+		if seen[v.Name] {
+			continue
+		}
+		seen[v.Name] = true
+
+		t := g.variableType(v)
+		if g.narrows(v, t) {
+			g.declareInt(v, t)
+			builder.WriteString(fmt.Sprintf("%svar %s int\n", indent, g.goName(v.Name)))
+		} else {
+			g.declare(v.Name, t)
+			builder.WriteString(fmt.Sprintf("%svar %s %s\n", indent, g.goName(v.Name), g.goType(t)))
+		}
+		if g.isUnread(v) {
+			builder.WriteString(fmt.Sprintf("%s_ = %s\n", indent, g.goName(v.Name)))
+		}
+	}
+	return builder.String()
+}
+
+// isUnread reports whether varDec declares a local Go variable that the
+// program never reads, which Go rejects unless the generated code reads it
+// with _ = name. Package-level variables and constants may go unread.
+func (g *Generator) isUnread(varDec *ast.VariableDeclaration) bool {
+	return !g.read[varDec] && !g.globals[varDec] && !g.isConst(varDec.Name)
+}
 
-	params := ""
-	for i, p := range fn.Params {
-		if i > 0 {
-			params += ", "
+// generatePackageVars declares the variables of the top-level statements
+// stmts at package level, where the functions of the program see them. The
+// constants Go can evaluate at compile time are declared with their values,
+// and the other variables are assigned where they are declared in main, so
+// that they are initialized in order. Functions may assign any number to
+// them, so they are never narrowed to int.
+func (g *Generator) generatePackageVars(stmts []Statement) string {
+	builder := strings.Builder{}
+	vars := strings.Builder{}
+	seen := map[string]bool{}
+	declare := func(v *ast.VariableDeclaration) {
+		g.globals[v] = true
+		g.wide[v] = true
+		if seen[v.Name] {
+			return
+		}
+		seen[v.Name] = true
+		t := g.variableType(v)
+		g.declare(v.Name, t)
+		vars.WriteString(fmt.Sprintf("var %s %s\n", g.goName(v.Name), g.goType(t)))
+	}
+	for _, stmt := range stmts {
+		v, ok := stmt.(*ast.VariableDeclaration)
+		switch {
+		case ok && v.Keyword.Type == token.CONST && g.isConstant(v.Expr) && !g.isUnion(g.variableType(v)):
+			builder.WriteString(g.generateCommented(v) + "\n")
+		case ok && v.Keyword.Type != token.VAR:
+			declare(v)
+		default:
+			for _, v := range ast.VarDeclarations([]Statement{stmt}) {
+				declare(v)
+			}
 		}
+	}
+	if builder.Len() > 0 {
+		builder.WriteString("\n")
+	}
+	if vars.Len() > 0 {
+		builder.WriteString(vars.String() + "\n")
+	}
+	return builder.String()
+}
 
-		params += p.String()
+// variableType returns the declared type of a variable, or the type of its
+// initializer if it has no type annotation.
+func (g *Generator) variableType(varDec *ast.VariableDeclaration) ast.TypeExpr {
+	if varDec.Type == nil && varDec.Expr != nil {
+		return g.typeOf(varDec.Expr)
 	}
-	builder.WriteString("func ")
-	builder.WriteString(fn.Name.Literal)
+	return varDec.Type
+}
+
+func (g *Generator) generateFunctionDeclaration(fn *ast.FunctionDeclaration) string {
+	if g.functions[fn.Name.Literal] != fn {
+		return g.generateNestedFunction(fn)
+	}
+	outer := g.declareTypeParams(fn.TypeParams)
+	defer func() { g.typeParams = outer }()
+	return g.generateFunction("func "+g.goName(fn.Name.Literal)+g.generateTypeParams(fn.TypeParams), fn.Params, fn.ReturnType, fn.Body)
+}
+
+// generateFunction generates a function with the given header, such as
+// "func f" or "func (this *T) m", followed by its signature and body.
+func (g *Generator) generateFunction(header string, params []ast.FunctionParam, ret ast.TypeExpr, body []Statement) string {
+	builder := strings.Builder{}
+
+	builder.WriteString(header)
 	builder.WriteString("(")
-	builder.WriteString(params)
+	builder.WriteString(g.generateParams(params))
 	builder.WriteString(")")
-	if fn.ReturnType.Type != token.TYPE_VOID {
+	if ret := g.goType(ret); ret != "" {
 		builder.WriteString(" ")
-		builder.WriteString(returnType(fn.ReturnType.Type))
+		builder.WriteString(ret)
 	}
 	builder.WriteString(" {\n")
+	builder.WriteString(g.generateFunctionBody(params, ret, body))
+	builder.WriteString("}\n")
 
-	for _, stmt := range fn.Body {
-		builder.WriteString("    " + g.generateStatement(stmt) + "\n")
+	return builder.String()
+}
+
+func (g *Generator) generateParams(params []ast.FunctionParam) string {
+	list := make([]string, len(params))
+	for i, p := range params {
+		list[i] = g.goName(p.Name.Literal) + " " + g.goType(p.Type)
 	}
+	return strings.Join(list, ", ")
+}
 
-	builder.WriteString("}\n")
+// generateFunctionBody generates the statements of a function body in a
+// scope declaring its parameters.
+func (g *Generator) generateFunctionBody(params []ast.FunctionParam, ret ast.TypeExpr, body []Statement) string {
+	return g.generateNarrowed(func() string {
+		return g.generateScopedBody(params, ret, body)
+	})
+}
 
-	return builder.String()
+// generateScopedBody is generateFunctionBody without integer narrowing
+// passes of its own.
+func (g *Generator) generateScopedBody(params []ast.FunctionParam, ret ast.TypeExpr, body []Statement) string {
+	g.pushScope()
+	defer g.popScope()
+	for _, p := range params {
+		g.declare(p.Name.Literal, p.Type)
+	}
+
+	outer := g.returnType
+	defer func() { g.returnType = outer }()
+	g.returnType = ret
+
+	code := g.hoistVars(body) + g.generateBlock(body)
+	// a function returning a nullable type may end without a return
+	if g.isNullable(ret) && !exits(&ast.BlockStatement{Statements: body}) {
+		code += indent + "return nil\n"
+	}
+	return code
 }
 
 func (g *Generator) generateReturnStatement(stmt *ast.ReturnStatement) string {
-	return fmt.Sprintf("return %s", stmt.Value.String())
+	if stmt.Value == nil {
+		if g.constructing && !g.hasSelf(g.class) {
+			return "return this"
+		}
+		if g.isNullable(g.returnType) {
+			return "return nil"
+		}
+		return "return"
+	}
+	// the predicates of array methods may return any value, which is tested
+	// for truthiness
+	if typeName(g.returnType) == "boolean" {
+		return "return " + g.generateTest(stmt.Value)
+	}
+	return fmt.Sprintf("return %s", g.generateExpressionAs(stmt.Value, g.returnType))
+}
+
+// generateThrowStatement lowers a throw statement to a panic, with an
+// error for the builtin Error class.
+func (g *Generator) generateThrowStatement(stmt *ast.ThrowStatement) string {
+	if n, ok := stmt.Value.(*ast.NewExpression); ok && n.Class.String() == "Error" && g.classes["Error"] == nil {
+		message := `""`
+		if len(n.Args) > 0 {
+			message = g.generateExpression(n.Args[0])
+		}
+		g.use("errors")
+		return fmt.Sprintf("panic(errors.New(%s))", message)
+	}
+	return fmt.Sprintf("panic(%s)", g.generateExpression(stmt.Value))
+}
+
+func (g *Generator) generateIfStatement(stmt *ast.IfStatement) string {
+	builder := strings.Builder{}
+
+	builder.WriteString("if ")
+	builder.WriteString(g.generateCondition(stmt.Condition))
+	builder.WriteString(" {\n")
+	builder.WriteString(g.generateNarrowedBody(stmt.Consequence, g.narrowings[stmt.Consequence]))
+	builder.WriteString("}")
+
+	switch alt := stmt.Alternative.(type) {
+	case nil:
+	case *ast.IfStatement:
+		// Go has no room for shadowing variables before the condition of
+		// an else if
+		builder.WriteString(" else ")
+		g.pushNarrowing(g.narrowings[alt], false)
+		builder.WriteString(g.generateIfStatement(alt))
+		g.popScope()
+	default:
+		builder.WriteString(" else {\n")
+		builder.WriteString(g.generateNarrowedBody(alt, g.narrowings[alt]))
+		builder.WriteString("}")
+	}
+
+	return builder.String()
+}
+
+// generateBody generates the body of a control flow statement, which Go
+// always requires to be a block.
+func (g *Generator) generateBody(stmt Statement) string {
+	if block, ok := stmt.(*ast.BlockStatement); ok {
+		return g.generateBlock(block.Statements)
+	}
+	return g.generateBlock([]Statement{stmt})
+}
+
+// generateCondition generates the condition of a control flow statement,
+// without the outer parentheses a binary expression would otherwise get.
+func (g *Generator) generateCondition(expr ast.Expression) string {
+	if bin, ok := expr.(*ast.BinaryExpression); ok && !isArithmetic(bin.Operator.Type) {
+		return g.generateBinaryOperands(bin)
+	}
+	return g.generateTest(expr)
+}
+
+func (g *Generator) generateExpression(expr ast.Expression) string {
+	if _, ok := expr.(*ast.NonNullExpression); !ok && ast.IsOptionalChain(expr) {
+		return g.generateOptionalChain(expr)
+	}
+
+	switch e := expr.(type) {
+	case *ast.BinaryExpression:
+		if s, ok := g.generateFolded(e); ok {
+			return s
+		}
+		if s, ok := g.generateRemainder(e); ok {
+			return s
+		}
+		if s, ok := g.generateBigIntArithmetic(e); ok {
+			return s
+		}
+		if e.Operator.Type == token.NULLISH {
+			return g.generateNullish(e)
+		}
+		if (e.Operator.Type == token.AND || e.Operator.Type == token.OR) && typeName(g.typeOf(e)) != "boolean" {
+			return g.generateLogical(e)
+		}
+		return "(" + g.generateBinaryOperands(e) + ")"
+	case *ast.VariableExpression:
+		if s, ok := g.globalNumber(e); ok {
+			g.use("math")
+			return s
+		}
+		if s, ok := g.generateNarrowedVariable(e); ok {
+			return s
+		}
+		if g.isConsole(e) {
+			g.errorf(e, "cannot translate 'console' other than in calls of console.log")
+		}
+		if fn := g.functions[e.Token.Literal]; fn != nil && len(fn.TypeParams) > 0 && g.lookup(e.Token.Literal) == nil {
+			g.errorf(e, "cannot translate generic function '%s' used as a value: Go needs its type arguments, which are only inferred where it is called", e.Token.Literal)
+		}
+		return g.goName(e.Token.Literal)
+	case *ast.UnaryExpression:
+		if e.Operator.Type == token.TYPEOF {
+			return g.generateTypeof(e)
+		}
+		if e.Operator.Type == token.MINUS && isBigInt(g.typeOf(e.Right)) {
+			return fmt.Sprintf("new(big.Int).Neg(%s)", g.generateExpression(e.Right))
+		}
+		if s, ok := g.generateFolded(e); ok {
+			return s
+		}
+		if e.Operator.Type == token.BANG && g.isNullable(g.typeOf(e.Right)) {
+			return "(" + g.generateTruthy(e.Right, false) + ")"
+		}
+		if e.Operator.Type == token.BANG {
+			if s, ok := g.generatePrimitiveTruthy(e.Right, false); ok {
+				return s
+			}
+		}
+		return e.Operator.Literal + g.generateExpression(e.Right)
+	case *ast.BigIntLiteral:
+		return g.generateBigIntLiteral(e)
+	case *ast.NullLiteral:
+		return "nil"
+	case *ast.NonNullExpression:
+		return g.generateNonNull(e)
+	case *ast.ParenthesizedExpression:
+		return "(" + g.generateExpression(e.Expression) + ")"
+	case *ast.StringLiteral:
+		return strconv.Quote(e.Token.Literal)
+	case *ast.TemplateLiteral:
+		return g.generateTemplateLiteral(e)
+	case *ast.ArrayLiteral:
+		return g.generateArrayLiteral(e, nil)
+	case *ast.IndexExpression:
+		if enum, member := g.enumMember(e); enum != nil {
+			return g.generateEnumMember(enum, member)
+		}
+		if enum := g.enumName(e.Left); enum != nil {
+			return g.generateReverseMapping(enum, e.Index)
+		}
+		if g.isString(g.typeOf(e.Left)) {
+			g.useHelper("sildCharAt")
+			return fmt.Sprintf("sildCharAt(%s, %s)", g.generateExpression(e.Left), g.generateIndex(e.Index))
+		}
+		if isArray(g.typeOf(e.Left)) {
+			return fmt.Sprintf("(%s)[%s]", deref(g.generateExpression(e.Left)), g.generateIndex(e.Index))
+		}
+		return fmt.Sprintf("%s[%s]", g.generateExpression(e.Left), g.generateIndex(e.Index))
+	case *ast.ObjectLiteral:
+		return g.generateObjectLiteral(e, nil)
+	case *ast.ThisExpression:
+		return "this"
+	case *ast.SuperExpression:
+		return g.generateSuper(e)
+	case *ast.NewExpression:
+		return g.generateNewExpression(e, nil)
+	case *ast.MemberExpression:
+		return g.generateNarrowedMember(e)
+	case *ast.FunctionCallExpression:
+		return g.generateFunctionCall(e)
+	case *ast.FunctionExpression:
+		return g.generateFunctionExpression(e, nil)
+	default:
+		return expr.String()
+	}
+}
+
+// generateMember generates the property access e.
+func (g *Generator) generateMember(e *ast.MemberExpression) string {
+	if m, ok := g.classMember(e); ok {
+		return g.generateClassMember(e, m)
+	}
+	if enum, member := g.enumMember(e); enum != nil {
+		return g.generateEnumMember(enum, member)
+	}
+	if u := g.discriminantOf(e); u != nil {
+		return fmt.Sprintf("%s.%s()", g.generateExpression(e.Object), fieldName(u.tag))
+	}
+	if g.field(e) != nil {
+		return fmt.Sprintf("%s.%s", g.generateObject(e.Object), fieldName(e.Property.String()))
+	}
+	if t := g.typeOf(e.Object); g.isUnion(t) {
+		g.errorf(e, "cannot translate property '%s' of a value of type '%s': narrow it to one of its members first", e.Property.String(), typeString(t))
+	}
+	// the other properties of strings and arrays are methods, which are
+	// lowered where they are called
+	if t := g.typeOf(e.Object); (g.isString(t) || isArray(g.resolveType(t))) && e.Property.String() != "length" {
+		g.errorf(e, "cannot translate method '%s' of a value of type '%s' other than in a call", e.Property.String(), typeString(g.typeOf(e.Object)))
+	}
+	if e.Property.String() == "length" && g.isString(g.typeOf(e.Object)) {
+		g.useHelper("sildLength")
+		return fmt.Sprintf("sildLength(%s)", g.generateExpression(e.Object))
+	}
+	if e.Property.String() == "length" && isArray(g.typeOf(e.Object)) {
+		return fmt.Sprintf("len(%s)", deref(g.generateExpression(e.Object)))
+	}
+	if e.Property.String() == "length" {
+		return fmt.Sprintf("len(%s)", g.generateExpression(e.Object))
+	}
+	return fmt.Sprintf("%s.%s", g.generateExpression(e.Object), e.Property.String())
+}
+
+// generateExpressionAs generates expr where a value of type expected is
+// wanted, which decides the type of otherwise untyped literals.
+func (g *Generator) generateExpressionAs(expr ast.Expression, expected ast.TypeExpr) string {
+	if s, ok := g.generateNullableAs(expr, expected); ok {
+		return s
+	}
+	if s, ok := g.generateUpcast(expr, expected); ok {
+		return s
+	}
+	if isNumber(expected) && g.isNumberValue(g.typeOf(expr)) {
+		return g.generateNumber(expr, false)
+	}
+	// numbers are float64 in the interface holding a union or any value,
+	// whatever Go type they have
+	if (g.isUnion(expected) || typeName(expected) == "any" || typeName(expected) == "unknown") && g.isNumberValue(g.typeOf(expr)) {
+		if g.numKind(expr) == untypedInt {
+			return g.floatConstant(expr)
+		}
+		return g.generateNumber(expr, false)
+	}
+	// a value of a type parameter is converted to the primitive type that
+	// constrains it
+	if c := g.primitiveConstraint(g.typeOf(expr)); c != nil && g.typeParam(expected) == nil && typeName(expected) == typeName(c) {
+		return conversion(g.goType(c), expr, g.generateExpression(expr))
+	}
+
+	// numbers are converted to numeric enums, and string enums to strings
+	if g.isNumericEnum(expected) && isNumber(g.typeOf(expr)) {
+		return conversion(g.goType(expected), expr, g.generateExpression(expr))
+	}
+	if g.isString(expected) && g.isStringEnum(g.typeOf(expr)) {
+		return conversion("string", expr, g.generateExpression(expr))
+	}
+
+	switch e := expr.(type) {
+	case *ast.ArrayLiteral:
+		return g.generateArrayLiteral(e, expected)
+	case *ast.ObjectLiteral:
+		return g.generateObjectLiteral(e, expected)
+	case *ast.FunctionExpression:
+		return g.generateFunctionExpression(e, expected)
+	case *ast.NewExpression:
+		return g.generateNewExpression(e, expected)
+	default:
+		return g.generateExpression(expr)
+	}
+}
+
+func (g *Generator) generateFunctionCall(call *ast.FunctionCallExpression) string {
+	var params []ast.FunctionParam
+	switch callee := call.Callee.(type) {
+	case *ast.SuperExpression:
+		if g.constructing && g.bases[g.class] != nil {
+			return g.generateSuperCall(call)
+		}
+		g.errorf(callee, "'super' calls are only allowed in constructors of derived classes")
+	case *ast.VariableExpression:
+		if fn, ok := g.functions[callee.Token.Literal]; ok {
+			if len(fn.TypeParams) > 0 {
+				return g.generateGenericCall(call, fn)
+			}
+			params = fn.Params
+		}
+	case *ast.MemberExpression:
+		if g.isConsole(callee.Object) && callee.Property.String() == "log" {
+			return g.generateConsoleLog(call.Args)
+		}
+		if m, ok := g.classMember(callee); ok && m.method != nil {
+			params = ast.SubstituteParams(m.method.Params, m.args)
+			break
+		}
+		if receiver := g.typeOf(callee.Object); isArray(receiver) {
+			if s, ok := g.generateArrayMethodCall(callee.Object, receiver, callee.Property.String(), call.Args); ok {
+				return s
+			}
+		}
+		if g.isString(g.typeOf(callee.Object)) {
+			if s, ok := g.generateStringMethodCall(callee.Object, callee.Property.String(), call.Args); ok {
+				return s
+			}
+		}
+	}
+	if params == nil {
+		// a call of a value of function type
+		if fn := g.funcType(g.typeOf(call.Callee)); fn != nil {
+			params = fn.Params
+		}
+	}
+
+	return fmt.Sprintf("%s(%s)", g.generateExpression(call.Callee), g.generateArgs(params, call.Args))
+}
+
+// generateGenericCall generates a call of a generic function, with the type
+// arguments Go can't infer.
+func (g *Generator) generateGenericCall(call *ast.FunctionCallExpression, fn *ast.FunctionDeclaration) string {
+	bound := g.typeArgs[call]
+	typeArgs := g.generateCallTypeArgs(fn.TypeParams, bound, fn.Params, call.Args)
+	return fmt.Sprintf("%s%s(%s)", g.goName(fn.Name.Literal), typeArgs, g.generateGenericArgs(fn.Params, bound, call.Args))
+}
+
+// generateArgs generates the arguments of a call, typed by the parameters of
+// the function called where they are known.
+func (g *Generator) generateArgs(params []ast.FunctionParam, args []ast.Expression) string {
+	list := make([]string, len(args))
+	for i, arg := range args {
+		var expected ast.TypeExpr
+		if i < len(params) {
+			expected = params[i].Type
+		}
+		list[i] = g.generateExpressionAs(arg, expected)
+	}
+	return strings.Join(list, ", ")
+}
+
+func (g *Generator) generateBinaryOperands(e *ast.BinaryExpression) string {
+	if s, ok := g.generateTypeParamOperands(e); ok {
+		return s
+	}
+	if g.isNumericOperation(e) {
+		return g.generateNumericOperands(e)
+	}
+	if s, ok := g.generateBigIntComparison(e); ok {
+		return s
+	}
+	if e.Operator.Type == token.PLUS && g.isString(g.typeOf(e)) {
+		return g.generateString(e.Left) + " + " + g.generateString(e.Right)
+	}
+
+	switch e.Operator.Type {
+	case token.IN:
+		return g.generateIn(e)
+	case token.NULLISH:
+		return g.generateNullish(e)
+	case token.AND, token.OR:
+		// the right operand is only evaluated if the left one didn't
+		// decide the result, which narrows it
+		left := g.generateTest(e.Left)
+		g.pushNarrowing(g.narrowings[e.Right], false)
+		defer g.popScope()
+		return fmt.Sprintf("%s %s %s", left, goOperator(e.Operator), g.generateTest(e.Right))
+	case token.EQUAL, token.STRICT_EQUAL, token.NOT_EQUAL, token.STRICT_NOT_EQUAL:
+		if s, ok := g.generateNullEquality(e); ok {
+			return s
+		}
+		if left, right := g.typeOf(e.Left), g.typeOf(e.Right); g.isUnion(left) || g.isUnion(right) {
+			return fmt.Sprintf("%s %s %s", g.generateExpressionAs(e.Left, right), goOperator(e.Operator), g.generateExpressionAs(e.Right, left))
+		}
+	}
+	return fmt.Sprintf("%s %s %s", g.generateExpression(e.Left), goOperator(e.Operator), g.generateExpression(e.Right))
+}
+
+func isArithmetic(op token.TokenType) bool {
+	switch op {
+	case token.PLUS, token.MINUS, token.MUL, token.DIV, token.MOD:
+		return true
+	}
+	return false
+}
+
+// goOperator maps a TypeScript binary operator to its Go equivalent.
+func goOperator(op token.Token) string {
+	switch op.Type {
+	case token.STRICT_EQUAL:
+		return "=="
+	case token.STRICT_NOT_EQUAL:
+		return "!="
+	default:
+		return op.Literal
+	}
 }
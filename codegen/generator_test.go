@@ -1,13 +1,20 @@
 package codegen
 
 import (
+	goast "go/ast"
+	"go/importer"
+	goparser "go/parser"
+	gotoken "go/token"
+	gotypes "go/types"
 	"strings"
 	"testing"
 
 	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/diag"
 	"github.com/toyaAoi/sild/parser"
 	"github.com/toyaAoi/sild/scanner"
 	"github.com/toyaAoi/sild/token"
+	"github.com/toyaAoi/sild/types"
 )
 
 type VariableDeclaration = ast.VariableDeclaration
@@ -22,11 +29,24 @@ func createProgram(statements ...Statement) *Program {
 	return &Program{Statements: statements}
 }
 
+func typeRef(name string) ast.TypeExpr {
+	return &ast.TypeReference{Name: token.Token{Type: token.LookupType(name), Literal: name}}
+}
+
 func createVariableDeclaration(name, typ, value string) *VariableDeclaration {
+	var expr ast.Expression
+	switch typ {
+	case "string":
+		expr = &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}}
+	case "boolean":
+		expr = &ast.BooleanLiteral{Token: token.Token{Type: token.BOOLEAN, Literal: value}}
+	default:
+		expr = &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: value}}
+	}
 	return &VariableDeclaration{
 		Name: name,
-		Type: typ,
-		Expr: &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: value}},
+		Type: typeRef(typ),
+		Expr: expr,
 	}
 }
 
@@ -35,12 +55,15 @@ func runTests(t *testing.T, tests []testCase) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := New()
-			result := g.Generate(tt.program)
+			result := generate(g, tt.program)
 
 			if result != tt.expected {
 				t.Errorf("Test %s failed\nExpected:\n%q\nGot:\n%q",
 					tt.name, tt.expected, result)
 			}
+			if err := typeCheck(result); err != nil {
+				t.Errorf("generated Go doesn't type check: %v\n%s", err, result)
+			}
 		})
 	}
 }
@@ -52,7 +75,7 @@ func TestVariableDeclarations(t *testing.T) {
 			program: createProgram(
 				createVariableDeclaration("x", "number", "42"),
 			),
-			expected: "package main\n\nfunc main() {\n    x := 42\n}\n",
+			expected: "package main\n\nvar x float64\n\nfunc main() {\n    x = 42\n}\n",
 		},
 		{
 			name: "multiple variables",
@@ -61,7 +84,7 @@ func TestVariableDeclarations(t *testing.T) {
 				createVariableDeclaration("name", "string", "hello"),
 				createVariableDeclaration("active", "boolean", "true"),
 			),
-			expected: "package main\n\nfunc main() {\n    x := 42\n    name := \"hello\"\n    active := true\n}\n",
+			expected: "package main\n\nvar x float64\nvar name string\nvar active bool\n\nfunc main() {\n    x = 42\n    name = \"hello\"\n    active = true\n}\n",
 		},
 	}
 
@@ -75,7 +98,7 @@ func TestExpressions(t *testing.T) {
 			program: createProgram(
 				&VariableDeclaration{
 					Name: "result",
-					Type: "number",
+					Type: typeRef("number"),
 					Expr: &ast.BinaryExpression{
 						Left:     &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "10"}},
 						Operator: token.Token{Type: token.PLUS, Literal: "+"},
@@ -83,14 +106,14 @@ func TestExpressions(t *testing.T) {
 					},
 				},
 			),
-			expected: "package main\n\nfunc main() {\n    result := (10 + 20)\n}\n",
+			expected: "package main\n\nvar result float64\n\nfunc main() {\n    result = (10 + 20)\n}\n",
 		},
 		{
 			name: "nested expressions",
 			program: createProgram(
 				&VariableDeclaration{
 					Name: "result",
-					Type: "number",
+					Type: typeRef("number"),
 					Expr: &ast.BinaryExpression{
 						Left: &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "10"}},
 						Operator: token.Token{Type: token.PLUS, Literal: "+"},
@@ -102,7 +125,7 @@ func TestExpressions(t *testing.T) {
 					},
 				},
 			),
-			expected: "package main\n\nfunc main() {\n    result := (10 + (5 * 3))\n}\n",
+			expected: "package main\n\nvar result float64\n\nfunc main() {\n    result = (10 + (5 * 3))\n}\n",
 		},
 	}
 
@@ -139,18 +162,14 @@ func TestEdgeCases(t *testing.T) {
 				}()
 			}
 
-			_ = g.Generate(tt.program)
+			_ = generate(g, tt.program)
 		})
 	}
 }
 
 
 func TestFunctionCodeGeneration(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{
+	tests := []generationTest{
 		{
 			name: "simple_void_function",
 			input: `function greet(): void {}`,
@@ -170,7 +189,7 @@ func main() {
 }`,
 			expected: `package main
 
-func getNumber() int {
+func getNumber() float64 {
     return 42
 }
 
@@ -185,7 +204,7 @@ func main() {
 }`,
 			expected: `package main
 
-func double(x int) int {
+func double(x float64) float64 {
     return (x * 2)
 }
 
@@ -200,7 +219,7 @@ func main() {
 }`,
 			expected: `package main
 
-func add(a int, b int) int {
+func add(a float64, b float64) float64 {
     return (a + b)
 }
 
@@ -245,7 +264,7 @@ func main() {
 }`,
 			expected: `package main
 
-func process(name string, age int, active bool) string {
+func process(name string, age float64, active bool) string {
     return name
 }
 
@@ -255,32 +274,11 @@ func main() {
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			scanner := scanner.New(strings.NewReader(tt.input))
-			parser := parser.New(scanner)
-			program := parser.ParseProgram()
-			
-			if program == nil {
-				t.Fatalf("Failed to parse input: %s", tt.input)
-			}
-			
-			generator := New()
-			output := generator.Generate(program)
-			
-			if !compareOutput(output, tt.expected) {
-				t.Errorf("Output mismatch\nExpected:\n%s\nGot:\n%s", tt.expected, output)
-			}
-		})
-	}
+	runGenerationTests(t, tests)
 }
 
 func TestFunctionAndVariableMix(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{
+	tests := []generationTest{
 		{
 			name: "variable_before_function",
 			input: `let x: number = 42;
@@ -289,12 +287,14 @@ function getValue(): number {
 }`,
 			expected: `package main
 
-func getValue() int {
+var x float64
+
+func getValue() float64 {
     return x
 }
 
 func main() {
-    x := 42
+    x = 42
 }
 `,
 		},
@@ -306,12 +306,14 @@ func main() {
 let x: number = 10;`,
 			expected: `package main
 
-func getValue() int {
+var x float64
+
+func getValue() float64 {
     return 42
 }
 
 func main() {
-    x := 10
+    x = 10
 }
 `,
 		},
@@ -327,17 +329,20 @@ function multiply(a: number, b: number): number {
 let y: number = 10;`,
 			expected: `package main
 
-func add(a int, b int) int {
+var x float64
+var y float64
+
+func add(a float64, b float64) float64 {
     return (a + b)
 }
 
-func multiply(a int, b int) int {
+func multiply(a float64, b float64) float64 {
     return (a * b)
 }
 
 func main() {
-    x := 5
-    y := 10
+    x = 5
+    y = 10
 }
 `,
 		},
@@ -349,43 +354,24 @@ func main() {
 let result: number = double(21);`,
 			expected: `package main
 
-func double(x int) int {
+var result float64
+
+func double(x float64) float64 {
     return (x * 2)
 }
 
 func main() {
-    result := double(21)
+    result = double(21)
 }
 `,
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			scanner := scanner.New(strings.NewReader(tt.input))
-			parser := parser.New(scanner)
-			program := parser.ParseProgram()
-			
-			if program == nil {
-				t.Fatalf("Failed to parse input: %s", tt.input)
-			}
-			
-			generator := New()
-			output := generator.Generate(program)
-			
-			if !compareOutput(output, tt.expected) {
-				t.Errorf("Output mismatch\nExpected:\n%s\nGot:\n%s", tt.expected, output)
-			}
-		})
-	}
+	runGenerationTests(t, tests)
 }
 
 func TestFunctionWithComplexBody(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{
+	tests := []generationTest{
 		{
 			name: "function_with_multiple_statements",
 			input: `function calculate(x: number): number {
@@ -395,7 +381,7 @@ func TestFunctionWithComplexBody(t *testing.T) {
 }`,
 			expected: `package main
 
-func calculate(x int) int {
+func calculate(x float64) float64 {
     doubled := (x * 2)
     result := (doubled + 10)
     return result
@@ -414,7 +400,7 @@ func main() {
 }`,
 			expected: `package main
 
-func compute(a int, b int) int {
+func compute(a float64, b float64) float64 {
     sum := (a + b)
     product := (sum * 2)
     return product
@@ -426,24 +412,7 @@ func main() {
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			scanner := scanner.New(strings.NewReader(tt.input))
-			parser := parser.New(scanner)
-			program := parser.ParseProgram()
-			
-			if program == nil {
-				t.Fatalf("Failed to parse input: %s", tt.input)
-			}
-			
-			generator := New()
-			output := generator.Generate(program)
-			
-			if !compareOutput(output, tt.expected) {
-				t.Errorf("Output mismatch\nExpected:\n%s\nGot:\n%s", tt.expected, output)
-			}
-		})
-	}
+	runGenerationTests(t, tests)
 }
 
 func TestMultipleFunctions(t *testing.T) {
@@ -463,37 +432,26 @@ let result: number = add(10, 5);`
 
 	expected := `package main
 
-func add(a int, b int) int {
+var result float64
+
+func add(a float64, b float64) float64 {
     return (a + b)
 }
 
-func subtract(a int, b int) int {
+func subtract(a float64, b float64) float64 {
     return (a - b)
 }
 
-func multiply(a int, b int) int {
+func multiply(a float64, b float64) float64 {
     return (a * b)
 }
 
 func main() {
-    result := add(10, 5)
+    result = add(10, 5)
 }
 `
 
-	scanner := scanner.New(strings.NewReader(input))
-	parser := parser.New(scanner)
-	program := parser.ParseProgram()
-	
-	if program == nil {
-		t.Fatalf("Failed to parse input")
-	}
-	
-	generator := New()
-	output := generator.Generate(program)
-	
-	if !compareOutput(output, expected) {
-		t.Errorf("Output mismatch\nExpected:\n%s\nGot:\n%s", expected, output)
-	}
+	checkGeneration(t, input, expected, false)
 }
 
 func TestEmptyFunctionBody(t *testing.T) {
@@ -509,28 +467,11 @@ func main() {
 }
 `
 
-	scanner := scanner.New(strings.NewReader(input))
-	parser := parser.New(scanner)
-	program := parser.ParseProgram()
-	
-	if program == nil {
-		t.Fatalf("Failed to parse input")
-	}
-	
-	generator := New()
-	output := generator.Generate(program)
-	
-	if !compareOutput(output, expected) {
-		t.Errorf("Output mismatch\nExpected:\n%s\nGot:\n%s", expected, output)
-	}
+	checkGeneration(t, input, expected, false)
 }
 
 func TestFunctionReturningExpression(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{
+	tests := []generationTest{
 		{
 			name: "return_arithmetic",
 			input: `function calculate(): number {
@@ -538,7 +479,7 @@ func TestFunctionReturningExpression(t *testing.T) {
 }`,
 			expected: `package main
 
-func calculate() int {
+func calculate() float64 {
     return (10 + 20)
 }
 
@@ -553,7 +494,7 @@ func main() {
 }`,
 			expected: `package main
 
-func compute(x int) int {
+func compute(x float64) float64 {
     return ((x * 2) + 10)
 }
 
@@ -568,7 +509,7 @@ func main() {
 }`,
 			expected: `package main
 
-func calc(a int, b int) int {
+func calc(a float64, b float64) float64 {
     return ((a + b) * 2)
 }
 
@@ -578,27 +519,9 @@ func main() {
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			scanner := scanner.New(strings.NewReader(tt.input))
-			parser := parser.New(scanner)
-			program := parser.ParseProgram()
-			
-			if program == nil {
-				t.Fatalf("Failed to parse input: %s", tt.input)
-			}
-			
-			generator := New()
-			output := generator.Generate(program)
-			
-			if !compareOutput(output, tt.expected) {
-				t.Errorf("Output mismatch\nExpected:\n%s\nGot:\n%s", tt.expected, output)
-			}
-		})
-	}
+	runGenerationTests(t, tests)
 }
 
-// Helper function to compare outputs ignoring minor whitespace differences
 func compareOutput(got, expected string) bool {
 	got = strings.TrimSpace(got)
 	expected = strings.TrimSpace(expected)
@@ -608,4 +531,7015 @@ func compareOutput(got, expected string) bool {
 	expected = strings.ReplaceAll(expected, "\r\n", "\n")
 	
 	return got == expected
-}
\ No newline at end of file
+}
+
+// generationTest is a program and the Go generated for it, or the first
+// diagnostic generating it reports.
+type generationTest struct {
+	name     string
+	narrow   bool // lower number variables holding integers to int
+	input    string
+	expected string
+}
+
+func runGenerationTests(t *testing.T, tests []generationTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkGeneration(t, tt.input, tt.expected, tt.narrow)
+		})
+	}
+}
+
+// checkGeneration generates the Go of input, compares it with expected and
+// type checks it.
+func checkGeneration(t *testing.T, input, expected string, narrow bool) {
+	t.Helper()
+	program := parse(t, input)
+
+	generator := New()
+	generator.NarrowIntegers = narrow
+	output := generate(generator, program)
+	if diag.HasErrors(generator.Diagnostics()) {
+		t.Fatalf("unexpected diagnostics: %v", generator.Diagnostics())
+	}
+	if !compareOutput(output, expected) {
+		t.Errorf("Output mismatch\nExpected:\n%s\nGot:\n%s", expected, output)
+	}
+	if err := typeCheck(output); err != nil {
+		t.Errorf("generated Go doesn't type check: %v\n%s", err, output)
+	}
+}
+
+func runDiagnosticTests(t *testing.T, tests []generationTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := New()
+			generate(generator, parse(t, tt.input))
+
+			diags := generator.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// generate generates the Go of program with the types the type checker
+// finds in it.
+func generate(g *Generator, program *Program) string {
+	info, _ := types.Check(program)
+	return g.Generate(program, info)
+}
+
+func parse(t *testing.T, input string) *Program {
+	t.Helper()
+	p := parser.New(scanner.New(strings.NewReader(input)))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("Failed to parse input: %v", p.Diagnostics())
+	}
+	return program
+}
+
+// typeCheck type checks the generated Go program src.
+func typeCheck(src string) error {
+	fset := gotoken.NewFileSet()
+	file, err := goparser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		return err
+	}
+	conf := gotypes.Config{Importer: importer.Default()}
+	_, err = conf.Check("main", fset, []*goast.File{file}, nil)
+	return err
+}
+
+func TestIfStatementGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "if_else_if_else",
+			input: `function sign(x: number): number {
+    if (x > 0) {
+        return 1;
+    } else if (x === 0) {
+        return 0;
+    } else {
+        return -1;
+    }
+}`,
+			expected: `package main
+
+func sign(x float64) float64 {
+    if x > 0 {
+        return 1
+    } else if x == 0 {
+        return 0
+    } else {
+        return -1
+    }
+}
+
+func main() {
+}
+`,
+		},
+		{
+			name: "single_statement_bodies",
+			input: `function max(a: number, b: number): number {
+    if (a >= b) return a;
+    return b;
+}`,
+			expected: `package main
+
+func max_(a float64, b float64) float64 {
+    if a >= b {
+        return a
+    }
+    return b
+}
+
+func main() {
+}
+`,
+		},
+		{
+			name: "logical_operators",
+			input: `function inRange(x: number, lo: number, hi: number): boolean {
+    if (!(x < lo) && x <= hi || x !== x) {
+        return true;
+    }
+    return false;
+}`,
+			expected: `package main
+
+func inRange(x float64, lo float64, hi float64) bool {
+    if (!(x < lo) && (x <= hi)) || (x != x) {
+        return true
+    }
+    return false
+}
+
+func main() {
+}
+`,
+		},
+		{
+			name: "truthiness_of_numbers_and_strings",
+			input: `function describe(n: number, s: string): string {
+    if (n && !s) {
+        return "number";
+    }
+    while (!n) {
+        n = n + 1;
+    }
+    return s;
+}`,
+			expected: `package main
+
+import (
+    "math"
+)
+
+func describe(n float64, s string) string {
+    if n != 0 && !math.IsNaN(n) && (s == "") {
+        return "number"
+    }
+    for (n == 0 || math.IsNaN(n)) {
+        n = (n + 1)
+    }
+    return s
+}
+
+func main() {
+}
+
+`,
+		},
+		{
+			name: "logical_operators_on_strings_and_numbers",
+			input: `function orDefault(s: string): string {
+    return s || "anonymous";
+}
+function decrement(n: number): number {
+    return n && n - 1;
+}`,
+			expected: `package main
+
+import (
+    "math"
+)
+
+func orDefault(s string) string {
+    return func() string {
+        if s != "" {
+            return s
+        }
+        return "anonymous"
+    }()
+}
+
+func decrement(n float64) float64 {
+    return func() float64 {
+        if (n == 0 || math.IsNaN(n)) {
+            return n
+        }
+        return (n - 1)
+    }()
+}
+
+func main() {
+}
+
+`,
+		},
+		{
+			name: "nested_if_in_main",
+			input: `let x: number = 3;
+if (x > 1) {
+    if (x < 5) {
+        let y: string = "mid";
+        console.log(y);
+    }
+}`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+var x float64
+
+func main() {
+    x = 3
+    if x > 1 {
+        if x < 5 {
+            y := "mid"
+            fmt.Println(y)
+        }
+    }
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestLoopGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "while_loop",
+			input: `function countdown(n: number): number {
+    while (n > 0) {
+        n -= 1;
+    }
+    return n;
+}`,
+			expected: `package main
+
+func countdown(n float64) float64 {
+    for n > 0 {
+        n -= 1
+    }
+    return n
+}
+
+func main() {
+}
+`,
+		},
+		{
+			name: "do_while_loop",
+			input: `function firstPowerOver(limit: number): number {
+    let p: number = 1;
+    do {
+        p *= 2;
+        if (p === 8) continue;
+    } while (p <= limit);
+    return p;
+}`,
+			expected: `package main
+
+func firstPowerOver(limit float64) float64 {
+    p := 1.0
+    for _do := true; _do; _do = p <= limit {
+        p *= 2
+        if p == 8 {
+            continue
+        }
+    }
+    return p
+}
+
+func main() {
+}
+`,
+		},
+		{
+			name: "c_style_for_loop",
+			input: `function sum(n: number): number {
+    let total: number = 0;
+    for (let i: number = 0; i < n; i++) {
+        if (i % 2 == 1) {
+            continue;
+        }
+        total += i;
+    }
+    return total;
+}`,
+			expected: `package main
+
+import (
+    "math"
+)
+
+func sum(n float64) float64 {
+    total := 0.0
+    for i := 0.0; i < n; i++ {
+        if math.Mod(i, 2) == 1 {
+            continue
+        }
+        total += i
+    }
+    return total
+}
+
+func main() {
+}
+`,
+		},
+		{
+			name: "for_without_init_and_update",
+			input: `let i: number = 0;
+for (; i < 3;) {
+    i++;
+}
+for (;;) {
+    break;
+}`,
+			expected: `package main
+
+var i float64
+
+func main() {
+    i = 0
+    for i < 3 {
+        i++
+    }
+    for {
+        break
+    }
+}
+`,
+		},
+		{
+			name: "labeled_break_and_continue",
+			input: `let found: boolean = false;
+outer: for (let i: number = 0; i < 3; i++) {
+    inner: for (let j: number = 0; j < 3; j++) {
+        if (i * j === 2) {
+            found = true;
+            break outer;
+        }
+        continue outer;
+    }
+}`,
+			expected: `package main
+
+var found bool
+
+func main() {
+    found = false
+    outer:
+    for i := 0.0; i < 3; i++ {
+        for j := 0.0; j < 3; j++ {
+            if (i * j) == 2 {
+                found = true
+                break outer
+            }
+            continue outer
+        }
+    }
+}
+`,
+		},
+		{
+			name: "infinite_loops_terminate_functions",
+			input: `function first(): number {
+    while (true) {
+        return 1;
+    }
+}
+function over(limit: number): number {
+    for (let i = 0; true; i++) {
+        if (i > limit) {
+            return i;
+        }
+    }
+}
+console.log(first(), over(2));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+func first() float64 {
+    for {
+        return 1
+    }
+}
+
+func over(limit float64) float64 {
+    for i := 0.0; ; i++ {
+        if i > limit {
+            return i
+        }
+    }
+}
+
+func main() {
+    fmt.Println(sildNumberString(first()), sildNumberString(over(2)))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestForInOfGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "for_of_string_by_code_point",
+			input: `function countVowels(word: string): number {
+    let count: number = 0;
+    for (const ch of word) {
+        if (ch === "a" || ch === "e") {
+            count++;
+        }
+    }
+    return count;
+}`,
+			expected: `package main
+
+func countVowels(word string) float64 {
+    count := 0.0
+    for _, _r := range word {
+        ch := string(_r)
+        if (ch == "a") || (ch == "e") {
+            count++
+        }
+    }
+    return count
+}
+
+func main() {
+}
+`,
+		},
+		{
+			name: "for_in_string_yields_string_indices",
+			input: `let s: string = "héllo";
+for (const i in s) {
+    console.log(i);
+}`,
+			expected: `package main
+
+import (
+    "fmt"
+    "strconv"
+    "unicode/utf16"
+)
+
+var s string
+
+func main() {
+    s = "héllo"
+    for _i := range utf16.Encode([]rune(s)) {
+        i := strconv.Itoa(_i)
+        fmt.Println(i)
+    }
+}
+`,
+		},
+		{
+			name: "for_of_evaluates_the_array_once",
+			input: `function items(): string[] {
+    return ["a"];
+}
+for (const item of items()) {
+    console.log(item);
+}`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+func items() *[]string {
+    return &[]string{"a"}
+}
+
+func main() {
+    for _v, _i := items(), 0; _i < len(*_v); _i++ {
+        item := (*_v)[_i]
+        fmt.Println(item)
+    }
+}
+`,
+		},
+		{
+			name: "for_in_skips_missing_optional_properties",
+			input: `type T = { b: number; a: number; c?: number };
+let o: T = { b: 1, a: 2 };
+for (const k in o) { console.log(k); }`,
+			expected: `package main
+
+import (
+    "fmt"
+    "reflect"
+    "strings"
+)
+
+type T struct {
+    B float64  ` + "`json:\"b\"`" + `
+    A float64  ` + "`json:\"a\"`" + `
+    C *float64 ` + "`json:\"c,omitempty\"`" + `
+}
+
+var o *T
+
+func main() {
+    o = &T{B: 1, A: 2}
+    for _, k := range sildPresentKeys(o) {
+        fmt.Println(k)
+    }
+}
+
+// sildPresentKeys returns the keys of the object o, leaving out the optional
+// properties it doesn't have, which are the nil fields tagged omitempty.
+func sildPresentKeys[T any](o T) []string {
+    v := reflect.Indirect(reflect.ValueOf(o))
+    keys := []string{}
+    for i := range v.NumField() {
+        name, opts, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+        if opts == "omitempty" && v.Field(i).IsNil() {
+            continue
+        }
+        keys = append(keys, name)
+    }
+    return keys
+}`,
+		},
+		{
+			name: "loops_with_unused_variables",
+			input: `let xs = [1, 2];
+let n = 0;
+for (const _ of xs) { n++; }
+for (const c of "ab") { n++; }
+for (const i in xs) { n++; }
+for (const i in "ab") { n++; }
+for (const k in { a: 1 }) { n++; }`,
+			expected: `package main
+
+import (
+    "unicode/utf16"
+)
+
+var xs *[]float64
+var n float64
+
+func main() {
+    xs = &[]float64{1, 2}
+    n = 0
+    for _i := 0; _i < len(*xs); _i++ {
+        n++
+    }
+    for range "ab" {
+        n++
+    }
+    for range *xs {
+        n++
+    }
+    for range utf16.Encode([]rune("ab")) {
+        n++
+    }
+    for range sildKeys(&struct{ A float64 ` + "`json:\"a\"`" + ` }{A: 1}, "a") {
+        n++
+    }
+}
+
+// sildKeys returns keys, the keys of the object o. Go knows them statically;
+// o is only taken so that it is still evaluated.
+func sildKeys[T any](o T, keys ...string) []string {
+    return keys
+}`,
+		},
+		{
+			name: "for_of_visits_pushed_elements",
+			input: `let xs: number[] = [1, 2];
+for (const x of xs) {
+    if (x < 4) {
+        xs.push(x + 2);
+    }
+}
+console.log(xs.length);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "strconv"
+)
+
+var xs *[]float64
+
+func main() {
+    xs = &[]float64{1, 2}
+    for _i := 0; _i < len(*xs); _i++ {
+        x := (*xs)[_i]
+        if x < 4 {
+            *xs = append(*xs, (x + 2))
+        }
+    }
+    fmt.Println(strconv.Itoa(len(*xs)))
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestForInOfDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "for_in_over_a_class_instance",
+			input:    `class Box { w: number = 1; }
+for (const key in new Box()) { console.log(key); }`,
+			expected: "2:19: error: cannot translate a for...in loop over a value of type 'Box': only plain objects, arrays and strings are supported",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestArrayGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "arrays_are_shared_by_reference",
+			input: `function add(xs: number[], x: number): void {
+    xs.push(x);
+}
+function grow(): number {
+    let a: number[] = [1];
+    let b = a;
+    add(b, 2);
+    return a.length;
+}`,
+			expected: `package main
+
+func add(xs *[]float64, x float64) {
+    *xs = append(*xs, x)
+}
+
+func grow() float64 {
+    a := &[]float64{1}
+    b := a
+    add(b, 2)
+    return float64(len(*a))
+}
+
+func main() {
+}
+
+`,
+		},
+		{
+			name: "literals_index_and_length",
+			input: `let xs: number[] = [1, 2, 3];
+let empty: Array<string> = [];
+xs[0] = xs[1] + xs.length;`,
+			expected: `package main
+
+var xs *[]float64
+var empty *[]string
+
+func main() {
+    xs = &[]float64{1, 2, 3}
+    empty = &[]string{}
+    (*xs)[0] = ((*xs)[1] + float64(len(*xs)))
+}
+`,
+		},
+		{
+			name: "array_params_and_returns",
+			input: `function pair(a: number, b: number): number[] {
+    return [a, b];
+}
+function total(xs: number[]): number {
+    let sum: number = 0;
+    for (const x of xs) {
+        sum += x;
+    }
+    return sum;
+}
+console.log(total([1, 2]));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+func pair(a float64, b float64) *[]float64 {
+    return &[]float64{a, b}
+}
+
+func total(xs *[]float64) float64 {
+    sum := 0.0
+    for _i := 0; _i < len(*xs); _i++ {
+        x := (*xs)[_i]
+        sum += x
+    }
+    return sum
+}
+
+func main() {
+    fmt.Println(sildNumberString(total(&[]float64{1, 2})))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "inline_methods",
+			input: `let xs: number[] = [];
+xs.push(1, 2);
+xs.reverse();
+let names: string[] = ["a", "b"];
+console.log(names.join(", "), xs.includes(2), xs.indexOf(1), xs.concat([3]));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "runtime"
+    "slices"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+var xs *[]float64
+var names *[]string
+
+func main() {
+    xs = &[]float64{}
+    *xs = append(*xs, 1, 2)
+    slices.Reverse(*xs)
+    names = &[]string{"a", "b"}
+    fmt.Println(strings.Join(*names, ", "), strconv.FormatBool(sildIncludes(*xs, 2)), strconv.Itoa(slices.Index(*xs, 1)), sildInspect(sildConcat(xs, &[]float64{3}), "undefined"))
+}
+
+// sildConcat returns a new array holding the elements of xs followed by
+// those of each of others.
+func sildConcat[T any](xs *[]T, others ...*[]T) *[]T {
+    out := append([]T{}, *xs...)
+    for _, o := range others {
+        out = append(out, *o...)
+    }
+    return &out
+}
+
+// sildIncludes reports whether xs contains x like Array.prototype.includes,
+// which finds NaN too.
+func sildIncludes[T comparable](xs []T, x T) bool {
+    return slices.ContainsFunc(xs, func(y T) bool {
+        return y == x || y != y && x != x
+    })
+}
+
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "helper_methods",
+			input: `function isEven(x: number): boolean {
+    return x % 2 === 0;
+}
+let xs: number[] = [1, 2, 3];
+console.log(xs.filter(isEven), xs.pop() ?? 0);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "runtime"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+var xs *[]float64
+
+func isEven(x float64) bool {
+    return (math.Mod(x, 2) == 0)
+}
+
+func main() {
+    xs = &[]float64{1, 2, 3}
+    fmt.Println(sildInspect(sildFilter(*xs, isEven), "undefined"), sildNumberString(func() float64 {
+        if _v := sildPopPtr(xs); _v != nil {
+            return *_v
+        }
+        return 0
+    }()))
+}
+
+func sildFilter[T any](xs []T, f func(T) bool) *[]T {
+    out := []T{}
+    for _, x := range xs {
+        if f(x) {
+            out = append(out, x)
+        }
+    }
+    return &out
+}
+
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPopPtr removes the last element of xs and returns a pointer to it, or
+// nil if xs is empty.
+func sildPopPtr[T any](xs *[]T) *T {
+    n := len(*xs)
+    if n == 0 {
+        return nil
+    }
+    last := (*xs)[n-1]
+    *xs = (*xs)[:n-1]
+    return &last
+}
+`,
+		},
+		{
+			name: "for_in_array_yields_string_indices",
+			input: `let xs: boolean[] = [true];
+for (const i in xs) {
+    console.log(i);
+}`,
+			expected: `package main
+
+import (
+    "fmt"
+    "strconv"
+)
+
+var xs *[]bool
+
+func main() {
+    xs = &[]bool{true}
+    for _i := range *xs {
+        i := strconv.Itoa(_i)
+        fmt.Println(i)
+    }
+}
+`,
+		},
+		{
+			name: "callbacks_take_the_index",
+			input: `let xs = [1, 2, 3];
+let names = ["a", "b"];
+xs.forEach((x, i) => console.log(x * i));
+console.log(xs.map((x: number, i: number) => i).join(), xs.filter((x, i) => i > 0).length, names.find((s, i) => i == 1) ?? "", xs.some((x, i) => x == i), xs.every((x, i) => x == i + 1));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+var xs *[]float64
+var names *[]string
+
+func main() {
+    xs = &[]float64{1, 2, 3}
+    names = &[]string{"a", "b"}
+    sildForEachIndexed(*xs, func(x float64, i float64) {
+        fmt.Println(sildNumberString((x * i)))
+    })
+    fmt.Println(sildJoin(*sildMapIndexed(*xs, func(x float64, i float64) float64 {
+        return i
+    })), strconv.Itoa(len(*sildFilterIndexed(*xs, func(x float64, i float64) bool {
+        return (i > 0)
+    }))), func() string {
+        if _v := sildFindPtrIndexed(*names, func(s string, i float64) bool {
+            return (i == 1)
+        }); _v != nil {
+            return *_v
+        }
+        return ""
+    }(), strconv.FormatBool(sildSomeIndexed(*xs, func(x float64, i float64) bool {
+        return (x == i)
+    })), strconv.FormatBool(sildEveryIndexed(*xs, func(x float64, i float64) bool {
+        return (x == (i + 1))
+    })))
+}
+
+func sildEveryIndexed[T any](xs []T, f func(T, float64) bool) bool {
+    for i, x := range xs {
+        if !f(x, float64(i)) {
+            return false
+        }
+    }
+    return true
+}
+
+func sildFilterIndexed[T any](xs []T, f func(T, float64) bool) *[]T {
+    out := []T{}
+    for i, x := range xs {
+        if f(x, float64(i)) {
+            out = append(out, x)
+        }
+    }
+    return &out
+}
+
+func sildFindPtrIndexed[T any](xs []T, f func(T, float64) bool) *T {
+    for i, x := range xs {
+        if f(x, float64(i)) {
+            return &x
+        }
+    }
+    return nil
+}
+
+func sildForEachIndexed[T any](xs []T, f func(T, float64)) {
+    for i, x := range xs {
+        f(x, float64(i))
+    }
+}
+
+// sildJoin converts the elements of xs to strings and joins them with sep,
+// or with commas, like Array.prototype.join.
+func sildJoin[T any](xs []T, sep ...string) string {
+    s := ","
+    if len(sep) > 0 {
+        s = sep[0]
+    }
+    return sildJoinValues(reflect.ValueOf(xs), s)
+}
+
+func sildMapIndexed[T, U any](xs []T, f func(T, float64) U) *[]U {
+    out := make([]U, len(xs))
+    for i, x := range xs {
+        out[i] = f(x, float64(i))
+    }
+    return &out
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+func sildSomeIndexed[T any](xs []T, f func(T, float64) bool) bool {
+    for i, x := range xs {
+        if f(x, float64(i)) {
+            return true
+        }
+    }
+    return false
+}
+
+// sildString converts v to a string the way JavaScript's String does, for
+// values whose type isn't known statically, such as the elements of arrays.
+// Arrays are joined with commas and other objects are "[object Object]".
+func sildString(v any) string {
+    switch x := v.(type) {
+    case nil:
+        return "undefined"
+    case *big.Int:
+        return x.String()
+    }
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.String:
+        return rv.String()
+    case reflect.Bool:
+        return strconv.FormatBool(rv.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(rv.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        return sildNumberString(rv.Float())
+    case reflect.Slice:
+        return sildJoinValues(rv, ",")
+    case reflect.Func:
+        return "function"
+    case reflect.Pointer:
+        switch {
+        case rv.IsNil():
+            return "undefined"
+        case rv.Elem().Kind() == reflect.Struct:
+            return "[object Object]"
+        }
+        return sildString(rv.Elem().Interface())
+    }
+    return "[object Object]"
+}
+
+// sildJoinValues converts the elements of the slice xs to strings and joins
+// them with sep. Like in Array.prototype.join, null and undefined elements
+// are empty.
+func sildJoinValues(xs reflect.Value, sep string) string {
+    parts := make([]string, xs.Len())
+    for i := range parts {
+        x := xs.Index(i)
+        switch x.Kind() {
+        case reflect.Pointer, reflect.Interface, reflect.Func:
+            if x.IsNil() {
+                continue
+            }
+        }
+        parts[i] = sildString(x.Interface())
+    }
+    return strings.Join(parts, sep)
+}`,
+		},
+		{
+			name: "includes_finds_nan",
+			input: `let xs = [1, NaN];
+let ys: (number | string)[] = ["a", NaN];
+console.log(xs.includes(NaN), ys.includes(NaN), xs.indexOf(NaN));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "slices"
+    "strconv"
+)
+
+var xs *[]float64
+var ys *[]any
+
+func main() {
+    xs = &[]float64{1, math.NaN()}
+    ys = &[]any{"a", math.NaN()}
+    fmt.Println(strconv.FormatBool(sildIncludes(*xs, math.NaN())), strconv.FormatBool(sildIncludes[any](*ys, math.NaN())), strconv.Itoa(slices.Index(*xs, math.NaN())))
+}
+
+// sildIncludes reports whether xs contains x like Array.prototype.includes,
+// which finds NaN too.
+func sildIncludes[T comparable](xs []T, x T) bool {
+    return slices.ContainsFunc(xs, func(y T) bool {
+        return y == x || y != y && x != x
+    })
+}`,
+		},
+		{
+			name: "join_and_sort_convert_elements_like_javascript",
+			input: `let xs = [100000000, 1.5];
+let ys: (number | undefined)[] = [1, undefined, 3];
+console.log(xs.join(","), [15000000, 1.6].sort().join(" "), [[1, 2], [3]].join(";"), ys.join("-"));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+var xs *[]float64
+var ys *[]*float64
+
+func main() {
+    xs = &[]float64{100000000, 1.5}
+    ys = &[]*float64{sildPtr(1.0), nil, sildPtr(3.0)}
+    fmt.Println(sildJoin(*xs, ","), sildJoin(*sildSort(&[]float64{15000000, 1.6}), " "), sildJoin([]*[]float64{&[]float64{1, 2}, &[]float64{3}}, ";"), sildJoin(*ys, "-"))
+}
+
+// sildJoin converts the elements of xs to strings and joins them with sep,
+// or with commas, like Array.prototype.join.
+func sildJoin[T any](xs []T, sep ...string) string {
+    s := ","
+    if len(sep) > 0 {
+        s = sep[0]
+    }
+    return sildJoinValues(reflect.ValueOf(xs), s)
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+
+// sildSort sorts xs in place and returns it. Like Array.prototype.sort
+// without a comparator, elements are compared by their string form.
+func sildSort[T any](xs *[]T) *[]T {
+    slices.SortStableFunc(*xs, func(a, b T) int {
+        return strings.Compare(sildString(a), sildString(b))
+    })
+    return xs
+}
+
+// sildString converts v to a string the way JavaScript's String does, for
+// values whose type isn't known statically, such as the elements of arrays.
+// Arrays are joined with commas and other objects are "[object Object]".
+func sildString(v any) string {
+    switch x := v.(type) {
+    case nil:
+        return "undefined"
+    case *big.Int:
+        return x.String()
+    }
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.String:
+        return rv.String()
+    case reflect.Bool:
+        return strconv.FormatBool(rv.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(rv.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        return sildNumberString(rv.Float())
+    case reflect.Slice:
+        return sildJoinValues(rv, ",")
+    case reflect.Func:
+        return "function"
+    case reflect.Pointer:
+        switch {
+        case rv.IsNil():
+            return "undefined"
+        case rv.Elem().Kind() == reflect.Struct:
+            return "[object Object]"
+        }
+        return sildString(rv.Elem().Interface())
+    }
+    return "[object Object]"
+}
+
+// sildJoinValues converts the elements of the slice xs to strings and joins
+// them with sep. Like in Array.prototype.join, null and undefined elements
+// are empty.
+func sildJoinValues(xs reflect.Value, sep string) string {
+    parts := make([]string, xs.Len())
+    for i := range parts {
+        x := xs.Index(i)
+        switch x.Kind() {
+        case reflect.Pointer, reflect.Interface, reflect.Func:
+            if x.IsNil() {
+                continue
+            }
+        }
+        parts[i] = sildString(x.Interface())
+    }
+    return strings.Join(parts, sep)
+}`,
+		},
+		{
+			name: "predicates_test_their_results_for_truthiness",
+			input: `let xs = [1, 2, 3];
+let odd = xs.filter((x) => x % 2);
+let u: (string | number)[] = ["a", 0];
+let present = u.filter((v) => v);`,
+			expected: `package main
+
+import (
+    "math"
+    "math/big"
+    "reflect"
+)
+
+var xs *[]float64
+var odd *[]float64
+var u *[]any
+var present *[]any
+
+func main() {
+    xs = &[]float64{1, 2, 3}
+    odd = sildFilter(*xs, func(x float64) bool {
+        return func() bool {
+            _v := math.Mod(x, 2)
+            return _v != 0 && !math.IsNaN(_v)
+        }()
+    })
+    u = &[]any{"a", 0.0}
+    present = sildFilter(*u, func(v any) bool {
+        return sildTruthy(v)
+    })
+}
+
+func sildFilter[T any](xs []T, f func(T) bool) *[]T {
+    out := []T{}
+    for _, x := range xs {
+        if f(x) {
+            out = append(out, x)
+        }
+    }
+    return &out
+}
+
+// sildTruthy reports whether v, a value whose type isn't known statically,
+// is truthy: not undefined, null, false, "", 0, NaN or 0n.
+func sildTruthy(v any) bool {
+    switch x := v.(type) {
+    case nil:
+        return false
+    case *big.Int:
+        return x != nil && x.Sign() != 0
+    }
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Bool:
+        return rv.Bool()
+    case reflect.String:
+        return rv.String() != ""
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return rv.Int() != 0
+    case reflect.Float32, reflect.Float64:
+        return rv.Float() != 0 && !math.IsNaN(rv.Float())
+    case reflect.Slice, reflect.Map, reflect.Func:
+        return !rv.IsNil()
+    case reflect.Pointer:
+        switch {
+        case rv.IsNil():
+            return false
+        case rv.Elem().Kind() == reflect.Struct:
+            return true
+        }
+        return sildTruthy(rv.Elem().Interface())
+    }
+    return true
+}
+`,
+		},
+		{
+			name: "map_to_any_gives_an_array_of_any",
+			input: `let xs = [1, 2];
+let e = [];
+let ws = xs.map((x) => e[0]);
+let n = ws.length;`,
+			expected: `package main
+
+var xs *[]float64
+var e *[]any
+var ws *[]any
+var n float64
+
+func main() {
+    xs = &[]float64{1, 2}
+    e = &[]any{}
+    ws = sildMap(*xs, func(x float64) any {
+        return (*e)[0]
+    })
+    n = float64(len(*ws))
+}
+
+func sildMap[T, U any](xs []T, f func(T) U) *[]U {
+    out := make([]U, len(xs))
+    for i, x := range xs {
+        out[i] = f(x)
+    }
+    return &out
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestObjectGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "interface_to_struct_with_json_tags",
+			input: `interface Point {
+    x: number;
+    y: number;
+    label?: string;
+}
+type Id = number;
+let p: Point = { x: 1, y: 2 };
+let q: Point = { x: 3, y: 4, label: "q" };
+p.x = q.y;
+q.label = "r";`,
+			expected: `package main
+
+type Point struct {
+    X     float64 ` + "`json:\"x\"`" + `
+    Y     float64 ` + "`json:\"y\"`" + `
+    Label *string ` + "`json:\"label,omitempty\"`" + `
+}
+
+type Id = float64
+
+var p *Point
+var q *Point
+
+func main() {
+    p = &Point{X: 1, Y: 2}
+    q = &Point{X: 3, Y: 4, Label: sildPtr("q")}
+    p.X = q.Y
+    q.Label = sildPtr("r")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+`,
+		},
+		{
+			name: "nested_objects_and_params",
+			input: `type Line = { from: Point, to: Point };
+interface Point { x: number; y: number }
+function width(l: Line): number {
+    return l.to.x - l.from.x;
+}
+console.log(width({ from: { x: 0, y: 0 }, to: { x: 2, y: 0 } }));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Line struct {
+    From *Point ` + "`json:\"from\"`" + `
+    To   *Point ` + "`json:\"to\"`" + `
+}
+
+type Point struct {
+    X float64 ` + "`json:\"x\"`" + `
+    Y float64 ` + "`json:\"y\"`" + `
+}
+
+func width(l *Line) float64 {
+    return (l.To.X - l.From.X)
+}
+
+func main() {
+    fmt.Println(sildNumberString(width(&Line{From: &Point{X: 0, Y: 0}, To: &Point{X: 2, Y: 0}})))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "untyped_object_literal_and_for_in",
+			input: `let p: boolean = { ok: true }.ok;
+let o: { b: number; a: number } = { b: 1, a: 2 };
+for (const k in o) {
+    console.log(k);
+}`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+var p bool
+var o *struct{ B float64 ` + "`json:\"b\"`" + `; A float64 ` + "`json:\"a\"`" + ` }
+
+func main() {
+    p = (&struct{ Ok bool ` + "`json:\"ok\"`" + ` }{Ok: true}).Ok
+    o = &struct{ B float64 ` + "`json:\"b\"`" + `; A float64 ` + "`json:\"a\"`" + ` }{B: 1, A: 2}
+    for _, k := range sildKeys(o, "b", "a") {
+        fmt.Println(k)
+    }
+}
+
+// sildKeys returns keys, the keys of the object o. Go knows them statically;
+// o is only taken so that it is still evaluated.
+func sildKeys[T any](o T, keys ...string) []string {
+    return keys
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestClassGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "fields_constructor_and_methods",
+			input: `class Counter {
+    private count: number = 0;
+    readonly name: string;
+    constructor(name: string) {
+        this.name = name;
+    }
+    increment(by: number): number {
+        this.count += by;
+        return this.count;
+    }
+    private reset(): void {
+        this.count = 0;
+    }
+}
+let c: Counter = new Counter("clicks");
+c.increment(2);`,
+			expected: `package main
+
+type Counter struct {
+    count float64
+    Name  string
+}
+
+func NewCounter(name string) *Counter {
+    this := &Counter{count: 0}
+    this.Name = name
+    return this
+}
+
+func (this *Counter) Increment(by float64) float64 {
+    this.count += by
+    return this.count
+}
+
+func (this *Counter) reset() {
+    this.count = 0
+}
+
+var c *Counter
+
+func main() {
+    c = NewCounter("clicks")
+    c.Increment(2)
+}
+`,
+		},
+		{
+			name: "static_members_and_early_return",
+			input: `class Id {
+    private static next: number = 1;
+    value: number;
+    constructor() {
+        this.value = Id.next;
+        Id.next++;
+        if (this.value > 9) {
+            return;
+        }
+    }
+    static reset(): void {
+        Id.next = 1;
+    }
+}
+Id.reset();
+console.log(new Id().value);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Id struct {
+    Value float64
+}
+
+var idNext float64 = 1
+
+func NewId() *Id {
+    this := &Id{}
+    this.Value = idNext
+    idNext++
+    if this.Value > 9 {
+        return this
+    }
+    return this
+}
+
+func IdReset() {
+    idNext = 1
+}
+
+func main() {
+    IdReset()
+    fmt.Println(sildNumberString(NewId().Value))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestInferenceGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "inferred from literals, operators and calls",
+			input: `function greet(name: string): string {
+    return "hello " + name;
+}
+let count = 42;
+const who = greet("a");
+let ratio = count / 2;
+let ok = count > 1 && true;
+let xs = [1, 2, 3];
+console.log(who, ratio, ok, xs[0]);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+var count float64
+var who string
+var ratio float64
+var ok bool
+var xs *[]float64
+
+func greet(name string) string {
+    return ("hello " + name)
+}
+
+func main() {
+    count = 42
+    who = greet("a")
+    ratio = (count / 2)
+    ok = ((count > 1) && true)
+    xs = &[]float64{1, 2, 3}
+    fmt.Println(who, sildNumberString(ratio), strconv.FormatBool(ok), sildNumberString((*xs)[0]))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "inferred arrays of mixed or unknown elements",
+			input: `function run(): void {
+    let xs = [1, "a"];
+    let e = [];
+    e.push(1);
+    console.log(xs.length, e.length);
+}
+run();`,
+			expected: `package main
+
+import (
+    "fmt"
+    "strconv"
+)
+
+func run() {
+    xs := &[]any{1.0, "a"}
+    e := &[]any{}
+    *e = append(*e, 1.0)
+    fmt.Println(strconv.Itoa(len(*xs)), strconv.Itoa(len(*e)))
+}
+
+func main() {
+    run()
+}
+`,
+		},
+		{
+			name: "uninitialized variable uses var",
+			input: `let total: number;
+let names: string[];
+total = 1;
+console.log(total, names);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "runtime"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+var total float64
+var names *[]string
+
+func main() {
+    total = 1
+    fmt.Println(sildNumberString(total), sildInspect(names, "undefined"))
+}
+
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "inferred object literal",
+			input: `let p = { x: 1, y: 2 };
+console.log(p.x);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+var p *struct{ X float64 ` + "`json:\"x\"`" + `; Y float64 ` + "`json:\"y\"`" + ` }
+
+func main() {
+    p = &struct{ X float64 ` + "`json:\"x\"`" + `; Y float64 ` + "`json:\"y\"`" + ` }{X: 1, Y: 2}
+    fmt.Println(sildNumberString(p.X))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "unread_locals",
+			input: `class Animal {
+    name: string;
+    constructor(name: string) {
+        this.name = name;
+    }
+}
+function run(): void {
+    let n = null;
+    let x = 1;
+    x = 2;
+    let b = new Animal("y");
+    var v = "v";
+    function unused(): void {}
+}
+run();`,
+			expected: `package main
+
+type Animal struct {
+    Name string
+}
+
+func NewAnimal(name string) *Animal {
+    this := &Animal{}
+    this.Name = name
+    return this
+}
+
+func run() {
+    var v string
+    _ = v
+    var unused func()
+    _ = unused
+    var n any
+    _ = n
+    x := 1.0
+    _ = x
+    x = 2
+    b := NewAnimal("y")
+    _ = b
+    v = "v"
+    unused = func() {
+    }
+}
+
+func main() {
+    run()
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestDeclarationGeneration(t *testing.T) {
+	input := `function pick(a: number): number {
+    if (a > 0) {
+        var r = 1;
+    } else {
+        var r = 2;
+    }
+    for (var i = 0; i < 3; i++) {
+        r += i;
+    }
+    return r + i;
+}
+const limit = 10;
+const half = limit / 2;
+const name: string = "sild";
+const picked = pick(limit);
+let xs = [1, 2];
+xs[0] = half;
+xs[1] += picked;
+console.log(name, xs[0], xs[1]);`
+
+	expected := `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+const limit = 10
+const half = (limit / 2.0)
+const name string = "sild"
+
+var picked float64
+var xs *[]float64
+
+func pick(a float64) float64 {
+    var r float64
+    var i float64
+    if a > 0 {
+        r = 1
+    } else {
+        r = 2
+    }
+    for i = 0; i < 3; i++ {
+        r += i
+    }
+    return (r + i)
+}
+
+func main() {
+    picked = pick(limit)
+    xs = &[]float64{1, 2}
+    (*xs)[0] = half
+    (*xs)[1] += picked
+    fmt.Println(name, sildNumberString((*xs)[0]), sildNumberString((*xs)[1]))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestTopLevelVariableGeneration(t *testing.T) {
+	input := `const limit = 3;
+let count = 0;
+const double = (x: number): number => x * 2;
+function bump(): number {
+    count += 1;
+    return double(count) + limit;
+}
+let total = bump();
+console.log(total, count);`
+
+	expected := `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+const limit = 3
+
+var count float64
+var double func(float64) float64
+var total float64
+
+func bump() float64 {
+    count += 1
+    return (double(count) + limit)
+}
+
+func main() {
+    count = 0
+    double = func(x float64) float64 {
+        return (x * 2)
+    }
+    total = bump()
+    fmt.Println(sildNumberString(total), sildNumberString(count))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestInheritanceGeneration(t *testing.T) {
+	input := `class Animal {
+    protected name: string;
+    constructor(name: string) {
+        this.name = name;
+    }
+    speak(): string {
+        return "...";
+    }
+    describe(): string {
+        return this.name + ": " + this.speak();
+    }
+}
+class Dog extends Animal {
+    speak(): string {
+        return "woof " + super.speak();
+    }
+}
+let a: Animal = new Dog("Rex");
+console.log(a.describe());`
+
+	expected := `package main
+
+import (
+    "fmt"
+)
+
+// animalMethods is implemented by Animal and its subclasses, so that
+// calls of methods overridden in a subclass go through animalSelf.
+type animalMethods interface {
+    Speak() string
+}
+
+type Animal struct {
+    animalSelf animalMethods
+    name       string
+}
+
+func NewAnimal(name string) *Animal {
+    this := &Animal{}
+    this.animalSelf = this
+    this.init(name)
+    return this
+}
+
+// init runs the constructor of Animal on this, whose self fields already
+// point to the instance being created.
+func (this *Animal) init(name string) {
+    this.name = name
+}
+
+func (this *Animal) Speak() string {
+    return "..."
+}
+
+func (this *Animal) Describe() string {
+    return ((this.name + ": ") + this.animalSelf.Speak())
+}
+
+type Dog struct {
+    *Animal
+}
+
+func NewDog(name string) *Dog {
+    this := &Dog{Animal: &Animal{}}
+    this.animalSelf = this
+    this.init(name)
+    return this
+}
+
+// init runs the constructor of Dog on this, whose self fields already
+// point to the instance being created.
+func (this *Dog) init(name string) {
+    this.Animal.init(name)
+}
+
+func (this *Dog) Speak() string {
+    return ("woof " + this.Animal.Speak())
+}
+
+var a *Animal
+
+func main() {
+    a = NewDog("Rex").Animal
+    fmt.Println(a.Describe())
+}
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+// Overridden methods called by the constructor of a base class dispatch to
+// the subclass being created, as in TypeScript.
+func TestConstructorDispatch(t *testing.T) {
+	input := `class Base {
+    label: string;
+    constructor() {
+        this.label = this.name();
+    }
+    name(): string {
+        return "base";
+    }
+}
+class Sub extends Base {
+    tag: string = "t";
+    constructor(early: boolean) {
+        super();
+        if (early) {
+            return;
+        }
+        this.tag = this.label;
+    }
+    name(): string {
+        return "sub";
+    }
+}
+console.log(new Sub(false).tag, new Sub(true).tag, new Base().label);`
+
+	expected := `package main
+
+import (
+    "fmt"
+)
+
+// baseMethods is implemented by Base and its subclasses, so that
+// calls of methods overridden in a subclass go through baseSelf.
+type baseMethods interface {
+    Name() string
+}
+
+type Base struct {
+    baseSelf baseMethods
+    Label    string
+}
+
+func NewBase() *Base {
+    this := &Base{}
+    this.baseSelf = this
+    this.init()
+    return this
+}
+
+// init runs the constructor of Base on this, whose self fields already
+// point to the instance being created.
+func (this *Base) init() {
+    this.Label = this.baseSelf.Name()
+}
+
+func (this *Base) Name() string {
+    return "base"
+}
+
+type Sub struct {
+    *Base
+    Tag string
+}
+
+func NewSub(early bool) *Sub {
+    this := &Sub{Base: &Base{}}
+    this.baseSelf = this
+    this.init(early)
+    return this
+}
+
+// init runs the constructor of Sub on this, whose self fields already
+// point to the instance being created.
+func (this *Sub) init(early bool) {
+    this.Base.init()
+    this.Tag = "t"
+    if early {
+        return
+    }
+    this.Tag = this.Label
+}
+
+func (this *Sub) Name() string {
+    return "sub"
+}
+
+func main() {
+    fmt.Println(NewSub(false).Tag, NewSub(true).Tag, NewBase().Label)
+}
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestMultilevelConstructorDispatch(t *testing.T) {
+	input := `class A {
+    constructor() { this.setup(); }
+    setup(): void { console.log("A.setup"); }
+    tag(): string { return "A"; }
+}
+class B extends A {
+    tag(): string { return "B"; }
+}
+class C extends B {
+    setup(): void { console.log(this.tag()); }
+    tag(): string { return "C"; }
+}
+let a: A = new C();
+console.log(a.tag());`
+
+	expected := `package main
+
+import (
+    "fmt"
+)
+
+// aMethods is implemented by A and its subclasses, so that
+// calls of methods overridden in a subclass go through aSelf.
+type aMethods interface {
+    Setup()
+    Tag() string
+}
+
+type A struct {
+    aSelf aMethods
+}
+
+func NewA() *A {
+    this := &A{}
+    this.aSelf = this
+    this.init()
+    return this
+}
+
+// init runs the constructor of A on this, whose self fields already
+// point to the instance being created.
+func (this *A) init() {
+    this.aSelf.Setup()
+}
+
+func (this *A) Setup() {
+    fmt.Println("A.setup")
+}
+
+func (this *A) Tag() string {
+    return "A"
+}
+
+type B struct {
+    *A
+}
+
+func NewB() *B {
+    this := &B{A: &A{}}
+    this.aSelf = this
+    this.init()
+    return this
+}
+
+// init runs the constructor of B on this, whose self fields already
+// point to the instance being created.
+func (this *B) init() {
+    this.A.init()
+}
+
+func (this *B) Tag() string {
+    return "B"
+}
+
+type C struct {
+    *B
+}
+
+func NewC() *C {
+    this := &C{B: &B{A: &A{}}}
+    this.aSelf = this
+    this.init()
+    return this
+}
+
+// init runs the constructor of C on this, whose self fields already
+// point to the instance being created.
+func (this *C) init() {
+    this.B.init()
+}
+
+func (this *C) Setup() {
+    fmt.Println(this.aSelf.Tag())
+}
+
+func (this *C) Tag() string {
+    return "C"
+}
+
+var a *A
+
+func main() {
+    a = NewC().A
+    fmt.Println(a.aSelf.Tag())
+}
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestInheritanceDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "redeclared_field",
+			input:    "class A { x: number = 0; } class B extends A { x: number = 1; }",
+			expected: "1:48: error: cannot translate redeclaration of property 'x' of 'A' in 'B': Go would store it separately",
+		},
+		{
+			name:     "override_with_different_signature",
+			input:    "class A { m(): void {} } class B extends A { m(x: number): void {} }",
+			expected: "1:46: error: cannot translate override of 'A.m' in 'B' with a different signature",
+		},
+		{
+			name:     "cyclic_inheritance",
+			input:    "class A extends B {} class B extends A {}",
+			expected: "1:17: error: 'A' is referenced directly or indirectly in its own base expression",
+		},
+		{
+			name:     "missing_super_call",
+			input:    "class A {} class B extends A { constructor() {} }",
+			expected: "1:32: error: constructors for derived classes must contain exactly one 'super' call as a statement of their body",
+		},
+		{
+			name:     "super_outside_derived_class",
+			input:    "class A { m(): void { super.m(); } }",
+			expected: "1:23: error: 'super' can only be referenced in a derived class",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestNumberGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "float_literals_and_division",
+			input: `let a = 7 / 2;
+let b = 0x1F + 0o17 + 0b1 + 1_000 + 1.5e3 + .5;
+let c = -3;
+let d = a / 2;
+console.log(a, b, c, d);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+var a float64
+var b float64
+var c float64
+var d float64
+
+func main() {
+    a = (7 / 2.0)
+    b = (((((0x1F + 0o17) + 0b1) + 1_000) + 1.5e3) + .5)
+    c = -3
+    d = (a / 2)
+    fmt.Println(sildNumberString(a), sildNumberString(b), sildNumberString(c), sildNumberString(d))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "remainder",
+			input: `let x = 5.5;
+const r = 7 % 3;
+x %= 2;
+console.log(x % 2, -7 % 2, r, x % 0);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+const r = (7 % 3)
+
+var x float64
+
+func main() {
+    x = 5.5
+    x = math.Mod(x, 2)
+    fmt.Println(sildNumberString(math.Mod(x, 2)), sildNumberString((-7 % 2)), sildNumberString(r), sildNumberString(math.Mod(x, 0)))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "constant_arithmetic_in_float64",
+			input: `const third = 0.1 + 0.2;
+const safe = 60 * 60 * 24;
+const huge = 1e308 * 10;
+console.log(0.1 + 0.2 === 0.3, third * 3, safe / 7, huge / 10, 1 / 0, 0 / 0, 9007199254740992 + 1 + 1);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+const third = 0.30000000000000004
+const safe = ((60 * 60) * 24)
+
+var huge float64
+
+func main() {
+    huge = math.Inf(1)
+    fmt.Println(strconv.FormatBool(0.30000000000000004 == 0.3), sildNumberString((third * 3)), sildNumberString((safe / 7.0)), sildNumberString((huge / 10)), sildNumberString(math.Inf(1)), sildNumberString(math.NaN()), sildNumberString(9007199254740992))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}`,
+		},
+		{
+			name: "int_conversions",
+			input: `let xs = [1, 2, 3];
+let n = xs.length;
+let i = 1;
+console.log(xs[i], n / 2, xs.length > i, xs.slice(i), xs.indexOf(2) + i);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "runtime"
+    "slices"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+var xs *[]float64
+var n float64
+var i float64
+
+func main() {
+    xs = &[]float64{1, 2, 3}
+    n = float64(len(*xs))
+    i = 1
+    fmt.Println(sildNumberString((*xs)[int(i)]), sildNumberString((n / 2)), strconv.FormatBool(float64(len(*xs)) > i), sildInspect(sildSlice(*xs, int(i)), "undefined"), sildNumberString((float64(slices.Index(*xs, 2)) + i)))
+}
+
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildSlice returns a copy of xs between the optional start and end bounds,
+// where negative bounds count from the end like in Array.prototype.slice.
+func sildSlice[T any](xs []T, bounds ...int) *[]T {
+    clamp := func(i int) int {
+        if i < 0 {
+            i += len(xs)
+        }
+        return max(0, min(i, len(xs)))
+    }
+
+    start, end := 0, len(xs)
+    if len(bounds) > 0 {
+        start = clamp(bounds[0])
+    }
+    if len(bounds) > 1 {
+        end = clamp(bounds[1])
+    }
+    if start >= end {
+        return &[]T{}
+    }
+    out := append([]T{}, xs[start:end]...)
+    return &out
+}
+`,
+		},
+		{
+			name: "nan_and_infinity",
+			input: `let x: number = NaN;
+console.log(x === x, -Infinity < 0);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+)
+
+var x float64
+
+func main() {
+    x = math.NaN()
+    fmt.Println(strconv.FormatBool(x == x), strconv.FormatBool(-math.Inf(1) < 0))
+}
+`,
+		},
+		{
+			name: "bigints",
+			input: `function fact(n: bigint): bigint {
+    let r = 1n;
+    for (let i = 1n; i <= n; i++) {
+        r *= i;
+    }
+    return r;
+}
+let x = fact(20n) / 123456789012345678901234567890n - -3n;
+console.log(x === 3n, x < 2.5, 1 <= x);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "strconv"
+)
+
+var x *big.Int
+
+func fact(n *big.Int) *big.Int {
+    r := big.NewInt(1)
+    for i := big.NewInt(1); i.Cmp(n) <= 0; i = new(big.Int).Add(i, big.NewInt(1)) {
+        r = new(big.Int).Mul(r, i)
+    }
+    return r
+}
+
+func main() {
+    x = new(big.Int).Sub(new(big.Int).Quo(fact(big.NewInt(20)), sildBigInt("123456789012345678901234567890")), new(big.Int).Neg(big.NewInt(3)))
+    fmt.Println(strconv.FormatBool(x.Cmp(big.NewInt(3)) == 0), strconv.FormatBool(sildCompareBig(x, 2.5) < 0), strconv.FormatBool(sildCompareBig(x, 1) >= 0))
+}
+
+// sildBigInt returns the integer written by the literal s, which doesn't fit
+// in an int64.
+func sildBigInt(s string) *big.Int {
+    n, _ := new(big.Int).SetString(s, 0)
+    return n
+}
+
+// sildCompareBig compares x and y like big.Int.Cmp, except that it returns
+// NaN if y is NaN so that comparing the result with 0 is always false.
+func sildCompareBig(x *big.Int, y float64) float64 {
+    switch {
+    case math.IsNaN(y):
+        return y
+    case math.IsInf(y, 0):
+        return -y
+    }
+    return float64(new(big.Float).SetInt(x).Cmp(big.NewFloat(y)))
+}
+`,
+		},
+		{
+			name:   "products_and_negative_zero_widen_narrowed_integers",
+			narrow: true,
+			input: `function run(): void {
+    let p = 1;
+    for (let i = 0; i < 25; i++) {
+        p *= 10;
+    }
+    let z = 0;
+    z = z * -1;
+    let m = 0;
+    m = -m;
+    let r = 7;
+    r = r % 2;
+    let n = 3;
+    n = n + 4;
+    console.log(p, 1 / z, 1 / m, r, n);
+}
+run();`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+func run() {
+    p := 1.0
+    for i := 0; i < 25; i++ {
+        p *= 10
+    }
+    z := 0.0
+    z = (z * -1)
+    m := 0.0
+    m = -m
+    r := 7.0
+    r = math.Mod(r, 2)
+    n := 3
+    n = (n + 4)
+    fmt.Println(sildNumberString(p), sildNumberString((1 / z)), sildNumberString((1 / m)), sildNumberString(r), strconv.Itoa(n))
+}
+
+func main() {
+    run()
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}`,
+		},
+		{
+			name:   "narrowed_integers",
+			narrow: true,
+			input: `function sum(xs: number[]): number {
+    let total = 0;
+    for (let i = 0; i < xs.length; i++) {
+        total += xs[i];
+    }
+    return total;
+}
+function run(): void {
+    let count = 0;
+    let half = 10;
+    half /= 4;
+    let a = 0;
+    let b = a;
+    a = 0.5;
+    var big = 10000000000000000;
+    for (let i = 0; i < 10; i++) {
+        count += i % 3;
+    }
+    console.log(sum([1, 2]), count, half, b, big);
+}
+run();`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+func sum(xs *[]float64) float64 {
+    total := 0.0
+    for i := 0; i < len(*xs); i++ {
+        total += (*xs)[i]
+    }
+    return total
+}
+
+func run() {
+    var big_ float64
+    count := 0
+    half := 10.0
+    half /= 4
+    a := 0.0
+    b := a
+    a = 0.5
+    big_ = 10000000000000000
+    for i := 0; i < 10; i++ {
+        count += (i % 3)
+    }
+    fmt.Println(sildNumberString(sum(&[]float64{1, 2})), strconv.Itoa(count), sildNumberString(half), sildNumberString(b), sildNumberString(big_))
+}
+
+func main() {
+    run()
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name:   "negative_zero",
+			narrow: true,
+			input: `function negate(): number {
+    let i = 0;
+    i -= 0;
+    let j = -i;
+    return 1 / j;
+}
+function zero(): number {
+    const a = 0;
+    return -a;
+}
+console.log(1 / negate(), 1 / zero(), -0);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+func negate() float64 {
+    i := 0
+    i -= 0
+    j := -float64(i)
+    return (1 / j)
+}
+
+func zero() float64 {
+    const a = 0
+    return math.Copysign(0, -1)
+}
+
+func main() {
+    fmt.Println(sildNumberString((1 / negate())), sildNumberString((1 / zero())), sildNumberString(math.Copysign(0, -1)))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name:   "doubling_variables_stay_float",
+			narrow: true,
+			input: `function grow(): number {
+    let g = 1;
+    while (g < 1e300) { g += g; }
+    return g;
+}
+function fib(n: number): number {
+    let a = 0;
+    let b = 1;
+    for (let i = 0; i < n; i++) {
+        let t = a + b;
+        a = b;
+        b = t;
+    }
+    return a;
+}
+function total(n: number): number {
+    let sum = 0;
+    for (let i = 0; i < n; i++) { sum += i % 7; sum -= 1; }
+    return sum;
+}`,
+			expected: `package main
+
+func grow() float64 {
+    g := 1.0
+    for g < 1e300 {
+        g += g
+    }
+    return g
+}
+
+func fib(n float64) float64 {
+    a := 0.0
+    b := 1.0
+    for i := 0; float64(i) < n; i++ {
+        t := (a + b)
+        a = b
+        b = t
+    }
+    return a
+}
+
+func total(n float64) float64 {
+    sum := 0
+    for i := 0; float64(i) < n; i++ {
+        sum += (i % 7)
+        sum -= 1
+    }
+    return float64(sum)
+}
+
+func main() {
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestStringGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "escapes_and_quotes",
+			input: `function greet(): string {
+    return 'hi';
+}
+let a: string = greet();
+let b = 'it\'s "quoted"\n\u{1F600}\x41';
+let c: string = ` + "`" + `line one
+line two` + "`" + `;
+console.log(a, b, c);`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+var a string
+var b string
+var c string
+
+func greet() string {
+    return "hi"
+}
+
+func main() {
+    a = greet()
+    b = "it's \"quoted\"\n😀A"
+    c = "line one\nline two"
+    fmt.Println(a, b, c)
+}
+`,
+		},
+		{
+			name: "template_literals",
+			input: `let name = "Ann";
+let xs: number[] = [1, 2];
+let total = 10n;
+let s = ` + "`${name} has ${xs.length} items, half ${xs[0] / 2}, ${total} ${xs.length > 1} 100%`" + `;
+console.log(s, ` + "`${name}`" + `, ` + "`${name}!`" + `);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "strconv"
+    "strings"
+)
+
+var name string
+var xs *[]float64
+var total *big.Int
+var s string
+
+func main() {
+    name = "Ann"
+    xs = &[]float64{1, 2}
+    total = big.NewInt(10)
+    s = (name + " has " + strconv.Itoa(len(*xs)) + " items, half " + sildNumberString(((*xs)[0] / 2)) + ", " + total.String() + " " + strconv.FormatBool(len(*xs) > 1) + " 100%")
+    fmt.Println(s, name, (name + "!"))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "concatenation",
+			input: `let n = 2;
+let s = "n: " + n + ", ok: " + true;
+s += n;
+s += "!";
+console.log(s);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+var n float64
+var s string
+
+func main() {
+    n = 2
+    s = ((("n: " + sildNumberString(n)) + ", ok: ") + "true")
+    s += sildNumberString(n)
+    s += "!"
+    fmt.Println(s)
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "string_methods",
+			input: `let s = " a,b ".trim();
+let parts = s.split(",");
+console.log(parts[0], s.toUpperCase(), s.length, s.startsWith("a"), s.includes(",", 1), s.indexOf("b"));
+console.log(s.padStart(5, "-"), s.slice(-1), s.substring(0, 1), s.charAt(0), s[2]);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "slices"
+    "strconv"
+    "strings"
+    "unicode/utf16"
+)
+
+var s string
+var parts *[]string
+
+func main() {
+    s = strings.TrimSpace(" a,b ")
+    parts = sildSplit(s, ",")
+    fmt.Println((*parts)[0], strings.ToUpper(s), strconv.Itoa(sildLength(s)), strconv.FormatBool(strings.HasPrefix(s, "a")), strconv.FormatBool((sildStringIndexOf(s, ",", 1) >= 0)), strconv.Itoa(sildStringIndexOf(s, "b")))
+    fmt.Println(sildPad(s, 5, "-", true), sildStringSlice(s, -1), sildSubstring(s, 0, 1), sildCharAt(s, 0), sildCharAt(s, 2))
+}
+
+// sildCharAt returns the UTF-16 code unit of s at index i as a string, or ""
+// if i is out of range.
+func sildCharAt(s string, i int) string {
+    u := utf16.Encode([]rune(s))
+    if i < 0 || i >= len(u) {
+        return ""
+    }
+    return string(utf16.Decode(u[i : i+1]))
+}
+
+// sildLength returns the length of s in UTF-16 code units, the units strings
+// are measured and indexed in by TypeScript.
+func sildLength(s string) int {
+    n := 0
+    for _, r := range s {
+        n += utf16.RuneLen(r)
+    }
+    return n
+}
+
+// sildPad pads s at its start, or at its end, with fill repeated and cut so
+// that the result is n UTF-16 code units long, like String.prototype.padStart
+// and padEnd.
+func sildPad(s string, n int, fill string, atStart bool) string {
+    u, f := utf16.Encode([]rune(s)), utf16.Encode([]rune(fill))
+    if n <= len(u) || len(f) == 0 {
+        return s
+    }
+    var pad []uint16
+    for len(pad) < n-len(u) {
+        pad = append(pad, f...)
+    }
+    p := string(utf16.Decode(pad[:n-len(u)]))
+    if atStart {
+        return p + s
+    }
+    return s + p
+}
+
+// sildSplit splits s around each instance of sep into a new array. An empty
+// sep splits s into its UTF-16 code units, like it does in TypeScript.
+func sildSplit(s, sep string) *[]string {
+    if sep == "" {
+        u := utf16.Encode([]rune(s))
+        parts := make([]string, len(u))
+        for i := range u {
+            parts[i] = string(utf16.Decode(u[i : i+1]))
+        }
+        return &parts
+    }
+    parts := strings.Split(s, sep)
+    return &parts
+}
+
+// sildStringIndexOf returns the UTF-16 index of the first occurrence of
+// search in s at or after the optional index from, or -1 if there is none.
+func sildStringIndexOf(s, search string, from ...int) int {
+    u, v := utf16.Encode([]rune(s)), utf16.Encode([]rune(search))
+    start := 0
+    if len(from) > 0 {
+        start = max(0, min(from[0], len(u)))
+    }
+    for i := start; i+len(v) <= len(u); i++ {
+        if slices.Equal(u[i:i+len(v)], v) {
+            return i
+        }
+    }
+    return -1
+}
+
+// sildStringSlice returns the UTF-16 code units of s between the optional
+// start and end bounds, where negative bounds count from the end like in
+// String.prototype.slice.
+func sildStringSlice(s string, bounds ...int) string {
+    u := utf16.Encode([]rune(s))
+    clamp := func(i int) int {
+        if i < 0 {
+            i += len(u)
+        }
+        return max(0, min(i, len(u)))
+    }
+
+    start, end := 0, len(u)
+    if len(bounds) > 0 {
+        start = clamp(bounds[0])
+    }
+    if len(bounds) > 1 {
+        end = clamp(bounds[1])
+    }
+    if start >= end {
+        return ""
+    }
+    return string(utf16.Decode(u[start:end]))
+}
+
+// sildSubstring returns the UTF-16 code units of s from start up to the
+// optional end, with both clamped to the bounds of s and swapped if start
+// comes after end, like String.prototype.substring.
+func sildSubstring(s string, start int, end ...int) string {
+    u := utf16.Encode([]rune(s))
+    stop := len(u)
+    if len(end) > 0 {
+        stop = end[0]
+    }
+    start, stop = max(0, min(start, len(u))), max(0, min(stop, len(u)))
+    if start > stop {
+        start, stop = stop, start
+    }
+    return string(utf16.Decode(u[start:stop]))
+}
+`,
+		},
+		{
+			name: "conversion_of_other_values",
+			input: `interface P { x: number }
+function show(n: number | undefined, m: string | null, xs: number[], p: P): string {
+    return "n=" + n + " m=" + m + " xs=" + xs + " p=" + p;
+}
+console.log(show(2, null, [1, 2], { x: 1 }));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+type P struct {
+    X float64 ` + "`json:\"x\"`" + `
+}
+
+func show(n *float64, m *string, xs *[]float64, p *P) string {
+    return ((((((("n=" + sildString(n)) + " m=") + sildNullString(m)) + " xs=") + sildString(xs)) + " p=") + sildString(p))
+}
+
+func main() {
+    fmt.Println(show(sildPtr(2.0), nil, &[]float64{1, 2}, &P{X: 1}))
+}
+
+// sildNullString converts v, a value of a type with null but not undefined,
+// to a string like sildString, except that nil is "null".
+func sildNullString(v any) string {
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Pointer, reflect.Slice, reflect.Func:
+        if rv.IsNil() {
+            return "null"
+        }
+    }
+    return sildString(v)
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+
+// sildString converts v to a string the way JavaScript's String does, for
+// values whose type isn't known statically, such as the elements of arrays.
+// Arrays are joined with commas and other objects are "[object Object]".
+func sildString(v any) string {
+    switch x := v.(type) {
+    case nil:
+        return "undefined"
+    case *big.Int:
+        return x.String()
+    }
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.String:
+        return rv.String()
+    case reflect.Bool:
+        return strconv.FormatBool(rv.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(rv.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        return sildNumberString(rv.Float())
+    case reflect.Slice:
+        return sildJoinValues(rv, ",")
+    case reflect.Func:
+        return "function"
+    case reflect.Pointer:
+        switch {
+        case rv.IsNil():
+            return "undefined"
+        case rv.Elem().Kind() == reflect.Struct:
+            return "[object Object]"
+        }
+        return sildString(rv.Elem().Interface())
+    }
+    return "[object Object]"
+}
+
+// sildJoinValues converts the elements of the slice xs to strings and joins
+// them with sep. Like in Array.prototype.join, null and undefined elements
+// are empty.
+func sildJoinValues(xs reflect.Value, sep string) string {
+    parts := make([]string, xs.Len())
+    for i := range parts {
+        x := xs.Index(i)
+        switch x.Kind() {
+        case reflect.Pointer, reflect.Interface, reflect.Func:
+            if x.IsNil() {
+                continue
+            }
+        }
+        parts[i] = sildString(x.Interface())
+    }
+    return strings.Join(parts, sep)
+}`,
+		},
+		{
+			name: "null_converts_to_null",
+			input: `function f(): string {
+    let a: string | null = null;
+    return "" + null + ` + "`${null}`" + ` + a;
+}`,
+			expected: `package main
+
+import (
+    "math"
+    "math/big"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+func f() string {
+    var a *string
+    return ((("" + sildNullString(nil)) + sildNullString(nil)) + sildNullString(a))
+}
+
+func main() {
+}
+
+// sildNullString converts v, a value of a type with null but not undefined,
+// to a string like sildString, except that nil is "null".
+func sildNullString(v any) string {
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Pointer, reflect.Slice, reflect.Func:
+        if rv.IsNil() {
+            return "null"
+        }
+    }
+    return sildString(v)
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildString converts v to a string the way JavaScript's String does, for
+// values whose type isn't known statically, such as the elements of arrays.
+// Arrays are joined with commas and other objects are "[object Object]".
+func sildString(v any) string {
+    switch x := v.(type) {
+    case nil:
+        return "undefined"
+    case *big.Int:
+        return x.String()
+    }
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.String:
+        return rv.String()
+    case reflect.Bool:
+        return strconv.FormatBool(rv.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(rv.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        return sildNumberString(rv.Float())
+    case reflect.Slice:
+        return sildJoinValues(rv, ",")
+    case reflect.Func:
+        return "function"
+    case reflect.Pointer:
+        switch {
+        case rv.IsNil():
+            return "undefined"
+        case rv.Elem().Kind() == reflect.Struct:
+            return "[object Object]"
+        }
+        return sildString(rv.Elem().Interface())
+    }
+    return "[object Object]"
+}
+
+// sildJoinValues converts the elements of the slice xs to strings and joins
+// them with sep. Like in Array.prototype.join, null and undefined elements
+// are empty.
+func sildJoinValues(xs reflect.Value, sep string) string {
+    parts := make([]string, xs.Len())
+    for i := range parts {
+        x := xs.Index(i)
+        switch x.Kind() {
+        case reflect.Pointer, reflect.Interface, reflect.Func:
+            if x.IsNil() {
+                continue
+            }
+        }
+        parts[i] = sildString(x.Interface())
+    }
+    return strings.Join(parts, sep)
+}
+`,
+		},
+		{
+			name: "console_log_formats_like_node",
+			input: `interface P { a: number; b?: string }
+class Point { x: number = 1; }
+let p: P = { a: 1 };
+let xs = ["a", "it's"];
+let n: number[] | null = null;
+console.log("p:", p, xs, new Point(), n, 2n, true);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "runtime"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+type P struct {
+    A float64 ` + "`json:\"a\"`" + `
+    B *string ` + "`json:\"b,omitempty\"`" + `
+}
+
+type Point struct {
+    X float64
+}
+
+func NewPoint() *Point {
+    this := &Point{X: 1}
+    return this
+}
+
+var p *P
+var xs *[]string
+var n *[]float64
+
+func main() {
+    p = &P{A: 1}
+    xs = &[]string{"a", "it's"}
+    n = nil
+    fmt.Println("p:", sildInspect(p, "undefined"), sildInspect(xs, "undefined"), sildInspect(NewPoint(), "undefined"), sildInspect(n, "null"), big.NewInt(2).String() + "n", "true")
+}
+
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name:  "split_with_an_empty_separator_gives_utf16_code_units",
+			input: `console.log("ab😀".split("").length);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "unicode/utf16"
+)
+
+func main() {
+    fmt.Println(strconv.Itoa(len(*sildSplit("ab😀", ""))))
+}
+
+// sildSplit splits s around each instance of sep into a new array. An empty
+// sep splits s into its UTF-16 code units, like it does in TypeScript.
+func sildSplit(s, sep string) *[]string {
+    if sep == "" {
+        u := utf16.Encode([]rune(s))
+        parts := make([]string, len(u))
+        for i := range u {
+            parts[i] = string(utf16.Decode(u[i : i+1]))
+        }
+        return &parts
+    }
+    parts := strings.Split(s, sep)
+    return &parts
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestConsoleDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "console_log_as_a_value",
+			input:    `let log = console.log;`,
+			expected: "1:11: error: cannot translate 'console' other than in calls of console.log",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestMethodDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "string_method_as_a_value",
+			input:    `let s = " a "; let t = s.trim;`,
+			expected: "1:24: error: cannot translate method 'trim' of a value of type 'string' other than in a call",
+		},
+		{
+			name:     "array_method_as_a_value",
+			input:    `let xs = [1]; let m = xs.map;`,
+			expected: "1:23: error: cannot translate method 'map' of a value of type 'number[]' other than in a call",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestNameMangling(t *testing.T) {
+	input := `let $el = "x";
+let type = 1;
+let type_ = 2;
+function len(map: number[]): number {
+    return map.length;
+}
+interface error { $msg: string }
+let e: error = { $msg: $el };
+outer: for (const range of [type, type_]) {
+    if (range > len([])) {
+        break outer;
+    }
+}
+console.log(e.$msg);`
+	expected := `package main
+
+import (
+    "fmt"
+)
+
+type error_ struct {
+    X_dollar_msg string ` + "`json:\"$msg\"`" + `
+}
+
+var _dollar_el string
+var type_ float64
+var type__ float64
+var e *error_
+
+func len_(map_ *[]float64) float64 {
+    return float64(len(*map_))
+}
+
+func main() {
+    _dollar_el = "x"
+    type_ = 1
+    type__ = 2
+    e = &error_{X_dollar_msg: _dollar_el}
+    outer:
+    for _, range_ := range []float64{type_, type__} {
+        if range_ > len_(&[]float64{}) {
+            break outer
+        }
+    }
+    fmt.Println(e.X_dollar_msg)
+}
+
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestNameCollisions(t *testing.T) {
+	input := `let $el = 1;
+let _dollar_el = 2;
+let sildMap = 3;
+class Point { x: number = 0 }
+function NewPoint(): Point { return new Point(); }
+console.log($el + _dollar_el + sildMap);
+console.log(NewPoint().x);`
+	expected := `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Point struct {
+    X float64
+}
+
+func NewPoint() *Point {
+    this := &Point{X: 0}
+    return this
+}
+
+var _dollar_el float64
+var _u005f_dollar_u005f_el float64
+var sildMap_ float64
+
+func NewPoint_() *Point {
+    return NewPoint()
+}
+
+func main() {
+    _dollar_el = 1
+    _u005f_dollar_u005f_el = 2
+    sildMap_ = 3
+    fmt.Println(sildNumberString(((_dollar_el + _u005f_dollar_u005f_el) + sildMap_)))
+    fmt.Println(sildNumberString(NewPoint_().X))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestPackageNames(t *testing.T) {
+	input := `let reflect = 1;
+function kind(x: string | number): string {
+    return typeof x;
+}
+console.log(reflect, kind(1));`
+	expected := `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+var reflect_ float64
+
+func kind(x any) string {
+    return sildTypeof(x)
+}
+
+func main() {
+    reflect_ = 1
+    fmt.Println(sildNumberString(reflect_), kind(1.0))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildTypeof returns what typeof evaluates to for v, a value of a union type.
+func sildTypeof(v any) string {
+    switch v.(type) {
+    case string:
+        return "string"
+    case float64, int:
+        return "number"
+    case *big.Int:
+        return "bigint"
+    case bool:
+        return "boolean"
+    case nil:
+        return "undefined"
+    }
+    if reflect.ValueOf(v).Kind() == reflect.Func {
+        return "function"
+    }
+    return "object"
+}`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestCommentGeneration(t *testing.T) {
+	input := `// Shapes and sums.
+
+/**
+ * Adds two numbers.
+ * @param {number} a - the first operand
+ * @param b the second operand
+ * @returns The sum of a and b.
+ */
+function add(a: number, b: number): number {
+    // plain addition
+    return a + b; // no overflow in float64
+}
+
+/** A point on the plane. */
+interface Point {
+    /** The horizontal coordinate. */
+    x: number; // across
+    y: number;
+}
+
+/**
+ * Counts things.
+ * @deprecated Use a plain number.
+ */
+class Counter {
+    /** The count so far. */
+    count: number = 0;
+    static made: number = 0; // instances
+    /** Creates a counter. */
+    constructor() {
+        Counter.made += 1;
+    }
+    /** Increments the count by n. */
+    inc(n: number): void {
+        this.count += n;
+    } // done
+}
+
+/* the result */
+let total = add(1, 2); /* three */
+let c = new Counter();
+c.inc(total);
+console.log(c.count, Counter.made); // 3 1`
+	expected := `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// Point is a point on the plane.
+type Point struct {
+    // X is the horizontal coordinate.
+    X float64 ` + "`json:\"x\"`" + ` // across
+    Y float64 ` + "`json:\"y\"`" + `
+}
+
+// Counter counts things.
+//
+// Deprecated: Use a plain number.
+type Counter struct {
+    // Count is the count so far.
+    Count float64
+}
+
+var CounterMade float64 = 0 // instances
+
+// NewCounter creates a counter.
+func NewCounter() *Counter {
+    this := &Counter{Count: 0}
+    CounterMade += 1
+    return this
+}
+
+// Inc increments the count by n.
+func (this *Counter) Inc(n float64) {
+    this.Count += n
+} // done
+
+var total float64
+var c *Counter
+
+// Shapes and sums.
+
+// add adds two numbers.
+//
+//   - a: the first operand
+//   - b: the second operand
+//
+// Returns the sum of a and b.
+func add(a float64, b float64) float64 {
+    // plain addition
+    return (a + b) // no overflow in float64
+}
+
+func main() {
+    // the result
+    total = add(1, 2) // three
+    c = NewCounter()
+    c.Inc(total)
+    fmt.Println(sildNumberString(c.Count), sildNumberString(CounterMade)) // 3 1
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`
+
+	checkGeneration(t, input, expected, false)
+}
+
+func TestFunctionExpressionGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "arrows_and_function_types",
+			input: `type Op = (a: number, b: number) => number;
+function apply(op: Op, a: number, b: number): number {
+    return op(a, b);
+}
+const add: Op = (a, b) => a + b;
+let first: Op = a => a;
+let half = function (x: number) {
+    return x / 2;
+};
+console.log(apply(add, 1, 2), apply((a, b) => a * b, 3, 4), first(5, 6), half(7));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Op = func(float64, float64) float64
+
+var add Op
+var first Op
+var half func(float64) float64
+
+func apply(op Op, a float64, b float64) float64 {
+    return op(a, b)
+}
+
+func main() {
+    add = func(a float64, b float64) float64 {
+        return (a + b)
+    }
+    first = func(a float64, _ float64) float64 {
+        return a
+    }
+    half = func(x float64) float64 {
+        return (x / 2)
+    }
+    fmt.Println(sildNumberString(apply(add, 1, 2)), sildNumberString(apply(func(a float64, b float64) float64 {
+        return (a * b)
+    }, 3, 4)), sildNumberString(first(5, 6)), sildNumberString(half(7)))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "callback_return_type_is_inferred",
+			input: `let xs: number[] = [1, 2, 3];
+let labels = xs.map(x => {
+    if (x > 1) {
+        return "many";
+    }
+    return "one";
+});
+console.log(labels);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "runtime"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+var xs *[]float64
+var labels *[]string
+
+func main() {
+    xs = &[]float64{1, 2, 3}
+    labels = sildMap(*xs, func(x float64) string {
+        if x > 1 {
+            return "many"
+        }
+        return "one"
+    })
+    fmt.Println(sildInspect(labels, "undefined"))
+}
+
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}
+
+func sildMap[T, U any](xs []T, f func(T) U) *[]U {
+    out := make([]U, len(xs))
+    for i, x := range xs {
+        out[i] = f(x)
+    }
+    return &out
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "closures_and_nested_functions",
+			input: `function counters(n: number): (() => number)[] {
+    let fns: (() => number)[] = [];
+    for (let i = 0; i < n; i++) {
+        fns.push(() => i);
+    }
+    return fns;
+}
+function fib(n: number): number {
+    function go(a: number, b: number, k: number): number {
+        if (k === 0) {
+            return a;
+        }
+        return go(b, a + b, k - 1);
+    }
+    return go(0, 1, n);
+}
+console.log(counters(2), fib(10));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "runtime"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+func counters(n float64) *[]func() float64 {
+    fns := &[]func() float64{}
+    for i := 0.0; i < n; i++ {
+        *fns = append(*fns, func() float64 {
+            return i
+        })
+    }
+    return fns
+}
+
+func fib(n float64) float64 {
+    var go_ func(float64, float64, float64) float64
+    go_ = func(a float64, b float64, k float64) float64 {
+        if k == 0 {
+            return a
+        }
+        return go_(b, (a + b), (k - 1))
+    }
+    return go_(0, 1, n)
+}
+
+func main() {
+    fmt.Println(sildInspect(counters(2), "undefined"), sildNumberString(fib(10)))
+}
+
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+`,
+		},
+		{
+			name: "arrow_captures_this",
+			input: `class Greeter {
+    name: string;
+    greet: (greeting: string) => string;
+    constructor(name: string) {
+        this.name = name;
+        this.greet = g => g + ", " + this.name;
+    }
+}
+console.log(new Greeter("Ann").greet("Hi"));`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+type Greeter struct {
+    Name  string
+    Greet func(string) string
+}
+
+func NewGreeter(name string) *Greeter {
+    this := &Greeter{}
+    this.Name = name
+    this.Greet = func(g string) string {
+        return ((g + ", ") + this.Name)
+    }
+    return this
+}
+
+func main() {
+    fmt.Println(NewGreeter("Ann").Greet("Hi"))
+}
+`,
+		},
+		{
+			name: "functions_returning_without_a_value_return_nil",
+			input: `let xs = [1, 2];
+let ws = xs.map((x) => { if (x > 1) { return; } return "one"; });
+let f = (x: number) => { if (x > 1) { return "a"; } };`,
+			expected: `package main
+
+var xs *[]float64
+var ws *[]*string
+var f func(float64) *string
+
+func main() {
+    xs = &[]float64{1, 2}
+    ws = sildMap(*xs, func(x float64) *string {
+        if x > 1 {
+            return nil
+        }
+        return sildPtr("one")
+    })
+    f = func(x float64) *string {
+        if x > 1 {
+            return sildPtr("a")
+        }
+        return nil
+    }
+}
+
+func sildMap[T, U any](xs []T, f func(T) U) *[]U {
+    out := make([]U, len(xs))
+    for i, x := range xs {
+        out[i] = f(x)
+    }
+    return &out
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestGenericGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "constraints",
+			input: `interface Comparable<T> {
+    compareTo(other: T): number;
+}
+function max<T extends Comparable<T>>(a: T, b: T): T {
+    if (a.compareTo(b) > 0) {
+        return a;
+    }
+    return b;
+}
+function sum<T extends number>(xs: T[]): number {
+    let total = 0;
+    for (const x of xs) {
+        total = total + x;
+    }
+    return total;
+}
+class Version {
+    major: number;
+    constructor(major: number) {
+        this.major = major;
+    }
+    compareTo(other: Version): number {
+        return this.major - other.major;
+    }
+}
+console.log(max(new Version(1), new Version(2)).major, sum([1, 2]));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Comparable[T any] interface {
+    CompareTo(other T) float64
+}
+
+type Version struct {
+    Major float64
+}
+
+func NewVersion(major float64) *Version {
+    this := &Version{}
+    this.Major = major
+    return this
+}
+
+func (this *Version) CompareTo(other *Version) float64 {
+    return (this.Major - other.Major)
+}
+
+func max_[T Comparable[T]](a T, b T) T {
+    if a.CompareTo(b) > 0 {
+        return a
+    }
+    return b
+}
+
+func sum[T ~float64](xs *[]T) float64 {
+    total := 0.0
+    for _i := 0; _i < len(*xs); _i++ {
+        x := (*xs)[_i]
+        total = (total + float64(x))
+    }
+    return total
+}
+
+func main() {
+    fmt.Println(sildNumberString(max_(NewVersion(1), NewVersion(2)).Major), sildNumberString(sum(&[]float64{1, 2})))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`,
+		},
+		{
+			name: "generic_class",
+			input: `class Stack<T> {
+    items: T[] = [];
+    push(x: T): void {
+        this.items.push(x);
+    }
+    pop(): T {
+        return this.items.pop()!;
+    }
+}
+const s = new Stack<number>();
+s.push(1);
+let t: Stack<string> = new Stack();
+t.push("a");
+console.log(s.pop() + 1, t.pop());`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Stack[T any] struct {
+    Items *[]T
+}
+
+func NewStack[T any]() *Stack[T] {
+    this := &Stack[T]{Items: &[]T{}}
+    return this
+}
+
+func (this *Stack[T]) Push(x T) {
+    *this.Items = append(*this.Items, x)
+}
+
+func (this *Stack[T]) Pop() T {
+    return (*sildPopPtr(this.Items))
+}
+
+var s *Stack[float64]
+var t *Stack[string]
+
+func main() {
+    s = NewStack[float64]()
+    s.Push(1)
+    t = NewStack[string]()
+    t.Push("a")
+    fmt.Println(sildNumberString((s.Pop() + 1)), t.Pop())
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPopPtr removes the last element of xs and returns a pointer to it, or
+// nil if xs is empty.
+func sildPopPtr[T any](xs *[]T) *T {
+    n := len(*xs)
+    if n == 0 {
+        return nil
+    }
+    last := (*xs)[n-1]
+    *xs = (*xs)[:n-1]
+    return &last
+}
+
+`,
+		},
+		{
+			name: "explicit_type_arguments",
+			input: `type Pair<A, B> = { first: A; second: B };
+function pair<A, B>(a: A, b: B): Pair<A, B> {
+    return { first: a, second: b };
+}
+function empty<T>(): T[] {
+    return [];
+}
+function same<T>(a: T, b: T): boolean {
+    return a === b;
+}
+const p = pair("a", 1);
+let names = empty<string>();
+console.log(p.first, p.second + 1, names.length, same(1, 2));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Pair[A any, B any] struct {
+    First  A ` + "`json:\"first\"`" + `
+    Second B ` + "`json:\"second\"`" + `
+}
+
+var p *Pair[string, float64]
+var names *[]string
+
+func pair[A any, B any](a A, b B) *Pair[A, B] {
+    return &Pair[A, B]{First: a, Second: b}
+}
+
+func empty[T any]() *[]T {
+    return &[]T{}
+}
+
+func same[T any](a T, b T) bool {
+    return (any(a) == any(b))
+}
+
+func main() {
+    p = pair("a", 1.0)
+    names = empty[string]()
+    fmt.Println(p.First, sildNumberString((p.Second + 1)), strconv.Itoa(len(*names)), strconv.FormatBool(same(1.0, 2.0)))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestGenericDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "generic_method",
+			input:    "class C { m<T>(x: T): T { return x; } }",
+			expected: "1:11: error: cannot translate generic method 'm' of 'C': Go methods can't have type parameters",
+		},
+		{
+			name:     "nested_generic_function",
+			input:    "function f(): void { function id<T>(x: T): T { return x; } }",
+			expected: "1:22: error: cannot translate generic function 'id' declared in a function: Go function literals can't have type parameters",
+		},
+		{
+			name:     "generic_function_as_a_value",
+			input:    `function pair<A, B>(a: A, b: B): A { return a; } const p = pair; p("x", 1);`,
+			expected: "1:60: error: cannot translate generic function 'pair' used as a value: Go needs its type arguments, which are only inferred where it is called",
+		},
+		{
+			name:     "constraint_with_property",
+			input:    "interface Named { name: string } function f<T extends Named>(x: T): void {}",
+			expected: "1:19: error: cannot translate property 'name' of 'Named', which is used as a constraint: Go constraints can only have methods",
+		},
+		{
+			name:     "object_literal_of_constraint",
+			input:    "interface Sized { size(): number } function f<T extends Sized>(x: T): void {} let s: Sized = { size: () => 1 };",
+			expected: "1:94: error: cannot translate object literal of type 'Sized', which is used as a constraint: Go lowers it to an interface of methods",
+		},
+		{
+			name:     "class_constraint",
+			input:    "class A {} function f<T extends A>(x: T): void {}",
+			expected: "1:33: error: cannot translate constraint 'A' of 'T': Go constraints can only be primitive types or interfaces of methods",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestUnionGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "property_of_narrowed_variable_assigned",
+			input: `type Shape = { kind: "circle"; r: number } | { kind: "square"; side: number };
+function grow(s: Shape): void {
+    if (s.kind === "circle") {
+        s.r = s.r * 2;
+    }
+}`,
+			expected: `package main
+
+type Shape interface {
+    Kind() string
+    isShape()
+}
+
+type ShapeCircle struct {
+    R float64 ` + "`json:\"r\"`" + `
+}
+
+func (ShapeCircle) Kind() string { return "circle" }
+func (ShapeCircle) isShape() {}
+
+type ShapeSquare struct {
+    Side float64 ` + "`json:\"side\"`" + `
+}
+
+func (ShapeSquare) Kind() string { return "square" }
+func (ShapeSquare) isShape() {}
+
+func grow(s Shape) {
+    if s.Kind() == "circle" {
+        s := s.(*ShapeCircle)
+        s.R = (s.R * 2)
+    }
+}
+
+func main() {
+}
+
+`,
+		},
+		{
+			name: "sealed_interface",
+			input: `interface Circle {
+    kind: "circle";
+    r: number;
+}
+type Shape = Circle | { kind: "square"; side: number };
+function area(s: Shape): number {
+    switch (s.kind) {
+        case "circle":
+            return s.r * s.r;
+        case "square":
+            return s.side * s.side;
+    }
+}
+let s: Shape = { kind: "square", side: 2 };
+console.log(area(s), s.kind);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Circle struct {
+    R float64 ` + "`json:\"r\"`" + `
+}
+
+type Shape interface {
+    Kind() string
+    isShape()
+}
+
+func (Circle) Kind() string { return "circle" }
+func (Circle) isShape() {}
+
+type ShapeSquare struct {
+    Side float64 ` + "`json:\"side\"`" + `
+}
+
+func (ShapeSquare) Kind() string { return "square" }
+func (ShapeSquare) isShape() {}
+
+var s Shape
+
+func area(s Shape) float64 {
+    switch s := s.(type) {
+    case *Circle:
+        return (s.R * s.R)
+    case *ShapeSquare:
+        return (s.Side * s.Side)
+    default:
+        panic("unreachable")
+    }
+}
+
+func main() {
+    s = &ShapeSquare{Side: 2}
+    fmt.Println(sildNumberString(area(s)), s.Kind())
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`,
+		},
+		{
+			name: "narrowing",
+			input: `type Shape = { kind: "circle"; r: number } | { kind: "square"; side: number };
+function size(s: Shape): number {
+    if (s.kind === "circle") {
+        return s.r;
+    }
+    return s.side;
+}
+function label(x: string | number): string {
+    if (typeof x === "string" && x.length > 0) {
+        return x;
+    }
+    return "";
+}
+console.log(size({ kind: "circle", r: 1 }), label(1));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "strconv"
+    "strings"
+    "unicode/utf16"
+)
+
+type Shape interface {
+    Kind() string
+    isShape()
+}
+
+type ShapeCircle struct {
+    R float64 ` + "`json:\"r\"`" + `
+}
+
+func (ShapeCircle) Kind() string { return "circle" }
+func (ShapeCircle) isShape() {}
+
+type ShapeSquare struct {
+    Side float64 ` + "`json:\"side\"`" + `
+}
+
+func (ShapeSquare) Kind() string { return "square" }
+func (ShapeSquare) isShape() {}
+
+func size(s Shape) float64 {
+    if s.Kind() == "circle" {
+        s := s.(*ShapeCircle)
+        return s.R
+    }
+    return s.(*ShapeSquare).Side
+}
+
+func label(x any) string {
+    if (sildTypeof(x) == "string") && (sildLength(x.(string)) > 0) {
+        x := x.(string)
+        return x
+    }
+    return ""
+}
+
+func main() {
+    fmt.Println(sildNumberString(size(&ShapeCircle{R: 1})), label(1.0))
+}
+
+// sildLength returns the length of s in UTF-16 code units, the units strings
+// are measured and indexed in by TypeScript.
+func sildLength(s string) int {
+    n := 0
+    for _, r := range s {
+        n += utf16.RuneLen(r)
+    }
+    return n
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildTypeof returns what typeof evaluates to for v, a value of a union type.
+func sildTypeof(v any) string {
+    switch v.(type) {
+    case string:
+        return "string"
+    case float64, int:
+        return "number"
+    case *big.Int:
+        return "bigint"
+    case bool:
+        return "boolean"
+    case nil:
+        return "undefined"
+    }
+    if reflect.ValueOf(v).Kind() == reflect.Func {
+        return "function"
+    }
+    return "object"
+}
+
+`,
+		},
+		{
+			name: "value_switch",
+			input: `type Dir = "left" | "right";
+function flip(d: Dir): Dir {
+    switch (d) {
+        case "left":
+            return "right";
+        case "right":
+            return "left";
+    }
+}
+function describe(n: number): string {
+    let s = "";
+    switch (n) {
+        case 0:
+        case 1:
+            s = "small";
+            break;
+        default:
+            s = "big";
+    }
+    return s;
+}
+console.log(flip("left"), describe(2));`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+type Dir = string
+
+func flip(d Dir) Dir {
+    switch d {
+    case "left":
+        return "right"
+    case "right":
+        return "left"
+    default:
+        panic("unreachable")
+    }
+}
+
+func describe(n float64) string {
+    s := ""
+    switch n {
+    case 0, 1:
+        s = "small"
+    default:
+        s = "big"
+    }
+    return s
+}
+
+func main() {
+    fmt.Println(flip("left"), describe(2))
+}
+
+`,
+		},
+		{
+			name: "narrowed_string_literal_union",
+			input: `type Dir = "up" | "down";
+function arrow(d: Dir): string {
+    if (d === "up") {
+        return "^" + d;
+    }
+    return d;
+}
+let d: Dir = "down";
+console.log(arrow(d), arrow("up"));`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+type Dir = string
+
+var d Dir
+
+func arrow(d Dir) string {
+    if d == "up" {
+        return ("^" + d)
+    }
+    return d
+}
+
+func main() {
+    d = "down"
+    fmt.Println(arrow(d), arrow("up"))
+}`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestUnionDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "common_property",
+			input:    `type S = { kind: "a"; x: number } | { kind: "b"; x: number }; function f(s: S): number { return s.x; }`,
+			expected: "1:97: error: cannot translate property 'x' of a value of type 'S': narrow it to one of its members first",
+		},
+		{
+			name:     "assignment_to_narrowed_variable",
+			input:    `type S = { kind: "a" } | { kind: "b" }; function f(s: S): void { if (s.kind === "a") { s = { kind: "b" }; } }`,
+			expected: "1:88: error: cannot assign to 's' where it is narrowed to '{ kind: \"a\" }': Go only has a copy of it",
+		},
+		{
+			name:     "unknown_variant",
+			input:    `type S = { kind: "a" } | { kind: "b" }; function f(k: string): S { return { kind: k }; }`,
+			expected: "1:75: error: cannot tell which member of 'S' the object literal is: its 'kind' must be a string literal naming one",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestNullGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "nullable_values_and_pointers",
+			input: `interface User { name: string; age?: number }
+function find(users: User[], name: string): User | null {
+    for (const u of users) {
+        if (u.name === name) {
+            return u;
+        }
+    }
+    return null;
+}
+function label(u: User | null): string {
+    if (u === null) {
+        return "nobody";
+    }
+    return u.name;
+}
+let s: string | null = null;
+s ??= "set";
+console.log(s === "set", label(find([{ name: "ann" }], "ann")));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "strconv"
+)
+
+type User struct {
+    Name string   ` + "`json:\"name\"`" + `
+    Age  *float64 ` + "`json:\"age,omitempty\"`" + `
+}
+
+var s *string
+
+func find(users *[]*User, name string) *User {
+    for _i := 0; _i < len(*users); _i++ {
+        u := (*users)[_i]
+        if u.Name == name {
+            return u
+        }
+    }
+    return nil
+}
+
+func label(u *User) string {
+    if u == nil {
+        return "nobody"
+    }
+    return u.Name
+}
+
+func main() {
+    s = nil
+    if s == nil {
+        s = sildPtr("set")
+    }
+    fmt.Println(strconv.FormatBool(sildEqualPtr(s, sildPtr("set"))), label(find(&[]*User{&User{Name: "ann"}}, "ann")))
+}
+
+// sildEqualPtr reports whether p and q are both nil or point to equal values.
+func sildEqualPtr[T comparable](p, q *T) bool {
+    return p == q || p != nil && q != nil && *p == *q
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+
+`,
+		},
+		{
+			name: "optional_chains",
+			input: `class Node {
+    value: number;
+    next?: Node;
+    constructor(value: number) { this.value = value; }
+}
+function second(n: Node | undefined): number {
+    return n?.next?.value ?? -1;
+}
+let n = new Node(1);
+n.next?.next?.value;
+console.log(second(n));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Node struct {
+    Value float64
+    Next  *Node
+}
+
+func NewNode(value float64) *Node {
+    this := &Node{}
+    this.Value = value
+    return this
+}
+
+var n *Node
+
+func second(n *Node) float64 {
+    return func() float64 {
+        if _v := func() *float64 {
+            if n == nil {
+                return nil
+            }
+            if n.Next == nil {
+                return nil
+            }
+            return sildPtr(n.Next.Value)
+        }(); _v != nil {
+            return *_v
+        }
+        return -1
+    }()
+}
+
+func main() {
+    n = NewNode(1)
+    if n.Next != nil {
+        if n.Next.Next != nil {
+            _ = n.Next.Next.Value
+        }
+    }
+    fmt.Println(sildNumberString(second(n)))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+
+`,
+		},
+		{
+			name: "truthiness_and_nilable_types",
+			input: `function greet(name: string | undefined): string {
+    if (name) {
+        return "hi " + name;
+    }
+    return "hi";
+}
+let xs: number[] | null = [1];
+xs?.push(2);
+let first = xs![0];
+let count: number | undefined;
+console.log(greet(undefined), first, count ?? 0);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+var xs *[]float64
+var first float64
+var count *float64
+
+func greet(name *string) string {
+    if name != nil && *name != "" {
+        name := *name
+        return ("hi " + name)
+    }
+    return "hi"
+}
+
+func main() {
+    xs = &[]float64{1}
+    if xs != nil {
+        *xs = append(*xs, 2)
+    }
+    first = (*xs)[0]
+    fmt.Println(greet(nil), sildNumberString(first), sildNumberString(func() float64 {
+        if count != nil {
+            return *count
+        }
+        return 0
+    }()))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`,
+		},
+		{
+			name: "assignments narrow variables",
+			input: `function pick(a: string | null): number {
+    let x: string | null = a;
+    x = "q";
+    return x.length;
+}
+function fill(a: number | undefined): number {
+    let x = a;
+    x ??= 7;
+    return x;
+}
+console.log(pick(null), fill(undefined));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "unicode/utf16"
+)
+
+func pick(a *string) float64 {
+    x := a
+    x = sildPtr("q")
+    return float64(sildLength((*x)))
+}
+
+func fill(a *float64) float64 {
+    x := a
+    if x == nil {
+        x = sildPtr(7.0)
+    }
+    return (*x)
+}
+
+func main() {
+    fmt.Println(sildNumberString(pick(nil)), sildNumberString(fill(nil)))
+}
+
+// sildLength returns the length of s in UTF-16 code units, the units strings
+// are measured and indexed in by TypeScript.
+func sildLength(s string) int {
+    n := 0
+    for _, r := range s {
+        n += utf16.RuneLen(r)
+    }
+    return n
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+`,
+		},
+		{
+			name: "conditions narrow properties",
+			input: `interface Tagged { label?: string; count: number | string }
+function describe(t: Tagged): string {
+    if (t.label !== undefined) {
+        return t.label;
+    }
+    if (typeof t.count === "number") {
+        return "" + (t.count + 1);
+    }
+    return t.count;
+}
+console.log(describe({ count: 2 }));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+type Tagged struct {
+    Label *string ` + "`json:\"label,omitempty\"`" + `
+    Count any     ` + "`json:\"count\"`" + `
+}
+
+func describe(t *Tagged) string {
+    if t.Label != nil {
+        return (*t.Label)
+    }
+    if sildTypeof(t.Count) == "number" {
+        return ("" + sildNumberString((t.Count.(float64) + 1)))
+    }
+    return t.Count.(string)
+}
+
+func main() {
+    fmt.Println(describe(&Tagged{Count: 2.0}))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildTypeof returns what typeof evaluates to for v, a value of a union type.
+func sildTypeof(v any) string {
+    switch v.(type) {
+    case string:
+        return "string"
+    case float64, int:
+        return "number"
+    case *big.Int:
+        return "bigint"
+    case bool:
+        return "boolean"
+    case nil:
+        return "undefined"
+    }
+    if reflect.ValueOf(v).Kind() == reflect.Func {
+        return "function"
+    }
+    return "object"
+}`,
+		},
+		{
+			name: "pop and find may return undefined",
+			input: `interface User { name: string }
+function run(xs: number[], users: User[]): void {
+    const found = xs.find((x) => x > 1) ?? -1;
+    const last = xs.pop();
+    const ann = users.find((u) => u.name === "ann");
+    if (last !== undefined && ann) {
+        console.log(found, last, ann.name);
+    }
+}
+run([1, 2], [{ name: "ann" }]);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type User struct {
+    Name string ` + "`json:\"name\"`" + `
+}
+
+func run(xs *[]float64, users *[]*User) {
+    found := func() float64 {
+        if _v := sildFindPtr(*xs, func(x float64) bool {
+            return (x > 1)
+        }); _v != nil {
+            return *_v
+        }
+        return -1
+    }()
+    last := sildPopPtr(xs)
+    ann := sildFind(*users, func(u *User) bool {
+        return (u.Name == "ann")
+    })
+    if (last != nil) && ann != nil {
+        last := *last
+        fmt.Println(sildNumberString(found), sildNumberString(last), ann.Name)
+    }
+}
+
+func main() {
+    run(&[]float64{1, 2}, &[]*User{&User{Name: "ann"}})
+}
+
+// sildFind returns the first element of xs satisfying f, or the zero value,
+// nil, if there is none.
+func sildFind[T any](xs []T, f func(T) bool) T {
+    for _, x := range xs {
+        if f(x) {
+            return x
+        }
+    }
+    var zero T
+    return zero
+}
+
+// sildFindPtr returns a pointer to the first element of xs satisfying f, or
+// nil if there is none.
+func sildFindPtr[T any](xs []T, f func(T) bool) *T {
+    for _, x := range xs {
+        if f(x) {
+            return &x
+        }
+    }
+    return nil
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPopPtr removes the last element of xs and returns a pointer to it, or
+// nil if xs is empty.
+func sildPopPtr[T any](xs *[]T) *T {
+    n := len(*xs)
+    if n == 0 {
+        return nil
+    }
+    last := (*xs)[n-1]
+    *xs = (*xs)[:n-1]
+    return &last
+}
+`,
+		},
+		{
+			name: "initializers_compound_assignments_and_if_statements_narrow",
+			input: `function initialized(): number {
+    let x: number | undefined = 1;
+    return x * 10;
+}
+function incremented(): number {
+    let x: number | undefined;
+    x = 3;
+    x += 1;
+    x++;
+    return x;
+}
+function defaulted(s: string | null): number {
+    if (s == null) { s = ""; }
+    return s.length;
+}
+function doubled(): number {
+    let n: string | number = "a";
+    n = 4;
+    n *= 2;
+    return n;
+}
+console.log(initialized(), incremented(), defaulted(null), doubled());`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "unicode/utf16"
+)
+
+func initialized() float64 {
+    var x *float64 = sildPtr(1.0)
+    return ((*x) * 10)
+}
+
+func incremented() float64 {
+    var x *float64
+    x = sildPtr(3.0)
+    x = sildPtr(((*x) + 1))
+    x = sildPtr(((*x) + 1))
+    return (*x)
+}
+
+func defaulted(s *string) float64 {
+    if s == nil {
+        s = sildPtr("")
+    }
+    return float64(sildLength((*s)))
+}
+
+func doubled() float64 {
+    var n any = "a"
+    n = 4.0
+    n = (n.(float64) * 2)
+    return n.(float64)
+}
+
+func main() {
+    fmt.Println(sildNumberString(initialized()), sildNumberString(incremented()), sildNumberString(defaulted(nil)), sildNumberString(doubled()))
+}
+
+// sildLength returns the length of s in UTF-16 code units, the units strings
+// are measured and indexed in by TypeScript.
+func sildLength(s string) int {
+    n := 0
+    for _, r := range s {
+        n += utf16.RuneLen(r)
+    }
+    return n
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}`,
+		},
+		{
+			name: "comparisons_of_narrowed_variables_with_null",
+			input: `function checked(): number {
+    let x: string | null = "abc";
+    if (x !== null) {
+        return x.length;
+    }
+    return 0;
+}
+function rechecked(): number {
+    let x: string | null = "abc";
+    if (x !== null) {
+        if (x === null) {
+            return 1;
+        }
+        return x.length;
+    }
+    return 0;
+}
+function name(): string { return "n"; }
+console.log(checked(), rechecked(), name() !== null);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "unicode/utf16"
+)
+
+func checked() float64 {
+    var x *string = sildPtr("abc")
+    if x != nil {
+        x := *x
+        return float64(sildLength(x))
+    }
+    return 0
+}
+
+func rechecked() float64 {
+    var x *string = sildPtr("abc")
+    if x != nil {
+        x := *x
+        if false {
+            return 1
+        }
+        return float64(sildLength(x))
+    }
+    return 0
+}
+
+func name() string {
+    return "n"
+}
+
+func main() {
+    fmt.Println(sildNumberString(checked()), sildNumberString(rechecked()), strconv.FormatBool(func() bool {
+        _ = name()
+        return true
+    }()))
+}
+
+// sildLength returns the length of s in UTF-16 code units, the units strings
+// are measured and indexed in by TypeScript.
+func sildLength(s string) int {
+    n := 0
+    for _, r := range s {
+        n += utf16.RuneLen(r)
+    }
+    return n
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}`,
+		},
+		{
+			name: "optional_chain_on_a_call",
+			input: `interface B { c: number }
+interface A { b?: B }
+function f(): A | null { return { b: { c: 3 } }; }
+console.log(f()?.b?.c ?? 7);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type B struct {
+    C float64 ` + "`json:\"c\"`" + `
+}
+
+type A struct {
+    B *B ` + "`json:\"b,omitempty\"`" + `
+}
+
+func f() *A {
+    return &A{B: &B{C: 3}}
+}
+
+func main() {
+    fmt.Println(sildNumberString(func() float64 {
+        if _v := func() *float64 {
+            _v1 := f()
+            if _v1 == nil {
+                return nil
+            }
+            if _v1.B == nil {
+                return nil
+            }
+            return sildPtr(_v1.B.C)
+        }(); _v != nil {
+            return *_v
+        }
+        return 7
+    }()))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestNullDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "truthiness_of_primitives_in_an_interface",
+			input:    `function f(x: string | number | null): void { if (x) { console.log(1); } }`,
+			expected: "1:51: error: cannot translate a test of whether a value of type 'string | number | null' is truthy: compare it with null instead",
+		},
+		{
+			name:     "logical_operator_on_a_nullable_operand",
+			input:    "function f(a: number | null): number { return a || 5; }",
+			expected: "1:47: error: cannot translate '||' on operands of types 'number | null' and 'number': test them with a condition instead",
+		},
+		{
+			name:     "assignment_to_dereferenced_variable",
+			input:    `function f(x: string | null): void { if (x) { x = "a"; } }`,
+			expected: "1:47: error: cannot assign to 'x' where it is narrowed to 'string': Go only has a copy of it",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestEnumGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "numeric_enum_with_reverse_mapping",
+			input: `enum Color { Red, Green, Blue }
+function paint(c: Color): string {
+    switch (c) {
+        case Color.Red:
+            return "red";
+    }
+    return Color[c];
+}
+let c: Color = Color.Green;
+let n: number = c + 1;
+console.log(paint(c), Color[0], n);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Color int
+
+const (
+    ColorRed Color = iota
+    ColorGreen
+    ColorBlue
+)
+
+func (c Color) String() string {
+    switch c {
+    case ColorRed:
+        return "Red"
+    case ColorGreen:
+        return "Green"
+    case ColorBlue:
+        return "Blue"
+    }
+    return "Color(" + strconv.Itoa(int(c)) + ")"
+}
+
+var c Color
+var n float64
+
+func paint(c Color) string {
+    switch c {
+    case ColorRed:
+        return "red"
+    }
+    return c.String()
+}
+
+func main() {
+    c = ColorGreen
+    n = (float64(c) + 1)
+    fmt.Println(paint(c), Color(0).String(), sildNumberString(n))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+`,
+		},
+		{
+			name: "explicit_string_and_const_enums",
+			input: `enum Level { Low = 1, High = 10, Top = High }
+enum Status { Ok = "ok", Fail = "fail" }
+const enum Dir { Up = 1, Down = Up * 2 }
+let s: Status = Status.Fail;
+let label: string = s;
+let d = Dir.Down;
+console.log(label + "!", d === Dir.Down, Level[10], ` + "`level ${Level.Low}`" + `);`,
+			expected: `package main
+
+import (
+    "fmt"
+    "strconv"
+)
+
+type Level int
+
+const (
+    LevelLow  Level = 1
+    LevelHigh Level = 10
+    LevelTop  Level = 10
+)
+
+func (l Level) String() string {
+    switch l {
+    case LevelLow:
+        return "Low"
+    case LevelTop:
+        return "Top"
+    }
+    return "Level(" + strconv.Itoa(int(l)) + ")"
+}
+
+type Status string
+
+const (
+    StatusOk   Status = "ok"
+    StatusFail Status = "fail"
+)
+
+func (s Status) String() string { return string(s) }
+
+type Dir int
+
+var s Status
+var label string
+var d Dir
+
+func main() {
+    s = StatusFail
+    label = string(s)
+    d = Dir(2)
+    fmt.Println((label + "!"), strconv.FormatBool(d == Dir(2)), Level(10).String(), ("level " + strconv.Itoa(int(LevelLow))))
+}
+
+`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
+func TestEnumDiagnostics(t *testing.T) {
+	tests := []generationTest{
+		{
+			name:     "mixed_enum",
+			input:    `enum E { A = 1, B = "b" }`,
+			expected: "1:1: error: cannot translate enum 'E' mixing numbers and strings",
+		},
+		{
+			name:     "computed_member",
+			input:    `enum E { A = "abc".length }`,
+			expected: "1:10: error: cannot translate enum member 'A' whose value isn't a constant",
+		},
+		{
+			name:     "fractional_member",
+			input:    `enum E { A = 1 / 2 }`,
+			expected: "1:10: error: cannot translate enum member 'A' whose value isn't an integer",
+		},
+		{
+			name:     "enum_inside_a_function",
+			input:    `function f(): void { enum E { A } }`,
+			expected: "1:22: error: cannot translate an enum declared inside a function",
+		},
+	}
+
+	runDiagnosticTests(t, tests)
+}
+
+func TestSwitchGeneration(t *testing.T) {
+	tests := []generationTest{
+		{
+			name: "fallthrough_and_default_in_the_middle",
+			input: `function describe(n: number): string {
+    let out = "";
+    switch (n) {
+        case 0:
+            out += "zero ";
+        case 1:
+            out += "small";
+            break;
+        default:
+            out += "big ";
+        case 2:
+            return out + "two";
+        case 3:
+            out += "three";
+    }
+    return out;
+}
+function half(n: number): string {
+    switch (n / 2) {
+        case 0.5:
+            return "half";
+        case 1:
+        case 1.0:
+        case 0x1:
+            return "one";
+    }
+    return "none";
+}
+console.log(describe(0), describe(5), half(1));`,
+			expected: `package main
+
+import (
+    "fmt"
+)
+
+func describe(n float64) string {
+    out := ""
+    switch n {
+    case 0:
+        out += "zero "
+        fallthrough
+    case 1:
+        out += "small"
+    default:
+        out += "big "
+        fallthrough
+    case 2:
+        return (out + "two")
+    case 3:
+        out += "three"
+    }
+    return out
+}
+
+func half(n float64) string {
+    switch (n / 2) {
+    case 0.5:
+        return "half"
+    case 1:
+        return "one"
+    }
+    return "none"
+}
+
+func main() {
+    fmt.Println(describe(0), describe(5), half(1))
+}
+
+`,
+		},
+		{
+			name: "repeated_cases",
+			input: `type Shape = { kind: "circle"; r: number } | { kind: "square"; side: number };
+const A = "a";
+function area(s: Shape): number {
+    switch (s.kind) {
+        case "circle":
+            return s.r * s.r;
+        case "circle":
+            return 0;
+        case "square":
+            return s.side * s.side;
+    }
+}
+function name(t: string): string {
+    switch (t) {
+        case "a":
+            return "a";
+        case 'a':
+        case A:
+            return "again";
+        case "b" + "c":
+            return "bc";
+    }
+    return "other";
+}
+console.log(area({ kind: "circle", r: 1 }), name("a"));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Shape interface {
+    Kind() string
+    isShape()
+}
+
+type ShapeCircle struct {
+    R float64 ` + "`json:\"r\"`" + `
+}
+
+func (ShapeCircle) Kind() string { return "circle" }
+func (ShapeCircle) isShape() {}
+
+type ShapeSquare struct {
+    Side float64 ` + "`json:\"side\"`" + `
+}
+
+func (ShapeSquare) Kind() string { return "square" }
+func (ShapeSquare) isShape() {}
+
+const A = "a"
+
+func area(s Shape) float64 {
+    switch s := s.(type) {
+    case *ShapeCircle:
+        return (s.R * s.R)
+    case *ShapeSquare:
+        return (s.Side * s.Side)
+    default:
+        panic("unreachable")
+    }
+}
+
+func name(t string) string {
+    switch t {
+    case "a":
+        return "a"
+    case ("b" + "c"):
+        return "bc"
+    }
+    return "other"
+}
+
+func main() {
+    fmt.Println(sildNumberString(area(&ShapeCircle{R: 1})), name("a"))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}`,
+		},
+		{
+			name: "union_and_nullable_discriminants",
+			input: `interface Circle { kind: "circle"; radius: number }
+interface Square { kind: "square"; side: number }
+type Shape = Circle | Square;
+function size(s: Shape): number {
+    let n = 0;
+    switch (s.kind) {
+        case "circle":
+            n += s.radius;
+        case "square":
+            n += 1;
+    }
+    return n;
+}
+function label(s: string | null): string {
+    switch (s) {
+        case null:
+            return "none";
+        case "a":
+            return "A";
+    }
+    return "other";
+}
+console.log(size({ kind: "circle", radius: 2 }), label(null));`,
+			expected: `package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+type Circle struct {
+    Radius float64 ` + "`json:\"radius\"`" + `
+}
+
+type Square struct {
+    Side float64 ` + "`json:\"side\"`" + `
+}
+
+type Shape interface {
+    Kind() string
+    isShape()
+}
+
+func (Circle) Kind() string { return "circle" }
+func (Circle) isShape() {}
+
+func (Square) Kind() string { return "square" }
+func (Square) isShape() {}
+
+func size(s Shape) float64 {
+    n := 0.0
+    switch s.Kind() {
+    case "circle":
+        s := s.(*Circle)
+        n += s.Radius
+        fallthrough
+    case "square":
+        n += 1
+    default:
+        panic("unreachable")
+    }
+    return n
+}
+
+func label(s *string) string {
+    switch {
+    case s == nil:
+        return "none"
+    case sildEqualPtr(s, sildPtr("a")):
+        return "A"
+    }
+    return "other"
+}
+
+func main() {
+    fmt.Println(sildNumberString(size(&Circle{Radius: 2})), label(nil))
+}
+
+// sildEqualPtr reports whether p and q are both nil or point to equal values.
+func sildEqualPtr[T comparable](p, q *T) bool {
+    return p == q || p != nil && q != nil && *p == *q
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}
+
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}
+
+`,
+		},
+		{
+			name: "throw_ends_a_case",
+			input: `function describe(x: number): string {
+    let s = "";
+    switch (x) {
+        case 1:
+            throw new Error("one");
+        case 2:
+            s += "two";
+        case 3:
+            s += "three";
+            break;
+        default:
+            throw "bad " + x;
+    }
+    return s;
+}
+console.log(describe(2));`,
+			expected: `package main
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+func describe(x float64) string {
+    s := ""
+    switch x {
+    case 1:
+        panic(errors.New("one"))
+    case 2:
+        s += "two"
+        fallthrough
+    case 3:
+        s += "three"
+    default:
+        panic(("bad " + sildNumberString(x)))
+    }
+    return s
+}
+
+func main() {
+    fmt.Println(describe(2))
+}
+
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}`,
+		},
+	}
+
+	runGenerationTests(t, tests)
+}
+
@@ -0,0 +1,276 @@
+package codegen
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// generateTypeParams generates the Go type parameter list of a generic
+// declaration, or returns "" if params is empty.
+func (g *Generator) generateTypeParams(params []*ast.TypeParam) string {
+	if len(params) == 0 {
+		return ""
+	}
+	list := make([]string, len(params))
+	for i, p := range params {
+		list[i] = g.goName(p.Name.Literal) + " " + g.goConstraint(p)
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+// generateTypeArgs generates the type argument list instantiating the type
+// parameters of a generic Go declaration with themselves, as in the
+// receiver of a method of a generic class.
+func (g *Generator) generateTypeArgs(params []*ast.TypeParam) string {
+	if len(params) == 0 {
+		return ""
+	}
+	list := make([]string, len(params))
+	for i, p := range params {
+		list[i] = g.goName(p.Name.Literal)
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+// goConstraint lowers the constraint of a type parameter to a Go constraint.
+// A primitive type admits the types whose underlying type is its Go type,
+// and an interface of methods becomes a Go interface; since Go constraints
+// can't require fields, interfaces with properties can't be constraints.
+func (g *Generator) goConstraint(p *ast.TypeParam) string {
+	switch c := p.Constraint.(type) {
+	case nil:
+		return "any"
+	case *ast.ObjectType:
+		if methods, ok := g.methodSpecs(c, p.Name.Literal); ok {
+			if len(methods) == 0 {
+				return "any"
+			}
+			return "interface{ " + strings.Join(methods, "; ") + " }"
+		}
+		return "any"
+	case *ast.TypeReference:
+		switch c.Name.Literal {
+		case "number", "string", "boolean":
+			return "~" + g.goType(c)
+		case "bigint":
+			return g.goType(c)
+		}
+		if g.constraints[c.Name.Literal] {
+			return g.goType(c)
+		}
+	}
+	g.errorf(p.Constraint, "cannot translate constraint '%s' of '%s': Go constraints can only be primitive types or interfaces of methods", p.Constraint.String(), p.Name.Literal)
+	return "any"
+}
+
+// methodSpecs generates the method specifications of a Go interface for the
+// members of obj, which must all be methods. name is the type that obj is
+// the constraint of, for diagnostics.
+func (g *Generator) methodSpecs(obj *ast.ObjectType, name string) ([]string, bool) {
+	methods := make([]string, len(obj.Members))
+	for i, m := range obj.Members {
+		fn, ok := m.Type.(*ast.FunctionType)
+		if !ok || m.Optional {
+			g.errorf(m, "cannot translate property '%s' of '%s', which is used as a constraint: Go constraints can only have methods", m.Name.Literal, name)
+			return nil, false
+		}
+		methods[i] = fieldName(m.Name.Literal) + "(" + g.generateParams(fn.Params) + ")"
+		if ret := g.goType(fn.ReturnType); ret != "" {
+			methods[i] += " " + ret
+		}
+	}
+	return methods, true
+}
+
+// generateConstraintInterface generates a Go interface for an interface that
+// is the constraint of a type parameter, which Go requires to be an
+// interface rather than the struct interfaces are otherwise lowered to.
+func (g *Generator) generateConstraintInterface(name string, params []*ast.TypeParam, obj *ast.ObjectType) string {
+	methods, _ := g.methodSpecs(obj, name)
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("type %s%s interface {\n", g.goName(name), g.generateTypeParams(params)))
+	for i, m := range obj.Members {
+		if i >= len(methods) {
+			break
+		}
+		builder.WriteString(indentComments(m.Leading, fieldName(m.Name.Literal)))
+		builder.WriteString(withTrailingComment(indent+methods[i], m.Trailing) + "\n")
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// collectConstraints records the interfaces that are named by the
+// constraint of a type parameter declared in stmts.
+func (g *Generator) collectConstraints(stmts []Statement) {
+	collect := func(params []*ast.TypeParam) {
+		for _, p := range params {
+			name := typeName(p.Constraint)
+			if _, ok := g.typeDecls[name].(*ast.ObjectType); ok {
+				g.constraints[name] = true
+			}
+		}
+	}
+
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.FunctionDeclaration:
+			collect(s.TypeParams)
+		case *ast.InterfaceDeclaration:
+			collect(s.TypeParams)
+		case *ast.TypeAliasDeclaration:
+			collect(s.TypeParams)
+		case *ast.ClassDeclaration:
+			collect(s.TypeParams)
+		}
+	}
+}
+
+// declareTypeParams brings the type parameters of a generic declaration
+// into scope and returns the ones that were in scope before, which the
+// caller restores at the end of the declaration.
+func (g *Generator) declareTypeParams(params []*ast.TypeParam) map[string]*ast.TypeParam {
+	outer := g.typeParams
+	if len(params) == 0 {
+		return outer
+	}
+	g.typeParams = maps.Clone(outer)
+	if g.typeParams == nil {
+		g.typeParams = map[string]*ast.TypeParam{}
+	}
+	for _, p := range params {
+		g.typeParams[p.Name.Literal] = p
+	}
+	return outer
+}
+
+// typeParam returns the type parameter in scope that t refers to, or nil if
+// t isn't a type parameter.
+func (g *Generator) typeParam(t ast.TypeExpr) *ast.TypeParam {
+	if ref, ok := t.(*ast.TypeReference); ok && len(ref.Args) == 0 {
+		return g.typeParams[ref.Name.Literal]
+	}
+	return nil
+}
+
+// primitiveConstraint returns the primitive type values of t, a type
+// parameter, have according to its constraint, or nil if t isn't a type
+// parameter constrained by a primitive type.
+func (g *Generator) primitiveConstraint(t ast.TypeExpr) ast.TypeExpr {
+	if p := g.typeParam(t); p != nil {
+		switch typeName(p.Constraint) {
+		case "number", "string", "boolean":
+			return p.Constraint
+		}
+	}
+	return nil
+}
+
+// declaredType returns the type named by ref, a reference to a type
+// declared in the program, with the type arguments of ref substituted for
+// the type parameters of the declaration. Type parameters in scope stand
+// for their constraint.
+func (g *Generator) declaredType(ref *ast.TypeReference) ast.TypeExpr {
+	if p := g.typeParam(ref); p != nil {
+		return p.Constraint
+	}
+	t := g.typeDecls[ref.Name.Literal]
+	if params := g.generics[ref.Name.Literal]; len(params) > 0 {
+		t = ast.Substitute(t, ast.TypeArgs(params, ref.Args))
+	}
+	return t
+}
+
+// classArgs returns the type arguments of t, an instance of a generic class,
+// by the name of the type parameters they are for.
+func (g *Generator) classArgs(t ast.TypeExpr) map[string]ast.TypeExpr {
+	ref, ok := t.(*ast.TypeReference)
+	if !ok || len(ref.Args) == 0 {
+		return nil
+	}
+	if class := g.classOf(t); class != nil {
+		return ast.TypeArgs(class.TypeParams, ref.Args)
+	}
+	return nil
+}
+
+// generateCallTypeArgs generates the type arguments of a call of a generic
+// Go function if Go can't infer them, which it can only do for the type
+// parameters that the types of the parameters given arguments mention.
+// Type parameters that TypeScript infers nothing for are any.
+func (g *Generator) generateCallTypeArgs(typeParams []*ast.TypeParam, bound map[string]ast.TypeExpr, params []ast.FunctionParam, args []ast.Expression) string {
+	inferable := func(p *ast.TypeParam) bool {
+		for i := range min(len(params), len(args)) {
+			if ast.Mentions(params[i].Type, p.Name.Literal) {
+				return true
+			}
+		}
+		return false
+	}
+
+	explicit := false
+	list := make([]string, len(typeParams))
+	for i, p := range typeParams {
+		list[i] = g.goType(bound[p.Name.Literal])
+		if !inferable(p) {
+			explicit = true
+		}
+	}
+	if !explicit {
+		return ""
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+// generateGenericArgs generates the arguments of a call of a generic
+// function, typed by the parameters with the type arguments of the call
+// substituted. Untyped constants passed for a type parameter are given
+// their TypeScript type, since Go would infer int for an integer constant.
+func (g *Generator) generateGenericArgs(params []ast.FunctionParam, bound map[string]ast.TypeExpr, args []ast.Expression) string {
+	list := make([]string, len(args))
+	for i, arg := range args {
+		if i >= len(params) {
+			list[i] = g.generateExpression(arg)
+			continue
+		}
+		list[i] = g.generateTypedExpressionAs(arg, ast.Substitute(params[i].Type, bound))
+	}
+	return strings.Join(list, ", ")
+}
+
+// generateTypeParamOperands generates the operands of a binary operation on
+// a value of a type parameter, which Go only allows between values of the
+// same type parameter. Values of a type parameter constrained by a
+// primitive type are converted to that type to combine them with other
+// values, and values compared for equality are compared as any if their
+// type parameter doesn't guarantee that they are comparable.
+func (g *Generator) generateTypeParamOperands(e *ast.BinaryExpression) (string, bool) {
+	lt, rt := g.typeOf(e.Left), g.typeOf(e.Right)
+	lp, rp := g.typeParam(lt), g.typeParam(rt)
+	if lp == nil && rp == nil {
+		return "", false
+	}
+
+	left, right := g.generateExpression(e.Left), g.generateExpression(e.Right)
+	switch e.Operator.Type {
+	case token.STRICT_EQUAL, token.STRICT_NOT_EQUAL, token.EQUAL, token.NOT_EQUAL:
+		if (lp != nil && g.primitiveConstraint(lt) == nil) || (rp != nil && g.primitiveConstraint(rt) == nil) {
+			return fmt.Sprintf("any(%s) %s any(%s)", left, goOperator(e.Operator), right), true
+		}
+	}
+	// string concatenation formats the values instead
+	if lp == rp || g.isString(g.typeOf(e)) {
+		return "", false
+	}
+	if c := g.primitiveConstraint(lt); c != nil {
+		left = conversion(g.goType(c), e.Left, left)
+	}
+	if c := g.primitiveConstraint(rt); c != nil {
+		right = conversion(g.goType(c), e.Right, right)
+	}
+	return fmt.Sprintf("%s %s %s", left, goOperator(e.Operator), right), true
+}
@@ -0,0 +1,382 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// hierarchy records how the classes of a program extend each other.
+//
+// A subclass embeds a pointer to its base class, which gives it the fields
+// and methods of the base class. Embedding doesn't give virtual dispatch
+// though: a method of the base class calling a method the subclass overrides
+// would still call its own. So each class whose methods are overridden gets
+// a field holding the instance as an interface of these methods, set by the
+// constructors of the class and all of its subclasses, and calls of
+// overridden methods go through that field.
+type hierarchy struct {
+	bases      map[*ast.ClassDeclaration]*ast.ClassDeclaration
+	overridden map[*ast.MethodDeclaration]bool
+}
+
+// resolveHierarchy links the classes declared in stmts to their base
+// classes, and finds the methods overridden in subclasses.
+func (g *Generator) resolveHierarchy(stmts []Statement) {
+	g.bases = map[*ast.ClassDeclaration]*ast.ClassDeclaration{}
+	g.overridden = map[*ast.MethodDeclaration]bool{}
+
+	var classes []*ast.ClassDeclaration
+	for _, stmt := range stmts {
+		if class, ok := stmt.(*ast.ClassDeclaration); ok {
+			classes = append(classes, class)
+		}
+	}
+
+	for _, class := range classes {
+		if class.Extends == nil {
+			continue
+		}
+		base, ok := g.classes[class.Extends.String()]
+		if !ok {
+			g.errorf(class.Extends, "cannot extend '%s', which isn't a class", class.Extends.String())
+			continue
+		}
+		g.bases[class] = base
+	}
+
+	for _, class := range classes {
+		for c := g.bases[class]; c != nil; c = g.bases[c] {
+			if c == class {
+				g.errorf(class.Extends, "'%s' is referenced directly or indirectly in its own base expression", class.Name.Literal)
+				delete(g.bases, class)
+				break
+			}
+		}
+	}
+
+	for _, class := range classes {
+		base := g.bases[class]
+		if base == nil {
+			continue
+		}
+
+		for _, f := range class.Fields {
+			if f.Static {
+				continue
+			}
+			if inherited, owner := g.findField(base, f.Name.Literal); inherited != nil {
+				g.errorf(f, "cannot translate redeclaration of property '%s' of '%s' in '%s': Go would store it separately", f.Name.Literal, owner.Name.Literal, class.Name.Literal)
+			}
+		}
+
+		for _, m := range class.Methods {
+			if m.Static {
+				continue
+			}
+			inherited, owner := g.findMethod(base, m.Name.Literal)
+			if inherited == nil {
+				continue
+			}
+			if signature(m) != signature(inherited) {
+				g.errorf(m, "cannot translate override of '%s.%s' in '%s' with a different signature", owner.Name.Literal, m.Name.Literal, class.Name.Literal)
+				continue
+			}
+			g.overridden[g.introduction(base, m.Name.Literal)] = true
+		}
+	}
+}
+
+func signature(m *ast.MethodDeclaration) string {
+	params := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		params[i] = p.Type.String()
+	}
+	return fmt.Sprintf("(%s): %s", strings.Join(params, ", "), m.ReturnType.String())
+}
+
+// findField returns the instance field called name of class or its closest
+// base class declaring one, along with the class declaring it.
+func (g *Generator) findField(class *ast.ClassDeclaration, name string) (*ast.FieldDeclaration, *ast.ClassDeclaration) {
+	for c := class; c != nil; c = g.bases[c] {
+		if f := c.Field(name); f != nil {
+			return f, c
+		}
+	}
+	return nil, nil
+}
+
+// findMethod returns the method called name of class or its closest base
+// class declaring one, along with the class declaring it.
+func (g *Generator) findMethod(class *ast.ClassDeclaration, name string) (*ast.MethodDeclaration, *ast.ClassDeclaration) {
+	for c := class; c != nil; c = g.bases[c] {
+		if m := c.Method(name); m != nil {
+			return m, c
+		}
+	}
+	return nil, nil
+}
+
+// introduction returns the declaration of the method called name in the
+// farthest base class of class declaring it, which is overridden by all
+// the other declarations.
+func (g *Generator) introduction(class *ast.ClassDeclaration, name string) *ast.MethodDeclaration {
+	var intro *ast.MethodDeclaration
+	for c := class; c != nil; c = g.bases[c] {
+		if m := c.Method(name); m != nil && !m.Static {
+			intro = m
+		}
+	}
+	return intro
+}
+
+// introducingClass returns the class declaring the method introduced by
+// intro, starting the search at class.
+func (g *Generator) introducingClass(class *ast.ClassDeclaration, intro *ast.MethodDeclaration) *ast.ClassDeclaration {
+	for c := class; c != nil; c = g.bases[c] {
+		if c.Method(intro.Name.Literal) == intro {
+			return c
+		}
+	}
+	return nil
+}
+
+// virtualMethods returns the methods introduced by class that are
+// overridden in a subclass.
+func (g *Generator) virtualMethods(class *ast.ClassDeclaration) []*ast.MethodDeclaration {
+	var methods []*ast.MethodDeclaration
+	for _, m := range class.Methods {
+		if g.overridden[m] {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+func selfField(class *ast.ClassDeclaration) string {
+	return unexportedName(mangleName(class.Name.Literal)) + "Self"
+}
+
+func (g *Generator) methodsInterface(class *ast.ClassDeclaration) string {
+	return unexportedName(g.goName(class.Name.Literal)) + "Methods"
+}
+
+// generateMethodsInterface generates the interface of the overridden methods
+// introduced by class, or "" if it has none.
+func (g *Generator) generateMethodsInterface(class *ast.ClassDeclaration) string {
+	methods := g.virtualMethods(class)
+	if len(methods) == 0 {
+		return ""
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("// %s is implemented by %s and its subclasses, so that\n", g.methodsInterface(class), g.goName(class.Name.Literal)))
+	builder.WriteString(fmt.Sprintf("// calls of methods overridden in a subclass go through %s.\n", selfField(class)))
+	builder.WriteString(fmt.Sprintf("type %s interface {\n", g.methodsInterface(class)))
+	for _, m := range methods {
+		builder.WriteString(indent + memberName(m.Modifiers, m.Name.Literal) + "(" + g.generateParams(m.Params) + ")")
+		if ret := g.goType(m.ReturnType); ret != "" {
+			builder.WriteString(" " + ret)
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("}\n\n")
+
+	return builder.String()
+}
+
+// generateSelfAssignments generates the statements pointing the self fields
+// of class and its base classes to this, so that overridden methods
+// dispatch to the implementations of class.
+func (g *Generator) generateSelfAssignments(class *ast.ClassDeclaration) []string {
+	var stmts []string
+	for c := class; c != nil; c = g.bases[c] {
+		if len(g.virtualMethods(c)) > 0 {
+			stmts = append(stmts, fmt.Sprintf("this.%s = this", selfField(c)))
+		}
+	}
+	return stmts
+}
+
+// isVirtualCall reports whether calling m on an instance of class has to
+// dispatch on the dynamic class of the instance, and if so returns the class
+// whose self field the call goes through.
+func (g *Generator) isVirtualCall(class *ast.ClassDeclaration, m *ast.MethodDeclaration) (*ast.ClassDeclaration, bool) {
+	if m.Static {
+		return nil, false
+	}
+	intro := g.introduction(class, m.Name.Literal)
+	if intro == nil || !g.overridden[intro] {
+		return nil, false
+	}
+	return g.introducingClass(class, intro), true
+}
+
+// isSubclass reports whether class extends base, directly or not.
+func (g *Generator) isSubclass(class, base *ast.ClassDeclaration) bool {
+	for c := g.bases[class]; c != nil; c = g.bases[c] {
+		if c == base {
+			return true
+		}
+	}
+	return false
+}
+
+// constructorParams returns the parameters of the constructor creating
+// instances of class, which is inherited from its base class if it doesn't
+// declare one.
+func (g *Generator) constructorParams(class *ast.ClassDeclaration) []ast.FunctionParam {
+	for c := class; c != nil; c = g.bases[c] {
+		if c.Constructor != nil {
+			return c.Constructor.Params
+		}
+	}
+	return nil
+}
+
+// generateSuperCall lowers a call of the base class constructor to creating
+// the embedded instance of the base class, or to initializing it if the
+// instance being created already holds it.
+func (g *Generator) generateSuperCall(call *ast.FunctionCallExpression) string {
+	base := g.bases[g.class]
+	args := g.generateArgs(g.constructorParams(base), call.Args)
+	if !g.hasSelf(g.class) {
+		stmts := []string{fmt.Sprintf("this.%s = %s(%s)", g.goName(base.Name.Literal), g.constructorName(base), args)}
+		stmts = append(stmts, g.generateSelfAssignments(g.class)...)
+		return strings.Join(stmts, "\n")
+	}
+	// the fields of the class are initialized once super returns
+	return strings.Join(append([]string{g.generateBaseInit(base, args)}, g.generateFieldInits(g.class)...), "\n")
+}
+
+// generateBaseInit generates the statement running the constructor of
+// base, the base class of a class with self fields, on the embedded
+// instance of base, which it creates unless base has self fields too.
+func (g *Generator) generateBaseInit(base *ast.ClassDeclaration, args string) string {
+	if g.hasSelf(base) {
+		return fmt.Sprintf("this.%s.init(%s)", g.goName(base.Name.Literal), args)
+	}
+	return fmt.Sprintf("this.%s = %s(%s)", g.goName(base.Name.Literal), g.constructorName(base), args)
+}
+
+// generateFieldInits generates the assignments of the initializers of the
+// instance fields of class.
+func (g *Generator) generateFieldInits(class *ast.ClassDeclaration) []string {
+	var stmts []string
+	for _, f := range class.Fields {
+		if !f.Static && f.Value != nil {
+			stmts = append(stmts, fmt.Sprintf("this.%s = %s", memberName(f.Modifiers, f.Name.Literal), g.generateFieldValue(f.Value, f.Type, f.Optional)))
+		}
+	}
+	return stmts
+}
+
+// hasSelf reports whether instances of class have self fields, because it
+// or one of its base classes introduces overridden methods.
+func (g *Generator) hasSelf(class *ast.ClassDeclaration) bool {
+	for c := class; c != nil; c = g.bases[c] {
+		if len(g.virtualMethods(c)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// generateSelfConstructor generates the constructor of class, a class with
+// self fields, and the init method running the body of the constructor.
+// Base class constructors may call overridden methods, which have to
+// dispatch to the class of the instance being created, as in TypeScript.
+// So the constructor allocates the embedded instances of the base classes
+// with self fields and points the self fields to the new instance before
+// any constructor body runs, and super calls initialize the embedded
+// instances with their init methods rather than creating them. Like in
+// TypeScript, the fields of a subclass are initialized once super returns.
+func (g *Generator) generateSelfConstructor(class *ast.ClassDeclaration) string {
+	base := g.bases[class]
+	params := g.constructorParams(class)
+	args := make([]string, len(params))
+	for i, p := range params {
+		args[i] = g.goName(p.Name.Literal)
+	}
+	var body []Statement
+	if class.Constructor != nil {
+		body = class.Constructor.Body
+	}
+
+	self := g.goName(class.Name.Literal) + g.generateTypeArgs(class.TypeParams)
+	// each embedded instance holds that of its own base
+	var chain []*ast.ClassDeclaration
+	for c := base; c != nil && g.hasSelf(c); c = g.bases[c] {
+		chain = append(chain, c)
+	}
+	alloc := ""
+	for i := len(chain) - 1; i >= 0; i-- {
+		name := g.goName(chain[i].Name.Literal)
+		alloc = fmt.Sprintf("%s: &%s{%s}", name, name, alloc)
+	}
+	alloc = fmt.Sprintf("this := &%s{%s}", self, alloc)
+
+	builder := strings.Builder{}
+	if class.Constructor != nil {
+		builder.WriteString(generateComments(class.Constructor.Leading, g.constructorName(class)))
+	}
+	builder.WriteString(fmt.Sprintf("func %s%s(%s) *%s {\n", g.constructorName(class), g.generateTypeParams(class.TypeParams), g.generateParams(params), self))
+	builder.WriteString(indent + alloc + "\n")
+	for _, stmt := range g.generateSelfAssignments(class) {
+		builder.WriteString(indent + stmt + "\n")
+	}
+	builder.WriteString(fmt.Sprintf("%sthis.init(%s)\n", indent, strings.Join(args, ", ")))
+	builder.WriteString(indent + "return this\n")
+	builder.WriteString("}\n\n")
+
+	var prologue []string
+	switch {
+	case base == nil:
+		prologue = g.generateFieldInits(class)
+	case class.Constructor == nil:
+		prologue = append([]string{g.generateBaseInit(base, strings.Join(args, ", "))}, g.generateFieldInits(class)...)
+	default:
+		supers := 0
+		for _, stmt := range body {
+			if isSuperCall(stmt) {
+				supers++
+			}
+		}
+		if supers != 1 {
+			g.errorf(class.Constructor, "constructors for derived classes must contain exactly one 'super' call as a statement of their body")
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("// init runs the constructor of %s on this, whose self fields already\n", g.goName(class.Name.Literal)))
+	builder.WriteString("// point to the instance being created.\n")
+	builder.WriteString(fmt.Sprintf("func (this *%s) init(%s) {\n", self, g.generateParams(params)))
+	for _, stmt := range prologue {
+		builder.WriteString(indent + stmt + "\n")
+	}
+
+	g.pushScope()
+	g.declare("this", classType(class))
+	g.constructing = true
+	builder.WriteString(g.generateFunctionBody(params, nil, body))
+	g.constructing = false
+	g.popScope()
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// isSuperCall reports whether stmt calls the constructor of the base class.
+func isSuperCall(stmt Statement) bool {
+	expr, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	call, ok := expr.Expression.(*ast.FunctionCallExpression)
+	if !ok {
+		return false
+	}
+	_, ok = call.Callee.(*ast.SuperExpression)
+	return ok
+}
@@ -0,0 +1,151 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// reservedNames are the names TypeScript identifiers can't keep in Go: Go's
+// keywords, the predeclared identifiers and the packages the generated code
+// refers to, which a declaration of the same name would shadow, and the
+// functions Go treats specially. The empty name stands for the blank
+// identifier _, which Go doesn't let code read.
+var reservedNames = map[string]bool{
+	"": true,
+
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true,
+	"complex128": true, "error": true, "float32": true, "float64": true, "int": true,
+	"int8": true, "int16": true, "int32": true, "int64": true, "rune": true,
+	"string": true, "uint": true, "uint8": true, "uint16": true, "uint32": true,
+	"uint64": true, "uintptr": true, "true": true, "false": true, "iota": true,
+	"nil": true, "append": true, "cap": true, "clear": true, "close": true,
+	"complex": true, "copy": true, "delete": true, "imag": true, "len": true,
+	"make": true, "max": true, "min": true, "new": true, "panic": true,
+	"real": true, "recover": true,
+
+	"big": true, "errors": true, "fmt": true, "maps": true, "math": true, "reflect": true,
+	"slices": true, "strconv": true, "strings": true, "unicode": true,
+	"utf16": true,
+
+	"main": true, "init": true,
+}
+
+// isReserved reports whether a name, without the underscores it ends in,
+// can't be kept in Go: one of reservedNames, a name starting with the sild
+// prefix of the runtime helpers, or a temporary variable of the generated
+// code, such as _v or _v1.
+func isReserved(name string) bool {
+	if reservedNames[name] || strings.HasPrefix(name, "sild") {
+		return true
+	}
+	switch name {
+	case "_v", "_r", "_i", "_do":
+		return true
+	}
+	digits := strings.TrimPrefix(name, "_v")
+	return digits != name && strings.Trim(digits, "0123456789") == ""
+}
+
+// mangleName returns the Go identifier a TypeScript identifier is lowered
+// to. Characters Go doesn't allow in identifiers are escaped, and reserved
+// names get a trailing underscore. Names that already end in underscores
+// after a reserved name get one more, so that type and type_ stay distinct.
+// Since the result only depends on name, every use of a name is mangled the
+// same way across the program, and no two names are mangled alike.
+func mangleName(name string) string {
+	name = escapeName(name)
+	if isReserved(strings.TrimRight(name, "_")) {
+		return name + "_"
+	}
+	return name
+}
+
+// goName mangles a TypeScript identifier like mangleName, and also renames
+// it with a trailing underscore if it would take the name of a declaration
+// the generated code introduces, such as NewPoint, the constructor of a
+// class Point. Temporary variables of the generated code keep their names.
+func (g *Generator) goName(name string) string {
+	if g.isTemp(name) {
+		return name
+	}
+	name = mangleName(name)
+	if g.reserved[strings.TrimRight(name, "_")] {
+		return name + "_"
+	}
+	return name
+}
+
+// generatedNames returns the names of the package-level declarations the
+// generated code introduces besides those the program declares: the
+// constructors, static members and interfaces of overridden methods of
+// classes, the constants of enums and the structs of the inline members of
+// discriminated unions.
+func (g *Generator) generatedNames() map[string]bool {
+	names := map[string]bool{}
+	for _, class := range g.classes {
+		names[g.constructorName(class)] = true
+		names[g.methodsInterface(class)] = true
+		for _, f := range class.Fields {
+			if f.Static {
+				names[g.staticName(class, f.Modifiers, f.Name.Literal)] = true
+			}
+		}
+		for _, m := range class.Methods {
+			if m.Static {
+				names[g.staticName(class, m.Modifiers, m.Name.Literal)] = true
+			}
+		}
+	}
+	for _, e := range g.enums {
+		for _, m := range e.decl.Members {
+			names[g.enumConst(e, m.Name.Literal)] = true
+		}
+	}
+	for _, u := range g.unions {
+		for _, v := range u.variants {
+			if v.inline {
+				names[v.goName] = true
+			}
+		}
+	}
+	return names
+}
+
+// escapePattern matches the escapes escapeName writes.
+var escapePattern = regexp.MustCompile(`_(dollar|u[0-9a-f]{4,6})_`)
+
+// escapeName replaces the characters of name that TypeScript allows in
+// identifiers but Go doesn't: '$' becomes _dollar_, and other characters,
+// such as combining marks or zero-width joiners, their code point. Once a
+// name is escaped, its underscores are too, and so are those of names that
+// already look escaped, so that $el and _dollar_el stay distinct.
+func escapeName(name string) string {
+	if !strings.ContainsFunc(name, func(r rune) bool { return !isGoIdentRune(r) }) && !escapePattern.MatchString(name) {
+		return name
+	}
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r != '_' && isGoIdentRune(r):
+			b.WriteRune(r)
+		case r == '$':
+			b.WriteString("_dollar_")
+		default:
+			fmt.Fprintf(&b, "_u%04x_", r)
+		}
+	}
+	return b.String()
+}
+
+func isGoIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,635 @@
+package codegen
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// isNull reports whether t is null or undefined, which are both lowered to
+// nil.
+func (g *Generator) isNull(t ast.TypeExpr) bool {
+	switch typeName(g.resolveType(t)) {
+	case "null", "undefined":
+		return true
+	}
+	return false
+}
+
+// nilIsNull reports whether nil stands for null in values of type t, which
+// is null or has null but not undefined among its members. nil is null or
+// undefined, whichever the type has, and undefined if it has both.
+func (g *Generator) nilIsNull(t ast.TypeExpr) bool {
+	if typeName(g.resolveType(t)) == "null" {
+		return true
+	}
+	return g.isNullable(t) && !slices.ContainsFunc(g.unionMembers(t), func(m ast.TypeExpr) bool { return typeName(g.resolveType(m)) == "undefined" })
+}
+
+// isNullable reports whether t is a union with null or undefined among its
+// members.
+func (g *Generator) isNullable(t ast.TypeExpr) bool {
+	if !isUnionType(g.resolveType(t)) {
+		return false
+	}
+	for _, m := range g.unionMembers(t) {
+		if g.isNull(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNull returns t without null and undefined.
+func (g *Generator) nonNull(t ast.TypeExpr) ast.TypeExpr {
+	if !g.isNullable(t) {
+		return t
+	}
+	// members are kept whole, so that a union of a discriminated union
+	// and null keeps its name
+	var members []ast.TypeExpr
+	for _, m := range g.resolveType(t).(*ast.UnionType).Types {
+		if !g.isNull(m) {
+			members = append(members, g.nonNull(m))
+		}
+	}
+	switch len(members) {
+	case 0:
+		return primitiveType("undefined")
+	case 1:
+		return members[0]
+	}
+	return &ast.UnionType{Types: members}
+}
+
+// nullable returns the union of t and undefined, the type of an optional
+// property or of an optional chain.
+func (g *Generator) nullable(t ast.TypeExpr) ast.TypeExpr {
+	if t == nil || g.isNull(t) || g.isNullable(t) {
+		return t
+	}
+	return &ast.UnionType{Types: []ast.TypeExpr{t, primitiveType("undefined")}}
+}
+
+// nilable reports whether the Go type of t has nil among its values, so
+// that it can also hold null and undefined.
+func (g *Generator) nilable(t ast.TypeExpr) bool {
+	if g.unionOf(t) != nil {
+		return true
+	}
+	goType := g.goType(t)
+	for _, prefix := range []string{"*", "[]", "map[", "func("} {
+		if strings.HasPrefix(goType, prefix) {
+			return true
+		}
+	}
+	return goType == "any"
+}
+
+// nullableGoType returns the Go type of t, a nullable type. Values of the
+// type without null and undefined are stored as is if their Go type can be
+// nil, like those of other unions held in an interface, and as pointers
+// otherwise.
+func (g *Generator) nullableGoType(t ast.TypeExpr) string {
+	inner := g.nonNull(t)
+	if g.isNull(inner) {
+		return "any"
+	}
+	if g.nilable(inner) {
+		return g.goType(inner)
+	}
+	return "*" + g.goType(inner)
+}
+
+// isPointer reports whether values of t, a nullable type, are stored as
+// pointers to the values of the type without null and undefined.
+func (g *Generator) isPointer(t ast.TypeExpr) bool {
+	return g.isNullable(t) && g.goType(t) == "*"+g.goType(g.nonNull(t))
+}
+
+// generateNonNull generates a non-null assertion, which dereferences values
+// stored as pointers. Like in Go, using nil panics.
+func (g *Generator) generateNonNull(e *ast.NonNullExpression) string {
+	if g.isPointer(g.typeOf(e.Expression)) {
+		return "(*" + g.generateExpression(e.Expression) + ")"
+	}
+	return g.generateExpression(e.Expression)
+}
+
+// generateObject generates the object of a property access, which Go
+// dereferences itself if it is a pointer to a struct.
+func (g *Generator) generateObject(expr ast.Expression) string {
+	if g.objectType(g.typeOf(expr)) == nil {
+		return g.generateExpression(expr)
+	}
+	switch e := expr.(type) {
+	case *ast.ObjectLiteral:
+		// & applies to the whole selector otherwise
+		return "(" + g.generateExpression(e) + ")"
+	case *ast.NonNullExpression:
+		return g.generateExpression(e.Expression)
+	case *ast.VariableExpression:
+		if nv := g.lookupUnion(e.Token.Literal); nv != nil && !nv.shadow {
+			if _, deref := g.assertion(e, nv.union); deref {
+				return g.goName(e.Token.Literal)
+			}
+		}
+	}
+	return g.generateExpression(expr)
+}
+
+// indentRest indents the lines of code after the first by one level, for
+// code spanning several lines written after an indented line.
+func indentRest(code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines[1:] {
+		if line != "" {
+			lines[i+1] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// generateNullableAs generates expr where a value of the nullable type
+// expected is wanted, taking the address of values that are stored as
+// pointers. It reports false if expr is nullable too, and needs no
+// conversion.
+func (g *Generator) generateNullableAs(expr ast.Expression, expected ast.TypeExpr) (string, bool) {
+	if !g.isNullable(expected) {
+		return "", false
+	}
+	t := g.typeOf(expr)
+	switch {
+	case g.isNull(t):
+		return "nil", true
+	case t == nil || g.isNullable(t) || g.goType(expected) == "any":
+		return "", false
+	case g.isPointer(expected):
+		// the type argument of sildPtr is inferred from its argument
+		g.useHelper("sildPtr")
+		return fmt.Sprintf("sildPtr(%s)", g.generateTypedExpressionAs(expr, g.nonNull(expected))), true
+	}
+	return g.generateExpressionAs(expr, g.nonNull(expected)), true
+}
+
+// generateTruthy generates a test of whether expr, of a nullable type or
+// narrowed to null, is truthy, or falsy if truth isn't set: not null or
+// undefined, and for primitives not the zero value either.
+func (g *Generator) generateTruthy(expr ast.Expression, truth bool) string {
+	t := g.typeOf(expr)
+	value := g.generateExpression(expr)
+	nilTest, and, not := "%s != nil", " && ", ""
+	if !truth {
+		nilTest, and, not = "%s == nil", " || ", "!"
+	}
+
+	var zero string
+	nan := false
+	switch inner := g.resolveType(g.nonNull(t)); {
+	case g.isNull(t) && value == "nil":
+		return strconv.FormatBool(!truth)
+	case g.isNull(t):
+		// narrowed to null, so never truthy
+		return fmt.Sprintf(nilTest, value)
+	case !g.isPointer(t):
+		for _, m := range g.unionMembers(inner) {
+			switch g.typeofName(m) {
+			case "string", "number", "bigint", "boolean":
+				g.errorf(expr, "cannot translate a test of whether a value of type '%s' is truthy: compare it with null instead", typeString(t))
+				return fmt.Sprintf(nilTest, value)
+			}
+		}
+		return fmt.Sprintf(nilTest, value)
+	case g.isString(inner):
+		zero = `""`
+	case isNumber(inner):
+		zero, nan = "0", true
+		g.use("math")
+	case typeName(inner) == "boolean":
+	default:
+		return fmt.Sprintf(nilTest, value)
+	}
+
+	test := func(v string) string {
+		op := " != "
+		if !truth {
+			op = " == "
+		}
+		code := fmt.Sprintf(nilTest, v) + and + "*" + v + op + zero
+		if zero == "" {
+			code = fmt.Sprintf(nilTest, v) + and + not + "*" + v
+		}
+		if nan && truth {
+			code += and + "!math.IsNaN(*" + v + ")"
+		} else if nan {
+			code += and + "math.IsNaN(*" + v + ")"
+		}
+		// || binds less tightly than the && it may be an operand of
+		if !truth {
+			return "(" + code + ")"
+		}
+		return code
+	}
+	if isPure(expr) {
+		return test(value)
+	}
+	return fmt.Sprintf("func() bool {\n%s_v := %s\n%sreturn %s\n}()", indent, indentRest(value), indent, test("_v"))
+}
+
+// isPure reports whether evaluating expr has no effects and is cheap, so
+// that it can be evaluated more than once.
+func isPure(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.VariableExpression, *ast.ThisExpression:
+		return true
+	case *ast.MemberExpression:
+		return !e.Optional && isPure(e.Object)
+	case *ast.NonNullExpression:
+		return isPure(e.Expression)
+	}
+	return false
+}
+
+// primitiveTest returns a function generating the test of whether v, a Go
+// value holding expr, is truthy, or falsy if truth isn't set, for expr of a
+// primitive type other than boolean: not "", 0, NaN or 0n. It also reports
+// whether the test refers to v more than once, and false if expr isn't of
+// such a type.
+func (g *Generator) primitiveTest(expr ast.Expression, truth bool) (test func(v string) string, repeats bool, ok bool) {
+	t := g.resolveType(g.typeOf(expr))
+	op, and, not := " != ", " && ", "!"
+	if !truth {
+		op, and, not = " == ", " || ", ""
+	}
+
+	var zero, method string
+	nan := false
+	switch {
+	case t == nil || g.isNullable(t) || g.isNull(t):
+		return nil, false, false
+	case g.isString(t):
+		zero = `""`
+	case isBigInt(t):
+		zero, method = "0", ".Sign()"
+	case g.isNumericEnum(t):
+		zero = "0"
+	case isNumber(t):
+		// only float64 values can be NaN
+		zero, nan = "0", g.numKind(expr) == floatNum
+	default:
+		return nil, false, false
+	}
+
+	test = func(v string) string {
+		code := v + method + op + zero
+		if nan {
+			g.use("math")
+			code += and + not + "math.IsNaN(" + v + ")"
+		}
+		// || binds less tightly than the && it may be an operand of
+		if !truth {
+			return "(" + code + ")"
+		}
+		return code
+	}
+	return test, nan, true
+}
+
+// generatePrimitiveTruthy generates a test of whether expr, of a primitive
+// type other than boolean, is truthy, or falsy if truth isn't set. It
+// reports false if expr isn't of such a type.
+func (g *Generator) generatePrimitiveTruthy(expr ast.Expression, truth bool) (string, bool) {
+	test, repeats, ok := g.primitiveTest(expr, truth)
+	if !ok {
+		return "", false
+	}
+	value := g.generateExpression(expr)
+	if !repeats || isPure(expr) {
+		return test(value), true
+	}
+	return fmt.Sprintf("func() bool {\n%s_v := %s\n%sreturn %s\n}()", indent, indentRest(value), indent, test("_v")), true
+}
+
+// generateTest generates expr as a condition, testing values of other types
+// than boolean for truthiness, at run time if their type isn't primitive.
+func (g *Generator) generateTest(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.ParenthesizedExpression:
+		return "(" + g.generateTest(e.Expression) + ")"
+	case *ast.BinaryExpression:
+		if e.Operator.Type == token.AND || e.Operator.Type == token.OR {
+			return "(" + g.generateBinaryOperands(e) + ")"
+		}
+	case *ast.UnaryExpression:
+		if e.Operator.Type != token.BANG {
+			break
+		}
+		if t := g.typeOf(e.Right); g.isNullable(t) || g.isNull(t) {
+			return g.generateTruthy(e.Right, false)
+		}
+		if s, ok := g.generatePrimitiveTruthy(e.Right, false); ok {
+			return s
+		}
+	}
+	if t := g.typeOf(expr); g.isNullable(t) || g.isNull(t) {
+		return g.generateTruthy(expr, true)
+	}
+	if s, ok := g.generatePrimitiveTruthy(expr, true); ok {
+		return s
+	}
+	if t := g.resolveType(g.typeOf(expr)); t != nil && typeName(t) != "boolean" {
+		g.useHelper("sildTruthy")
+		return "sildTruthy(" + g.generateExpression(expr) + ")"
+	}
+	return g.generateExpression(expr)
+}
+
+// generateLogical lowers a || b and a && b on operands of a type other than
+// boolean to a function literal that evaluates a once and, as in
+// JavaScript, results in a if it decides the result and in b otherwise.
+func (g *Generator) generateLogical(e *ast.BinaryExpression) string {
+	t := g.typeOf(e)
+	test, _, ok := g.primitiveTest(e.Left, e.Operator.Type == token.OR)
+	if t == nil || !ok {
+		g.errorf(e, "cannot translate '%s' on operands of types '%s' and '%s': test them with a condition instead", e.Operator.Literal, typeString(g.typeOf(e.Left)), typeString(g.typeOf(e.Right)))
+		return "(" + g.generateBinaryOperands(e) + ")"
+	}
+
+	value := "_v"
+	init := fmt.Sprintf("_v := %s; ", indentRest(g.generateExpressionAs(e.Left, t)))
+	if isPure(e.Left) {
+		value, init = g.generateExpressionAs(e.Left, t), ""
+	}
+
+	g.pushNarrowing(g.narrowings[e.Right], false)
+	right := g.generateExpressionAs(e.Right, t)
+	g.popScope()
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("func() %s {\n", g.goType(t)))
+	builder.WriteString(fmt.Sprintf("%sif %s%s {\n", indent, init, test(value)))
+	builder.WriteString(fmt.Sprintf("%s%sreturn %s\n", indent, indent, value))
+	builder.WriteString(indent + "}\n")
+	builder.WriteString(fmt.Sprintf("%sreturn %s\n", indent, indentRest(right)))
+	builder.WriteString("}()")
+	return builder.String()
+}
+
+// generateNullEquality generates the comparison of a value of a nullable type
+// with null, undefined or another value. Values stored as pointers are
+// compared by what they point to, and values the type checker found can't
+// be null, such as narrowed variables, are never equal to null. It reports
+// false if neither operand is nullable.
+func (g *Generator) generateNullEquality(e *ast.BinaryExpression) (string, bool) {
+	left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+	equal := e.Operator.Type == token.EQUAL || e.Operator.Type == token.STRICT_EQUAL
+
+	op := "=="
+	if !equal {
+		op = "!="
+	}
+	switch {
+	case g.isNull(right):
+		return g.generateNilComparison(e.Left, op), true
+	case g.isNull(left):
+		return g.generateNilComparison(e.Right, op), true
+	case !g.isPointer(left) && !g.isPointer(right):
+		return "", false
+	}
+
+	t := left
+	if !g.isPointer(left) {
+		t = right
+	}
+	g.useHelper("sildEqualPtr")
+	code := fmt.Sprintf("sildEqualPtr(%s, %s)", g.generateExpressionAs(e.Left, t), g.generateExpressionAs(e.Right, t))
+	if !equal {
+		code = "!" + code
+	}
+	return code, true
+}
+
+// generateNilComparison generates the comparison of expr with nil, with op
+// == or !=. A variable narrowed to a type without null is compared as it is
+// stored, unless a shadowing variable holds its narrowed value, and other
+// values whose Go type can't be nil are only evaluated for their effects.
+func (g *Generator) generateNilComparison(expr ast.Expression, op string) string {
+	if v, ok := expr.(*ast.VariableExpression); ok {
+		name := v.Token.Literal
+		declared := g.lookup(name)
+		nv := g.lookupUnion(name)
+		if nv != nil {
+			declared = nv.union
+		}
+		if (nv == nil || !nv.shadow || nv.assert == "") && declared != nil && g.nilable(declared) {
+			return fmt.Sprintf("%s %s nil", g.goName(name), op)
+		}
+	}
+	t := g.typeOf(expr)
+	if t == nil || g.isNullable(t) || g.isNull(t) || g.nilable(t) || g.typeParam(t) != nil {
+		return fmt.Sprintf("%s %s nil", g.generateExpression(expr), op)
+	}
+	result := strconv.FormatBool(op == "!=")
+	if isPure(expr) {
+		return result
+	}
+	return fmt.Sprintf("func() bool {\n%s_ = %s\n%sreturn %s\n}()", indent, indentRest(g.generateExpression(expr)), indent, result)
+}
+
+// generateNullish lowers a ?? b to a function literal that evaluates a once
+// and only evaluates b if a is nil.
+func (g *Generator) generateNullish(e *ast.BinaryExpression) string {
+	t := g.typeOf(e)
+	left := g.typeOf(e.Left)
+	// a value of type any may be nil too
+	if !g.isNullable(left) && !g.isNull(left) && typeName(left) != "any" {
+		return g.generateExpressionAs(e.Left, t)
+	}
+	// a variable narrowed to null or undefined is never the result
+	if g.isNull(left) && isPure(e.Left) {
+		return g.generateExpressionAs(e.Right, t)
+	}
+
+	value := "_v"
+	init := fmt.Sprintf("_v := %s; ", indentRest(g.generateExpression(e.Left)))
+	if isPure(e.Left) {
+		value, init = g.generateExpression(e.Left), ""
+	}
+	result := value
+	if g.isPointer(left) && !g.isNullable(t) {
+		result = "*" + value
+	}
+
+	goType := g.goType(t)
+	if goType == "" {
+		goType = "any"
+	}
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("func() %s {\n", goType))
+	builder.WriteString(fmt.Sprintf("%sif %s%s != nil {\n", indent, init, value))
+	builder.WriteString(fmt.Sprintf("%s%sreturn %s\n", indent, indent, result))
+	builder.WriteString(indent + "}\n")
+	builder.WriteString(fmt.Sprintf("%sreturn %s\n", indent, indentRest(g.generateExpressionAs(e.Right, t))))
+	builder.WriteString("}()")
+	return builder.String()
+}
+
+// generateNullishAssignment lowers a ??= b to an assignment that only
+// happens if a is nil.
+func (g *Generator) generateNullishAssignment(stmt *ast.AssignmentStatement) string {
+	target := g.generateExpression(stmt.Target)
+	value := g.generateExpressionAs(stmt.Value, g.typeOf(stmt.Target))
+	return fmt.Sprintf("if %s == nil {\n%s%s = %s\n}", target, indent, target, indentRest(value))
+}
+
+// generateNarrowedUpdate generates a compound assignment of value to target,
+// or an increment or decrement if value is nil, where target is a variable
+// narrowed to a member of its union type of another Go type, like a
+// nullable number stored behind a pointer. Go can't update the variable
+// with the operator, and the pointer may be shared, so the variable is
+// assigned the result of the operation instead. It reports false for other
+// targets.
+func (g *Generator) generateNarrowedUpdate(target ast.Expression, op token.TokenType, value ast.Expression) (string, bool) {
+	v, ok := target.(*ast.VariableExpression)
+	if !ok {
+		return "", false
+	}
+	name := v.Token.Literal
+	nv := g.lookupUnion(name)
+	if nv == nil || nv.shadow || g.goType(nv.union) == g.goType(g.lookup(name)) {
+		return "", false
+	}
+
+	operand := &ast.VariableExpression{Token: v.Token}
+	g.types[operand] = g.lookup(name)
+	if value == nil {
+		value = &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "1"}}
+		g.types[value] = primitiveType("number")
+	}
+	operator := token.TokenType(strings.TrimSuffix(string(op), "="))
+	result := &ast.BinaryExpression{Left: operand, Operator: token.Token{Type: operator, Literal: string(operator)}, Right: value}
+	g.types[result] = g.lookup(name)
+	return fmt.Sprintf("%s = %s", g.goName(name), g.generateExpressionAs(result, nv.union)), true
+}
+
+// chainLink is an optional link of an optional chain: the Go code of its
+// object, either a temporary variable initialized by init or an expression
+// that can be evaluated again.
+type chainLink struct {
+	name string
+	init string
+}
+
+// rewriteChain returns expr, a link of an optional chain, with the objects
+// of its optional links replaced by link, from the innermost outwards. The
+// links rewritten have the types they have when the chain doesn't
+// short-circuit.
+func (g *Generator) rewriteChain(expr ast.Expression, link func(ast.Expression) ast.Expression) ast.Expression {
+	var rewritten ast.Expression
+	switch e := expr.(type) {
+	case *ast.MemberExpression:
+		object := g.rewriteChain(e.Object, link)
+		if e.Optional {
+			object = link(object)
+		}
+		rewritten = &ast.MemberExpression{Object: object, Property: e.Property}
+	case *ast.IndexExpression:
+		left := g.rewriteChain(e.Left, link)
+		if e.Optional {
+			left = link(left)
+		}
+		rewritten = &ast.IndexExpression{Loc: e.Loc, Left: left, Index: e.Index}
+	case *ast.FunctionCallExpression:
+		callee := g.rewriteChain(e.Callee, link)
+		if e.Optional {
+			callee = link(callee)
+		}
+		rewritten = &ast.FunctionCallExpression{Loc: e.Loc, Callee: callee, TypeArgs: e.TypeArgs, Args: e.Args}
+	case *ast.NonNullExpression:
+		rewritten = &ast.NonNullExpression{Expression: g.rewriteChain(e.Expression, link), Token: e.Token}
+	default:
+		return expr
+	}
+
+	t, ok := g.chains[expr]
+	if !ok {
+		t = g.typeOf(expr)
+	}
+	g.types[rewritten] = t
+	return rewritten
+}
+
+// unchain generates the objects of the optional links of expr, an optional
+// chain, into temporary variables declared in the current scope, and
+// returns the links with the expression the chain evaluates when none of
+// them is nil.
+func (g *Generator) unchain(expr ast.Expression) ([]chainLink, ast.Expression) {
+	var links []chainLink
+	temps := 0
+	stripped := g.rewriteChain(expr, func(object ast.Expression) ast.Expression {
+		t := g.typeOf(object)
+		nonNull := &ast.NonNullExpression{Expression: object}
+		g.types[nonNull] = g.nonNull(t)
+		if isPure(object) {
+			links = append(links, chainLink{name: g.generateExpression(object)})
+			return nonNull
+		}
+		temps++
+		name := "_v" + strconv.Itoa(temps)
+		links = append(links, chainLink{name: name, init: g.generateExpression(object)})
+		g.declareTemp(name, t)
+		temp := &ast.VariableExpression{Token: token.Token{Type: token.IDENT, Literal: name}}
+		g.types[temp] = t
+		nonNull.Expression = temp
+		return nonNull
+	})
+	return links, stripped
+}
+
+// generateOptionalChain lowers an optional chain to a function literal that
+// returns nil as soon as an optional link is nil, keeping the order in which
+// TypeScript evaluates the chain.
+func (g *Generator) generateOptionalChain(expr ast.Expression) string {
+	t := g.typeOf(expr)
+	g.pushScope()
+	defer g.popScope()
+	links, stripped := g.unchain(expr)
+
+	goType := g.goType(t)
+	if goType == "" {
+		goType = "any"
+	}
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("func() %s {\n", goType))
+	for _, link := range links {
+		if link.init != "" {
+			builder.WriteString(fmt.Sprintf("%s%s := %s\n", indent, link.name, indentRest(link.init)))
+		}
+		builder.WriteString(fmt.Sprintf("%sif %s == nil {\n%s%sreturn nil\n%s}\n", indent, link.name, indent, indent, indent))
+	}
+	builder.WriteString(fmt.Sprintf("%sreturn %s\n", indent, indentRest(g.generateExpressionAs(stripped, t))))
+	builder.WriteString("}()")
+	return builder.String()
+}
+
+// generateOptionalChainStatement lowers an optional chain evaluated for its
+// effects, like a?.f(), to nested if statements testing its optional links.
+func (g *Generator) generateOptionalChainStatement(expr ast.Expression) string {
+	g.pushScope()
+	defer g.popScope()
+	links, stripped := g.unchain(expr)
+
+	code := g.generateExpressionStatement(&ast.ExpressionStatement{Expression: stripped})
+	for i := len(links) - 1; i >= 0; i-- {
+		header := links[i].name + " != nil"
+		if links[i].init != "" {
+			header = fmt.Sprintf("%s := %s; %s", links[i].name, links[i].init, header)
+		}
+		code = fmt.Sprintf("if %s {\n%s%s\n}", header, indent, indentRest(code))
+	}
+	return code
+}
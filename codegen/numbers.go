@@ -0,0 +1,722 @@
+package codegen
+
+import (
+	"go/constant"
+	gotoken "go/token"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// numKind is the Go type of an expression of TypeScript type number.
+// Numbers are float64 in Go, except for untyped constants and for values Go
+// computes as int, such as the length of a slice or a variable narrowed to
+// int by the integer analysis.
+type numKind int
+
+const (
+	floatNum numKind = iota
+	intNum
+	// untyped constants whose value is an integer, such as 42 or 1 + 2
+	untypedInt
+	// other untyped constants, such as 0.5 or 1e3
+	untypedFloat
+)
+
+func (k numKind) untyped() bool {
+	return k == untypedInt || k == untypedFloat
+}
+
+// integral reports whether values of kind k can be stored in an int.
+func (k numKind) integral() bool {
+	return k == intNum || k == untypedInt
+}
+
+// maxSafeInteger is Number.MAX_SAFE_INTEGER, the largest integer n such that
+// n and n + 1 are both exactly representable as a float64.
+const maxSafeInteger = 1<<53 - 1
+
+// globalNumbers are the global number constants and their Go equivalents.
+var globalNumbers = map[string]string{
+	"NaN":      "math.NaN()",
+	"Infinity": "math.Inf(1)",
+}
+
+// globalNumber returns the Go expression of the global number constant v
+// refers to, if it refers to one rather than to a declared variable.
+func (g *Generator) globalNumber(v *ast.VariableExpression) (string, bool) {
+	s, ok := globalNumbers[v.Token.Literal]
+	return s, ok && g.lookup(v.Token.Literal) == nil
+}
+
+// numKind returns the Go kind of expr, which must be of type number.
+func (g *Generator) numKind(expr ast.Expression) numKind {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		if isIntegerLiteral(e.Token.Literal) {
+			return untypedInt
+		}
+		return untypedFloat
+	case *ast.ParenthesizedExpression:
+		return g.numKind(e.Expression)
+	case *ast.NonNullExpression:
+		// numbers stored as pointers are float64
+		if !g.isNullable(g.typeOf(e.Expression)) {
+			return g.numKind(e.Expression)
+		}
+	case *ast.UnaryExpression:
+		if n, ok := g.foldNumber(e); ok && n.folded {
+			return n.kind()
+		}
+		return g.numKind(e.Right)
+	case *ast.VariableExpression:
+		return g.lookupKind(e.Token.Literal)
+	case *ast.MemberExpression:
+		if _, ok := g.classMember(e); !ok && g.field(e) == nil && e.Property.String() == "length" {
+			return intNum
+		}
+	case *ast.FunctionCallExpression:
+		if callee, ok := e.Callee.(*ast.MemberExpression); ok && isArray(g.typeOf(callee.Object)) {
+			switch callee.Property.String() {
+			case "push", "indexOf":
+				return intNum
+			}
+		}
+		if callee, ok := e.Callee.(*ast.MemberExpression); ok && g.isString(g.typeOf(callee.Object)) {
+			switch callee.Property.String() {
+			case "indexOf", "lastIndexOf":
+				return intNum
+			}
+		}
+	case *ast.BinaryExpression:
+		if n, ok := g.foldNumber(e); ok && n.folded {
+			return n.kind()
+		}
+		return g.binaryKind(e)
+	}
+	return floatNum
+}
+
+// binaryKind returns the Go kind of an arithmetic expression. Like in Go,
+// operations on untyped constants are untyped, and an int operand takes the
+// kind of the other operand unless that can't be stored in an int.
+func (g *Generator) binaryKind(e *ast.BinaryExpression) numKind {
+	left, right := g.numKind(e.Left), g.numKind(e.Right)
+
+	switch e.Operator.Type {
+	case token.DIV:
+		if left.untyped() && right.untyped() {
+			return untypedFloat
+		}
+		return floatNum
+	case token.MOD:
+		// Go's % only matches JavaScript's for integers and a divisor
+		// known not to be zero; other remainders go through math.Mod
+		if !left.integral() || !right.integral() || !isNonZeroInteger(e.Right) {
+			return floatNum
+		}
+	}
+
+	switch {
+	case left.untyped() && right.untyped():
+		if left == untypedFloat || right == untypedFloat {
+			return untypedFloat
+		}
+		return untypedInt
+	case left.integral() && right.integral():
+		return intNum
+	}
+	return floatNum
+}
+
+// isNumericOperation reports whether e is an arithmetic operation or a
+// comparison between two numbers. Values of numeric enums count as numbers,
+// except when compared with values of the same enum.
+func (g *Generator) isNumericOperation(e *ast.BinaryExpression) bool {
+	switch e.Operator.Type {
+	case token.PLUS, token.MINUS, token.MUL, token.DIV, token.MOD,
+		token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL,
+		token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+		left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+		// values of the same enum are compared as they are
+		if !isArithmetic(e.Operator.Type) && g.enumOf(left) != nil && g.enumOf(left) == g.enumOf(right) {
+			return false
+		}
+		return g.isNumberValue(left) && g.isNumberValue(right)
+	}
+	return false
+}
+
+// generateNumericOperands generates the operands of an operation on two
+// numbers, converting an int operand to float64 where Go would otherwise
+// reject the mismatched types. Division of ints and of untyped integer
+// constants is made floating-point, as it is in TypeScript.
+func (g *Generator) generateNumericOperands(e *ast.BinaryExpression) string {
+	lk, rk := g.numKind(e.Left), g.numKind(e.Right)
+	left, right := g.generateNumberOperand(e.Left), g.generateNumberOperand(e.Right)
+
+	switch {
+	case e.Operator.Type == token.DIV && lk.untyped() && rk.untyped():
+		if lk == untypedInt && rk == untypedInt {
+			if lit, ok := e.Right.(*ast.NumberLiteral); ok && isDecimal(lit.Token.Literal) {
+				right = lit.Token.Literal + ".0"
+			} else {
+				left = g.floatConstant(e.Left)
+			}
+		}
+	case e.Operator.Type == token.DIV:
+		left, right = toFloat(lk, e.Left, left), toFloat(rk, e.Right, right)
+	case lk == floatNum || rk == floatNum || lk == untypedFloat || rk == untypedFloat:
+		left, right = toFloat(lk, e.Left, left), toFloat(rk, e.Right, right)
+	}
+
+	return left + " " + goOperator(e.Operator) + " " + right
+}
+
+// generateRemainder lowers a % b to math.Mod where Go's % would differ from
+// TypeScript's, reporting false if it doesn't.
+func (g *Generator) generateRemainder(e *ast.BinaryExpression) (string, bool) {
+	if e.Operator.Type != token.MOD || !g.isNumericOperation(e) || g.binaryKind(e) != floatNum {
+		return "", false
+	}
+	g.use("math")
+	left := toFloat(g.numKind(e.Left), e.Left, g.generateNumberOperand(e.Left))
+	right := toFloat(g.numKind(e.Right), e.Right, g.generateNumberOperand(e.Right))
+	return "math.Mod(" + left + ", " + right + ")", true
+}
+
+// generateNumber generates expr, which must be of type number, where a value
+// of Go type int is wanted if integer is true and a float64 otherwise.
+func (g *Generator) generateNumber(expr ast.Expression, integer bool) string {
+	if g.isNumericEnum(g.typeOf(expr)) {
+		if integer {
+			return conversion("int", expr, g.generateExpression(expr))
+		}
+		return g.generateNumberOperand(expr)
+	}
+	kind, s := g.numKind(expr), g.generateExpression(expr)
+	switch {
+	case integer && kind == floatNum:
+		return conversion("int", expr, s)
+	case !integer:
+		return toFloat(kind, expr, s)
+	}
+	return s
+}
+
+// generateIndex generates an index or a bound of a slice, which Go wants to
+// be an int.
+func (g *Generator) generateIndex(expr ast.Expression) string {
+	if !isNumber(g.typeOf(expr)) {
+		return g.generateExpression(expr)
+	}
+	return g.generateNumber(expr, true)
+}
+
+// floatConstant generates the untyped integer constant expr as a floating
+// point constant, so that it gets type float64 when it initializes a
+// variable and so that Go divides it as a float.
+func (g *Generator) floatConstant(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		if lit := e.Token.Literal; isDecimal(lit) {
+			return lit + ".0"
+		}
+	case *ast.UnaryExpression:
+		if _, ok := e.Right.(*ast.NumberLiteral); ok {
+			return e.Operator.Literal + g.floatConstant(e.Right)
+		}
+	}
+	return conversion("float64", expr, g.generateExpression(expr))
+}
+
+// generateTypedExpressionAs is generateExpressionAs for values whose Go type
+// is inferred from the expression, such as the initializer of a variable
+// declared with :=, where an untyped integer constant would become an int.
+func (g *Generator) generateTypedExpressionAs(expr ast.Expression, expected ast.TypeExpr) string {
+	if isNumber(expected) && isNumber(g.typeOf(expr)) && g.numKind(expr) == untypedInt {
+		return g.floatConstant(expr)
+	}
+	return g.generateExpressionAs(expr, expected)
+}
+
+// toFloat converts s, the Go code of expr, to float64 if it is an int. A
+// negated int is converted before it is negated, since only a float64 can
+// be -0.
+func toFloat(kind numKind, expr ast.Expression, s string) string {
+	if kind != intNum {
+		return s
+	}
+	if e, ok := expr.(*ast.UnaryExpression); ok && e.Operator.Type == token.MINUS {
+		return "-" + toFloat(kind, e.Right, strings.TrimPrefix(s, "-"))
+	}
+	return conversion("float64", expr, s)
+}
+
+// conversion converts s, the Go code of expr, to the Go type t, reusing the
+// parentheses s already has.
+func conversion(t string, expr ast.Expression, s string) string {
+	switch expr.(type) {
+	case *ast.ParenthesizedExpression:
+		return t + s
+	case *ast.BinaryExpression:
+		// unless it is a remainder computed with math.Mod or a folded
+		// constant
+		if strings.HasPrefix(s, "(") {
+			return t + s
+		}
+	}
+	return t + "(" + s + ")"
+}
+
+// constValue is the value of a constant expression as Go evaluates it,
+// exactly, and for numbers also as TypeScript does, with float64
+// arithmetic. Go's exact value is unknown where it can't evaluate the
+// expression at all, as for a division by zero. An operation on numbers Go
+// would compute differently is folded: it is generated as the literal of
+// TypeScript's value, so its exact value is that of the literal.
+type constValue struct {
+	exact  constant.Value
+	value  float64
+	folded bool
+}
+
+func (n constValue) isNumber() bool {
+	return n.exact.Kind() == constant.Int || n.exact.Kind() == constant.Float
+}
+
+// key returns a string that is the same for the values Go sees as equal
+// cases of a switch, where numbers are float64.
+func (n constValue) key() string {
+	if n.isNumber() {
+		f, _ := constant.Float64Val(n.exact)
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return n.exact.ExactString()
+}
+
+// agrees reports whether Go computes the same float64 as TypeScript, which
+// it does when rounding its exact value gives the value TypeScript computed.
+// Go's constants have no -0, so they never agree with it.
+func (n constValue) agrees() bool {
+	if !n.isNumber() {
+		return false
+	}
+	f, _ := constant.Float64Val(n.exact)
+	return f == n.value && !math.IsInf(n.value, 0) && !isNegativeZero(n.value)
+}
+
+// literal returns the Go code of the value TypeScript computed, which is a
+// constant unless it is infinite or NaN.
+func (n constValue) literal() string {
+	switch {
+	case math.IsNaN(n.value):
+		return "math.NaN()"
+	case math.IsInf(n.value, 1):
+		return "math.Inf(1)"
+	case math.IsInf(n.value, -1):
+		return "math.Inf(-1)"
+	case isNegativeZero(n.value):
+		return "math.Copysign(0, -1)"
+	case n.value == math.Trunc(n.value) && math.Abs(n.value) < 1e21:
+		return strconv.FormatFloat(n.value, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(n.value, 'g', -1, 64)
+}
+
+// kind returns the Go kind of the literal of n.
+func (n constValue) kind() numKind {
+	switch lit := n.literal(); {
+	case strings.HasPrefix(lit, "math."):
+		return floatNum
+	case strings.ContainsAny(lit, ".e"):
+		return untypedFloat
+	}
+	return untypedInt
+}
+
+// foldNumber evaluates expr if it is an arithmetic expression of number
+// literals and constants, reporting false if it isn't.
+func (g *Generator) foldNumber(expr ast.Expression) (constValue, bool) {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		lit := strings.ToLower(e.Token.Literal)
+		exact := constant.MakeFromLiteral(lit, gotoken.INT, 0)
+		if exact.Kind() != constant.Int {
+			exact = constant.MakeFromLiteral(lit, gotoken.FLOAT, 0)
+		}
+		value, _ := constant.Float64Val(exact)
+		return constValue{exact: exact, value: value}, exact.Kind() != constant.Unknown
+	case *ast.ParenthesizedExpression:
+		return g.foldNumber(e.Expression)
+	case *ast.UnaryExpression:
+		n, ok := g.foldNumber(e.Right)
+		switch {
+		case !ok:
+			return n, false
+		case e.Operator.Type == token.MINUS:
+			return fold(constValue{exact: constant.UnaryOp(gotoken.SUB, n.exact, 0), value: -n.value}), true
+		case e.Operator.Type == token.PLUS:
+			return n, true
+		}
+	case *ast.VariableExpression:
+		if n, ok := g.lookupValue(e.Token.Literal); ok && n.isNumber() {
+			return n, true
+		}
+	case *ast.BinaryExpression:
+		if !isArithmetic(e.Operator.Type) || !g.isNumericOperation(e) {
+			return constValue{}, false
+		}
+		l, ok := g.foldNumber(e.Left)
+		if !ok {
+			return l, false
+		}
+		r, ok := g.foldNumber(e.Right)
+		if !ok {
+			return r, false
+		}
+		return fold(foldBinary(e.Operator.Type, l, r)), true
+	}
+	return constValue{}, false
+}
+
+// fold marks n as folded if Go would compute it differently, giving it the
+// exact value of its literal.
+func fold(n constValue) constValue {
+	if n.folded = !n.agrees(); n.folded {
+		n.exact = constant.MakeUnknown()
+		if lit := n.literal(); !strings.HasPrefix(lit, "math.") {
+			n.exact = literalValue(lit)
+		}
+	}
+	return n
+}
+
+// foldBinary applies the arithmetic operator op to l and r.
+func foldBinary(op token.TokenType, l, r constValue) constValue {
+	known := l.exact.Kind() != constant.Unknown && r.exact.Kind() != constant.Unknown
+	zero := known && constant.Sign(r.exact) == 0
+	n := constValue{exact: constant.MakeUnknown()}
+	switch op {
+	case token.PLUS:
+		n.value = l.value + r.value
+		if known {
+			n.exact = constant.BinaryOp(l.exact, gotoken.ADD, r.exact)
+		}
+	case token.MINUS:
+		n.value = l.value - r.value
+		if known {
+			n.exact = constant.BinaryOp(l.exact, gotoken.SUB, r.exact)
+		}
+	case token.MUL:
+		n.value = l.value * r.value
+		if known {
+			n.exact = constant.BinaryOp(l.exact, gotoken.MUL, r.exact)
+		}
+	case token.DIV:
+		n.value = l.value / r.value
+		if known && !zero {
+			n.exact = constant.BinaryOp(constant.ToFloat(l.exact), gotoken.QUO, constant.ToFloat(r.exact))
+		}
+	case token.MOD:
+		n.value = math.Mod(l.value, r.value)
+		// Go only has remainders of integers
+		if known && !zero && l.exact.Kind() == constant.Int && r.exact.Kind() == constant.Int {
+			n.exact = constant.BinaryOp(l.exact, gotoken.REM, r.exact)
+		}
+	}
+	return n
+}
+
+// literalValue returns the exact value of lit, a possibly negated Go
+// literal of a number.
+func literalValue(lit string) constant.Value {
+	if abs, ok := strings.CutPrefix(lit, "-"); ok {
+		return constant.UnaryOp(gotoken.SUB, literalValue(abs), 0)
+	}
+	if strings.ContainsAny(lit, ".e") {
+		return constant.MakeFromLiteral(lit, gotoken.FLOAT, 0)
+	}
+	return constant.MakeFromLiteral(lit, gotoken.INT, 0)
+}
+
+// generateFolded generates the arithmetic expression e as the literal of its
+// value if it is an expression of constants that Go would compute
+// differently from TypeScript, as 0.1 + 0.2, which Go computes exactly,
+// 1e308 * 10, which overflows, or -0, which Go's constants can't hold,
+// reporting false if it isn't.
+func (g *Generator) generateFolded(e ast.Expression) (string, bool) {
+	n, ok := g.foldNumber(e)
+	if !ok || !n.folded {
+		return "", false
+	}
+	lit := n.literal()
+	if strings.HasPrefix(lit, "math.") {
+		g.use("math")
+	}
+	return lit, true
+}
+
+// isIntegerLiteral reports whether the numeric literal lit is an integer in
+// Go, which treats decimal literals with a fraction or an exponent as
+// floating-point.
+func isIntegerLiteral(lit string) bool {
+	return !isDecimal(lit) || !strings.ContainsAny(lit, ".eE")
+}
+
+func isDecimal(lit string) bool {
+	if len(lit) < 2 || lit[0] != '0' {
+		return true
+	}
+	return !strings.ContainsRune("xXoObB", rune(lit[1]))
+}
+
+// isNonZeroInteger reports whether expr is an integer literal other than
+// zero, possibly negated.
+func isNonZeroInteger(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.ParenthesizedExpression:
+		return isNonZeroInteger(e.Expression)
+	case *ast.UnaryExpression:
+		return e.Operator.Type == token.MINUS && isNonZeroInteger(e.Right)
+	case *ast.NumberLiteral:
+		if !isIntegerLiteral(e.Token.Literal) {
+			return false
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(e.Token.Literal, "_", ""), 0, 64)
+		return err != nil || n != 0
+	}
+	return false
+}
+
+// generateNarrowed runs generate, which generates a function body, with
+// integer narrowing when it is enabled. Number variables are float64 unless
+// the narrowing shows that they only ever hold integers: starting from all
+// the variables of the body, generation is repeated, widening back to
+// float64 each variable that is assigned a value that may not be integral,
+// until no variable is widened. Only the output of the last pass is kept.
+func (g *Generator) generateNarrowed(generate func() string) string {
+	if !g.NarrowIntegers {
+		return generate()
+	}
+
+	outer := g.sums
+	defer func() { g.sums = append(outer, g.sums...) }()
+	for {
+		imports, helpers, labels := maps.Clone(g.imports), maps.Clone(g.helpers), maps.Clone(g.usedLabels)
+		diagnostics := len(g.diagnostics)
+		g.widened, g.sums = false, nil
+
+		generate()
+		g.widenGrowing()
+
+		g.imports, g.helpers, g.usedLabels = imports, helpers, labels
+		g.diagnostics = g.diagnostics[:diagnostics]
+		if !g.widened {
+			g.sums = nil
+			return generate()
+		}
+	}
+}
+
+// narrowedSum is a sum assigned to a variable narrowed to int, with the
+// narrowed variables each of its terms reads.
+type narrowedSum struct {
+	target *ast.VariableDeclaration
+	terms  [][]*ast.VariableDeclaration
+}
+
+// recordSum records the terms of value, assigned to the narrowed variable
+// varDec with the assignment operator op, for widenGrowing.
+func (g *Generator) recordSum(varDec *ast.VariableDeclaration, op token.TokenType, value ast.Expression) {
+	sum := narrowedSum{target: varDec}
+	if op != token.ASSIGN {
+		sum.terms = append(sum.terms, []*ast.VariableDeclaration{varDec})
+	}
+	for _, term := range addends(value) {
+		sum.terms = append(sum.terms, g.narrowedReads(term, nil))
+	}
+	g.sums = append(g.sums, sum)
+}
+
+// addends returns the terms of the sums and differences expr is made of.
+func addends(expr ast.Expression) []ast.Expression {
+	switch e := expr.(type) {
+	case *ast.ParenthesizedExpression:
+		return addends(e.Expression)
+	case *ast.BinaryExpression:
+		if e.Operator.Type == token.PLUS || e.Operator.Type == token.MINUS {
+			return append(addends(e.Left), addends(e.Right)...)
+		}
+	}
+	return []ast.Expression{expr}
+}
+
+// narrowedReads appends to reads the narrowed variables whose values expr
+// may grow with. A remainder is smaller than its divisor, whatever its
+// dividend.
+func (g *Generator) narrowedReads(expr ast.Expression, reads []*ast.VariableDeclaration) []*ast.VariableDeclaration {
+	switch e := expr.(type) {
+	case *ast.VariableExpression:
+		if varDec := g.lookupNarrowed(e.Token.Literal); varDec != nil {
+			reads = append(reads, varDec)
+		}
+	case *ast.ParenthesizedExpression:
+		return g.narrowedReads(e.Expression, reads)
+	case *ast.UnaryExpression:
+		return g.narrowedReads(e.Right, reads)
+	case *ast.BinaryExpression:
+		if e.Operator.Type != token.MOD {
+			reads = g.narrowedReads(e.Left, reads)
+		}
+		return g.narrowedReads(e.Right, reads)
+	}
+	return reads
+}
+
+// widenGrowing widens the narrowed variables that may grow without a bound
+// and overflow, where float64 would only lose precision: those assigned a
+// sum of which two terms depend on the variable's own earlier values, like
+// g in g += g or the variables of a Fibonacci sequence. Sums with one such
+// term, like i += 1 or total += i, grow no faster than the loops around
+// them run.
+func (g *Generator) widenGrowing() {
+	deps := map[*ast.VariableDeclaration][]*ast.VariableDeclaration{}
+	for _, sum := range g.sums {
+		for _, term := range sum.terms {
+			deps[sum.target] = append(deps[sum.target], term...)
+		}
+	}
+
+	// reaches reports whether the value of from depends on that of to
+	reaches := func(from, to *ast.VariableDeclaration) bool {
+		seen := map[*ast.VariableDeclaration]bool{}
+		stack := []*ast.VariableDeclaration{from}
+		for len(stack) > 0 {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if v == to {
+				return true
+			}
+			if !seen[v] {
+				seen[v] = true
+				stack = append(stack, deps[v]...)
+			}
+		}
+		return false
+	}
+
+	for _, sum := range g.sums {
+		growing := 0
+		for _, term := range sum.terms {
+			if slices.ContainsFunc(term, func(v *ast.VariableDeclaration) bool { return reaches(v, sum.target) }) {
+				growing++
+			}
+		}
+		if growing > 1 {
+			g.widen(sum.target)
+		}
+	}
+}
+
+// narrows reports whether the number variable declared by varDec is
+// lowered to an int.
+func (g *Generator) narrows(varDec *ast.VariableDeclaration, t ast.TypeExpr) bool {
+	return g.NarrowIntegers && isNumber(t) && !g.wide[varDec]
+}
+
+// widen records that the variable declared by varDec may hold values that
+// aren't integers.
+func (g *Generator) widen(varDec *ast.VariableDeclaration) {
+	if !g.wide[varDec] {
+		g.wide[varDec] = true
+		g.widened = true
+	}
+}
+
+// checkNarrowed widens the variable name if it is narrowed to int and
+// assigning value to it with the assignment operator op may store a value
+// that isn't an integer, or one that ints don't compute like float64 does.
+// Adding or subtracting -0 leaves a number unchanged, but storing it
+// doesn't. Sums are recorded for widenGrowing.
+func (g *Generator) checkNarrowed(name string, op token.TokenType, value ast.Expression) {
+	varDec := g.lookupNarrowed(name)
+	if varDec == nil {
+		return
+	}
+	switch op {
+	case token.PLUS_ASSIGN, token.MINUS_ASSIGN:
+		if !isNumber(g.typeOf(value)) || !g.numKind(value).integral() || !g.intExact(value) {
+			g.widen(varDec)
+		}
+		g.recordSum(varDec, op, value)
+	case token.ASSIGN:
+		if !g.holdsInteger(value) {
+			g.widen(varDec)
+		}
+		g.recordSum(varDec, op, value)
+	default:
+		// products may overflow, and remainders may be -0
+		g.widen(varDec)
+	}
+}
+
+// holdsInteger reports whether expr is a number that can be stored in a
+// variable narrowed to int.
+func (g *Generator) holdsInteger(expr ast.Expression) bool {
+	return isNumber(g.typeOf(expr)) && g.numKind(expr).integral() && g.intExact(expr) && !g.mayBeNegativeZero(expr)
+}
+
+// intExact reports whether Go ints compute the integral expression expr
+// exactly like float64 does. Constants must be in the range in which
+// float64 represents every integer exactly, and products of values that
+// aren't constants have no bound and may overflow.
+func (g *Generator) intExact(expr ast.Expression) bool {
+	if n, ok := g.foldNumber(expr); ok {
+		return math.Abs(n.value) <= maxSafeInteger
+	}
+	switch e := expr.(type) {
+	case *ast.ParenthesizedExpression:
+		return g.intExact(e.Expression)
+	case *ast.UnaryExpression:
+		return g.intExact(e.Right)
+	case *ast.BinaryExpression:
+		return e.Operator.Type != token.MUL && g.intExact(e.Left) && g.intExact(e.Right)
+	}
+	return true
+}
+
+func isNegativeZero(f float64) bool {
+	return f == 0 && math.Signbit(f)
+}
+
+// mayBeNegativeZero reports whether the integral expression expr may be -0,
+// which ints can't hold: the negation of zero, a product with a negative
+// number, the remainder of a negative multiple of the divisor, or a sum of
+// such values.
+func (g *Generator) mayBeNegativeZero(expr ast.Expression) bool {
+	if n, ok := g.foldNumber(expr); ok {
+		return isNegativeZero(n.value)
+	}
+	switch e := expr.(type) {
+	case *ast.ParenthesizedExpression:
+		return g.mayBeNegativeZero(e.Expression)
+	case *ast.UnaryExpression:
+		return e.Operator.Type == token.MINUS || g.mayBeNegativeZero(e.Right)
+	case *ast.BinaryExpression:
+		switch e.Operator.Type {
+		case token.MUL, token.MOD:
+			return true
+		case token.PLUS:
+			return g.mayBeNegativeZero(e.Left) && g.mayBeNegativeZero(e.Right)
+		case token.MINUS:
+			return g.mayBeNegativeZero(e.Left)
+		}
+	}
+	return false
+}
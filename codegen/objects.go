@@ -0,0 +1,218 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// generateTypeDeclaration generates a named Go type for an interface or a
+// type alias. Object types become structs, or interfaces if they are used
+// as constraints, discriminated unions sealed interfaces, and other aliases
+// Go type aliases.
+func (g *Generator) generateTypeDeclaration(name string, params []*ast.TypeParam, t ast.TypeExpr) string {
+	if u := g.unions[name]; u != nil && g.typeDecls[name] == t {
+		return g.generateUnionDeclaration(u)
+	}
+
+	outer := g.declareTypeParams(params)
+	defer func() { g.typeParams = outer }()
+
+	obj, ok := t.(*ast.ObjectType)
+	switch {
+	case ok && g.constraints[name]:
+		return g.generateConstraintInterface(name, params, obj)
+	case ok:
+		return fmt.Sprintf("type %s%s %s\n", g.goName(name), g.generateTypeParams(params), g.structType(obj))
+	}
+	return fmt.Sprintf("type %s%s = %s\n", g.goName(name), g.generateTypeParams(params), g.goType(t))
+}
+
+// structType generates a struct type with one exported field per member of
+// obj, tagged with the original name so that encoding/json round-trips the
+// TypeScript shape. Optional members are nullable, nil when absent.
+func (g *Generator) structType(obj *ast.ObjectType) string {
+	members := g.structMembers(obj)
+	if len(members) == 0 {
+		return "struct{}"
+	}
+
+	names := make([]string, len(members))
+	types := make([]string, len(members))
+	nameWidth, typeWidth := 0, 0
+	for i, m := range members {
+		names[i] = fieldName(m.Name.Literal)
+		types[i] = g.fieldType(m)
+		nameWidth = max(nameWidth, len(names[i]))
+		typeWidth = max(typeWidth, len(types[i]))
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("struct {\n")
+	for i, m := range members {
+		builder.WriteString(indentComments(m.Leading, names[i]))
+		field := fmt.Sprintf("%s%-*s %-*s %s", indent, nameWidth, names[i], typeWidth, types[i], fieldTag(m))
+		builder.WriteString(withTrailingComment(field, m.Trailing) + "\n")
+	}
+	builder.WriteString("}")
+
+	return builder.String()
+}
+
+// inlineStructType generates an anonymous struct type on a single line.
+func (g *Generator) inlineStructType(obj *ast.ObjectType) string {
+	if len(obj.Members) == 0 {
+		return "struct{}"
+	}
+
+	fields := make([]string, len(obj.Members))
+	for i, m := range obj.Members {
+		fields[i] = fmt.Sprintf("%s %s %s", fieldName(m.Name.Literal), g.fieldType(m), fieldTag(m))
+	}
+	return fmt.Sprintf("struct{ %s }", strings.Join(fields, "; "))
+}
+
+func (g *Generator) fieldType(m *ast.PropertySignature) string {
+	if m.Optional {
+		return g.goType(g.nullable(m.Type))
+	}
+	return g.goType(m.Type)
+}
+
+func fieldTag(m *ast.PropertySignature) string {
+	if m.Optional {
+		return fmt.Sprintf("`json:\"%s,omitempty\"`", m.Name.Literal)
+	}
+	return fmt.Sprintf("`json:\"%s\"`", m.Name.Literal)
+}
+
+// fieldName exports a property name so that the field is visible to
+// packages such as encoding/json.
+func fieldName(name string) string {
+	name = escapeName(name)
+	r := []rune(name)
+	switch {
+	case unicode.IsUpper(r[0]):
+		return name
+	case unicode.IsLetter(r[0]):
+		r[0] = unicode.ToUpper(r[0])
+		return string(r)
+	default:
+		return "X" + name
+	}
+}
+
+// objectType resolves t to the object type it names, following type aliases,
+// or returns nil if t isn't an object type.
+func (g *Generator) objectType(t ast.TypeExpr) *ast.ObjectType {
+	obj, _ := g.resolveType(t).(*ast.ObjectType)
+	return obj
+}
+
+// resolveType replaces the name of an interface or a type alias with the
+// type it stands for, and a type parameter with its constraint.
+func (g *Generator) resolveType(t ast.TypeExpr) ast.TypeExpr {
+	for range len(g.typeDecls) + len(g.typeParams) + 1 {
+		ref, ok := t.(*ast.TypeReference)
+		if !ok {
+			break
+		}
+		declared := g.declaredType(ref)
+		if declared == nil {
+			break
+		}
+		t = declared
+	}
+	return t
+}
+
+// field returns the declaration of the struct field expr refers to, or nil
+// if expr isn't a property access on an object type.
+func (g *Generator) field(expr ast.Expression) *ast.PropertySignature {
+	member, ok := expr.(*ast.MemberExpression)
+	if !ok {
+		return nil
+	}
+	if obj := g.objectType(g.typeOf(member.Object)); obj != nil {
+		return obj.Member(member.Property.String())
+	}
+	return nil
+}
+
+// generateObjectLiteral generates a pointer to a composite literal of the
+// struct the expected type resolves to, or of an anonymous struct matching
+// the literal if there is no expected type. Objects whose shape isn't known
+// at all become maps.
+func (g *Generator) generateObjectLiteral(lit *ast.ObjectLiteral, expected ast.TypeExpr) string {
+	if v := g.literalVariant(lit, expected); v != nil {
+		expected = v.typ
+	}
+
+	typ := expected
+	obj := g.objectType(typ)
+	if obj == nil {
+		typ = g.typeOf(lit)
+		obj = g.objectType(typ)
+	}
+
+	if name := typeName(typ); g.constraints[name] {
+		g.errorf(lit, "cannot translate object literal of type '%s', which is used as a constraint: Go lowers it to an interface of methods", name)
+	}
+
+	if obj == nil {
+		props := make([]string, len(lit.Properties))
+		for i, prop := range lit.Properties {
+			props[i] = fmt.Sprintf("%s: %s", strconv.Quote(prop.Key.Literal), g.generateExpression(prop.Value))
+		}
+		return fmt.Sprintf("map[string]any{%s}", strings.Join(props, ", "))
+	}
+
+	var fields []string
+	for _, prop := range lit.Properties {
+		// the discriminant of a variant is implied by its type
+		if v := g.variants[obj]; v != nil && prop.Key.Literal == v.union.tag {
+			continue
+		}
+		var value string
+		if m := obj.Member(prop.Key.Literal); m != nil {
+			value = g.generateFieldValue(prop.Value, m.Type, m.Optional)
+		} else {
+			value = g.generateExpression(prop.Value)
+		}
+		fields = append(fields, fmt.Sprintf("%s: %s", fieldName(prop.Key.Literal), value))
+	}
+
+	return fmt.Sprintf("&%s{%s}", strings.TrimPrefix(g.goType(typ), "*"), strings.Join(fields, ", "))
+}
+
+// generateFieldValue generates a value stored in a field of type t, taking
+// the address of values of optional fields stored as pointers.
+func (g *Generator) generateFieldValue(value ast.Expression, t ast.TypeExpr, optional bool) string {
+	if optional {
+		t = g.nullable(t)
+	}
+	return g.generateExpressionAs(value, t)
+}
+
+// hasOptional reports whether obj has optional members.
+func hasOptional(obj *ast.ObjectType) bool {
+	for _, m := range obj.Members {
+		if m.Optional {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyNames returns the quoted names of the members of obj, in the order
+// in which they were declared.
+func propertyNames(obj *ast.ObjectType) []string {
+	names := make([]string, len(obj.Members))
+	for i, m := range obj.Members {
+		names[i] = strconv.Quote(m.Name.Literal)
+	}
+	return names
+}
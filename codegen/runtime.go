@@ -0,0 +1,756 @@
+package codegen
+
+import (
+	"sort"
+	"strings"
+)
+
+// helper is a Go function emitted into the generated program when some
+// TypeScript construct has no direct Go equivalent. helpers are the other
+// helpers its source calls.
+type helper struct {
+	imports []string
+	helpers []string
+	source  string
+}
+
+var helpers = map[string]helper{
+	"sildBigInt": {imports: []string{"math/big"}, source: `
+// sildBigInt returns the integer written by the literal s, which doesn't fit
+// in an int64.
+func sildBigInt(s string) *big.Int {
+    n, _ := new(big.Int).SetString(s, 0)
+    return n
+}`},
+	"sildCompareBig": {imports: []string{"math", "math/big"}, source: `
+// sildCompareBig compares x and y like big.Int.Cmp, except that it returns
+// NaN if y is NaN so that comparing the result with 0 is always false.
+func sildCompareBig(x *big.Int, y float64) float64 {
+    switch {
+    case math.IsNaN(y):
+        return y
+    case math.IsInf(y, 0):
+        return -y
+    }
+    return float64(new(big.Float).SetInt(x).Cmp(big.NewFloat(y)))
+}`},
+	"sildNumberString": {imports: []string{"math", "strconv", "strings"}, source: `
+// sildNumberString formats x the way JavaScript converts numbers to strings.
+func sildNumberString(x float64) string {
+    switch {
+    case math.IsNaN(x):
+        return "NaN"
+    case math.IsInf(x, 1):
+        return "Infinity"
+    case math.IsInf(x, -1):
+        return "-Infinity"
+    case x == 0:
+        return "0"
+    }
+    if abs := math.Abs(x); abs >= 1e-6 && abs < 1e21 {
+        return strconv.FormatFloat(x, 'f', -1, 64)
+    }
+    mantissa, exp, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+    return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+}`},
+	"sildLength": {imports: []string{"unicode/utf16"}, source: `
+// sildLength returns the length of s in UTF-16 code units, the units strings
+// are measured and indexed in by TypeScript.
+func sildLength(s string) int {
+    n := 0
+    for _, r := range s {
+        n += utf16.RuneLen(r)
+    }
+    return n
+}`},
+	"sildCharAt": {imports: []string{"unicode/utf16"}, source: `
+// sildCharAt returns the UTF-16 code unit of s at index i as a string, or ""
+// if i is out of range.
+func sildCharAt(s string, i int) string {
+    u := utf16.Encode([]rune(s))
+    if i < 0 || i >= len(u) {
+        return ""
+    }
+    return string(utf16.Decode(u[i : i+1]))
+}`},
+	"sildCharCodeAt": {imports: []string{"math", "unicode/utf16"}, source: `
+// sildCharCodeAt returns the UTF-16 code unit of s at index i, or NaN if i is
+// out of range.
+func sildCharCodeAt(s string, i int) float64 {
+    u := utf16.Encode([]rune(s))
+    if i < 0 || i >= len(u) {
+        return math.NaN()
+    }
+    return float64(u[i])
+}`},
+	"sildSubstring": {imports: []string{"unicode/utf16"}, source: `
+// sildSubstring returns the UTF-16 code units of s from start up to the
+// optional end, with both clamped to the bounds of s and swapped if start
+// comes after end, like String.prototype.substring.
+func sildSubstring(s string, start int, end ...int) string {
+    u := utf16.Encode([]rune(s))
+    stop := len(u)
+    if len(end) > 0 {
+        stop = end[0]
+    }
+    start, stop = max(0, min(start, len(u))), max(0, min(stop, len(u)))
+    if start > stop {
+        start, stop = stop, start
+    }
+    return string(utf16.Decode(u[start:stop]))
+}`},
+	"sildStringSlice": {imports: []string{"unicode/utf16"}, source: `
+// sildStringSlice returns the UTF-16 code units of s between the optional
+// start and end bounds, where negative bounds count from the end like in
+// String.prototype.slice.
+func sildStringSlice(s string, bounds ...int) string {
+    u := utf16.Encode([]rune(s))
+    clamp := func(i int) int {
+        if i < 0 {
+            i += len(u)
+        }
+        return max(0, min(i, len(u)))
+    }
+
+    start, end := 0, len(u)
+    if len(bounds) > 0 {
+        start = clamp(bounds[0])
+    }
+    if len(bounds) > 1 {
+        end = clamp(bounds[1])
+    }
+    if start >= end {
+        return ""
+    }
+    return string(utf16.Decode(u[start:end]))
+}`},
+	"sildStringIndexOf": {imports: []string{"slices", "unicode/utf16"}, source: `
+// sildStringIndexOf returns the UTF-16 index of the first occurrence of
+// search in s at or after the optional index from, or -1 if there is none.
+func sildStringIndexOf(s, search string, from ...int) int {
+    u, v := utf16.Encode([]rune(s)), utf16.Encode([]rune(search))
+    start := 0
+    if len(from) > 0 {
+        start = max(0, min(from[0], len(u)))
+    }
+    for i := start; i+len(v) <= len(u); i++ {
+        if slices.Equal(u[i:i+len(v)], v) {
+            return i
+        }
+    }
+    return -1
+}`},
+	"sildStringLastIndexOf": {imports: []string{"slices", "unicode/utf16"}, source: `
+// sildStringLastIndexOf returns the UTF-16 index of the last occurrence of
+// search in s, or -1 if there is none.
+func sildStringLastIndexOf(s, search string) int {
+    u, v := utf16.Encode([]rune(s)), utf16.Encode([]rune(search))
+    for i := len(u) - len(v); i >= 0; i-- {
+        if slices.Equal(u[i:i+len(v)], v) {
+            return i
+        }
+    }
+    return -1
+}`},
+	"sildSplit": {imports: []string{"strings", "unicode/utf16"}, source: `
+// sildSplit splits s around each instance of sep into a new array. An empty
+// sep splits s into its UTF-16 code units, like it does in TypeScript.
+func sildSplit(s, sep string) *[]string {
+    if sep == "" {
+        u := utf16.Encode([]rune(s))
+        parts := make([]string, len(u))
+        for i := range u {
+            parts[i] = string(utf16.Decode(u[i : i+1]))
+        }
+        return &parts
+    }
+    parts := strings.Split(s, sep)
+    return &parts
+}`},
+	"sildPad": {imports: []string{"unicode/utf16"}, source: `
+// sildPad pads s at its start, or at its end, with fill repeated and cut so
+// that the result is n UTF-16 code units long, like String.prototype.padStart
+// and padEnd.
+func sildPad(s string, n int, fill string, atStart bool) string {
+    u, f := utf16.Encode([]rune(s)), utf16.Encode([]rune(fill))
+    if n <= len(u) || len(f) == 0 {
+        return s
+    }
+    var pad []uint16
+    for len(pad) < n-len(u) {
+        pad = append(pad, f...)
+    }
+    p := string(utf16.Decode(pad[:n-len(u)]))
+    if atStart {
+        return p + s
+    }
+    return s + p
+}`},
+	"sildPtr": {source: `
+// sildPtr returns a pointer to a copy of v, for values of nullable types.
+func sildPtr[T any](v T) *T {
+    return &v
+}`},
+	"sildEqualPtr": {source: `
+// sildEqualPtr reports whether p and q are both nil or point to equal values.
+func sildEqualPtr[T comparable](p, q *T) bool {
+    return p == q || p != nil && q != nil && *p == *q
+}`},
+	"sildPush": {source: `
+func sildPush[T any](xs *[]T, items ...T) int {
+    *xs = append(*xs, items...)
+    return len(*xs)
+}`},
+	"sildPop": {source: `
+// sildPop removes and returns the last element of xs, or the zero value,
+// nil, if xs is empty.
+func sildPop[T any](xs *[]T) T {
+    var last T
+    if n := len(*xs); n > 0 {
+        last = (*xs)[n-1]
+        *xs = (*xs)[:n-1]
+    }
+    return last
+}`},
+	"sildPopPtr": {source: `
+// sildPopPtr removes the last element of xs and returns a pointer to it, or
+// nil if xs is empty.
+func sildPopPtr[T any](xs *[]T) *T {
+    n := len(*xs)
+    if n == 0 {
+        return nil
+    }
+    last := (*xs)[n-1]
+    *xs = (*xs)[:n-1]
+    return &last
+}`},
+	"sildSlice": {source: `
+// sildSlice returns a copy of xs between the optional start and end bounds,
+// where negative bounds count from the end like in Array.prototype.slice.
+func sildSlice[T any](xs []T, bounds ...int) *[]T {
+    clamp := func(i int) int {
+        if i < 0 {
+            i += len(xs)
+        }
+        return max(0, min(i, len(xs)))
+    }
+
+    start, end := 0, len(xs)
+    if len(bounds) > 0 {
+        start = clamp(bounds[0])
+    }
+    if len(bounds) > 1 {
+        end = clamp(bounds[1])
+    }
+    if start >= end {
+        return &[]T{}
+    }
+    out := append([]T{}, xs[start:end]...)
+    return &out
+}`},
+	"sildConcat": {source: `
+// sildConcat returns a new array holding the elements of xs followed by
+// those of each of others.
+func sildConcat[T any](xs *[]T, others ...*[]T) *[]T {
+    out := append([]T{}, *xs...)
+    for _, o := range others {
+        out = append(out, *o...)
+    }
+    return &out
+}`},
+	"sildIndexOf": {imports: []string{"slices"}, source: `
+func sildIndexOf[T comparable](xs []T, x T, from int) int {
+    if from < 0 {
+        from = max(0, from+len(xs))
+    }
+    if from >= len(xs) {
+        return -1
+    }
+    if i := slices.Index(xs[from:], x); i >= 0 {
+        return i + from
+    }
+    return -1
+}`},
+	"sildIncludes": {imports: []string{"slices"}, source: `
+// sildIncludes reports whether xs contains x like Array.prototype.includes,
+// which finds NaN too.
+func sildIncludes[T comparable](xs []T, x T) bool {
+    return slices.ContainsFunc(xs, func(y T) bool {
+        return y == x || y != y && x != x
+    })
+}`},
+	"sildJoin": {imports: []string{"reflect"}, helpers: []string{"sildString"}, source: `
+// sildJoin converts the elements of xs to strings and joins them with sep,
+// or with commas, like Array.prototype.join.
+func sildJoin[T any](xs []T, sep ...string) string {
+    s := ","
+    if len(sep) > 0 {
+        s = sep[0]
+    }
+    return sildJoinValues(reflect.ValueOf(xs), s)
+}`},
+	"sildReverse": {imports: []string{"slices"}, source: `
+// sildReverse reverses xs in place and returns it.
+func sildReverse[T any](xs *[]T) *[]T {
+    slices.Reverse(*xs)
+    return xs
+}`},
+	"sildMap": {source: `
+func sildMap[T, U any](xs []T, f func(T) U) *[]U {
+    out := make([]U, len(xs))
+    for i, x := range xs {
+        out[i] = f(x)
+    }
+    return &out
+}`},
+	"sildFilter": {source: `
+func sildFilter[T any](xs []T, f func(T) bool) *[]T {
+    out := []T{}
+    for _, x := range xs {
+        if f(x) {
+            out = append(out, x)
+        }
+    }
+    return &out
+}`},
+	"sildReduce": {source: `
+func sildReduce[T, U any](xs []T, f func(U, T) U, acc U) U {
+    for _, x := range xs {
+        acc = f(acc, x)
+    }
+    return acc
+}`},
+	"sildReduceFirst": {source: `
+// sildReduceFirst reduces xs using its first element as the initial value.
+func sildReduceFirst[T any](xs []T, f func(T, T) T) T {
+    if len(xs) == 0 {
+        panic("TypeError: Reduce of empty array with no initial value")
+    }
+    acc := xs[0]
+    for _, x := range xs[1:] {
+        acc = f(acc, x)
+    }
+    return acc
+}`},
+	"sildFind": {source: `
+// sildFind returns the first element of xs satisfying f, or the zero value,
+// nil, if there is none.
+func sildFind[T any](xs []T, f func(T) bool) T {
+    for _, x := range xs {
+        if f(x) {
+            return x
+        }
+    }
+    var zero T
+    return zero
+}`},
+	"sildFindPtr": {source: `
+// sildFindPtr returns a pointer to the first element of xs satisfying f, or
+// nil if there is none.
+func sildFindPtr[T any](xs []T, f func(T) bool) *T {
+    for _, x := range xs {
+        if f(x) {
+            return &x
+        }
+    }
+    return nil
+}`},
+	"sildEvery": {source: `
+func sildEvery[T any](xs []T, f func(T) bool) bool {
+    for _, x := range xs {
+        if !f(x) {
+            return false
+        }
+    }
+    return true
+}`},
+	"sildForEach": {source: `
+func sildForEach[T any](xs []T, f func(T)) {
+    for _, x := range xs {
+        f(x)
+    }
+}`},
+	"sildMapIndexed": {source: `
+func sildMapIndexed[T, U any](xs []T, f func(T, float64) U) *[]U {
+    out := make([]U, len(xs))
+    for i, x := range xs {
+        out[i] = f(x, float64(i))
+    }
+    return &out
+}`},
+	"sildFilterIndexed": {source: `
+func sildFilterIndexed[T any](xs []T, f func(T, float64) bool) *[]T {
+    out := []T{}
+    for i, x := range xs {
+        if f(x, float64(i)) {
+            out = append(out, x)
+        }
+    }
+    return &out
+}`},
+	"sildFindIndexed": {source: `
+func sildFindIndexed[T any](xs []T, f func(T, float64) bool) T {
+    for i, x := range xs {
+        if f(x, float64(i)) {
+            return x
+        }
+    }
+    var zero T
+    return zero
+}`},
+	"sildFindPtrIndexed": {source: `
+func sildFindPtrIndexed[T any](xs []T, f func(T, float64) bool) *T {
+    for i, x := range xs {
+        if f(x, float64(i)) {
+            return &x
+        }
+    }
+    return nil
+}`},
+	"sildSomeIndexed": {source: `
+func sildSomeIndexed[T any](xs []T, f func(T, float64) bool) bool {
+    for i, x := range xs {
+        if f(x, float64(i)) {
+            return true
+        }
+    }
+    return false
+}`},
+	"sildEveryIndexed": {source: `
+func sildEveryIndexed[T any](xs []T, f func(T, float64) bool) bool {
+    for i, x := range xs {
+        if !f(x, float64(i)) {
+            return false
+        }
+    }
+    return true
+}`},
+	"sildForEachIndexed": {source: `
+func sildForEachIndexed[T any](xs []T, f func(T, float64)) {
+    for i, x := range xs {
+        f(x, float64(i))
+    }
+}`},
+	"sildSort": {imports: []string{"slices", "strings"}, helpers: []string{"sildString"}, source: `
+// sildSort sorts xs in place and returns it. Like Array.prototype.sort
+// without a comparator, elements are compared by their string form.
+func sildSort[T any](xs *[]T) *[]T {
+    slices.SortStableFunc(*xs, func(a, b T) int {
+        return strings.Compare(sildString(a), sildString(b))
+    })
+    return xs
+}`},
+	"sildString": {imports: []string{"math/big", "reflect", "strconv", "strings"}, helpers: []string{"sildNumberString"}, source: `
+// sildString converts v to a string the way JavaScript's String does, for
+// values whose type isn't known statically, such as the elements of arrays.
+// Arrays are joined with commas and other objects are "[object Object]".
+func sildString(v any) string {
+    switch x := v.(type) {
+    case nil:
+        return "undefined"
+    case *big.Int:
+        return x.String()
+    }
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.String:
+        return rv.String()
+    case reflect.Bool:
+        return strconv.FormatBool(rv.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(rv.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        return sildNumberString(rv.Float())
+    case reflect.Slice:
+        return sildJoinValues(rv, ",")
+    case reflect.Func:
+        return "function"
+    case reflect.Pointer:
+        switch {
+        case rv.IsNil():
+            return "undefined"
+        case rv.Elem().Kind() == reflect.Struct:
+            return "[object Object]"
+        }
+        return sildString(rv.Elem().Interface())
+    }
+    return "[object Object]"
+}
+
+// sildJoinValues converts the elements of the slice xs to strings and joins
+// them with sep. Like in Array.prototype.join, null and undefined elements
+// are empty.
+func sildJoinValues(xs reflect.Value, sep string) string {
+    parts := make([]string, xs.Len())
+    for i := range parts {
+        x := xs.Index(i)
+        switch x.Kind() {
+        case reflect.Pointer, reflect.Interface, reflect.Func:
+            if x.IsNil() {
+                continue
+            }
+        }
+        parts[i] = sildString(x.Interface())
+    }
+    return strings.Join(parts, sep)
+}`},
+	"sildNullString": {imports: []string{"reflect"}, helpers: []string{"sildString"}, source: `
+// sildNullString converts v, a value of a type with null but not undefined,
+// to a string like sildString, except that nil is "null".
+func sildNullString(v any) string {
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Pointer, reflect.Slice, reflect.Func:
+        if rv.IsNil() {
+            return "null"
+        }
+    }
+    return sildString(v)
+}`},
+	"sildTruthy": {imports: []string{"math", "math/big", "reflect"}, source: `
+// sildTruthy reports whether v, a value whose type isn't known statically,
+// is truthy: not undefined, null, false, "", 0, NaN or 0n.
+func sildTruthy(v any) bool {
+    switch x := v.(type) {
+    case nil:
+        return false
+    case *big.Int:
+        return x != nil && x.Sign() != 0
+    }
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Bool:
+        return rv.Bool()
+    case reflect.String:
+        return rv.String() != ""
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return rv.Int() != 0
+    case reflect.Float32, reflect.Float64:
+        return rv.Float() != 0 && !math.IsNaN(rv.Float())
+    case reflect.Slice, reflect.Map, reflect.Func:
+        return !rv.IsNil()
+    case reflect.Pointer:
+        switch {
+        case rv.IsNil():
+            return false
+        case rv.Elem().Kind() == reflect.Struct:
+            return true
+        }
+        return sildTruthy(rv.Elem().Interface())
+    }
+    return true
+}`},
+	"sildInspect": {imports: []string{"math", "math/big", "reflect", "runtime", "strconv", "strings", "unicode", "unicode/utf8"}, helpers: []string{"sildNumberString"}, source: `
+// sildInspect formats v the way console.log does: strings as they are, and
+// other values like util.inspect, with the elements of arrays and the
+// properties of objects, in which strings are quoted. nil is null, or
+// undefined where v can't be null; inside arrays and objects it is null,
+// and unset optional properties are left out.
+func sildInspect(v any, null string) string {
+    rv := reflect.ValueOf(v)
+    for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct && rv.Type() != reflect.TypeOf((*big.Int)(nil)) {
+        rv = rv.Elem()
+    }
+    switch rv.Kind() {
+    case reflect.Invalid:
+        return null
+    case reflect.Pointer, reflect.Slice, reflect.Func, reflect.Interface:
+        if rv.IsNil() {
+            return null
+        }
+    case reflect.String:
+        return rv.String()
+    }
+    return sildInspectValue(rv)
+}
+
+// sildInspectValue formats v like util.inspect, for sildInspect.
+func sildInspectValue(v reflect.Value) string {
+    switch v.Kind() {
+    case reflect.Invalid:
+        return "null"
+    case reflect.Interface:
+        if v.IsNil() {
+            return "null"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.Pointer:
+        switch {
+        case v.IsNil():
+            return "null"
+        case v.Type() == reflect.TypeOf((*big.Int)(nil)):
+            return v.Interface().(*big.Int).String() + "n"
+        }
+        return sildInspectValue(v.Elem())
+    case reflect.String:
+        // single quotes, unless the string has some and no double quotes
+        s, quote := v.String(), "'"
+        if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+            quote = "\""
+        }
+        return quote + strings.NewReplacer("\\", "\\\\", quote, "\\"+quote, "\n", "\\n").Replace(s) + quote
+    case reflect.Bool:
+        return strconv.FormatBool(v.Bool())
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(v.Int(), 10)
+    case reflect.Float32, reflect.Float64:
+        if x := v.Float(); x == 0 && math.Signbit(x) {
+            return "-0"
+        }
+        return sildNumberString(v.Float())
+    case reflect.Slice:
+        if v.IsNil() {
+            return "null"
+        }
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = sildInspectValue(v.Index(i))
+        }
+        return sildInspectList("[", parts, "]")
+    case reflect.Func:
+        if v.IsNil() {
+            return "null"
+        }
+        name := runtime.FuncForPC(v.Pointer()).Name()
+        name = name[strings.LastIndex(name, ".")+1:]
+        if strings.HasPrefix(name, "func") && strings.TrimLeft(name[4:], "0123456789") == "" {
+            return "[Function (anonymous)]"
+        }
+        return "[Function: " + name + "]"
+    case reflect.Struct:
+        parts := sildInspectFields(v, nil)
+        // classes, unlike object types, have no JSON names for their fields
+        if v.Type().Name() != "" && (v.NumField() == 0 || v.Type().Field(0).Tag.Get("json") == "") {
+            return v.Type().Name() + " " + sildInspectList("{", parts, "}")
+        }
+        return sildInspectList("{", parts, "}")
+    }
+    return "[object Object]"
+}
+
+// sildInspectFields appends to parts the properties of the struct v, with
+// those of embedded base classes first.
+func sildInspectFields(v reflect.Value, parts []string) []string {
+    for i := 0; i < v.NumField(); i++ {
+        f, x := v.Type().Field(i), v.Field(i)
+        switch {
+        case f.Anonymous && x.Kind() == reflect.Pointer:
+            if !x.IsNil() {
+                parts = sildInspectFields(x.Elem(), parts)
+            }
+            continue
+        case !f.IsExported():
+            continue
+        }
+        name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+        if name == "" {
+            r, n := utf8.DecodeRuneInString(f.Name)
+            name = string(unicode.ToLower(r)) + f.Name[n:]
+        }
+        if opts == "omitempty" && x.IsZero() {
+            continue
+        }
+        parts = append(parts, name+": "+sildInspectValue(x))
+    }
+    return parts
+}
+
+// sildInspectList formats parts between the brackets open and close.
+func sildInspectList(open string, parts []string, close string) string {
+    if len(parts) == 0 {
+        return open + close
+    }
+    return open + " " + strings.Join(parts, ", ") + " " + close
+}`},
+	"sildTypeof": {imports: []string{"math/big", "reflect"}, source: `
+// sildTypeof returns what typeof evaluates to for v, a value of a union type.
+func sildTypeof(v any) string {
+    switch v.(type) {
+    case string:
+        return "string"
+    case float64, int:
+        return "number"
+    case *big.Int:
+        return "bigint"
+    case bool:
+        return "boolean"
+    case nil:
+        return "undefined"
+    }
+    if reflect.ValueOf(v).Kind() == reflect.Func {
+        return "function"
+    }
+    return "object"
+}`},
+	"sildTypeofPtr": {source: `
+// sildTypeofPtr returns what typeof evaluates to for p, a pointer to a value
+// whose typeof is name.
+func sildTypeofPtr[T any](p *T, name string) string {
+    if p == nil {
+        return "undefined"
+    }
+    return name
+}`},
+	"sildKeys": {source: `
+// sildKeys returns keys, the keys of the object o. Go knows them statically;
+// o is only taken so that it is still evaluated.
+func sildKeys[T any](o T, keys ...string) []string {
+    return keys
+}`},
+	"sildPresentKeys": {imports: []string{"reflect", "strings"}, source: `
+// sildPresentKeys returns the keys of the object o, leaving out the optional
+// properties it doesn't have, which are the nil fields tagged omitempty.
+func sildPresentKeys[T any](o T) []string {
+    v := reflect.Indirect(reflect.ValueOf(o))
+    keys := []string{}
+    for i := range v.NumField() {
+        name, opts, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+        if opts == "omitempty" && v.Field(i).IsNil() {
+            continue
+        }
+        keys = append(keys, name)
+    }
+    return keys
+}`},
+	"sildSortFunc": {imports: []string{"slices"}, source: `
+// sildSortFunc sorts xs in place by the sign of cmp and returns it.
+func sildSortFunc[T any, N int | float64](xs *[]T, cmp func(T, T) N) *[]T {
+    slices.SortStableFunc(*xs, func(a, b T) int {
+        switch c := cmp(a, b); {
+        case c < 0:
+            return -1
+        case c > 0:
+            return 1
+        }
+        return 0
+    })
+    return xs
+}`},
+}
+
+// useHelper records that the generated code calls the named helper, and so
+// the helpers it calls.
+func (g *Generator) useHelper(name string) {
+	g.helpers[name] = true
+	for _, path := range helpers[name].imports {
+		g.use(path)
+	}
+	for _, h := range helpers[name].helpers {
+		g.useHelper(h)
+	}
+}
+
+func (g *Generator) writeHelpers(builder *strings.Builder) {
+	names := make([]string, 0, len(g.helpers))
+	for name := range g.helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		builder.WriteString(helpers[name].source)
+		builder.WriteString("\n")
+	}
+}
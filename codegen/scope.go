@@ -0,0 +1,159 @@
+package codegen
+
+import (
+	"go/constant"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// scope maps the variables visible at a point of the program to their
+// declared TypeScript types, so that the generator can pick a lowering that
+// depends on the type of an operand. consts holds the variables lowered to
+// Go constants, values the values of those Go can evaluate, kinds
+// the Go kind of number variables that aren't float64,
+// narrowed the declarations of the variables narrowed to int, unions
+// the variables of union types narrowed by a condition, and temps the
+// temporary variables of the generated code, whose names aren't mangled.
+type scope struct {
+	parent   *scope
+	types    map[string]ast.TypeExpr
+	consts   map[string]bool
+	values   map[string]constValue
+	kinds    map[string]numKind
+	narrowed map[string]*ast.VariableDeclaration
+	unions   map[string]*narrowedVar
+	temps    map[string]bool
+}
+
+func (g *Generator) pushScope() {
+	g.scope = &scope{
+		parent:   g.scope,
+		types:    map[string]ast.TypeExpr{},
+		consts:   map[string]bool{},
+		values:   map[string]constValue{},
+		kinds:    map[string]numKind{},
+		narrowed: map[string]*ast.VariableDeclaration{},
+		unions:   map[string]*narrowedVar{},
+		temps:    map[string]bool{},
+	}
+}
+
+func (g *Generator) popScope() {
+	g.scope = g.scope.parent
+}
+
+func (g *Generator) declare(name string, t ast.TypeExpr) {
+	g.scope.types[name] = t
+	delete(g.scope.consts, name)
+	delete(g.scope.values, name)
+	delete(g.scope.kinds, name)
+	delete(g.scope.narrowed, name)
+	delete(g.scope.unions, name)
+	delete(g.scope.temps, name)
+}
+
+// declareTemp declares a temporary variable of the generated code, such as
+// _v1, which is written as is rather than mangled like a TypeScript
+// identifier.
+func (g *Generator) declareTemp(name string, t ast.TypeExpr) {
+	g.declare(name, t)
+	g.scope.temps[name] = true
+}
+
+// declareConst declares a Go constant, whose kind is that of its
+// initializer if it is an untyped number, with the value of its initializer.
+func (g *Generator) declareConst(name string, t ast.TypeExpr, kind numKind, init ast.Expression) {
+	n, ok := g.constantValue(init)
+	g.declare(name, t)
+	g.scope.consts[name] = true
+	g.scope.kinds[name] = kind
+	if !ok {
+		return
+	}
+	// typed constants are rounded to float64
+	if kind == floatNum && n.isNumber() {
+		n.exact = constant.MakeFloat64(n.value)
+	}
+	g.scope.values[name] = n
+}
+
+// declareInt declares a number variable narrowed to int.
+func (g *Generator) declareInt(varDec *ast.VariableDeclaration, t ast.TypeExpr) {
+	g.declare(varDec.Name, t)
+	g.scope.kinds[varDec.Name] = intNum
+	g.scope.narrowed[varDec.Name] = varDec
+}
+
+func (g *Generator) lookup(name string) ast.TypeExpr {
+	for s := g.scope; s != nil; s = s.parent {
+		if t, ok := s.types[name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// isConst reports whether name refers to a Go constant.
+func (g *Generator) isConst(name string) bool {
+	for s := g.scope; s != nil; s = s.parent {
+		if _, ok := s.types[name]; ok {
+			return s.consts[name]
+		}
+	}
+	return false
+}
+
+// lookupValue returns the value of the constant name, reporting false if it
+// isn't a constant Go can evaluate.
+func (g *Generator) lookupValue(name string) (constValue, bool) {
+	for s := g.scope; s != nil; s = s.parent {
+		if _, ok := s.types[name]; ok {
+			n, ok := s.values[name]
+			return n, ok
+		}
+	}
+	return constValue{}, false
+}
+
+// lookupKind returns the Go kind of the number variable name.
+func (g *Generator) lookupKind(name string) numKind {
+	for s := g.scope; s != nil; s = s.parent {
+		if _, ok := s.types[name]; ok {
+			return s.kinds[name]
+		}
+	}
+	return floatNum
+}
+
+// lookupNarrowed returns the declaration of the variable name if it is
+// narrowed to int, and nil otherwise.
+func (g *Generator) lookupNarrowed(name string) *ast.VariableDeclaration {
+	for s := g.scope; s != nil; s = s.parent {
+		if _, ok := s.types[name]; ok {
+			return s.narrowed[name]
+		}
+	}
+	return nil
+}
+
+// lookupUnion returns how the variable name of a union type is narrowed, or
+// nil if it isn't.
+func (g *Generator) lookupUnion(name string) *narrowedVar {
+	for s := g.scope; s != nil; s = s.parent {
+		if _, ok := s.types[name]; ok {
+			return s.unions[name]
+		}
+	}
+	return nil
+}
+
+// isTemp reports whether name refers to a temporary variable of the
+// generated code.
+func (g *Generator) isTemp(name string) bool {
+	for s := g.scope; s != nil; s = s.parent {
+		if _, ok := s.types[name]; ok {
+			return s.temps[name]
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// generateTemplateLiteral lowers a template literal to the concatenation of
+// its text with its substitutions converted to strings.
+func (g *Generator) generateTemplateLiteral(t *ast.TemplateLiteral) string {
+	var parts []string
+	for i, quasi := range t.Quasis {
+		if i > 0 {
+			parts = append(parts, g.generateString(t.Expressions[i-1]))
+		}
+		if quasi != "" {
+			parts = append(parts, strconv.Quote(quasi))
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return `""`
+	case 1:
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " + ") + ")"
+}
+
+// generateString generates expr converted to a string the way TypeScript
+// converts values concatenated with strings. Values of other types than
+// primitives, such as arrays, objects and nullable values, are converted at
+// run time.
+func (g *Generator) generateString(expr ast.Expression) string {
+	t := g.typeOf(expr)
+	switch {
+	case g.isString(t):
+		return g.generateExpression(expr)
+	case isNumber(t):
+		if g.numKind(expr) == intNum {
+			g.use("strconv")
+			return fmt.Sprintf("strconv.Itoa(%s)", g.generateExpression(expr))
+		}
+		g.useHelper("sildNumberString")
+		return fmt.Sprintf("sildNumberString(%s)", g.generateNumber(expr, false))
+	case isBigInt(t):
+		return g.generateExpression(expr) + ".String()"
+	case g.isNumericEnum(t):
+		// the String method of an enum returns the name of a member
+		g.use("strconv")
+		return fmt.Sprintf("strconv.Itoa(%s)", conversion("int", expr, g.generateExpression(expr)))
+	case g.isStringEnum(t):
+		return conversion("string", expr, g.generateExpression(expr))
+	case typeName(t) == "boolean":
+		if lit, ok := expr.(*ast.BooleanLiteral); ok {
+			return strconv.Quote(lit.Token.Literal)
+		}
+		g.use("strconv")
+		return fmt.Sprintf("strconv.FormatBool(%s)", g.generateCondition(expr))
+	case g.isString(g.primitiveConstraint(t)):
+		return conversion("string", expr, g.generateExpression(expr))
+	case isNumber(g.primitiveConstraint(t)):
+		g.useHelper("sildNumberString")
+		return fmt.Sprintf("sildNumberString(float64(%s))", g.generateExpression(expr))
+	}
+	if g.nilIsNull(t) {
+		g.useHelper("sildNullString")
+		return fmt.Sprintf("sildNullString(%s)", g.generateExpression(expr))
+	}
+	g.useHelper("sildString")
+	return fmt.Sprintf("sildString(%s)", g.generateExpression(expr))
+}
+
+// generateStringMethodCall lowers a call of a String.prototype method to the
+// strings package or to a runtime helper. Indices count UTF-16 code units
+// like they do in TypeScript, rather than the bytes Go indexes strings by.
+// It reports false if the call can't be lowered.
+func (g *Generator) generateStringMethodCall(receiver ast.Expression, method string, callArgs []ast.Expression) (string, bool) {
+	s := g.generateExpression(receiver)
+
+	args := make([]string, len(callArgs))
+	for i, arg := range callArgs {
+		if isNumber(g.typeOf(arg)) {
+			args[i] = g.generateIndex(arg)
+		} else {
+			args[i] = g.generateExpression(arg)
+		}
+	}
+	argList := strings.Join(args, ", ")
+
+	switch n := len(args); {
+	case method == "toUpperCase" && n == 0:
+		g.use("strings")
+		return fmt.Sprintf("strings.ToUpper(%s)", s), true
+	case method == "toLowerCase" && n == 0:
+		g.use("strings")
+		return fmt.Sprintf("strings.ToLower(%s)", s), true
+	case method == "trim" && n == 0:
+		g.use("strings")
+		return fmt.Sprintf("strings.TrimSpace(%s)", s), true
+	case method == "trimStart" && n == 0:
+		g.use("strings")
+		g.use("unicode")
+		return fmt.Sprintf("strings.TrimLeftFunc(%s, unicode.IsSpace)", s), true
+	case method == "trimEnd" && n == 0:
+		g.use("strings")
+		g.use("unicode")
+		return fmt.Sprintf("strings.TrimRightFunc(%s, unicode.IsSpace)", s), true
+	case method == "split" && n == 0:
+		return fmt.Sprintf("&[]string{%s}", s), true
+	case method == "split" && n == 1:
+		g.useHelper("sildSplit")
+		return fmt.Sprintf("sildSplit(%s, %s)", s, argList), true
+	case method == "startsWith" && n == 1:
+		g.use("strings")
+		return fmt.Sprintf("strings.HasPrefix(%s, %s)", s, argList), true
+	case method == "startsWith" && n == 2:
+		g.use("strings")
+		g.useHelper("sildSubstring")
+		return fmt.Sprintf("strings.HasPrefix(sildSubstring(%s, %s), %s)", s, args[1], args[0]), true
+	case method == "endsWith" && n == 1:
+		g.use("strings")
+		return fmt.Sprintf("strings.HasSuffix(%s, %s)", s, argList), true
+	case method == "endsWith" && n == 2:
+		g.use("strings")
+		g.useHelper("sildSubstring")
+		return fmt.Sprintf("strings.HasSuffix(sildSubstring(%s, 0, %s), %s)", s, args[1], args[0]), true
+	case method == "includes" && n == 1:
+		g.use("strings")
+		return fmt.Sprintf("strings.Contains(%s, %s)", s, argList), true
+	case method == "includes" && n == 2:
+		g.useHelper("sildStringIndexOf")
+		return fmt.Sprintf("(sildStringIndexOf(%s, %s) >= 0)", s, argList), true
+	case method == "indexOf" && n <= 2:
+		g.useHelper("sildStringIndexOf")
+		return helperCall("sildStringIndexOf", s, args), true
+	case method == "lastIndexOf" && n == 1:
+		g.useHelper("sildStringLastIndexOf")
+		return fmt.Sprintf("sildStringLastIndexOf(%s, %s)", s, argList), true
+	case method == "replace" && n == 2:
+		g.use("strings")
+		return fmt.Sprintf("strings.Replace(%s, %s, 1)", s, argList), true
+	case method == "replaceAll" && n == 2:
+		g.use("strings")
+		return fmt.Sprintf("strings.ReplaceAll(%s, %s)", s, argList), true
+	case (method == "padStart" || method == "padEnd") && n <= 2:
+		fill := `" "`
+		if n == 2 {
+			fill = args[1]
+		}
+		g.useHelper("sildPad")
+		return fmt.Sprintf("sildPad(%s, %s, %s, %t)", s, args[0], fill, method == "padStart"), true
+	case method == "repeat" && n == 1:
+		g.use("strings")
+		return fmt.Sprintf("strings.Repeat(%s, %s)", s, argList), true
+	case method == "charAt" && n <= 1:
+		g.useHelper("sildCharAt")
+		return fmt.Sprintf("sildCharAt(%s, %s)", s, firstOr(args, "0")), true
+	case method == "charCodeAt" && n <= 1:
+		g.useHelper("sildCharCodeAt")
+		return fmt.Sprintf("sildCharCodeAt(%s, %s)", s, firstOr(args, "0")), true
+	case method == "substring" && (n == 1 || n == 2):
+		g.useHelper("sildSubstring")
+		return helperCall("sildSubstring", s, args), true
+	case method == "slice" && n <= 2:
+		g.useHelper("sildStringSlice")
+		return helperCall("sildStringSlice", s, args), true
+	}
+
+	return "", false
+}
+
+func firstOr(args []string, def string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return def
+}
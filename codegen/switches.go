@@ -0,0 +1,278 @@
+package codegen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// caseGroup is a run of case clauses of a switch statement sharing a body:
+// all of them but the last are empty and fall through to it. A body that
+// can complete normally falls through to the body of the next group.
+type caseGroup struct {
+	clauses      []*ast.CaseClause
+	body         []Statement
+	fallsThrough bool
+}
+
+// caseGroups splits the clauses of s into groups sharing a body, without
+// the break ending it, which Go doesn't need.
+func caseGroups(s *ast.SwitchStatement) []caseGroup {
+	var groups []caseGroup
+	var clauses []*ast.CaseClause
+	for i, clause := range s.Cases {
+		clauses = append(clauses, clause)
+		last := i == len(s.Cases)-1
+		if len(clause.Body) == 0 && !last {
+			continue
+		}
+		groups = append(groups, caseGroup{
+			clauses:      clauses,
+			body:         withoutBreak(clause.Body),
+			fallsThrough: !last && !exits(&ast.BlockStatement{Statements: clause.Body}),
+		})
+		clauses = nil
+	}
+	return groups
+}
+
+// withoutBreak returns body without the break statement ending it.
+func withoutBreak(body []Statement) []Statement {
+	if len(body) == 0 {
+		return body
+	}
+	if b, ok := body[len(body)-1].(*ast.BranchStatement); ok && b.Token.Type == token.BREAK && b.Label == nil {
+		return body[:len(body)-1]
+	}
+	return body
+}
+
+// switchClause is a clause of the Go switch a switch statement is lowered
+// to: a group of case clauses with the Go code of their cases.
+type switchClause struct {
+	caseGroup
+	tests     []string
+	isDefault bool
+}
+
+// switchClauses generates the cases of the groups of a switch statement
+// with generateCase. Go rejects constant cases of the same value, which
+// can't match in TypeScript either, so all but the first are dropped, and a group left without cases,
+// which can only be reached by falling through to it, is joined to the
+// group before it.
+func (g *Generator) switchClauses(groups []caseGroup, generateCase func(ast.Expression) string) []*switchClause {
+	var clauses []*switchClause
+	constants := map[string]bool{}
+	for _, group := range groups {
+		clause := &switchClause{caseGroup: group}
+		for _, c := range group.clauses {
+			if c.Test == nil {
+				clause.isDefault = true
+				continue
+			}
+			if v, ok := g.constantValue(c.Test); ok && g.isConstantCase(c.Test) {
+				if constants[v.key()] {
+					continue
+				}
+				constants[v.key()] = true
+			}
+			clause.tests = append(clause.tests, generateCase(c.Test))
+		}
+
+		var prev *switchClause
+		if len(clauses) > 0 {
+			prev = clauses[len(clauses)-1]
+		}
+		switch {
+		case len(clause.tests) == 0 && !clause.isDefault && (prev == nil || !prev.fallsThrough):
+			// unreachable
+			continue
+		case len(clause.tests) == 0 && !clause.isDefault:
+			prev.body = append(slices.Clip(prev.body), clause.body...)
+			prev.fallsThrough = clause.fallsThrough
+			continue
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses
+}
+
+// isConstantCase reports whether Go sees the case test as a constant.
+func (g *Generator) isConstantCase(test ast.Expression) bool {
+	if e, _ := g.enumMember(test); e != nil {
+		return true
+	}
+	return g.isConstant(test)
+}
+
+// isPureCase reports whether evaluating the case test has no effects.
+func (g *Generator) isPureCase(test ast.Expression) bool {
+	return isPure(test) || g.isConstantCase(test)
+}
+
+// generateSwitchStatement lowers a switch statement to a Go switch. Like in
+// TypeScript, Go evaluates the cases in order and picks the default clause,
+// wherever it is, if none matches, and a clause whose body can complete
+// normally ends with fallthrough. Clauses sharing a body share a case, and
+// each body is narrowed by the cases leading to it. A switch covering every
+// member of a union without a default clause gets one that panics, so that
+// Go sees it is exhaustive too.
+func (g *Generator) generateSwitchStatement(s *ast.SwitchStatement) string {
+	groups := caseGroups(s)
+	exhaustive := g.exhaustive[s]
+	if code, ok := g.generateTypeSwitch(s, groups, exhaustive); ok {
+		return code
+	}
+
+	discriminant := g.typeOf(s.Discriminant)
+	// numbers are compared as ints if they all are ints
+	integer := isNumber(discriminant) && g.numKind(s.Discriminant) == intNum
+	for _, clause := range s.Cases {
+		if clause.Test != nil && !g.numKind(clause.Test).integral() {
+			integer = false
+		}
+	}
+	value := g.generateExpression(s.Discriminant)
+	if typeName(discriminant) == "boolean" {
+		value = g.generateCondition(s.Discriminant)
+	}
+	if g.numKind(s.Discriminant) == intNum && !integer {
+		value = g.generateNumber(s.Discriminant, false)
+	}
+	generateCase := func(test ast.Expression) string {
+		if integer {
+			return g.generateNumber(test, true)
+		}
+		return g.generateExpressionAs(test, discriminant)
+	}
+
+	header := fmt.Sprintf("switch %s {\n", value)
+	if g.isPointer(discriminant) {
+		// a value stored as a pointer is compared by what it points to, in
+		// a switch without a tag
+		value = g.generateExpression(s.Discriminant)
+		header = "switch {\n"
+		if !isPure(s.Discriminant) {
+			header = fmt.Sprintf("switch _v := %s; {\n", indentRest(value))
+			value = "_v"
+		}
+		generateCase = func(test ast.Expression) string {
+			if g.isNull(g.typeOf(test)) {
+				return value + " == nil"
+			}
+			g.useHelper("sildEqualPtr")
+			return fmt.Sprintf("sildEqualPtr(%s, %s)", value, g.generateExpressionAs(test, discriminant))
+		}
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(header)
+	for _, clause := range g.switchClauses(groups, generateCase) {
+		label := "case " + strings.Join(clause.tests, ", ")
+		if clause.isDefault {
+			// a case sharing its body with the default clause needs no
+			// test, unless evaluating it has effects
+			if !all(clause.clauses, func(c *ast.CaseClause) bool { return c.Test == nil || g.isPureCase(c.Test) }) {
+				builder.WriteString(label + ":\n" + indent + "fallthrough\n")
+			}
+			label = "default"
+		}
+		builder.WriteString(label + ":\n")
+		builder.WriteString(g.generateNarrowedBlock(clause.body, g.narrowings[clause.clauses[len(clause.clauses)-1]]))
+		if clause.fallsThrough {
+			builder.WriteString(indent + "fallthrough\n")
+		}
+	}
+	if exhaustive {
+		builder.WriteString("default:\n" + indent + "panic(\"unreachable\")\n")
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+func all[T any](list []T, f func(T) bool) bool {
+	for _, x := range list {
+		if !f(x) {
+			return false
+		}
+	}
+	return true
+}
+
+// generateTypeSwitch lowers a switch on the discriminant of a variable of a
+// discriminated union to a type switch on the variable, reporting false if
+// s isn't one. In the clauses of a single variant the variable is bound to
+// its value as that variant. Go's type switches can't fall through, so a
+// switch with clauses falling through to the next is left to a switch on
+// the value of the discriminant, which narrows the variable with type
+// assertions where it is used.
+func (g *Generator) generateTypeSwitch(s *ast.SwitchStatement, groups []caseGroup, exhaustive bool) (string, bool) {
+	member, ok := s.Discriminant.(*ast.MemberExpression)
+	if !ok || slices.ContainsFunc(groups, func(group caseGroup) bool { return group.fallsThrough }) {
+		return "", false
+	}
+	v, ok := member.Object.(*ast.VariableExpression)
+	u := g.discriminantOf(member)
+	if !ok || u == nil || g.unionOf(g.typeOf(v)) == nil {
+		return "", false
+	}
+	for _, clause := range s.Cases {
+		if _, ok := clause.Test.(*ast.StringLiteral); clause.Test != nil && !ok {
+			return "", false
+		}
+	}
+
+	name := v.Token.Literal
+	bound := false
+	seen := map[string]bool{}
+	clauses := strings.Builder{}
+	for _, group := range groups {
+		var types []string
+		label := ""
+		for _, clause := range group.clauses {
+			if clause.Test == nil {
+				label = "default"
+				continue
+			}
+			// a repeated case can't match
+			value := clause.Test.(*ast.StringLiteral).Token.Literal
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			for _, variant := range u.variants {
+				if variant.value == value {
+					types = append(types, "*"+variant.goName)
+				}
+			}
+		}
+		switch {
+		case label != "":
+		case len(types) == 0:
+			// no variant has the discriminant, so the clause is unreachable
+			continue
+		default:
+			label = "case " + strings.Join(types, ", ")
+		}
+
+		g.pushNarrowing(g.narrowings[group.clauses[len(group.clauses)-1]], label != "default" && len(types) == 1)
+		body := g.generateBlock(group.body)
+		if nv := g.scope.unions[name]; nv != nil && nv.shadow && nv.used {
+			bound = true
+		}
+		g.popScope()
+		clauses.WriteString(label + ":\n" + body)
+	}
+	if exhaustive {
+		clauses.WriteString("default:\n" + indent + "panic(\"unreachable\")\n")
+	}
+
+	header := fmt.Sprintf("switch %s.(type) {\n", g.goName(name))
+	if bound {
+		header = fmt.Sprintf("switch %s := %s.(type) {\n", g.goName(name), g.goName(name))
+	}
+	return header + clauses.String() + "}", true
+}
@@ -0,0 +1,127 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// goType maps a TypeScript type annotation to the Go type it is lowered to.
+// void maps to the empty string.
+func (g *Generator) goType(t ast.TypeExpr) string {
+	// arrays are references in TypeScript, so that they can grow through
+	// any of them
+	if elem := ast.ElementType(t); elem != nil {
+		return "*[]" + g.goType(elem)
+	}
+	// objects are references in TypeScript, like arrays and instances of
+	// classes
+	if obj, ok := t.(*ast.ObjectType); ok {
+		if v := g.variants[obj]; v != nil {
+			return "*" + v.goName
+		}
+		return "*" + g.inlineStructType(obj)
+	}
+	if fn, ok := t.(*ast.FunctionType); ok {
+		return g.generateFunctionType(fn)
+	}
+	switch t.(type) {
+	case *ast.LiteralType:
+		return "string"
+	case *ast.UnionType:
+		if g.isNullable(t) {
+			return g.nullableGoType(t)
+		}
+		// only unions of strings have a Go type of their own
+		if g.isString(t) {
+			return "string"
+		}
+		return "any"
+	}
+
+	if p := g.typeParam(t); p != nil {
+		return g.goName(p.Name.Literal)
+	}
+
+	switch name := typeName(t); name {
+	case "number":
+		return "float64"
+	case "bigint":
+		g.use("math/big")
+		return "*big.Int"
+	case "string":
+		return "string"
+	case "boolean":
+		return "bool"
+	case "void":
+		return ""
+	case "", "any", "unknown", "null", "undefined":
+		return "any"
+	default:
+		// a type declared in the program, instantiated with the type
+		// arguments of t if it is generic
+		name = g.goName(name)
+		if args := t.(*ast.TypeReference).Args; len(args) > 0 {
+			list := make([]string, len(args))
+			for i, a := range args {
+				list[i] = g.goType(a)
+			}
+			name += "[" + strings.Join(list, ", ") + "]"
+		}
+		if g.classOf(t) != nil || g.isStruct(t) {
+			return "*" + name
+		}
+		return name
+	}
+}
+
+// typeName returns the name of a type reference without type arguments, or
+// "" for other types.
+func typeName(t ast.TypeExpr) string {
+	if ref, ok := t.(*ast.TypeReference); ok {
+		return ref.Name.Literal
+	}
+	return ""
+}
+
+// isString reports whether values of type t are strings: t is string, a
+// string literal type, or a union or an alias of them.
+func (g *Generator) isString(t ast.TypeExpr) bool {
+	if g.typeParam(t) != nil {
+		return false
+	}
+	for _, m := range g.unionMembers(t) {
+		r := g.resolveType(m)
+		if _, ok := r.(*ast.LiteralType); !ok && typeName(r) != "string" {
+			return false
+		}
+	}
+	return true
+}
+
+// isStruct reports whether t names an interface or an alias of an object
+// type, which is declared as a Go struct.
+func (g *Generator) isStruct(t ast.TypeExpr) bool {
+	name := typeName(t)
+	_, ok := g.typeDecls[name].(*ast.ObjectType)
+	return ok && !g.constraints[name]
+}
+
+func isNumber(t ast.TypeExpr) bool {
+	return typeName(t) == "number"
+}
+
+func isArray(t ast.TypeExpr) bool {
+	return ast.ElementType(t) != nil
+}
+
+func primitiveType(name string) ast.TypeExpr {
+	return &ast.TypeReference{Name: token.Token{Type: token.LookupType(name), Literal: name}}
+}
+
+// typeOf returns the TypeScript type the type checker found for expr, or nil
+// if it isn't known.
+func (g *Generator) typeOf(expr ast.Expression) ast.TypeExpr {
+	return g.types[expr]
+}
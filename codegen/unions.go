@@ -0,0 +1,563 @@
+package codegen
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// discriminatedUnion is a union of object types that all have a property of
+// a different string literal type, its discriminant, like the kind of
+// { kind: "circle"; r: number } | { kind: "square"; side: number }. It is
+// lowered to a sealed Go interface implemented by one struct per variant,
+// which returns the discriminant from a method rather than storing it.
+type discriminatedUnion struct {
+	name     string
+	tag      string
+	variants []*variant
+}
+
+// variant is a member of a discriminated union. Members written inline are
+// named after the union and their discriminant, and those referring to an
+// interface or a type alias keep its name.
+type variant struct {
+	union  *discriminatedUnion
+	value  string
+	typ    ast.TypeExpr
+	obj    *ast.ObjectType
+	goName string
+	inline bool
+}
+
+// collectUnions finds the top-level type aliases that are discriminated
+// unions. Go can't declare methods of local types, so unions declared in
+// functions are left as any.
+func (g *Generator) collectUnions(stmts []Statement) {
+	for _, stmt := range stmts {
+		alias, ok := stmt.(*ast.TypeAliasDeclaration)
+		if !ok || len(alias.TypeParams) > 0 {
+			continue
+		}
+		u := g.discriminate(alias.Name.Literal, alias.Type)
+		if u == nil {
+			continue
+		}
+		g.unions[u.name] = u
+		for _, v := range u.variants {
+			if g.variants[v.obj] == nil {
+				g.variants[v.obj] = v
+			}
+		}
+	}
+}
+
+// discriminate returns the discriminated union the type alias name declares,
+// or nil if t isn't one. The discriminant is the first property of the first
+// member with a distinct string literal type in all of them.
+func (g *Generator) discriminate(name string, t ast.TypeExpr) *discriminatedUnion {
+	if _, ok := t.(*ast.UnionType); !ok {
+		return nil
+	}
+	members := g.unionMembers(t)
+	objs := make([]*ast.ObjectType, len(members))
+	for i, m := range members {
+		if ref, ok := m.(*ast.TypeReference); ok && len(ref.Args) > 0 {
+			return nil
+		}
+		if objs[i] = g.objectType(m); objs[i] == nil {
+			return nil
+		}
+	}
+
+	for _, p := range objs[0].Members {
+		values := g.discriminantValues(p.Name.Literal, objs)
+		if values == nil {
+			continue
+		}
+		u := &discriminatedUnion{name: name, tag: p.Name.Literal}
+		for i, m := range members {
+			v := &variant{union: u, value: values[i], typ: m, obj: objs[i]}
+			if ref, ok := m.(*ast.TypeReference); ok {
+				v.goName = g.goName(ref.Name.Literal)
+			} else {
+				v.goName, v.inline = g.goName(name)+fieldName(values[i]), true
+			}
+			u.variants = append(u.variants, v)
+		}
+		return u
+	}
+	return nil
+}
+
+// discriminantValues returns the values of the property tag in each of objs,
+// or nil if it doesn't have a different string literal type in each. A
+// variant of another union must have the same discriminant in both.
+func (g *Generator) discriminantValues(tag string, objs []*ast.ObjectType) []string {
+	values := make([]string, len(objs))
+	for i, obj := range objs {
+		m := obj.Member(tag)
+		if m == nil || m.Optional {
+			return nil
+		}
+		lit, ok := g.resolveType(m.Type).(*ast.LiteralType)
+		if !ok || slices.Contains(values[:i], lit.Token.Literal) {
+			return nil
+		}
+		if v := g.variants[obj]; v != nil && v.union.tag != tag {
+			return nil
+		}
+		values[i] = lit.Token.Literal
+	}
+	return values
+}
+
+// generateUnionDeclaration generates the sealed interface of a discriminated
+// union: a method returning the discriminant and an unexported marker method
+// that only its variants implement. It is followed by the structs of the
+// variants written inline and the methods of all of them.
+func (g *Generator) generateUnionDeclaration(u *discriminatedUnion) string {
+	name, method, marker := g.goName(u.name), fieldName(u.tag), "is"+g.goName(u.name)
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("type %s interface {\n%s%s() string\n%s%s()\n}\n", name, indent, method, indent, marker))
+	for _, v := range u.variants {
+		builder.WriteString("\n")
+		if v.inline {
+			builder.WriteString(fmt.Sprintf("type %s %s\n\n", v.goName, g.structType(v.obj)))
+		}
+		// a variant of several unions returns its discriminant once
+		if g.variants[v.obj] == v {
+			builder.WriteString(fmt.Sprintf("func (%s) %s() string { return %s }\n", v.goName, method, strconv.Quote(v.value)))
+		}
+		builder.WriteString(fmt.Sprintf("func (%s) %s() {}\n", v.goName, marker))
+	}
+	return builder.String()
+}
+
+// unionOf returns the discriminated union t refers to, or nil if it doesn't
+// refer to one.
+func (g *Generator) unionOf(t ast.TypeExpr) *discriminatedUnion {
+	for range len(g.typeDecls) + 1 {
+		ref, ok := t.(*ast.TypeReference)
+		if !ok || g.typeParam(ref) != nil {
+			return nil
+		}
+		if u := g.unions[ref.Name.Literal]; u != nil {
+			return u
+		}
+		t = g.typeDecls[ref.Name.Literal]
+	}
+	return nil
+}
+
+// discriminantOf returns the discriminated union whose discriminant e reads,
+// from a value of the union or of one of its variants, or nil if e doesn't
+// read one.
+func (g *Generator) discriminantOf(e *ast.MemberExpression) *discriminatedUnion {
+	t := g.typeOf(e.Object)
+	u := g.unionOf(t)
+	if v := g.variants[g.objectType(t)]; u == nil && v != nil {
+		u = v.union
+	}
+	if u == nil || u.tag != e.Property.String() {
+		return nil
+	}
+	return u
+}
+
+// literalVariant returns the variant of the discriminated union expected an
+// object literal is written for, by the value of its discriminant, or nil if
+// expected isn't a discriminated union.
+func (g *Generator) literalVariant(lit *ast.ObjectLiteral, expected ast.TypeExpr) *variant {
+	u := g.unionOf(expected)
+	if u == nil {
+		return nil
+	}
+	for _, prop := range lit.Properties {
+		if prop.Key.Literal != u.tag {
+			continue
+		}
+		if s, ok := prop.Value.(*ast.StringLiteral); ok {
+			for _, v := range u.variants {
+				if v.value == s.Token.Literal {
+					return v
+				}
+			}
+		}
+	}
+	g.errorf(lit, "cannot tell which member of '%s' the object literal is: its '%s' must be a string literal naming one", u.name, u.tag)
+	return nil
+}
+
+// structMembers returns the members of obj stored in its struct: all of them
+// but the discriminant of a variant, which is a method instead.
+func (g *Generator) structMembers(obj *ast.ObjectType) []*ast.PropertySignature {
+	v := g.variants[obj]
+	if v == nil {
+		return obj.Members
+	}
+	var members []*ast.PropertySignature
+	for _, m := range obj.Members {
+		if m.Name.Literal != v.union.tag {
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// unionMembers returns the types t is a union of, following type aliases, or
+// t itself if it isn't a union.
+func (g *Generator) unionMembers(t ast.TypeExpr) []ast.TypeExpr {
+	return g.unionMembersDepth(t, 0)
+}
+
+func (g *Generator) unionMembersDepth(t ast.TypeExpr, depth int) []ast.TypeExpr {
+	u, ok := g.resolveType(t).(*ast.UnionType)
+	if !ok || depth > len(g.typeDecls) {
+		return []ast.TypeExpr{t}
+	}
+	var types []ast.TypeExpr
+	for _, m := range u.Types {
+		types = append(types, g.unionMembersDepth(m, depth+1)...)
+	}
+	return types
+}
+
+// isUnion reports whether t is a union Go holds in an interface, which all
+// are but unions of strings.
+func (g *Generator) isUnion(t ast.TypeExpr) bool {
+	_, ok := g.resolveType(t).(*ast.UnionType)
+	return ok && !g.isString(t)
+}
+
+// typeofName returns what typeof evaluates to for values of type t, or "" if
+// it isn't known.
+func (g *Generator) typeofName(t ast.TypeExpr) string {
+	switch r := g.resolveType(t); {
+	case r == nil || typeName(r) == "any":
+		return ""
+	case g.isString(r):
+		return "string"
+	case isNumber(r):
+		return "number"
+	case isBigInt(r):
+		return "bigint"
+	case typeName(r) == "boolean":
+		return "boolean"
+	case isVoid(r), typeName(r) == "undefined":
+		return "undefined"
+	default:
+		if _, ok := r.(*ast.FunctionType); ok {
+			return "function"
+		}
+	}
+	return "object"
+}
+
+// generateTypeof generates a typeof expression. It is a constant if all the
+// values the operand may hold have the same type and evaluating the operand
+// has no effects, and a call of sildTypeof otherwise.
+func (g *Generator) generateTypeof(e *ast.UnaryExpression) string {
+	names := map[string]bool{}
+	for _, m := range g.unionMembers(g.typeOf(e.Right)) {
+		names[g.typeofName(m)] = true
+	}
+	switch e.Right.(type) {
+	case *ast.VariableExpression, *ast.MemberExpression, *ast.ThisExpression:
+		if len(names) == 1 && !names[""] {
+			for name := range names {
+				return strconv.Quote(name)
+			}
+		}
+	}
+	if t := g.typeOf(e.Right); g.isPointer(t) {
+		g.useHelper("sildTypeofPtr")
+		return fmt.Sprintf("sildTypeofPtr(%s, %s)", g.generateExpression(e.Right), strconv.Quote(g.typeofName(g.nonNull(t))))
+	}
+	g.useHelper("sildTypeof")
+	return fmt.Sprintf("sildTypeof(%s)", g.generateExpression(e.Right))
+}
+
+// generateIn generates an in expression, which is decided statically for
+// struct types, and by the discriminant for discriminated unions.
+func (g *Generator) generateIn(e *ast.BinaryExpression) string {
+	t := g.typeOf(e.Right)
+	key, ok := e.Left.(*ast.StringLiteral)
+	if !ok {
+		g.errorf(e.Left, "cannot translate 'in' with a key that isn't a string literal")
+		return "false"
+	}
+
+	if obj := g.objectType(t); obj != nil {
+		switch m := obj.Member(key.Token.Literal); {
+		case m == nil:
+			return "false"
+		case m.Optional:
+			return fmt.Sprintf("%s.%s != nil", g.generateExpression(e.Right), fieldName(m.Name.Literal))
+		}
+		return "true"
+	}
+
+	u := g.unionOf(t)
+	if u == nil {
+		g.errorf(e, "cannot translate 'in' on a value of type '%s'", typeString(t))
+		return "false"
+	}
+	object := g.generateExpression(e.Right)
+	var tests []string
+	for _, v := range u.variants {
+		switch m := v.obj.Member(key.Token.Literal); {
+		case m == nil:
+			continue
+		case m.Optional:
+			g.errorf(e, "cannot translate 'in' for the optional property '%s' of '%s'", key.Token.Literal, v.goName)
+		}
+		tests = append(tests, fmt.Sprintf("%s.%s() == %s", object, fieldName(u.tag), strconv.Quote(v.value)))
+	}
+	switch len(tests) {
+	case 0:
+		return "false"
+	case len(u.variants):
+		return "true"
+	case 1:
+		return tests[0]
+	}
+	return "(" + strings.Join(tests, " || ") + ")"
+}
+
+// narrowedVar is a variable of a union type narrowed by a condition to the
+// members it may still hold. When only one is left and Go stores it with a
+// different type, the variable is asserted to that type, or dereferenced if
+// it is a pointer to it, either at each use or once by a shadowing variable.
+type narrowedVar struct {
+	union   ast.TypeExpr
+	members []ast.TypeExpr
+	assert  string
+	deref   bool
+	shadow  bool
+	used    bool
+}
+
+// narrowing maps the variables of union types that a condition narrows to
+// their narrowed types, as the type checker found them.
+type narrowing map[string]ast.TypeExpr
+
+// pushNarrowing opens a scope in which the variables of n have their
+// narrowed types. A variable narrowed to a single member is asserted to its
+// Go type, by a shadowing variable if shadow is set.
+func (g *Generator) pushNarrowing(n narrowing, shadow bool) {
+	g.pushScope()
+
+	names := make([]string, 0, len(n))
+	for name := range n {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// properties are asserted where they are read
+		if strings.Contains(name, ".") {
+			continue
+		}
+		nv := &narrowedVar{union: g.lookup(name), members: g.unionMembers(n[name]), shadow: shadow}
+		if typeName(n[name]) == "never" {
+			nv.members = nil
+		}
+		if outer := g.lookupUnion(name); outer != nil {
+			// a shadowing variable only holds the member it was asserted to
+			if outer.assert != "" && outer.shadow {
+				continue
+			}
+			nv.union = outer.union
+		}
+
+		t := nv.union
+		if !slices.ContainsFunc(nv.members, g.isNull) {
+			t = g.nonNull(t)
+		}
+		if len(nv.members) == 1 {
+			t = nv.members[0]
+			// nil needs no assertion
+			if !g.isNull(t) {
+				nv.assert, nv.deref = g.conversion(t, nv.union)
+			}
+		}
+		g.declare(name, t)
+		g.scope.unions[name] = nv
+	}
+}
+
+// generateNarrowedBlock generates stmts in a scope where the variables of n
+// have their narrowed types, declaring the shadowing variables that were
+// used first.
+func (g *Generator) generateNarrowedBlock(stmts []Statement, n narrowing) string {
+	g.pushNarrowing(n, true)
+	defer g.popScope()
+
+	body := g.generateBlock(stmts)
+
+	builder := strings.Builder{}
+	names := make([]string, 0, len(g.scope.unions))
+	for name := range g.scope.unions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch nv := g.scope.unions[name]; {
+		case nv.shadow && nv.used && nv.deref:
+			builder.WriteString(fmt.Sprintf("%s%s := *%s\n", indent, g.goName(name), g.goName(name)))
+		case nv.shadow && nv.used:
+			builder.WriteString(fmt.Sprintf("%s%s := %s.(%s)\n", indent, g.goName(name), g.goName(name), nv.assert))
+		}
+	}
+	return builder.String() + body
+}
+
+// generateNarrowedBody is generateBody narrowed by n.
+func (g *Generator) generateNarrowedBody(stmt Statement, n narrowing) string {
+	if block, ok := stmt.(*ast.BlockStatement); ok {
+		return g.generateNarrowedBlock(block.Statements, n)
+	}
+	return g.generateNarrowedBlock([]Statement{stmt}, n)
+}
+
+// generateNarrowedVariable generates a use of the variable v if it is
+// narrowed to a member of its union type of another Go type.
+func (g *Generator) generateNarrowedVariable(v *ast.VariableExpression) (string, bool) {
+	name := v.Token.Literal
+	nv := g.lookupUnion(name)
+	if nv == nil {
+		return "", false
+	}
+	if nv.shadow {
+		if nv.assert == "" {
+			return "", false
+		}
+		nv.used = true
+		return g.goName(name), true
+	}
+	assert, deref := g.assertion(v, nv.union)
+	switch {
+	case assert == "":
+		return "", false
+	case deref:
+		return fmt.Sprintf("(*%s)", g.goName(name)), true
+	}
+	return fmt.Sprintf("%s.(%s)", g.goName(name), assert), true
+}
+
+// generateNarrowedMember generates the property access e, asserted to its
+// narrowed type if a condition narrowed it.
+func (g *Generator) generateNarrowedMember(e *ast.MemberExpression) string {
+	code := g.generateMember(e)
+	declared, ok := g.declared[e]
+	if !ok {
+		return code
+	}
+	switch assert, deref := g.assertion(e, declared); {
+	case assert == "":
+		return code
+	case deref:
+		return fmt.Sprintf("(*%s)", code)
+	default:
+		return fmt.Sprintf("%s.(%s)", code, assert)
+	}
+}
+
+// assertion returns the Go type e, a variable narrowed without a shadowing
+// variable or a narrowed property, is asserted to where it is used, and
+// whether it is dereferenced instead, or "" if it isn't narrowed there to a
+// single member of another Go type than that of declared, its declared type.
+// Assignments widen it again, so the narrowed type is the one the type
+// checker found for that use.
+func (g *Generator) assertion(e ast.Expression, declared ast.TypeExpr) (string, bool) {
+	t := g.typeOf(e)
+	if t == nil || g.isNull(t) || len(g.unionMembers(t)) != 1 {
+		return "", false
+	}
+	return g.conversion(t, declared)
+}
+
+// conversion returns the Go type a value of the declared type narrowed to
+// t is asserted to, and whether it is dereferenced instead, or "" if Go
+// stores both alike. Only values held in an interface are asserted: a
+// union of string literal types is a Go string whichever member it holds.
+func (g *Generator) conversion(t, declared ast.TypeExpr) (string, bool) {
+	goType, union := g.goType(t), g.goType(declared)
+	switch {
+	case goType == union:
+		return "", false
+	case g.isPointer(declared):
+		return goType, true
+	case g.unionOf(declared) == nil && union != "any":
+		return "", false
+	}
+	return goType, union == "*"+goType
+}
+
+// checkNarrowedTarget reports an assignment to a variable narrowed to a
+// member of its union type by a shadowing variable, or to a property of a
+// narrowed variable unless the member is a reference: Go only has a copy of
+// the value asserted to the type of the member.
+func (g *Generator) checkNarrowedTarget(target ast.Expression) {
+	root, property := target, false
+	for {
+		switch e := root.(type) {
+		case *ast.MemberExpression:
+			root, property = e.Object, true
+			continue
+		case *ast.IndexExpression:
+			root, property = e.Left, true
+			continue
+		case *ast.VariableExpression:
+			nv := g.lookupUnion(e.Token.Literal)
+			if nv == nil {
+				break
+			}
+			assert, narrowed := nv.assert, nv.members
+			if !nv.shadow {
+				// the variable itself is assigned rather than a copy
+				if !property {
+					break
+				}
+				assert, _ = g.assertion(e, nv.union)
+				narrowed = []ast.TypeExpr{g.typeOf(e)}
+			}
+			if assert == "" || property && strings.HasPrefix(assert, "*") {
+				break
+			}
+			g.errorf(target, "cannot assign to '%s' where it is narrowed to '%s': Go only has a copy of it", target.String(), typeString(narrowed[0]))
+		}
+		return
+	}
+}
+
+// exits reports whether control never reaches the end of stmt, because it
+// ends with a return, throw, break or continue statement.
+func exits(stmt Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BranchStatement:
+		return true
+	case *ast.BlockStatement:
+		return len(s.Statements) > 0 && exits(s.Statements[len(s.Statements)-1])
+	case *ast.IfStatement:
+		return s.Alternative != nil && exits(s.Consequence) && exits(s.Alternative)
+	}
+	return false
+}
+
+func isUnionType(t ast.TypeExpr) bool {
+	_, ok := t.(*ast.UnionType)
+	return ok
+}
+
+func typeString(t ast.TypeExpr) string {
+	if t == nil {
+		return "any"
+	}
+	return t.String()
+}
@@ -0,0 +1,98 @@
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/toyaAoi/sild/token"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a message about a span of source code. Expected and Found
+// are set when the diagnostic was caused by an unexpected token. Code is the
+// number of the equivalent TypeScript error, such as 2322, or 0 if there is
+// none.
+type Diagnostic struct {
+	Severity Severity
+	Pos      token.Position
+	End      token.Position
+	Message  string
+	Expected token.TokenType
+	Found    token.Token
+	Code     int
+}
+
+func (d Diagnostic) Error() string {
+	if d.Code != 0 {
+		return fmt.Sprintf("%s: %s TS%d: %s", d.Pos, d.Severity, d.Code, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+// HasErrors reports whether any of the diagnostics is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Fprint writes d to w followed by the offending source line with a caret
+// underlining the span. src is the full source the positions refer to.
+func Fprint(w io.Writer, src []byte, d Diagnostic) {
+	fmt.Fprintln(w, d.Error())
+
+	if !d.Pos.IsValid() || d.Pos.Offset > len(src) {
+		return
+	}
+
+	start := bytes.LastIndexByte(src[:d.Pos.Offset], '\n') + 1
+	end := bytes.IndexByte(src[d.Pos.Offset:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += d.Pos.Offset
+	}
+	line := strings.TrimRight(string(src[start:end]), "\r")
+
+	gutter := fmt.Sprintf("%d", d.Pos.Line)
+	fmt.Fprintf(w, " %s | %s\n", gutter, line)
+
+	width := 1
+	if d.End.Line == d.Pos.Line && d.End.Offset > d.Pos.Offset {
+		width = utf8.RuneCount(src[d.Pos.Offset:d.End.Offset])
+	}
+
+	var pad strings.Builder
+	for _, ch := range string(src[start:d.Pos.Offset]) {
+		if ch == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+
+	fmt.Fprintf(w, " %s | %s^%s\n", strings.Repeat(" ", len(gutter)), pad.String(), strings.Repeat("~", width-1))
+}
@@ -0,0 +1,95 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/toyaAoi/sild/token"
+)
+
+func TestFprint(t *testing.T) {
+	src := "let x: number = 1;\nlet y: number = oops;\n"
+
+	tests := []struct {
+		name     string
+		diag     Diagnostic
+		expected string
+	}{
+		{
+			name: "single character span",
+			diag: Diagnostic{
+				Severity: Error,
+				Pos:      token.Position{Filename: "main.ts", Offset: 17, Line: 1, Column: 18},
+				End:      token.Position{Filename: "main.ts", Offset: 18, Line: 1, Column: 19},
+				Message:  "something is wrong",
+			},
+			expected: "main.ts:1:18: error: something is wrong\n" +
+				" 1 | let x: number = 1;\n" +
+				"   |                  ^\n",
+		},
+		{
+			name: "multi character span",
+			diag: Diagnostic{
+				Severity: Warning,
+				Pos:      token.Position{Offset: 35, Line: 2, Column: 17},
+				End:      token.Position{Offset: 39, Line: 2, Column: 21},
+				Message:  "unknown name",
+			},
+			expected: "2:17: warning: unknown name\n" +
+				" 2 | let y: number = oops;\n" +
+				"   |                 ^~~~\n",
+		},
+		{
+			name: "typescript error code",
+			diag: Diagnostic{
+				Severity: Error,
+				Pos:      token.Position{Offset: 35, Line: 2, Column: 17},
+				End:      token.Position{Offset: 39, Line: 2, Column: 21},
+				Message:  "cannot find name 'oops'",
+				Code:     2304,
+			},
+			expected: "2:17: error TS2304: cannot find name 'oops'\n" +
+				" 2 | let y: number = oops;\n" +
+				"   |                 ^~~~\n",
+		},
+		{
+			name: "no position",
+			diag: Diagnostic{
+				Severity: Error,
+				Message:  "error reading input",
+			},
+			expected: "-: error: error reading input\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			Fprint(&out, []byte(src), tt.diag)
+
+			if out.String() != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestFprintUnicode(t *testing.T) {
+	src := "let café: number = naïve;\n"
+	d := Diagnostic{
+		Severity: Error,
+		Pos:      token.Position{Offset: 20, Line: 1, Column: 20},
+		End:      token.Position{Offset: 26, Line: 1, Column: 25},
+		Message:  "cannot find name 'naïve'",
+		Code:     2304,
+	}
+	expected := "1:20: error TS2304: cannot find name 'naïve'\n" +
+		" 1 | let café: number = naïve;\n" +
+		"   |                    ^~~~~\n"
+
+	var out strings.Builder
+	Fprint(&out, []byte(src), d)
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,308 @@
+package parser
+
+import (
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+func (p *Parser) parseClassDeclaration() *ast.ClassDeclaration {
+	class := &ast.ClassDeclaration{}
+	class.StartPos = p.nextTok().Pos
+	defer p.endTypeParameters(len(p.typeParams))
+
+	name, ok := p.expect(token.IDENT)
+	if !ok {
+		return nil
+	}
+	class.Name = name
+	p.declareType(name)
+
+	if class.TypeParams, ok = p.parseTypeParameters(); !ok {
+		return nil
+	}
+
+	if p.match(token.EXTENDS) {
+		p.nextTok()
+		base, ok := p.expect(token.IDENT)
+		if !ok {
+			return nil
+		}
+		class.Extends = &ast.Identifier{Token: base}
+	}
+
+	if _, ok := p.expect(token.LEFT_BRACE); !ok {
+		return nil
+	}
+
+	names := map[string]bool{}
+	for !p.match(token.RIGHT_BRACE) {
+		if p.match(token.EOF) {
+			p.errorExpected(p.currTok, token.RIGHT_BRACE, token.Describe(token.RIGHT_BRACE))
+			return nil
+		}
+		if p.match(token.SEMICOLON) {
+			p.nextTok()
+			continue
+		}
+
+		start := p.currTok
+		mods, ok := p.parseModifiers()
+		if !ok {
+			p.synchronizeMember()
+			continue
+		}
+		if !isIdentifierName(p.currTok) {
+			p.errorExpected(p.currTok, token.IDENT, "member name")
+			p.synchronizeMember()
+			continue
+		}
+
+		if p.currTok.Literal == "constructor" && (p.peekTok.Type == token.LEFT_PAREN || p.peekTok.Type == token.LESS) {
+			ctor := p.parseMethod(start, mods, false)
+			if ctor == nil {
+				p.synchronizeMember()
+				continue
+			}
+			if class.Constructor != nil {
+				p.errorf(ctor.Name, "multiple constructor implementations are not allowed")
+			}
+			if mods.Static || mods.Readonly {
+				p.errorf(start, "'%s' modifier cannot appear on a constructor declaration", staticOrReadonly(mods))
+			}
+			p.attachComments(&ctor.Trivia, start.Comments)
+			class.Constructor = ctor
+			continue
+		}
+
+		if names[p.currTok.Literal] {
+			p.errorf(p.currTok, "duplicate identifier '%s'", p.currTok.Literal)
+		}
+		names[p.currTok.Literal] = true
+
+		if p.peekTok.Type == token.LEFT_PAREN || p.peekTok.Type == token.LESS {
+			method := p.parseMethod(start, mods, true)
+			if method == nil {
+				p.synchronizeMember()
+				continue
+			}
+			if mods.Readonly {
+				p.errorf(start, "'readonly' modifier can only appear on a property declaration")
+			}
+			p.attachComments(&method.Trivia, start.Comments)
+			class.Methods = append(class.Methods, method)
+			continue
+		}
+
+		field := p.parseField(start, mods)
+		if field == nil {
+			p.synchronizeMember()
+			continue
+		}
+		p.attachComments(&field.Trivia, start.Comments)
+		class.Fields = append(class.Fields, field)
+	}
+	class.EndPos = p.nextTok().End
+
+	return class
+}
+
+// synchronizeMember skips the rest of a class member that failed to parse,
+// so that the members after it are still parsed: up to and including a ';'
+// or the closing brace of a body the member opens, or up to a name starting
+// a line or the brace closing the class.
+func (p *Parser) synchronizeMember() {
+	depth := 0
+	for {
+		switch p.currTok.Type {
+		case token.EOF:
+			return
+		case token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_BRACE:
+			if depth == 0 {
+				return
+			}
+			if depth--; depth == 0 {
+				p.nextTok()
+				return
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				p.nextTok()
+				return
+			}
+		}
+		p.nextTok()
+		if depth == 0 && p.newlineBefore() && isIdentifierName(p.currTok) {
+			return
+		}
+	}
+}
+
+func staticOrReadonly(mods ast.Modifiers) string {
+	if mods.Static {
+		return "static"
+	}
+	return "readonly"
+}
+
+// parseModifiers parses the modifiers of a class member. Modifier keywords
+// are only reserved inside of class bodies, and may still name a member,
+// as in "static: number".
+func (p *Parser) parseModifiers() (ast.Modifiers, bool) {
+	var mods ast.Modifiers
+
+	for p.currTok.Type == token.IDENT && !isMemberNameEnd(p.peekTok.Type) {
+		switch p.currTok.Literal {
+		case "public", "private", "protected":
+			if mods.Access != "" {
+				p.errorf(p.currTok, "accessibility modifier already seen")
+				return mods, false
+			}
+			if mods.Static || mods.Readonly {
+				p.errorf(p.currTok, "'%s' modifier must precede '%s' modifier", p.currTok.Literal, staticOrReadonly(mods))
+				return mods, false
+			}
+			mods.Access = p.currTok.Literal
+		case "static":
+			if mods.Static {
+				p.errorf(p.currTok, "'static' modifier already seen")
+				return mods, false
+			}
+			if mods.Readonly {
+				p.errorf(p.currTok, "'static' modifier must precede 'readonly' modifier")
+				return mods, false
+			}
+			mods.Static = true
+		case "readonly":
+			if mods.Readonly {
+				p.errorf(p.currTok, "'readonly' modifier already seen")
+				return mods, false
+			}
+			mods.Readonly = true
+		default:
+			return mods, true
+		}
+		p.nextTok()
+	}
+
+	return mods, true
+}
+
+// isMemberNameEnd reports whether a token of type t may follow the name of a
+// class member.
+func isMemberNameEnd(t token.TokenType) bool {
+	switch t {
+	case token.LEFT_PAREN, token.LESS, token.COLON, token.QUESTION, token.ASSIGN, token.SEMICOLON, token.RIGHT_BRACE:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseMethod parses a method or, without a return type, a constructor,
+// starting at its name.
+func (p *Parser) parseMethod(start token.Token, mods ast.Modifiers, hasReturnType bool) *ast.MethodDeclaration {
+	method := &ast.MethodDeclaration{Modifiers: mods, Name: p.nextTok()}
+	method.StartPos = start.Pos
+	defer p.endTypeParameters(len(p.typeParams))
+
+	typeParams, ok := p.parseTypeParameters()
+	if !ok {
+		return nil
+	}
+	if typeParams != nil && !hasReturnType {
+		p.errorf(method.Name, "type parameters cannot appear on a constructor declaration")
+	}
+	method.TypeParams = typeParams
+
+	params, ok := p.parseParameters(true)
+	if !ok {
+		return nil
+	}
+	method.Params = params
+
+	if hasReturnType {
+		method.ReturnType = p.parseReturnType()
+		if method.ReturnType == nil {
+			return nil
+		}
+	}
+
+	if !p.match(token.LEFT_BRACE) {
+		p.errorExpected(p.currTok, token.LEFT_BRACE, token.Describe(token.LEFT_BRACE))
+		return nil
+	}
+	body, ok := p.parseBlockStatements()
+	if !ok {
+		return nil
+	}
+	method.Body = body
+	method.EndPos = p.prevEnd
+
+	return method
+}
+
+func (p *Parser) parseField(start token.Token, mods ast.Modifiers) *ast.FieldDeclaration {
+	field := &ast.FieldDeclaration{Modifiers: mods, Name: p.nextTok()}
+	field.StartPos = start.Pos
+
+	if p.match(token.QUESTION) {
+		p.nextTok()
+		field.Optional = true
+	}
+
+	if _, ok := p.expect(token.COLON); !ok {
+		return nil
+	}
+	field.Type = p.parseType()
+	if field.Type == nil {
+		return nil
+	}
+
+	if p.match(token.ASSIGN) {
+		p.nextTok()
+		field.Value = p.parseExpression()
+		if field.Value == nil {
+			return nil
+		}
+	}
+	field.EndPos = p.prevEnd
+
+	if !p.expectSemicolon() {
+		return nil
+	}
+
+	return field
+}
+
+func (p *Parser) parseNewExpression() ast.Expression {
+	expr := &ast.NewExpression{}
+	expr.StartPos = p.nextTok().Pos
+
+	name, ok := p.expect(token.IDENT)
+	if !ok {
+		return nil
+	}
+	expr.Class = &ast.Identifier{Token: name}
+	expr.EndPos = name.End
+
+	if p.match(token.LESS) {
+		if expr.TypeArgs, ok = p.parseTypeArguments(); !ok {
+			return nil
+		}
+		expr.EndPos = p.prevEnd
+	}
+
+	expr.Args = []ast.Expression{}
+	if p.match(token.LEFT_PAREN) {
+		call, ok := p.parseFunctionCall(expr.Class).(*ast.FunctionCallExpression)
+		if !ok {
+			return nil
+		}
+		expr.Args = call.Args
+		expr.EndPos = call.End()
+	}
+
+	return expr
+}
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/diag"
 	"github.com/toyaAoi/sild/scanner"
 	"github.com/toyaAoi/sild/token"
 )
@@ -12,46 +15,221 @@ type Parser struct {
 	s       *scanner.Scanner
 	currTok token.Token
 	peekTok token.Token
+	prevEnd token.Position
+
+	diagnostics []diag.Diagnostic
+
+	// names of the types declared in the program, which may only be
+	// declared once
+	typeNames map[string]bool
+
+	// names of the type parameters in scope, innermost last
+	typeParams []string
+
+	// whether the next postfix expression starts a statement, where it may
+	// be followed by '++' or '--'
+	updateAllowed bool
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
+	if err := p.s.Err(); err != nil {
+		p.errorf(p.currTok, "error reading input: %v", err)
+		return program
+	}
+
 	for p.currTok.Type != token.EOF {
-		stmt := p.parseStatement()
-		if stmt == nil {
-			return program
+		program.Statements = append(program.Statements, p.parseStatementWithRecovery())
+	}
+
+	return program
+}
+
+// parseStatementWithRecovery parses a statement. On a syntax error it skips
+// ahead to the next synchronization point and returns an *ast.BadStatement
+// covering the skipped tokens, so that parsing can continue.
+func (p *Parser) parseStatementWithRecovery() ast.Statement {
+	start := p.currTok
+	stmt := p.parseStatement()
+	if stmt != nil {
+		if c, ok := stmt.(ast.Commented); ok {
+			p.attachComments(c.Comments(), start.Comments)
 		}
-		program.Statements = append(program.Statements, stmt)
+		return stmt
+	}
 
-		if p.peekTok.Type != token.EOF {
-			p.nextTok()
-		} else {
-			break
+	// always make progress, otherwise a token we can't start a statement with
+	// would be reported forever
+	if p.currTok.Pos == start.Pos && p.currTok.Type != token.EOF {
+		p.nextTok()
+	}
+	p.synchronize()
+
+	return &ast.BadStatement{Loc: ast.Loc{StartPos: start.Pos, EndPos: p.prevEnd}}
+}
+
+// attachComments attaches the comments before a node that was just parsed,
+// and those following it on the line it ends on, to the node. The comments
+// following it are removed from the current token, so that they aren't also
+// attached to the next node.
+func (p *Parser) attachComments(trivia *ast.Trivia, leading []token.Comment) {
+	trivia.Leading = commentGroup(leading)
+
+	comments := p.currTok.Comments
+	n := 0
+	for n < len(comments) && comments[n].Pos.Line == p.prevEnd.Line {
+		n++
+	}
+	trivia.Trailing = commentGroup(comments[:n])
+	p.currTok.Comments = comments[n:]
+}
+
+func commentGroup(comments []token.Comment) *ast.CommentGroup {
+	if len(comments) == 0 {
+		return nil
+	}
+	return &ast.CommentGroup{List: comments}
+}
+
+// synchronize skips tokens until the parser is positioned at a point where a
+// new statement may begin: just past a ';', or at a '}' or statement keyword.
+// Blocks opened by the skipped tokens are skipped whole, so that their
+// closing braces aren't taken for that of the enclosing block.
+func (p *Parser) synchronize() {
+	depth := 0
+	for {
+		switch p.currTok.Type {
+		case token.EOF:
+			return
+		case token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_BRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case token.SEMICOLON:
+			if depth == 0 {
+				p.nextTok()
+				return
+			}
+		default:
+			if depth == 0 && isStatementKeyword(p.currTok.Type) {
+				return
+			}
 		}
+		p.nextTok()
 	}
+}
 
-	return program
+func isStatementKeyword(t token.TokenType) bool {
+	switch t {
+	case token.LET, token.CONST, token.VAR, token.FUNCTION, token.RETURN, token.THROW, token.IF, token.WHILE,
+		token.DO, token.FOR, token.BREAK, token.CONTINUE, token.INTERFACE, token.CLASS, token.SWITCH, token.CASE,
+		token.DEFAULT, token.ENUM:
+		return true
+	default:
+		return false
+	}
+}
+
+// Diagnostics returns the problems found while parsing, in source order.
+func (p *Parser) Diagnostics() []diag.Diagnostic {
+	return p.diagnostics
 }
 
 func (p *Parser) nextTok() token.Token {
 	tok := p.currTok
+	p.prevEnd = tok.End
 	p.currTok = p.peekTok
 	p.peekTok = p.s.NextToken()
 	return tok
 }
 
+// expectPeek reports whether the next token has type t and records a
+// diagnostic if it doesn't.
 func (p *Parser) expectPeek(t token.TokenType) bool {
-	return p.peekTok.Type == t
+	if p.peekTok.Type == t {
+		return true
+	}
+	p.errorExpected(p.peekTok, t, token.Describe(t))
+	return false
 }
 
-func (p *Parser) expectPeekValueType() bool {
-	switch p.peekTok.Type {
-	case token.TYPE_NUMBER, token.TYPE_STRING, token.TYPE_BOOLEAN, token.TYPE_VOID:
-		return true
+// expect consumes the current token if it has type t and records a
+// diagnostic otherwise.
+func (p *Parser) expect(t token.TokenType) (token.Token, bool) {
+	if p.currTok.Type != t {
+		p.errorExpected(p.currTok, t, token.Describe(t))
+		return p.currTok, false
+	}
+	return p.nextTok(), true
+}
+
+func (p *Parser) errorf(at token.Token, format string, args ...any) {
+	p.report(diag.Diagnostic{
+		Severity: diag.Error,
+		Pos:      at.Pos,
+		End:      at.End,
+		Message:  fmt.Sprintf(format, args...),
+		Found:    at,
+	})
+}
+
+// nodeErrorf records an error spanning the whole of node.
+func (p *Parser) nodeErrorf(node ast.Node, format string, args ...any) {
+	p.report(diag.Diagnostic{
+		Severity: diag.Error,
+		Pos:      node.Pos(),
+		End:      node.End(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *Parser) report(d diag.Diagnostic) {
+	// only report the first error at a position, later ones are usually
+	// caused by it
+	if n := len(p.diagnostics); n > 0 && p.diagnostics[n-1].Pos == d.Pos {
+		return
+	}
+
+	p.diagnostics = append(p.diagnostics, d)
+}
+
+func (p *Parser) errorExpected(found token.Token, t token.TokenType, what string) {
+	n := len(p.diagnostics)
+	p.errorf(found, "expected %s, found %s", what, describeToken(found))
+	if len(p.diagnostics) > n {
+		p.diagnostics[n].Expected = t
+	}
+}
+
+func describeToken(tok token.Token) string {
+	switch tok.Type {
+	case token.EOF:
+		return "end of file"
+	case token.STRING:
+		return fmt.Sprintf("%q", tok.Literal)
+	case token.TEMPLATE_HEAD, token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL:
+		return "template literal"
+	case token.ILLEGAL:
+		if len(tok.Literal) > 1 && (tok.Literal[0] == '.' || '0' <= tok.Literal[0] && tok.Literal[0] <= '9') {
+			return fmt.Sprintf("invalid numeric literal '%s'", tok.Literal)
+		}
+		if len(tok.Literal) > 1 && (tok.Literal[0] == '"' || tok.Literal[0] == '\'') {
+			return fmt.Sprintf("invalid string literal '%s'", tok.Literal)
+		}
+		if len(tok.Literal) > 1 && (tok.Literal[0] == '`' || tok.Literal[0] == '}') {
+			return fmt.Sprintf("invalid template literal '%s'", tok.Literal)
+		}
+		if strings.HasPrefix(tok.Literal, "/*") {
+			return "unterminated comment"
+		}
+		return fmt.Sprintf("illegal character '%s'", tok.Literal)
 	default:
-		return false
+		return fmt.Sprintf("'%s'", tok.Literal)
 	}
 }
 
@@ -66,7 +244,20 @@ func New(sc *scanner.Scanner) *Parser {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currTok.Type {
-	case token.LET:
+	case token.CONST:
+		if p.peekTok.Type == token.ENUM {
+			stmt := p.parseEnumDeclaration()
+			if stmt == nil {
+				return nil
+			}
+			return stmt
+		}
+		stmt := p.parseVariableDeclaration()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.LET, token.VAR:
 		stmt := p.parseVariableDeclaration()
 		if stmt == nil {
 			return nil
@@ -84,132 +275,755 @@ func (p *Parser) parseStatement() ast.Statement {
 			return nil
 		}
 		return stmt
+	case token.THROW:
+		stmt := p.parseThrowStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.IF:
+		stmt := p.parseIfStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.LEFT_BRACE:
+		stmt := p.parseBlockStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.WHILE:
+		stmt := p.parseWhileStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.DO:
+		stmt := p.parseDoWhileStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.FOR:
+		return p.parseForStatement()
+	case token.SWITCH:
+		stmt := p.parseSwitchStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.BREAK, token.CONTINUE:
+		stmt := p.parseBranchStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.INTERFACE:
+		stmt := p.parseInterfaceDeclaration()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.CLASS:
+		stmt := p.parseClassDeclaration()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.ENUM:
+		stmt := p.parseEnumDeclaration()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.IDENT:
+		// 'type' is only a keyword when followed by the name of an alias
+		if p.currTok.Literal == "type" && p.peekTok.Type == token.IDENT {
+			stmt := p.parseTypeAliasDeclaration()
+			if stmt == nil {
+				return nil
+			}
+			return stmt
+		}
+		if p.peekTok.Type == token.COLON {
+			stmt := p.parseLabeledStatement()
+			if stmt == nil {
+				return nil
+			}
+			return stmt
+		}
+		return p.parseSimpleStatementWithSemicolon()
+	default:
+		if canStartExpression(p.currTok.Type) {
+			return p.parseSimpleStatementWithSemicolon()
+		}
+		p.errorExpected(p.currTok, "", "statement")
+		return nil
+	}
+}
+
+func canStartExpression(t token.TokenType) bool {
+	switch t {
+	case token.IDENT, token.NUMBER, token.BIGINT, token.STRING, token.TEMPLATE_HEAD, token.BOOLEAN, token.LEFT_PAREN,
+		token.LEFT_BRACKET, token.THIS, token.SUPER, token.NEW, token.BANG, token.MINUS, token.TYPEOF, token.PLUS_PLUS,
+		token.MINUS_MINUS, token.NULL, token.UNDEFINED:
+		return true
+	default:
+		return false
+	}
+}
+
+// expectSemicolon consumes the semicolon terminating a statement. Following
+// the automatic semicolon insertion rules of ECMAScript, it may be omitted
+// before a closing brace, at the end of the file or at the end of a line.
+func (p *Parser) expectSemicolon() bool {
+	if p.match(token.SEMICOLON) {
+		p.nextTok()
+		return true
+	}
+	if p.canInsertSemicolon() {
+		return true
+	}
+	p.errorExpected(p.currTok, token.SEMICOLON, token.Describe(token.SEMICOLON))
+	return false
+}
+
+// canInsertSemicolon reports whether a statement may end before the current
+// token without a semicolon.
+func (p *Parser) canInsertSemicolon() bool {
+	return p.match(token.RIGHT_BRACE, token.EOF) || p.newlineBefore()
+}
+
+// newlineBefore reports whether a line break separates the current token
+// from the previous one. Some productions, like the value of a return
+// statement or a postfix increment, must not be preceded by one.
+func (p *Parser) newlineBefore() bool {
+	return p.currTok.Pos.Line > p.prevEnd.Line
+}
+
+func (p *Parser) parseSimpleStatementWithSemicolon() ast.Statement {
+	stmt := p.parseSimpleStatement()
+	if stmt == nil || !p.expectSemicolon() {
+		return nil
+	}
+	return stmt
+}
+
+// parseSimpleStatement parses an expression statement, an assignment or an
+// increment or decrement, without a terminating semicolon.
+func (p *Parser) parseSimpleStatement() ast.Statement {
+	start := p.currTok.Pos
+
+	if p.match(token.PLUS_PLUS, token.MINUS_MINUS) {
+		stmt := &ast.IncDecStatement{Operator: p.nextTok(), Prefix: true}
+		stmt.StartPos = start
+		stmt.Target = p.parseAssignmentTarget()
+		if stmt.Target == nil {
+			return nil
+		}
+		stmt.EndPos = p.prevEnd
+		return stmt
+	}
+
+	p.updateAllowed = true
+	expr := p.parseExpression()
+	p.updateAllowed = false
+	if expr == nil {
+		return nil
+	}
+
+	switch {
+	case p.match(token.PLUS_PLUS, token.MINUS_MINUS) && !p.newlineBefore():
+		if !p.checkAssignmentTarget(expr) {
+			return nil
+		}
+		stmt := &ast.IncDecStatement{Target: expr, Operator: p.nextTok()}
+		stmt.StartPos = start
+		stmt.EndPos = p.prevEnd
+		return stmt
+	case p.match(token.ASSIGN, token.PLUS_ASSIGN, token.MINUS_ASSIGN, token.MUL_ASSIGN, token.DIV_ASSIGN, token.MOD_ASSIGN, token.NULLISH_ASSIGN):
+		if !p.checkAssignmentTarget(expr) {
+			return nil
+		}
+		stmt := &ast.AssignmentStatement{Target: expr, Operator: p.nextTok()}
+		stmt.StartPos = start
+		stmt.Value = p.parseExpression()
+		if stmt.Value == nil {
+			return nil
+		}
+		stmt.EndPos = p.prevEnd
+		return stmt
+	}
+
+	stmt := &ast.ExpressionStatement{Expression: expr}
+	stmt.StartPos = start
+	stmt.EndPos = p.prevEnd
+	return stmt
+}
+
+func (p *Parser) parseAssignmentTarget() ast.Expression {
+	expr := p.parsePostfix()
+	if expr == nil || !p.checkAssignmentTarget(expr) {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) checkAssignmentTarget(expr ast.Expression) bool {
+	if ast.IsOptionalChain(expr) {
+		p.nodeErrorf(expr, "the left-hand side of an assignment expression may not be an optional property access")
+		return false
+	}
+	switch expr.(type) {
+	case *ast.VariableExpression, *ast.IndexExpression, *ast.MemberExpression:
+		return true
+	}
+	p.nodeErrorf(expr, "invalid assignment target")
+	return false
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
+
+	stmt.Condition = p.parseParenthesizedCondition()
+	if stmt.Condition == nil {
+		return nil
+	}
+	stmt.Body = p.parseStatementWithRecovery()
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+func (p *Parser) parseDoWhileStatement() *ast.DoWhileStatement {
+	stmt := &ast.DoWhileStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
+
+	stmt.Body = p.parseStatementWithRecovery()
+
+	if _, ok := p.expect(token.WHILE); !ok {
+		return nil
+	}
+	stmt.Condition = p.parseParenthesizedCondition()
+	if stmt.Condition == nil {
+		return nil
+	}
+	// the semicolon after a do-while is always optional
+	if p.match(token.SEMICOLON) {
+		p.nextTok()
+	}
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+// parseForStatement parses a C-style for loop, or a for...in or for...of
+// loop. It returns nil on a syntax error.
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
+
+	if _, ok := p.expect(token.LEFT_PAREN); !ok {
+		return nil
+	}
+
+	// the initializer consumes its own semicolon
+	switch {
+	case p.match(token.SEMICOLON):
+		p.nextTok()
+	case p.match(token.LET, token.CONST, token.VAR):
+		keyword := p.currTok
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		p.nextTok()
+
+		if p.peekTok.Type == token.IN || isContextualKeyword(p.peekTok, "of") {
+			return p.parseForInOfStatement(stmt.Token, keyword)
+		}
+
+		init := p.parseVariableBinding(keyword)
+		if init == nil {
+			return nil
+		}
+		// semicolons are never inserted in the header of a for loop
+		semi, ok := p.expect(token.SEMICOLON)
+		if !ok {
+			return nil
+		}
+		init.EndPos = semi.End
+		stmt.Init = init
 	default:
+		stmt.Init = p.parseSimpleStatement()
+		if stmt.Init == nil {
+			return nil
+		}
+		if _, ok := p.expect(token.SEMICOLON); !ok {
+			return nil
+		}
+	}
+
+	if !p.match(token.SEMICOLON) {
+		stmt.Condition = p.parseExpression()
+		if stmt.Condition == nil {
+			return nil
+		}
+	}
+	if _, ok := p.expect(token.SEMICOLON); !ok {
+		return nil
+	}
+
+	if !p.match(token.RIGHT_PAREN) {
+		stmt.Update = p.parseSimpleStatement()
+		if stmt.Update == nil {
+			return nil
+		}
+	}
+	if _, ok := p.expect(token.RIGHT_PAREN); !ok {
+		return nil
+	}
+
+	stmt.Body = p.parseStatementWithRecovery()
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+// parseForInOfStatement parses the rest of a for...in or for...of loop
+// starting at the loop variable.
+func (p *Parser) parseForInOfStatement(forTok, keyword token.Token) ast.Statement {
+	variable := &ast.Identifier{Token: p.nextTok()}
+	isIn := p.match(token.IN)
+
+	// skip 'in' or 'of'
+	p.nextTok()
+
+	iterable := p.parseExpression()
+	if iterable == nil {
+		return nil
+	}
+	if _, ok := p.expect(token.RIGHT_PAREN); !ok {
+		return nil
+	}
+
+	body := p.parseStatementWithRecovery()
+	loc := ast.Loc{StartPos: forTok.Pos, EndPos: p.prevEnd}
+
+	if isIn {
+		return &ast.ForInStatement{Loc: loc, Token: forTok, Keyword: keyword, Variable: variable, Object: iterable, Body: body}
+	}
+	return &ast.ForOfStatement{Loc: loc, Token: forTok, Keyword: keyword, Variable: variable, Iterable: iterable, Body: body}
+}
+
+// isContextualKeyword reports whether tok is an identifier that acts as a
+// keyword in some positions only, such as "of" in a for...of loop.
+func isContextualKeyword(tok token.Token, word string) bool {
+	return tok.Type == token.IDENT && tok.Literal == word
+}
+
+func (p *Parser) parseBranchStatement() *ast.BranchStatement {
+	stmt := &ast.BranchStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
+
+	if p.match(token.IDENT) && !p.newlineBefore() {
+		stmt.Label = &ast.Identifier{Token: p.nextTok()}
+	}
+	if !p.expectSemicolon() {
+		return nil
+	}
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+func (p *Parser) parseLabeledStatement() *ast.LabeledStatement {
+	stmt := &ast.LabeledStatement{Label: &ast.Identifier{Token: p.nextTok()}}
+	stmt.StartPos = stmt.Label.Pos()
+
+	// skip ':'
+	p.nextTok()
+
+	stmt.Body = p.parseStatementWithRecovery()
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+// parseParenthesizedCondition parses the '(' condition ')' of a loop or if
+// statement.
+func (p *Parser) parseParenthesizedCondition() ast.Expression {
+	if _, ok := p.expect(token.LEFT_PAREN); !ok {
+		return nil
+	}
+	cond := p.parseExpression()
+	if cond == nil {
 		return nil
 	}
+	if _, ok := p.expect(token.RIGHT_PAREN); !ok {
+		return nil
+	}
+	return cond
 }
 
 func (p *Parser) parseFunctionDeclaration() *ast.FunctionDeclaration {
 	fn := &ast.FunctionDeclaration{}
+	fn.StartPos = p.currTok.Pos
+	defer p.endTypeParameters(len(p.typeParams))
+
+	// skip 'function'
+	p.nextTok()
+
+	if !p.parseFunctionSignature(fn) {
+		// still look for errors in the body, if we can find it
+		p.skipUntil(token.LEFT_BRACE)
+		if p.currTok.Type == token.LEFT_BRACE {
+			p.parseBlockStatements()
+		}
+		return nil
+	}
+
+	body, ok := p.parseBlockStatements()
+	if !ok {
+		return nil
+	}
+	fn.Body = body
+	fn.EndPos = p.prevEnd
+
+	return fn
+}
+
+// parseFunctionSignature parses the name, parameters and return type of fn,
+// leaving the current token on the opening brace of the body.
+func (p *Parser) parseFunctionSignature(fn *ast.FunctionDeclaration) bool {
+	name, ok := p.expect(token.IDENT)
+	if !ok {
+		return false
+	}
+	fn.Name = name
+
+	fn.TypeParams, ok = p.parseTypeParameters()
+	if !ok {
+		return false
+	}
+
+	fn.Params, ok = p.parseParameters(true)
+	if !ok {
+		return false
+	}
+
+	fn.ReturnType = p.parseReturnType()
+	if fn.ReturnType == nil {
+		return false
+	}
+
+	if !p.match(token.LEFT_BRACE) {
+		p.errorExpected(p.currTok, token.LEFT_BRACE, token.Describe(token.LEFT_BRACE))
+		return false
+	}
+	return true
+}
+
+// parseParameters parses a parenthesized parameter list, the current token
+// being the opening parenthesis. Unless typed is set, the parameters may
+// omit their type, as those of arrow functions and function expressions,
+// whose types can be inferred from the context.
+func (p *Parser) parseParameters(typed bool) ([]ast.FunctionParam, bool) {
+	if _, ok := p.expect(token.LEFT_PAREN); !ok {
+		return nil, false
+	}
+
+	params := []ast.FunctionParam{}
+	for !p.match(token.RIGHT_PAREN) {
+		paramName, ok := p.expect(token.IDENT)
+		if !ok {
+			return nil, false
+		}
+		if !typed && !p.match(token.COLON) {
+			params = append(params, ast.FunctionParam{Name: paramName})
+			if !p.match(token.COMMA) {
+				break
+			}
+			p.nextTok()
+			continue
+		}
+		if _, ok := p.expect(token.COLON); !ok {
+			return nil, false
+		}
+		paramType := p.parseType()
+		if paramType == nil {
+			return nil, false
+		}
+
+		params = append(params, ast.FunctionParam{Type: paramType, Name: paramName})
+		if !p.match(token.COMMA) {
+			break
+		}
+		p.nextTok()
+	}
+	if _, ok := p.expect(token.RIGHT_PAREN); !ok {
+		return nil, false
+	}
+
+	return params, true
+}
+
+// parseReturnType parses the return type annotation following a parameter
+// list, including the colon.
+func (p *Parser) parseReturnType() ast.TypeExpr {
+	if _, ok := p.expect(token.COLON); !ok {
+		return nil
+	}
+	return p.parseType()
+}
+
+// parseBlockStatements parses statements up to and including the closing
+// brace of a block. The current token must be the opening brace. Statements
+// that fail to parse are recorded as *ast.BadStatement.
+func (p *Parser) parseBlockStatements() ([]ast.Statement, bool) {
+	p.nextTok()
+
+	stmts := []ast.Statement{}
+	for p.currTok.Type != token.RIGHT_BRACE {
+		if p.currTok.Type == token.EOF {
+			p.errorExpected(p.currTok, token.RIGHT_BRACE, token.Describe(token.RIGHT_BRACE))
+			return nil, false
+		}
+
+		stmts = append(stmts, p.parseStatementWithRecovery())
+	}
+	p.nextTok()
+
+	return stmts, true
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{}
+	block.StartPos = p.currTok.Pos
+
+	stmts, ok := p.parseBlockStatements()
+	if !ok {
+		return nil
+	}
+	block.Statements = stmts
+	block.EndPos = p.prevEnd
+
+	return block
+}
+
+func (p *Parser) parseIfStatement() *ast.IfStatement {
+	stmt := &ast.IfStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
+
+	stmt.Condition = p.parseParenthesizedCondition()
+	if stmt.Condition == nil {
+		return nil
+	}
+
+	stmt.Consequence = p.parseStatementWithRecovery()
+
+	if p.currTok.Type == token.ELSE {
+		p.nextTok()
+		stmt.Alternative = p.parseStatementWithRecovery()
+	}
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+// parseSwitchStatement parses a switch statement. The statements of a case
+// clause extend up to the next clause or the closing brace.
+func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
+	stmt := &ast.SwitchStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
+
+	stmt.Discriminant = p.parseParenthesizedCondition()
+	if stmt.Discriminant == nil {
+		return nil
+	}
+	if _, ok := p.expect(token.LEFT_BRACE); !ok {
+		return nil
+	}
+
+	hasDefault := false
+	for !p.match(token.RIGHT_BRACE) {
+		clause := &ast.CaseClause{}
+		clause.StartPos = p.currTok.Pos
+
+		switch p.currTok.Type {
+		case token.CASE:
+			p.nextTok()
+			if clause.Test = p.parseExpression(); clause.Test == nil {
+				return nil
+			}
+		case token.DEFAULT:
+			if hasDefault {
+				p.errorf(p.currTok, "a 'default' clause cannot appear more than once in a 'switch' statement")
+			}
+			hasDefault = true
+			p.nextTok()
+		default:
+			p.errorExpected(p.currTok, token.CASE, "'case' or 'default'")
+			return nil
+		}
+		if _, ok := p.expect(token.COLON); !ok {
+			return nil
+		}
+
+		clause.Body = []ast.Statement{}
+		for !p.match(token.CASE, token.DEFAULT, token.RIGHT_BRACE, token.EOF) {
+			clause.Body = append(clause.Body, p.parseStatementWithRecovery())
+		}
+		clause.EndPos = p.prevEnd
+		stmt.Cases = append(stmt.Cases, clause)
+	}
+	p.nextTok()
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+// skipUntil advances to the next token of type t, stopping early at anything
+// that looks like the start of another statement.
+func (p *Parser) skipUntil(t token.TokenType) {
+	for p.currTok.Type != t && p.currTok.Type != token.EOF {
+		if p.match(token.SEMICOLON, token.RIGHT_BRACE) || isStatementKeyword(p.currTok.Type) {
+			return
+		}
+		p.nextTok()
+	}
+}
+
+func (p *Parser) parseVariableDeclaration() *ast.VariableDeclaration {
+	keyword := p.currTok
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 	p.nextTok()
 
-	fn.Name = p.currTok
-
-	if !p.expectPeek(token.LEFT_PAREN) {
+	stmt := p.parseVariableBinding(keyword)
+	if stmt == nil || !p.expectSemicolon() {
 		return nil
 	}
-	p.nextTok()
+	stmt.EndPos = p.prevEnd
 
-	fn.Params = []ast.FunctionParam{}
-	for p.currTok.Type != token.RIGHT_PAREN {
-		if p.expectPeek(token.RIGHT_PAREN) {
-			p.nextTok()
-			break
-		}
+	return stmt
+}
 
-		if !p.expectPeek(token.IDENT) {
-			return nil
-		}
-		p.nextTok()
+// parseVariableBinding parses the rest of a variable declaration introduced
+// by keyword, starting at the variable name, up to the semicolon ending it.
+// The type annotation may be omitted if there is an initializer, and the
+// initializer may be omitted from let and var declarations.
+func (p *Parser) parseVariableBinding(keyword token.Token) *ast.VariableDeclaration {
+	stmt := &ast.VariableDeclaration{Keyword: keyword}
+	stmt.StartPos = keyword.Pos
 
-		paramName := p.currTok
-		if !p.expectPeek(token.COLON) {
-			return nil
+	name := p.nextTok()
+	stmt.Name = name.Literal
 
-		}
+	if p.match(token.COLON) {
 		p.nextTok()
-
-		if !p.expectPeekValueType() {
+		stmt.Type = p.parseType()
+		if stmt.Type == nil {
 			return nil
 		}
-		p.nextTok()
-
-		fn.Params = append(fn.Params, ast.FunctionParam{Type: p.currTok, Name: paramName})
-		if !p.expectPeek(token.COMMA) {
-			p.nextTok()
-			break
-		}
-
-		p.nextTok()
 	}
 
-	if !p.expectPeek(token.COLON) {
+	if p.match(token.ASSIGN) {
+		p.nextTok()
+		expr := p.parseExpression()
+		if expr == nil {
+			return nil
+		}
+		stmt.Expr = expr
+	} else if keyword.Type == token.CONST {
+		p.errorf(name, "'const' declarations must be initialized")
 		return nil
-	}
-	p.nextTok()
-	if !p.expectPeekValueType() {
+	} else if stmt.Type == nil && !p.match(token.SEMICOLON) && !p.canInsertSemicolon() {
+		p.errorExpected(p.currTok, token.ASSIGN, token.Describe(token.ASSIGN))
 		return nil
 	}
-	p.nextTok()
-	fn.ReturnType = p.currTok
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+func (p *Parser) parseExpression() ast.Expression {
+	return p.parseNullish()
+}
 
-	if !p.expectPeek(token.LEFT_BRACE) {
+// parseNullish parses a chain of ?? operators. Like in TypeScript, their
+// operands can't be && or || expressions without parentheses, since which
+// operator applies first would be unclear.
+func (p *Parser) parseNullish() ast.Expression {
+	expr := p.parseLogicalOr()
+	if expr == nil || !p.match(token.NULLISH) {
+		return expr
+	}
+	if !p.checkNullishOperand(expr) {
 		return nil
 	}
-	p.nextTok()
 
-	fn.Body = []ast.Statement{}
-	for p.currTok.Type != token.RIGHT_BRACE {
-		if p.currTok.Type == token.EOF {
+	for p.match(token.NULLISH) {
+		operator := p.nextTok()
+		right := p.parseLogicalOr()
+		if right == nil || !p.checkNullishOperand(right) {
 			return nil
 		}
-
-		if p.peekTok.Type == token.RIGHT_BRACE {
-			p.nextTok()
-			break
-		}
-		p.nextTok()
-		fn.Body = append(fn.Body, p.parseStatement())
+		expr = &ast.BinaryExpression{Left: expr, Operator: operator, Right: right}
 	}
-	// p.nextTok()
+	return expr
+}
 
-	return fn
+// checkNullishOperand reports an error if the operand of ?? just parsed is
+// a && or || expression that isn't parenthesized, which it is if the
+// closing parenthesis ends after it.
+func (p *Parser) checkNullishOperand(expr ast.Expression) bool {
+	bin, ok := expr.(*ast.BinaryExpression)
+	if !ok || (bin.Operator.Type != token.AND && bin.Operator.Type != token.OR) || bin.End() != p.prevEnd {
+		return true
+	}
+	p.nodeErrorf(expr, "'%s' and '??' operations cannot be mixed without parentheses", bin.Operator.Literal)
+	return false
 }
 
-func (p *Parser) parseVariableDeclaration() *ast.VariableDeclaration {
-	stmt := &ast.VariableDeclaration{}
+func (p *Parser) parseLogicalOr() ast.Expression {
+	return p.parseBinary(p.parseLogicalAnd, token.OR)
+}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
-	}
-	p.nextTok()
+func (p *Parser) parseLogicalAnd() ast.Expression {
+	return p.parseBinary(p.parseEquality, token.AND)
+}
 
-	stmt.Name = p.currTok.Literal
+func (p *Parser) parseEquality() ast.Expression {
+	return p.parseBinary(p.parseComparison, token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL)
+}
 
-	if !p.expectPeek(token.COLON) {
-		return nil
-	}
-	p.nextTok()
-	if !p.expectPeekValueType() {
-		return nil
-	}
-	p.nextTok()
-	stmt.Type = p.currTok.Literal
+func (p *Parser) parseComparison() ast.Expression {
+	return p.parseBinary(p.parseTerm, token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL, token.IN)
+}
 
-	p.nextTok()
-	if p.currTok.Type != token.ASSIGN {
-		return nil
-	}
-	p.nextTok()
-	expr := p.parseExpression()
+// parseBinary parses a left-associative chain of operands produced by next,
+// separated by any of the given operators.
+func (p *Parser) parseBinary(next func() ast.Expression, operators ...token.TokenType) ast.Expression {
+	expr := next()
 	if expr == nil {
 		return nil
 	}
-	stmt.Expr = expr
-
-	if p.currTok.Type != token.SEMICOLON {
-		return nil
-	}
 
-	return stmt
-}
+	for p.match(operators...) {
+		operator := p.currTok
+		p.nextTok()
+		right := next()
 
-func (p *Parser) parseExpression() ast.Expression {
-	return p.parseTerm()
+		if right == nil {
+			return nil
+		}
+		expr = &ast.BinaryExpression{Left: expr, Operator: operator, Right: right}
+	}
+	return expr
 }
 
 func (p *Parser) parseTerm() ast.Expression {
@@ -237,7 +1051,7 @@ func (p *Parser) parseFactor() ast.Expression {
 		return nil
 	}
 
-	for p.match(token.MUL, token.DIV) {
+	for p.match(token.MUL, token.DIV, token.MOD) {
 		operator := p.currTok
 		p.nextTok()
 		right := p.parseUnary()
@@ -251,7 +1065,11 @@ func (p *Parser) parseFactor() ast.Expression {
 }
 
 func (p *Parser) parseUnary() ast.Expression {
-	if p.match(token.BANG, token.MINUS) {
+	if p.match(token.PLUS_PLUS, token.MINUS_MINUS) {
+		p.errorf(p.currTok, "update expressions are only supported as statements")
+		return nil
+	}
+	if p.match(token.BANG, token.MINUS, token.TYPEOF) {
 		operator := p.currTok
 		p.nextTok()
 		right := p.parseUnary()
@@ -260,44 +1078,276 @@ func (p *Parser) parseUnary() ast.Expression {
 		}
 		return &ast.UnaryExpression{Operator: operator, Right: right}
 	}
-	return p.parsePrimary()
+	return p.parsePostfix()
+}
+
+// parsePostfix parses a primary expression followed by any number of calls,
+// index expressions, property accesses and non-null assertions, any of the
+// first three being optional. Calls may have type arguments. Only the
+// expression a statement starts with may be followed by '++' or '--', which
+// parseSimpleStatement parses.
+func (p *Parser) parsePostfix() ast.Expression {
+	updateAllowed := p.updateAllowed
+	p.updateAllowed = false
+	expr := p.parsePrimary()
+	if expr == nil {
+		return nil
+	}
+
+	for {
+		switch p.currTok.Type {
+		case token.LEFT_PAREN:
+			expr = p.parseFunctionCall(expr)
+		case token.LEFT_BRACKET:
+			expr = p.parseIndexExpression(expr)
+		case token.LESS:
+			if !p.isTypeArguments() {
+				return expr
+			}
+			expr = p.parseGenericCall(expr)
+		case token.DOT:
+			p.nextTok()
+			if !isIdentifierName(p.currTok) {
+				p.errorExpected(p.currTok, token.IDENT, "property name")
+				return nil
+			}
+			expr = &ast.MemberExpression{Object: expr, Property: &ast.Identifier{Token: p.nextTok()}}
+		case token.QUESTION_DOT:
+			expr = p.parseOptionalLink(expr)
+		case token.BANG:
+			// a '!' on the next line starts a new statement
+			if p.newlineBefore() {
+				return expr
+			}
+			expr = &ast.NonNullExpression{Expression: expr, Token: p.nextTok()}
+		case token.PLUS_PLUS, token.MINUS_MINUS:
+			// a '++' on the next line starts a new statement
+			if updateAllowed || p.newlineBefore() {
+				return expr
+			}
+			p.errorf(p.currTok, "update expressions are only supported as statements")
+			return nil
+		default:
+			return expr
+		}
+
+		if expr == nil {
+			return nil
+		}
+	}
+}
+
+// parseOptionalLink parses what follows the '?.' at the current token: a
+// property name, an index or the arguments of a call.
+func (p *Parser) parseOptionalLink(object ast.Expression) ast.Expression {
+	p.nextTok()
+	switch {
+	case p.match(token.LEFT_PAREN):
+		call, ok := p.parseFunctionCall(object).(*ast.FunctionCallExpression)
+		if !ok {
+			return nil
+		}
+		call.Optional = true
+		return call
+	case p.match(token.LEFT_BRACKET):
+		index, ok := p.parseIndexExpression(object).(*ast.IndexExpression)
+		if !ok {
+			return nil
+		}
+		index.Optional = true
+		return index
+	case isIdentifierName(p.currTok):
+		return &ast.MemberExpression{Object: object, Property: &ast.Identifier{Token: p.nextTok()}, Optional: true}
+	}
+	p.errorExpected(p.currTok, token.IDENT, "property name")
+	return nil
 }
 
-func (p *Parser) parseFunctionCall() ast.Expression {
-	expr := p.currTok
-	
-	if !p.expectPeek(token.LEFT_PAREN) {
-		return p.parsePrimary()
+// isIdentifierName reports whether tok may be used as a property name, which
+// unlike a variable name may also be a reserved word.
+func isIdentifierName(tok token.Token) bool {
+	if tok.Type == token.IDENT {
+		return true
 	}
+	if tok.Literal == "" {
+		return false
+	}
+	return token.LookupIdent(tok.Literal) == tok.Type || token.LookupType(tok.Literal) == tok.Type
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	expr := &ast.IndexExpression{Left: left}
+	expr.StartPos = left.Pos()
 
+	// skip '['
 	p.nextTok()
-	
-	args := []ast.Expression{}
-	for p.currTok.Type != token.RIGHT_PAREN {
-		// if p.expectPeek(token.RIGHT_PAREN) {
+
+	expr.Index = p.parseExpression()
+	if expr.Index == nil {
+		return nil
+	}
+
+	rbracket, ok := p.expect(token.RIGHT_BRACKET)
+	if !ok {
+		return nil
+	}
+	expr.EndPos = rbracket.End
+
+	return expr
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{}
+	array.StartPos = p.nextTok().Pos
+
+	array.Elements = []ast.Expression{}
+	for !p.match(token.RIGHT_BRACKET) {
+		elem := p.parseExpression()
+		if elem == nil {
+			return nil
+		}
+		array.Elements = append(array.Elements, elem)
+
+		if !p.match(token.COMMA) {
+			break
+		}
+		p.nextTok()
+	}
+
+	rbracket, ok := p.expect(token.RIGHT_BRACKET)
+	if !ok {
+		return nil
+	}
+	array.EndPos = rbracket.End
+
+	return array
+}
+
+func (p *Parser) parseObjectLiteral() ast.Expression {
+	obj := &ast.ObjectLiteral{}
+	obj.StartPos = p.nextTok().Pos
+
+	obj.Properties = []*ast.Property{}
+	for !p.match(token.RIGHT_BRACE) {
+		if !isIdentifierName(p.currTok) {
+			p.errorExpected(p.currTok, token.IDENT, "property name")
+			return nil
+		}
+		prop := &ast.Property{Key: p.nextTok()}
+
+		if p.match(token.COLON) {
 			p.nextTok()
-		// 	break
-		// }
-		args = append(args, p.parseExpression())
+			prop.Value = p.parseExpression()
+			if prop.Value == nil {
+				return nil
+			}
+		} else if prop.Key.Type == token.IDENT {
+			// shorthand property
+			prop.Value = &ast.VariableExpression{Token: prop.Key}
+		} else {
+			p.errorExpected(p.currTok, token.COLON, token.Describe(token.COLON))
+			return nil
+		}
+		obj.Properties = append(obj.Properties, prop)
+
+		if !p.match(token.COMMA) {
+			break
+		}
+		p.nextTok()
+	}
+
+	rbrace, ok := p.expect(token.RIGHT_BRACE)
+	if !ok {
+		return nil
+	}
+	obj.EndPos = rbrace.End
+
+	return obj
+}
+
+func (p *Parser) parseFunctionCall(callee ast.Expression) ast.Expression {
+	call := &ast.FunctionCallExpression{Callee: callee}
+	call.StartPos = callee.Pos()
+
+	// skip '('
+	p.nextTok()
+
+	call.Args = []ast.Expression{}
+	for p.currTok.Type != token.RIGHT_PAREN {
+		arg := p.parseExpression()
+		if arg == nil {
+			return nil
+		}
+		call.Args = append(call.Args, arg)
+
 		if p.currTok.Type != token.COMMA {
-			p.nextTok()
 			break
 		}
+		p.nextTok()
+	}
+
+	rparen, ok := p.expect(token.RIGHT_PAREN)
+	if !ok {
+		return nil
+	}
+	call.EndPos = rparen.End
+
+	return call
+}
+
+// parseGenericCall parses a call with explicit type arguments, the current
+// token being the '<' opening them.
+func (p *Parser) parseGenericCall(callee ast.Expression) ast.Expression {
+	typeArgs, ok := p.parseTypeArguments()
+	if !ok {
+		return nil
 	}
-	
-	 ex := &ast.FunctionCallExpression{Token: expr, Args: args}
-	 return ex
+	if !p.match(token.LEFT_PAREN) {
+		p.errorExpected(p.currTok, token.LEFT_PAREN, token.Describe(token.LEFT_PAREN))
+		return nil
+	}
+	call, ok := p.parseFunctionCall(callee).(*ast.FunctionCallExpression)
+	if !ok {
+		return nil
+	}
+	call.TypeArgs = typeArgs
+	return call
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
-	stmt := &ast.ReturnStatement{Token: p.currTok}
+	stmt := &ast.ReturnStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
 
-	p.nextTok()
+	if !p.match(token.SEMICOLON) && !p.canInsertSemicolon() {
+		stmt.Value = p.parseExpression()
+		if stmt.Value == nil {
+			return nil
+		}
+	}
 
+	if !p.expectSemicolon() {
+		return nil
+	}
+	stmt.EndPos = p.prevEnd
+
+	return stmt
+}
+
+// parseThrowStatement parses a throw statement, whose value has to start on
+// the same line.
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.nextTok()}
+	stmt.StartPos = stmt.Token.Pos
+
+	if p.newlineBefore() {
+		p.errorf(p.currTok, "line break not permitted after 'throw'")
+		return nil
+	}
 	stmt.Value = p.parseExpression()
-	if p.peekTok.Type == token.SEMICOLON {
-		p.nextTok()
+	if stmt.Value == nil || !p.expectSemicolon() {
+		return nil
 	}
+	stmt.EndPos = p.prevEnd
 
 	return stmt
 }
@@ -307,31 +1357,213 @@ func (p *Parser) parsePrimary() ast.Expression {
 	case token.NUMBER:
 
 		return &ast.NumberLiteral{Token: p.nextTok()}
+	case token.BIGINT:
+		return &ast.BigIntLiteral{Token: p.nextTok()}
 	case token.STRING:
 
 		return &ast.StringLiteral{Token: p.nextTok()}
+	case token.TEMPLATE_HEAD:
+		return p.parseTemplateLiteral()
 	case token.BOOLEAN:
 		return &ast.BooleanLiteral{Token: p.nextTok()}
+	case token.NULL, token.UNDEFINED:
+		return &ast.NullLiteral{Token: p.nextTok()}
 	case token.LEFT_PAREN:
+		if p.isArrowFunction() {
+			return p.parseArrowFunction()
+		}
 		p.nextTok()
 		expr := p.parseExpression()
 		if expr == nil {
 			return nil
 		}
-		if p.nextTok().Type != token.RIGHT_PAREN {
+		if _, ok := p.expect(token.RIGHT_PAREN); !ok {
 			return nil
 		}
 		return expr
-	case token.IDENT: {
-		if p.expectPeek(token.LEFT_PAREN) {
-			return p.parseFunctionCall()
+	case token.IDENT:
+		if p.peekTok.Type == token.ARROW {
+			return p.parseArrowFunction()
 		}
-		
 		return &ast.VariableExpression{Token: p.nextTok()}
-	}
+	case token.FUNCTION:
+		return p.parseFunctionExpression()
+	case token.LEFT_BRACKET:
+		return p.parseArrayLiteral()
+	case token.LEFT_BRACE:
+		return p.parseObjectLiteral()
+	case token.THIS:
+		return &ast.ThisExpression{Token: p.nextTok()}
+	case token.SUPER:
+		if p.peekTok.Type != token.LEFT_PAREN && p.peekTok.Type != token.DOT {
+			p.errorf(p.currTok, "'super' must be followed by an argument list or member access")
+			return nil
+		}
+		return &ast.SuperExpression{Token: p.nextTok()}
+	case token.NEW:
+		return p.parseNewExpression()
 	default:
+		p.errorExpected(p.currTok, "", "expression")
+		return nil
+	}
+}
+
+// isArrowFunction reports whether the parenthesis at the current token
+// opens the parameter list of an arrow function rather than a parenthesized
+// expression, which is only known once the token after the closing
+// parenthesis has been seen: either '=>' or the ':' of a return type
+// followed by '=>'.
+func (p *Parser) isArrowFunction() bool {
+	s := p.s.Clone()
+	tok := p.peekTok
+	next := func() { tok = s.NextToken() }
+
+	for depth := 1; depth > 0; next() {
+		switch tok.Type {
+		case token.LEFT_PAREN, token.LEFT_BRACKET, token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_BRACE:
+			depth--
+		case token.EOF:
+			return false
+		}
+	}
+
+	switch tok.Type {
+	case token.ARROW:
+		return true
+	case token.COLON:
+		// skip the tokens a return type may consist of
+		depth := 0
+		for next(); ; next() {
+			switch tok.Type {
+			case token.LEFT_PAREN, token.LEFT_BRACKET, token.LEFT_BRACE:
+				depth++
+				continue
+			case token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_BRACE:
+				depth--
+				if depth < 0 {
+					return false
+				}
+				continue
+			case token.EOF:
+				return false
+			}
+			if depth > 0 {
+				continue
+			}
+			switch tok.Type {
+			case token.ARROW:
+				return true
+			case token.IDENT, token.TYPE_NUMBER, token.TYPE_BIGINT, token.TYPE_STRING, token.TYPE_BOOLEAN, token.TYPE_VOID,
+				token.LESS, token.GREATER, token.COMMA, token.DOT:
+			default:
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// parseArrowFunction parses an arrow function, whose parameter list is
+// either parenthesized or a single parameter name, and whose body is either
+// a block or an expression.
+func (p *Parser) parseArrowFunction() ast.Expression {
+	fn := &ast.FunctionExpression{Arrow: true}
+	fn.StartPos = p.currTok.Pos
+
+	if p.match(token.IDENT) {
+		fn.Params = []ast.FunctionParam{{Name: p.nextTok()}}
+	} else {
+		params, ok := p.parseParameters(false)
+		if !ok {
+			return nil
+		}
+		fn.Params = params
+
+		if p.match(token.COLON) {
+			if fn.ReturnType = p.parseReturnType(); fn.ReturnType == nil {
+				return nil
+			}
+		}
+	}
+
+	if _, ok := p.expect(token.ARROW); !ok {
+		return nil
+	}
+
+	if p.match(token.LEFT_BRACE) {
+		body, ok := p.parseBlockStatements()
+		if !ok {
+			return nil
+		}
+		fn.Body = body
+	} else {
+		if fn.Expr = p.parseExpression(); fn.Expr == nil {
+			return nil
+		}
+	}
+	fn.EndPos = p.prevEnd
+
+	return fn
+}
+
+// parseFunctionExpression parses an anonymous function expression, whose
+// return type may be omitted.
+func (p *Parser) parseFunctionExpression() ast.Expression {
+	fn := &ast.FunctionExpression{}
+	fn.StartPos = p.nextTok().Pos
+
+	params, ok := p.parseParameters(false)
+	if !ok {
+		return nil
+	}
+	fn.Params = params
+
+	if p.match(token.COLON) {
+		if fn.ReturnType = p.parseReturnType(); fn.ReturnType == nil {
+			return nil
+		}
+	}
+
+	if !p.match(token.LEFT_BRACE) {
+		p.errorExpected(p.currTok, token.LEFT_BRACE, token.Describe(token.LEFT_BRACE))
 		return nil
 	}
+	body, ok := p.parseBlockStatements()
+	if !ok {
+		return nil
+	}
+	fn.Body = body
+	fn.EndPos = p.prevEnd
+
+	return fn
+}
+
+// parseTemplateLiteral parses a template literal with substitutions, which
+// the scanner splits into a head, middles and a tail around them.
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	head := p.nextTok()
+	tmpl := &ast.TemplateLiteral{Quasis: []string{head.Literal}}
+	for {
+		expr := p.parseExpression()
+		if expr == nil {
+			return nil
+		}
+		tmpl.Expressions = append(tmpl.Expressions, expr)
+
+		if !p.match(token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL) {
+			p.errorExpected(p.currTok, token.RIGHT_BRACE, "'}'")
+			return nil
+		}
+		part := p.nextTok()
+		tmpl.Quasis = append(tmpl.Quasis, part.Literal)
+		if part.Type == token.TEMPLATE_TAIL {
+			break
+		}
+	}
+	tmpl.Loc = ast.Loc{StartPos: head.Pos, EndPos: p.prevEnd}
+	return tmpl
 }
 
 func (p *Parser) match(types ...token.TokenType) bool {
@@ -2,11 +2,14 @@ package parser
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/diag"
 	"github.com/toyaAoi/sild/scanner"
+	"github.com/toyaAoi/sild/token"
 )
 
 func TestParseProgram(t *testing.T) {
@@ -69,22 +72,43 @@ func TestParseProgram(t *testing.T) {
 		},
 		{
 			name:        "missing semicolon",
-			input:       "let x: number = 42",
+			input:       "let x: number = 42 let y: number = 1;",
 			expectError: true,
 		},
 		{
-			name:        "missing type annotation",
-			input:       "let x = 42;",
+			name:          "inferred type",
+			input:         "let x = 42;",
+			expectedName:  "x",
+			expectedType:  "",
+			expectedValue: "42",
+		},
+		{
+			name:          "const with inferred type",
+			input:         `const greeting = greet("a");`,
+			expectedName:  "greeting",
+			expectedType:  "",
+			expectedValue: "greet(a)",
+		},
+		{
+			name:          "uninitialized let",
+			input:         "let total: number;",
+			expectedName:  "total",
+			expectedType:  "number",
+			expectedValue: "",
+		},
+		{
+			name:        "missing type annotation and value",
+			input:       "let x 42;",
 			expectError: true,
 		},
 		{
-			name:        "missing value",
-			input:       "let x: number = ;",
+			name:        "uninitialized const",
+			input:       "const x: number;",
 			expectError: true,
 		},
 		{
-			name:        "invalid type",
-			input:       "let x: invalid = 42;",
+			name:        "missing value",
+			input:       "let x: number = ;",
 			expectError: true,
 		},
 	}
@@ -96,9 +120,13 @@ func TestParseProgram(t *testing.T) {
 			program := p.ParseProgram()
 
 			if tt.expectError {
-				if len(program.Statements) > 0 && program.Statements[0] != nil {
-					fmt.Printf("%T", program.Statements[0])
-					t.Fatalf("expected error but got valid statement: %s", program.Statements[0].String())
+				if len(p.Diagnostics()) == 0 {
+					t.Fatalf("expected error but got no diagnostics")
+				}
+				if len(program.Statements) > 0 {
+					if _, ok := program.Statements[0].(*ast.BadStatement); !ok {
+						t.Fatalf("expected error but got valid statement: %s", program.Statements[0].String())
+					}
 				}
 				return
 			}
@@ -271,8 +299,8 @@ func TestFunctionDeclarationParsing(t *testing.T) {
 			tsInput:      "function add(x: number, y: number): number { return x + y }",
 			expectedName: "add",
 			expectedParams: []string{
-				"x int",
-				"y int",
+				"x float64",
+				"y float64",
 			},
 			expectedReturnType: "number",
 			expectedBody:       []string{"return (x + y)"},
@@ -318,8 +346,8 @@ func TestFunctionDeclarationParsing(t *testing.T) {
 		// 	tsInput:      "function complex(a: number, b: number): number { let x: number = 10 * a; let y: number = 20 * b; let z: number = x + y; return x + y + z}",
 		// 	expectedName: "complex",
 		// 	expectedParams: []string{
-		// 		"a int",
-		// 		"b int",
+		// 		"a float64",
+		// 		"b float64",
 		// 	},
 		// 	expectedReturnType: "number",
 		// 	expectedBody: []string{
@@ -361,8 +389,8 @@ func TestFunctionDeclarationParsing(t *testing.T) {
 				}
 			}
 
-			if fnDecl.ReturnType.Literal != tt.expectedReturnType {
-				t.Errorf("expected return type %q, got %q", tt.expectedReturnType, fnDecl.ReturnType.Literal)
+			if fnDecl.ReturnType.String() != tt.expectedReturnType {
+				t.Errorf("expected return type %q, got %q", tt.expectedReturnType, fnDecl.ReturnType.String())
 			}
 
 			for i, stmt := range fnDecl.Body {
@@ -453,5 +481,1591 @@ func TestFunctionErrorCases(t *testing.T) {
 }
 
 func isValidFunctionDeclaration(funcDecl *ast.FunctionDeclaration) bool {
-	return funcDecl.Name.Literal != "" && funcDecl.ReturnType.Literal != "" && funcDecl.Body != nil
+	return funcDecl.Name.Literal != "" && funcDecl.ReturnType.String() != "" && funcDecl.Body != nil
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		line     int
+		column   int
+		expected token.TokenType
+		message  string
+	}{
+		{
+			name:     "missing semicolon",
+			input:    "let x: number = 42 let y: number = 1;",
+			line:     1,
+			column:   20,
+			expected: token.SEMICOLON,
+			message:  "expected ';', found 'let'",
+		},
+		{
+			name:    "missing type",
+			input:   "let x: = 42;",
+			line:    1,
+			column:  8,
+			message: "expected type, found '='",
+		},
+		{
+			name:    "missing value",
+			input:   "let x: number = ;",
+			line:    1,
+			column:  17,
+			message: "expected expression, found ';'",
+		},
+		{
+			name:     "missing closing paren",
+			input:    "let x: number = (1 + 2;",
+			line:     1,
+			column:   23,
+			expected: token.RIGHT_PAREN,
+			message:  "expected ')', found ';'",
+		},
+		{
+			name:     "missing closing brace",
+			input:    "function greet(): void {",
+			line:     1,
+			column:   25,
+			expected: token.RIGHT_BRACE,
+			message:  "expected '}', found end of file",
+		},
+		{
+			name:     "missing function name",
+			input:    "function (): void {}",
+			line:     1,
+			column:   10,
+			expected: token.IDENT,
+			message:  "expected identifier, found '('",
+		},
+		{
+			name:    "invalid numeric literal",
+			input:   "let x: number = 1__0;",
+			line:    1,
+			column:  17,
+			message: "expected expression, found invalid numeric literal '1__0'",
+		},
+		{
+			name:    "unterminated string literal",
+			input:   "let s: string = 'abc;",
+			line:    1,
+			column:  17,
+			message: "expected expression, found invalid string literal ''abc;'",
+		},
+		{
+			name:     "unclosed template substitution",
+			input:    "let s = `${a b}`;",
+			line:     1,
+			column:   14,
+			expected: token.RIGHT_BRACE,
+			message:  "expected '}', found 'b'",
+		},
+		{
+			name:     "statements on one line",
+			input:    "x = 1 y = 2",
+			line:     1,
+			column:   7,
+			expected: token.SEMICOLON,
+			message:  "expected ';', found 'y'",
+		},
+		{
+			name:    "unterminated comment",
+			input:   "let x = 1; /* note",
+			line:    1,
+			column:  12,
+			message: "expected statement, found unterminated comment",
+		},
+		{
+			name:    "not a statement",
+			input:   "else;",
+			line:    1,
+			column:  1,
+			message: "expected statement, found 'else'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected a diagnostic for %q", tt.input)
+			}
+
+			d := diags[0]
+			if d.Severity != diag.Error {
+				t.Errorf("expected severity error, got %s", d.Severity)
+			}
+			if d.Pos.Line != tt.line || d.Pos.Column != tt.column {
+				t.Errorf("expected position %d:%d, got %d:%d", tt.line, tt.column, d.Pos.Line, d.Pos.Column)
+			}
+			if d.Expected != tt.expected {
+				t.Errorf("expected token %q, got %q", tt.expected, d.Expected)
+			}
+			if d.Message != tt.message {
+				t.Errorf("expected message %q, got %q", tt.message, d.Message)
+			}
+		})
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "function add(a: number, b: number): number {\n  return a + b;\n}\nlet x: number = add(1, 2);"
+
+	p := New(scanner.New(strings.NewReader(input)))
+	program := p.ParseProgram()
+
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+	}
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Statements))
+	}
+
+	fn := program.Statements[0].(*ast.FunctionDeclaration)
+	ret := fn.Body[0].(*ast.ReturnStatement)
+	varDecl := program.Statements[1].(*ast.VariableDeclaration)
+
+	tests := []struct {
+		name       string
+		node       ast.Node
+		start, end string
+	}{
+		{"function", fn, "1:1", "3:2"},
+		{"return", ret, "2:3", "2:16"},
+		{"return value", ret.Value, "2:10", "2:15"},
+		{"variable", varDecl, "4:1", "4:27"},
+		{"call", varDecl.Expr, "4:17", "4:26"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.node.Pos().String(); got != tt.start {
+			t.Errorf("%s: expected start %s, got %s", tt.name, tt.start, got)
+		}
+		if got := tt.node.End().String(); got != tt.end {
+			t.Errorf("%s: expected end %s, got %s", tt.name, tt.end, got)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedStmts []string
+		expectedDiags []string
+	}{
+		{
+			name: "bad statement between good ones",
+			input: `let x: number = 1;
+let y: number = ;
+let z: number = 3;`,
+			expectedStmts: []string{
+				`name: "x", type: "number", value: "1"`,
+				"<bad statement>",
+				`name: "z", type: "number", value: "3"`,
+			},
+			expectedDiags: []string{"2:17: error: expected expression, found ';'"},
+		},
+		{
+			name: "every error is reported",
+			input: `let a: number = 1 1
+let b: = 2;
+let c: number = (3;
+let d: number = 4;`,
+			expectedStmts: []string{
+				"<bad statement>",
+				"<bad statement>",
+				"<bad statement>",
+				`name: "d", type: "number", value: "4"`,
+			},
+			expectedDiags: []string{
+				"1:19: error: expected ';', found '1'",
+				"2:8: error: expected type, found '='",
+				"3:19: error: expected ')', found ';'",
+			},
+		},
+		{
+			name: "errors inside a function body",
+			input: `function f(a: number): number {
+    let x: number = ;
+    return a +;
+}
+let y: number = f(1);`,
+			expectedStmts: []string{
+				`name: "f", params: ["a float64"], body: ["<bad statement>" "<bad statement>"], return type: "number"`,
+				`name: "y", type: "number", value: "f(1)"`,
+			},
+			expectedDiags: []string{
+				"2:21: error: expected expression, found ';'",
+				"3:15: error: expected expression, found ';'",
+			},
+		},
+		{
+			name: "bad function signature still checks the body",
+			input: `function f(a number): number {
+    let x: number = ;
+}
+let y: number = 2;`,
+			expectedStmts: []string{
+				"<bad statement>",
+				`name: "y", type: "number", value: "2"`,
+			},
+			expectedDiags: []string{
+				"1:14: error: expected ':', found 'number'",
+				"2:21: error: expected expression, found ';'",
+			},
+		},
+		{
+			name:  "stray closing brace",
+			input: "}\nlet x: number = 1;",
+			expectedStmts: []string{
+				"<bad statement>",
+				`name: "x", type: "number", value: "1"`,
+			},
+			expectedDiags: []string{"1:1: error: expected statement, found '}'"},
+		},
+		{
+			name: "block skipped while recovering",
+			input: `function f(a: number): number {
+    if (a > 1 { return 1; }
+    return 0;
+}
+let y: number = 2;`,
+			expectedStmts: []string{
+				`name: "f", params: ["a float64"], body: ["<bad statement>" "return 0"], return type: "number"`,
+				`name: "y", type: "number", value: "2"`,
+			},
+			expectedDiags: []string{"2:15: error: expected ')', found '{'"},
+		},
+		{
+			name: "bad class member",
+			input: `class A {
+    x: = 1;
+    y: number = 2;
+    m(): number { return 1; }
+}
+let z: number = 1;`,
+			expectedStmts: []string{
+				"class A { y: number = 2; m(): number { return 1 } }",
+				`name: "z", type: "number", value: "1"`,
+			},
+			expectedDiags: []string{"2:8: error: expected type, found '='"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(program.Statements) != len(tt.expectedStmts) {
+				t.Fatalf("expected %d statements, got %d", len(tt.expectedStmts), len(program.Statements))
+			}
+			for i, stmt := range program.Statements {
+				if got := stmt.String(); got != tt.expectedStmts[i] {
+					t.Errorf("statement %d: expected %s, got %s", i, tt.expectedStmts[i], got)
+				}
+			}
+
+			diags := p.Diagnostics()
+			if len(diags) != len(tt.expectedDiags) {
+				t.Fatalf("expected %d diagnostics, got %d: %v", len(tt.expectedDiags), len(diags), diags)
+			}
+			for i, d := range diags {
+				if got := d.Error(); got != tt.expectedDiags[i] {
+					t.Errorf("diagnostic %d: expected %s, got %s", i, tt.expectedDiags[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestComparisonAndLogicalPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"comparison binds looser than arithmetic",
+			"let b: boolean = 1 + 2 < 3 * 4;",
+			"((1 + 2) < (3 * 4))",
+		},
+		{
+			"equality binds looser than comparison",
+			"let b: boolean = a < b === c >= d;",
+			"((a < b) === (c >= d))",
+		},
+		{
+			"and binds tighter than or",
+			"let b: boolean = a || b && c;",
+			"(a || (b && c))",
+		},
+		{
+			"or is left associative",
+			"let b: boolean = a || b || c;",
+			"((a || b) || c)",
+		},
+		{
+			"mixed",
+			"let b: boolean = !a && x + 1 != y || z !== 2;",
+			"((!a && ((x + 1) != y)) || (z !== 2))",
+		},
+		{
+			"parentheses override precedence",
+			"let b: boolean = (a || b) && c == d;",
+			"((a || b) && (c == d))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+
+			varDecl, ok := program.Statements[0].(*ast.VariableDeclaration)
+			if !ok {
+				t.Fatalf("expected VariableDeclaration, got %T", program.Statements[0])
+			}
+
+			if got := varDecl.Expr.String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIfStatementParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"if with block",
+			"if (x > 1) { let y: number = 2; }",
+			`if (x > 1) { name: "y", type: "number", value: "2" }`,
+		},
+		{
+			"if with single statement",
+			"if (x) return 1;",
+			"if x return 1",
+		},
+		{
+			"if else",
+			"if (x) { return 1; } else { return 2; }",
+			"if x { return 1 } else { return 2 }",
+		},
+		{
+			"else if chain",
+			"if (x < 1) return 1; else if (x < 2) return 2; else return 3;",
+			"if (x < 1) return 1 else if (x < 2) return 2 else return 3",
+		},
+		{
+			"nested if",
+			"if (a) { if (b) { return 1; } }",
+			"if a { if b { return 1 } }",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if len(program.Statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+			}
+
+			if _, ok := program.Statements[0].(*ast.IfStatement); !ok {
+				t.Fatalf("expected IfStatement, got %T", program.Statements[0])
+			}
+
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoopParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"while",
+			"while (i < 10) { i++; }",
+			"while (i < 10) { i++ }",
+		},
+		{
+			"while with single statement",
+			"while (x) x -= 1;",
+			"while x x -= 1",
+		},
+		{
+			"do while",
+			"do { i = i + 1; } while (i < 10);",
+			"do { i = (i + 1) } while (i < 10)",
+		},
+		{
+			"do while without semicolon",
+			"do i *= 2; while (i < 100)",
+			"do i *= 2 while (i < 100)",
+		},
+		{
+			"for",
+			"for (let i: number = 0; i < n; i++) { total += i; }",
+			`for (name: "i", type: "number", value: "0"; (i < n); i++) { total += i }`,
+		},
+		{
+			"for with assignment init and prefix update",
+			"for (i = 0; i < n; ++i) {}",
+			"for (i = 0; (i < n); ++i) {  }",
+		},
+		{
+			"for with empty clauses",
+			"for (;;) { break; }",
+			"for (; ; ) { break }",
+		},
+		{
+			"labeled loops",
+			"outer: for (;;) { while (true) { continue outer; } }",
+			"outer: for (; ; ) { while true { continue outer } }",
+		},
+		{
+			"expression statement",
+			"print(i % 2);",
+			"print((i % 2))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if len(program.Statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+			}
+
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeclarationKeywords(t *testing.T) {
+	input := `let a = 1;
+const b: string = "b";
+var c;
+for (var i = 0; i < 3; i++) {}
+xs[i].count += b.length;`
+
+	p := New(scanner.New(strings.NewReader(input)))
+	program := p.ParseProgram()
+
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+	}
+
+	expected := []token.TokenType{token.LET, token.CONST, token.VAR}
+	for i, kw := range expected {
+		decl, ok := program.Statements[i].(*ast.VariableDeclaration)
+		if !ok {
+			t.Fatalf("statement %d: expected VariableDeclaration, got %T", i, program.Statements[i])
+		}
+		if decl.Keyword.Type != kw {
+			t.Errorf("statement %d: expected keyword %s, got %s", i, kw, decl.Keyword.Type)
+		}
+	}
+
+	init := program.Statements[3].(*ast.ForStatement).Init.(*ast.VariableDeclaration)
+	if init.Keyword.Type != token.VAR {
+		t.Errorf("expected var in for initializer, got %s", init.Keyword.Type)
+	}
+
+	assign, ok := program.Statements[4].(*ast.AssignmentStatement)
+	if !ok {
+		t.Fatalf("expected AssignmentStatement, got %T", program.Statements[4])
+	}
+	if got := assign.String(); got != "xs[i].count += b.length" {
+		t.Errorf("expected xs[i].count += b.length, got %s", got)
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	p := New(scanner.New(strings.NewReader("1 + 2 = 3;\nf() += 1;\nx++;")))
+	program := p.ParseProgram()
+
+	expected := []string{
+		"1:1: error: invalid assignment target",
+		"2:1: error: invalid assignment target",
+	}
+
+	diags := p.Diagnostics()
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %v", len(expected), diags)
+	}
+	for i, d := range diags {
+		if d.Error() != expected[i] {
+			t.Errorf("diagnostic %d: expected %s, got %s", i, expected[i], d.Error())
+		}
+	}
+
+	if _, ok := program.Statements[2].(*ast.IncDecStatement); !ok {
+		t.Errorf("expected IncDecStatement, got %T", program.Statements[2])
+	}
+}
+
+func TestForInOfParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		typ      string
+	}{
+		{
+			"for of with const",
+			"for (const ch of word) { count += 1; }",
+			"for (const ch of word) { count += 1 }",
+			"*ast.ForOfStatement",
+		},
+		{
+			"for of with let and call",
+			"for (let x of items()) print(x);",
+			"for (let x of items()) print(x)",
+			"*ast.ForOfStatement",
+		},
+		{
+			"for in",
+			"for (const key in obj) { print(key); }",
+			"for (const key in obj) { print(key) }",
+			"*ast.ForInStatement",
+		},
+		{
+			"c style for is still parsed",
+			"for (let i: number = 0; i < 3; i++) {}",
+			`for (name: "i", type: "number", value: "0"; (i < 3); i++) {  }`,
+			"*ast.ForStatement",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if len(program.Statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+			}
+
+			if got := fmt.Sprintf("%T", program.Statements[0]); got != tt.typ {
+				t.Fatalf("expected %s, got %s", tt.typ, got)
+			}
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestArrayParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"array type and literal",
+			"let xs: number[] = [1, 2, 3];",
+			`name: "xs", type: "number[]", value: "[1, 2, 3]"`,
+		},
+		{
+			"generic array type",
+			"let names: Array<string> = [];",
+			`name: "names", type: "Array<string>", value: "[]"`,
+		},
+		{
+			"nested array type",
+			"let grid: number[][] = [[1], [2, 3]];",
+			`name: "grid", type: "number[][]", value: "[[1], [2, 3]]"`,
+		},
+		{
+			"index and length",
+			"let n: number = xs[i + 1] + xs.length;",
+			`name: "n", type: "number", value: "(xs[(i + 1)] + xs.length)"`,
+		},
+		{
+			"chained method calls",
+			"xs.map(double).filter(isEven);",
+			"xs.map(double).filter(isEven)",
+		},
+		{
+			"index assignment",
+			"xs[0] = 1;",
+			"xs[0] = 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if len(program.Statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+			}
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStringParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"single quotes",
+			"let s: string = 'it\\'s';",
+			`name: "s", type: "string", value: "it's"`,
+		},
+		{
+			"template literal",
+			"let s: string = `${a} + ${b} = ${a + b}`;",
+			"name: \"s\", type: \"string\", value: \"`${a} + ${b} = ${(a + b)}`\"",
+		},
+		{
+			"nested template literal",
+			"let s: string = `<${`${tag}`}>`;",
+			"name: \"s\", type: \"string\", value: \"`<${`${tag}`}>`\"",
+		},
+		{
+			"template literal without substitutions",
+			"let s: string = `hi`;",
+			`name: "s", type: "string", value: "hi"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if len(program.Statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+			}
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAutomaticSemicolonInsertion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"newline terminated statements",
+			"let x = 1\nlet y: number = x\nx = y + 1\nprint(x)",
+			[]string{`name: "x", type: "", value: "1"`, `name: "y", type: "number", value: "x"`, "x = (y + 1)", "print(x)"},
+		},
+		{
+			"declarations without initializers",
+			"let x\nlet y: number",
+			[]string{`name: "x", type: "", value: ""`, `name: "y", type: "number", value: ""`},
+		},
+		{
+			"before a closing brace",
+			"function f(): number { return 1 }",
+			[]string{`name: "f", params: [], body: ["return 1"], return type: "number"`},
+		},
+		{
+			"return value on the next line",
+			"function f(): void {\n    return\n    g()\n}",
+			[]string{`name: "f", params: [], body: ["return" "g()"], return type: "void"`},
+		},
+		{
+			"label on the next line",
+			"outer: while (true) {\n    break\n    outer\n}",
+			[]string{"outer: while true { break; outer }"},
+		},
+		{
+			"increment on the next line",
+			"x\n++y",
+			[]string{"x", "++y"},
+		},
+		{
+			"postfix increments",
+			"x++\ny--",
+			[]string{"x++", "y--"},
+		},
+		{
+			"line starting with a parenthesis continues a call",
+			"let a = b\n(c)",
+			[]string{`name: "a", type: "", value: "b(c)"`},
+		},
+		{
+			"line starting with a bracket continues an index",
+			"let a = b\n[0]",
+			[]string{`name: "a", type: "", value: "b[0]"`},
+		},
+		{
+			"line starting with an operator continues the expression",
+			"let s = a\n+ b",
+			[]string{`name: "s", type: "", value: "(a + b)"`},
+		},
+		{
+			"members separated by line breaks",
+			"interface P {\n    x: number\n    y: number\n}\nclass C {\n    x: number = 1\n    m(): void {}\n}",
+			[]string{"interface P { x: number; y: number }", "class C { x: number = 1; m(): void {  } }"},
+		},
+		{
+			"do-while followed by a statement",
+			"do x++\nwhile (x < 3) print(x)",
+			[]string{"do x++ while (x < 3)", "print(x)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			var got []string
+			for _, stmt := range program.Statements {
+				got = append(got, stmt.String())
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCommentAttachment(t *testing.T) {
+	input := `// counts things
+/**
+ * Adds two numbers.
+ * @param a the first
+ */
+function add(a: number, b: number): number {
+    return a + b; // sum
+    // not attached
+}
+let x = add(1, 2); /* three */ // done
+interface Point {
+    /** The x coordinate. */
+    x: number; // across
+    y: number
+}
+class Counter {
+    // The count so far.
+    count: number = 0;
+    /** Increments the count. */
+    inc(): void {} // by one
+}`
+
+	p := New(scanner.New(strings.NewReader(input)))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+	}
+
+	fn := program.Statements[0].(*ast.FunctionDeclaration)
+	ret := fn.Body[0].(*ast.ReturnStatement)
+	decl := program.Statements[1].(*ast.VariableDeclaration)
+	iface := program.Statements[2].(*ast.InterfaceDeclaration)
+	class := program.Statements[3].(*ast.ClassDeclaration)
+
+	tests := []struct {
+		name     string
+		comments *ast.CommentGroup
+		expected []string
+	}{
+		{"function", fn.Leading, []string{"counts things", "Adds two numbers.", "@param a the first"}},
+		{"return", ret.Leading, nil},
+		{"return trailing", ret.Trailing, []string{"sum"}},
+		{"variable", decl.Leading, nil},
+		{"variable trailing", decl.Trailing, []string{"three", "done"}},
+		{"property", iface.Type.Members[0].Leading, []string{"The x coordinate."}},
+		{"property trailing", iface.Type.Members[0].Trailing, []string{"across"}},
+		{"last property", iface.Type.Members[1].Leading, nil},
+		{"field", class.Fields[0].Leading, []string{"The count so far."}},
+		{"method", class.Methods[0].Leading, []string{"Increments the count."}},
+		{"method trailing", class.Methods[0].Trailing, []string{"by one"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.comments.Text(); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+	if !ast.IsDocComment(fn.Leading.List[1]) || ast.IsDocComment(fn.Leading.List[0]) {
+		t.Errorf("expected only the second comment of %q to be a doc comment", fn.Name.Literal)
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let xs: Array = [];", "1:9: error: generic type 'Array' requires 1 type argument"},
+		{"let xs: number[] = [1, 2;", "1:25: error: expected ']', found ';'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestObjectParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"interface",
+			"interface Point { x: number; y: number; label?: string }",
+			"interface Point { x: number; y: number; label?: string }",
+		},
+		{
+			"type alias of object type",
+			"type Line = { from: Point, to: Point }; interface Point {}",
+			"type Line = { from: Point; to: Point }",
+		},
+		{
+			"object literal",
+			"let p: Point = { x: 1, y: -2, }; interface Point { x: number; y: number }",
+			`name: "p", type: "Point", value: "{ x: 1, y: -2 }"`,
+		},
+		{
+			"shorthand properties",
+			"let p: { x: number } = { x };",
+			`name: "p", type: "{ x: number }", value: "{ x: x }"`,
+		},
+		{
+			"member assignment",
+			"p.x = p.y;",
+			"p.x = p.y",
+		},
+		{
+			"type is an ordinary identifier",
+			"type = 1;",
+			"type = 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestObjectTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"interface P { x: number; x: string }", "1:26: error: duplicate identifier 'x'"},
+		{"interface P {} type P = number;", "1:21: error: duplicate identifier 'P'"},
+		{"interface P { x number }", "1:17: error: expected ':', found 'number'"},
+		{"let p: P = { 1: 2 };", "1:14: error: expected property name, found '1'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClassParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"fields constructor and methods",
+			`class Point {
+    x: number = 0;
+    private y: number;
+    constructor(y: number) { this.y = y; }
+    norm(): number { return this.x * this.y; }
+}`,
+			"class Point { x: number = 0; private y: number; constructor(y: number) { this.y = y }; norm(): number { return (this.x * this.y) } }",
+		},
+		{
+			"modifiers",
+			`class C {
+    public static readonly max: number = 1;
+    protected label?: string;
+    static create(): C { return new C(); }
+}`,
+			"class C { public static readonly max: number = 1; protected label?: string; static create(): C { return new C() } }",
+		},
+		{
+			"modifier names as member names",
+			"class C { static: number; readonly(): void {} }",
+			"class C { static: number; readonly(): void {  } }",
+		},
+		{
+			"empty class",
+			"class Empty {}",
+			"class Empty {}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClassErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"class C { constructor() {} constructor() {} }", "1:28: error: multiple constructor implementations are not allowed"},
+		{"class C { private public x: number; }", "1:19: error: accessibility modifier already seen"},
+		{"class C { static private x: number; }", "1:18: error: 'private' modifier must precede 'static' modifier"},
+		{"class C { static constructor() {} }", "1:11: error: 'static' modifier cannot appear on a constructor declaration"},
+		{"class C { x: number; x(): void {} }", "1:22: error: duplicate identifier 'x'"},
+		{"class C { m() {} }", "1:15: error: expected ':', found '{'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInheritanceParsing(t *testing.T) {
+	input := `class Animal {}
+class Dog extends Animal {
+    constructor() { super(); }
+    speak(): string { return super.speak(); }
+}`
+
+	p := New(scanner.New(strings.NewReader(input)))
+	program := p.ParseProgram()
+
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+	}
+
+	expected := "class Dog extends Animal { constructor() { super() }; speak(): string { return super.speak() } }"
+	if got := program.Statements[1].String(); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestInheritanceErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"class A { m(): void { let x: number = super; } }", "1:39: error: 'super' must be followed by an argument list or member access"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFunctionExpressionParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"concise arrow",
+			"let f = (a: number, b: number): number => a * b;",
+			`name: "f", type: "", value: "(a: number, b: number): number => (a * b)"`,
+		},
+		{
+			"single parameter without parentheses",
+			"xs.map(x => x + 1);",
+			"xs.map((x) => (x + 1))",
+		},
+		{
+			"block body",
+			"let f = () => { return 1; };",
+			`name: "f", type: "", value: "() => { return 1 }"`,
+		},
+		{
+			"function expression",
+			"let f = function (a, b: string) { return b; };",
+			`name: "f", type: "", value: "function (a, b: string) { return b }"`,
+		},
+		{
+			"parenthesized expression",
+			"x = (a) + (b);",
+			"x = (a + b)",
+		},
+		{
+			"function type",
+			"let f: (x: number, y: string) => boolean[];",
+			`name: "f", type: "(x: number, y: string) => boolean[]", value: ""`,
+		},
+		{
+			"array of function types",
+			"let fs: ((x: number) => void)[] = [];",
+			`name: "fs", type: "((x: number) => void)[]", value: "[]"`,
+		},
+		{
+			"arrow returning an arrow",
+			"let add = (a: number) => (b: number) => a + b;",
+			`name: "add", type: "", value: "(a: number) => (b: number) => (a + b)"`,
+		},
+		{
+			"immediately invoked",
+			"(() => 1)();",
+			"(() => 1)()",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if got := program.Statements[0].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFunctionExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = (a: number) => ;", "1:24: error: expected expression, found ';'"},
+		{"let f = (a: number): => a;", "1:22: error: expected type, found '=>'"},
+		{"let f = function (a: number) a;", "1:30: error: expected '{', found 'a'"},
+		{"let f: (x: number) = g;", "1:20: error: expected '=>', found '='"},
+		{"let f: (x) => void = g;", "1:10: error: expected ':', found ')'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenericParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"generic function",
+			"function first<T>(xs: T[]): T { return xs[0]; }",
+			`name: "first<T>", params: ["xs []any"], body: ["return xs[0]"], return type: "T"`,
+		},
+		{
+			"constraint",
+			"interface Comparable<T> { compareTo(other: T): number; } function max<T extends Comparable<T>>(a: T, b: T): T { return a; }",
+			`name: "max<T extends Comparable<T>>", params: ["a any" "b any"], body: ["return a"], return type: "T"`,
+		},
+		{
+			"generic interface",
+			"interface Pair<A, B> { first: A; second: B }",
+			"interface Pair<A, B> { first: A; second: B }",
+		},
+		{
+			"generic class",
+			"class Stack<T> { items: T[] = []; push(x: T): void {} }",
+			"class Stack<T> { items: T[] = []; push(x: T): void {  } }",
+		},
+		{
+			"explicit type arguments",
+			"function f<A, B>(a: A): B[] { return []; } f<number, string[]>(1);",
+			"f<number, string[]>(1)",
+		},
+		{
+			"nested type arguments",
+			"interface Box<T> { value: T } let b: Box<Box<number>>;",
+			`name: "b", type: "Box<Box<number>>", value: ""`,
+		},
+		{
+			"new with type arguments",
+			"class Stack<T> {} let s = new Stack<number>();",
+			`name: "s", type: "", value: "new Stack<number>()"`,
+		},
+		{
+			"comparisons are not type arguments",
+			"let x = a < b && c > d;",
+			`name: "x", type: "", value: "((a < b) && (c > d))"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if got := program.Statements[len(program.Statements)-1].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenericErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"function f<>(): void {}", "1:11: error: type parameter list cannot be empty"},
+		{"function f<T, T>(): void {}", "1:15: error: duplicate identifier 'T'"},
+		{"function f<T>(x: T<number>): void {}", "1:18: error: type 'T' is not generic"},
+		{"let n: number<string>;", "1:8: error: type 'number' is not generic"},
+		{"class C { constructor<T>() {} }", "1:11: error: type parameters cannot appear on a constructor declaration"},
+		{"f<>(1);", "1:2: error: type argument list cannot be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnionParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"union",
+			"let x: string | number;",
+			`name: "x", type: "string | number", value: ""`,
+		},
+		{
+			"string literal types",
+			`type Dir = "left" | "right";`,
+			`type Dir = "left" | "right"`,
+		},
+		{
+			"leading bar",
+			"type Id =\n  | string\n  | number;",
+			"type Id = string | number",
+		},
+		{
+			"array of a union",
+			"let xs: (string | number)[];",
+			`name: "xs", type: "(string | number)[]", value: ""`,
+		},
+		{
+			"function in a union",
+			"let f: (() => void) | string;",
+			`name: "f", type: "(() => void) | string", value: ""`,
+		},
+		{
+			"discriminated union",
+			`type Shape = { kind: "circle"; r: number } | { kind: "square"; side: number };`,
+			`type Shape = { kind: "circle"; r: number } | { kind: "square"; side: number }`,
+		},
+		{
+			"typeof",
+			`let b = typeof x === "string";`,
+			`name: "b", type: "", value: "(typeof x === string)"`,
+		},
+		{
+			"in",
+			`let b = "r" in s && x;`,
+			`name: "b", type: "", value: "((r in s) && x)"`,
+		},
+		{
+			"switch",
+			`switch (s.kind) { case "a": case "b": f(); break; default: g(); }`,
+			`switch (s.kind) { case a: case b: f(); break; default: g(); }`,
+		},
+		{
+			"default in the middle",
+			"switch (x) { case 1: default: break; case 2: }",
+			"switch (x) { case 1: default: break; case 2: }",
+		},
+		{
+			"throw",
+			`switch (x) { case 1: throw new Error("x"); }`,
+			"switch (x) { case 1: throw new Error(x); }",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if got := program.Statements[len(program.Statements)-1].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUpdateExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let y = x++;", "1:10: error: update expressions are only supported as statements"},
+		{"print(xs[i++]);", "1:11: error: update expressions are only supported as statements"},
+		{"print(--i);", "1:7: error: update expressions are only supported as statements"},
+		{"x + y++;", "1:6: error: update expressions are only supported as statements"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) != 1 {
+				t.Fatalf("expected 1 diagnostic for %q, got %v", tt.input, diags)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSwitchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"switch (x) { default: default: }", "1:23: error: a 'default' clause cannot appear more than once in a 'switch' statement"},
+		{"switch (x) { f(); }", "1:14: error: expected 'case' or 'default', found 'f'"},
+		{"switch (x) { case 1 f(); }", "1:21: error: expected ':', found 'f'"},
+		{"switch x { }", "1:8: error: expected '(', found 'x'"},
+		{"switch (x) { case 1: throw\nx; }", "2:1: error: line break not permitted after 'throw'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNullParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"nullable type",
+			"let t: string | null | undefined;",
+			`name: "t", type: "string | null | undefined", value: ""`,
+		},
+		{
+			"null initializer",
+			"let t: string | null = null;",
+			`name: "t", type: "string | null", value: "null"`,
+		},
+		{
+			"optional property access",
+			"let a = x?.y.z;",
+			`name: "a", type: "", value: "x?.y.z"`,
+		},
+		{
+			"optional call and element access",
+			"let b = f?.(1)?.[0];",
+			`name: "b", type: "", value: "f?.(1)?.[0]"`,
+		},
+		{
+			"non-null assertions",
+			"let d = x!.y!;",
+			`name: "d", type: "", value: "x!.y!"`,
+		},
+		{
+			"nullish coalescing is left-associative",
+			"let e = a ?? b ?? c;",
+			`name: "e", type: "", value: "((a ?? b) ?? c)"`,
+		},
+		{
+			"parenthesized logical operand",
+			"let c = (a || b) ?? c;",
+			`name: "c", type: "", value: "((a || b) ?? c)"`,
+		},
+		{
+			"nullish assignment",
+			"x ??= 1;",
+			"x ??= 1",
+		},
+		{
+			"bang on the next line is a negation",
+			"let n = x\n!y;",
+			"!y",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if got := program.Statements[len(program.Statements)-1].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNullErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = a || b ?? c;", "1:9: error: '||' and '??' operations cannot be mixed without parentheses"},
+		{"let z = a ?? b && c;", "1:14: error: '&&' and '??' operations cannot be mixed without parentheses"},
+		{"a?.b = 1;", "1:1: error: the left-hand side of an assignment expression may not be an optional property access"},
+		{"let y = a?.;", "1:12: error: expected property name, found ';'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEnumParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"numeric enum",
+			"enum Color { Red, Green, Blue }",
+			"enum Color { Red, Green, Blue }",
+		},
+		{
+			"initializers and a trailing comma",
+			"enum Level { Low = 1, High = Low * 10, }",
+			"enum Level { Low = 1, High = (Low * 10) }",
+		},
+		{
+			"string enum on several lines",
+			"enum Status {\n  Ok = \"ok\",\n  Fail = \"fail\"\n}",
+			"enum Status { Ok = ok, Fail = fail }",
+		},
+		{
+			"const enum",
+			"const enum Dir { Up, Down }",
+			"const enum Dir { Up, Down }",
+		},
+		{
+			"empty enum",
+			"enum Empty {}",
+			"enum Empty {}",
+		},
+		{
+			"enum as a type",
+			"enum Color { Red }\nlet c: Color = Color.Red;",
+			`name: "c", type: "Color", value: "Color.Red"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			program := p.ParseProgram()
+
+			if len(p.Diagnostics()) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", p.Diagnostics())
+			}
+			if got := program.Statements[len(program.Statements)-1].String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEnumErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Color { Red, Red }", "1:19: error: duplicate identifier 'Red'"},
+		{"enum Color { Red Green }", "1:18: error: expected '}', found 'Green'"},
+		{"enum Color { 1 }", "1:14: error: expected enum member name, found '1'"},
+		{"enum { A }", "1:6: error: expected identifier, found '{'"},
+		{"enum Color { Red }\ninterface Color {}", "2:11: error: duplicate identifier 'Color'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := New(scanner.New(strings.NewReader(tt.input)))
+			p.ParseProgram()
+
+			diags := p.Diagnostics()
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
 }
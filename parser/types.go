@@ -0,0 +1,490 @@
+package parser
+
+import (
+	"slices"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// parseType parses a type annotation starting at the current token. The
+// members of a union may be preceded by a '|' too, as in a type alias
+// listing them one per line.
+func (p *Parser) parseType() ast.TypeExpr {
+	union := &ast.UnionType{}
+	union.StartPos = p.currTok.Pos
+	if p.match(token.PIPE) {
+		p.nextTok()
+	}
+
+	for {
+		typ := p.parseTypeOperand()
+		if typ == nil {
+			return nil
+		}
+		// a parenthesized union, as in (A | B) | C, is flattened
+		if u, ok := typ.(*ast.UnionType); ok {
+			union.Types = append(union.Types, u.Types...)
+		} else {
+			union.Types = append(union.Types, typ)
+		}
+
+		if !p.match(token.PIPE) {
+			break
+		}
+		p.nextTok()
+	}
+	if len(union.Types) == 1 {
+		return union.Types[0]
+	}
+	union.EndPos = p.prevEnd
+
+	return union
+}
+
+// parseTypeOperand parses a type other than a union, which is either a
+// single type or an array type.
+func (p *Parser) parseTypeOperand() ast.TypeExpr {
+	var typ ast.TypeExpr
+
+	switch p.currTok.Type {
+	case token.TYPE_NUMBER, token.TYPE_BIGINT, token.TYPE_STRING, token.TYPE_BOOLEAN, token.TYPE_VOID, token.IDENT:
+		typ = p.parseTypeReference()
+	case token.STRING:
+		typ = &ast.LiteralType{Token: p.nextTok()}
+	case token.NULL, token.UNDEFINED:
+		name := p.nextTok()
+		typ = &ast.TypeReference{Loc: ast.Loc{StartPos: name.Pos, EndPos: name.End}, Name: name}
+	case token.LEFT_BRACE:
+		if obj := p.parseObjectType(); obj != nil {
+			typ = obj
+		}
+	case token.LEFT_PAREN:
+		if p.isFunctionType() {
+			return p.parseFunctionType()
+		}
+		// a parenthesized type, as in ((x: number) => number)[]
+		p.nextTok()
+		typ = p.parseType()
+		if typ == nil {
+			return nil
+		}
+		if _, ok := p.expect(token.RIGHT_PAREN); !ok {
+			return nil
+		}
+	default:
+		p.errorExpected(p.currTok, "", "type")
+		return nil
+	}
+	if typ == nil {
+		return nil
+	}
+
+	for p.match(token.LEFT_BRACKET) && p.peekTok.Type == token.RIGHT_BRACKET {
+		p.nextTok()
+		typ = &ast.ArrayType{Loc: ast.Loc{StartPos: typ.Pos(), EndPos: p.nextTok().End}, Elem: typ}
+	}
+
+	return typ
+}
+
+// isFunctionType reports whether the parenthesis at the current token starts
+// a function type rather than a parenthesized type. Unlike the parameters of
+// arrow functions, those of function types always have a type, so the
+// parameter list can be recognized before its end.
+func (p *Parser) isFunctionType() bool {
+	switch p.peekTok.Type {
+	case token.RIGHT_PAREN:
+		return true
+	case token.IDENT:
+		if next := p.s.Clone().NextToken(); next.Type == token.COLON || next.Type == token.COMMA {
+			return true
+		}
+	}
+	return p.isArrowFunction()
+}
+
+// parseFunctionType parses a function type such as (x: number) => string.
+// The return type extends as far as possible, so (x: number) => string[]
+// returns an array.
+func (p *Parser) parseFunctionType() ast.TypeExpr {
+	fn := &ast.FunctionType{}
+	fn.StartPos = p.currTok.Pos
+
+	params, ok := p.parseParameters(true)
+	if !ok {
+		return nil
+	}
+	fn.Params = params
+
+	if _, ok := p.expect(token.ARROW); !ok {
+		return nil
+	}
+	if fn.ReturnType = p.parseType(); fn.ReturnType == nil {
+		return nil
+	}
+	fn.EndPos = p.prevEnd
+
+	return fn
+}
+
+func (p *Parser) parseTypeReference() ast.TypeExpr {
+	ref := &ast.TypeReference{Name: p.nextTok()}
+	ref.StartPos = ref.Name.Pos
+
+	// type names after '<' aren't recognized by the scanner
+	if typ := token.LookupType(ref.Name.Literal); typ != token.IDENT {
+		ref.Name.Type = typ
+	}
+
+	isTypeParam := slices.Contains(p.typeParams, ref.Name.Literal)
+
+	if p.match(token.LESS) {
+		p.nextTok()
+
+		for {
+			arg := p.parseType()
+			if arg == nil {
+				return nil
+			}
+			ref.Args = append(ref.Args, arg)
+
+			if !p.match(token.COMMA) {
+				break
+			}
+			p.nextTok()
+		}
+
+		if _, ok := p.expect(token.GREATER); !ok {
+			return nil
+		}
+	}
+	ref.EndPos = p.prevEnd
+
+	if ref.Name.Literal == "Array" && len(ref.Args) != 1 {
+		p.nodeErrorf(ref, "generic type 'Array' requires 1 type argument")
+		return nil
+	}
+	if (isTypeParam || ref.Name.Type != token.IDENT) && len(ref.Args) > 0 {
+		p.nodeErrorf(ref, "type '%s' is not generic", ref.Name.Literal)
+		return nil
+	}
+
+	return ref
+}
+
+// parseTypeParameters parses the type parameter list of a generic
+// declaration, if it has one, and brings the type parameters into scope.
+// A type parameter is in scope in the constraints of those that follow it,
+// and in its own, as in <T extends Comparable<T>>. The caller takes them
+// out of scope with endTypeParameters at the end of the declaration.
+func (p *Parser) parseTypeParameters() ([]*ast.TypeParam, bool) {
+	if !p.match(token.LESS) {
+		return nil, true
+	}
+	less := p.nextTok()
+
+	params := []*ast.TypeParam{}
+	for !p.match(token.GREATER) {
+		name, ok := p.expect(token.IDENT)
+		if !ok {
+			return nil, false
+		}
+		for _, prev := range params {
+			if prev.Name.Literal == name.Literal {
+				p.errorf(name, "duplicate identifier '%s'", name.Literal)
+			}
+		}
+		param := &ast.TypeParam{Name: name}
+		params = append(params, param)
+		p.typeParams = append(p.typeParams, name.Literal)
+
+		if p.match(token.EXTENDS) {
+			p.nextTok()
+			if param.Constraint = p.parseType(); param.Constraint == nil {
+				return nil, false
+			}
+		}
+
+		if !p.match(token.COMMA) {
+			break
+		}
+		p.nextTok()
+	}
+	if len(params) == 0 {
+		p.errorf(less, "type parameter list cannot be empty")
+	}
+
+	if _, ok := p.expect(token.GREATER); !ok {
+		return nil, false
+	}
+	return params, true
+}
+
+// endTypeParameters takes the type parameters declared since there were n
+// in scope out of scope.
+func (p *Parser) endTypeParameters(n int) {
+	p.typeParams = p.typeParams[:n]
+}
+
+// parseTypeArguments parses the type arguments of a call or of a new
+// expression, the current token being the '<' opening them.
+func (p *Parser) parseTypeArguments() ([]ast.TypeExpr, bool) {
+	less := p.nextTok()
+
+	args := []ast.TypeExpr{}
+	for !p.match(token.GREATER) {
+		arg := p.parseType()
+		if arg == nil {
+			return nil, false
+		}
+		args = append(args, arg)
+
+		if !p.match(token.COMMA) {
+			break
+		}
+		p.nextTok()
+	}
+	if len(args) == 0 {
+		p.errorf(less, "type argument list cannot be empty")
+	}
+
+	if _, ok := p.expect(token.GREATER); !ok {
+		return nil, false
+	}
+	return args, true
+}
+
+// isTypeArguments reports whether the '<' at the current token opens the
+// type arguments of a call rather than being a comparison, which it is if
+// only tokens that may be part of types follow up to the matching '>', and
+// the '>' is followed by the parenthesis opening the arguments.
+func (p *Parser) isTypeArguments() bool {
+	s := p.s.Clone()
+	tok := p.peekTok
+	next := func() { tok = s.NextToken() }
+
+	for depth := 1; depth > 0; next() {
+		switch tok.Type {
+		case token.LESS, token.LEFT_PAREN, token.LEFT_BRACKET, token.LEFT_BRACE:
+			depth++
+		case token.GREATER, token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_BRACE:
+			depth--
+		case token.IDENT, token.TYPE_NUMBER, token.TYPE_BIGINT, token.TYPE_STRING, token.TYPE_BOOLEAN, token.TYPE_VOID,
+			token.STRING, token.PIPE, token.COMMA, token.DOT, token.COLON, token.SEMICOLON, token.QUESTION, token.ARROW:
+		default:
+			return false
+		}
+	}
+	return tok.Type == token.LEFT_PAREN
+}
+
+// declareType records the name of a type declaration, reporting duplicates.
+func (p *Parser) declareType(name token.Token) {
+	if p.typeNames == nil {
+		p.typeNames = map[string]bool{}
+	}
+	if p.typeNames[name.Literal] || token.LookupType(name.Literal) != token.IDENT || name.Literal == "Array" {
+		p.errorf(name, "duplicate identifier '%s'", name.Literal)
+		return
+	}
+	p.typeNames[name.Literal] = true
+}
+
+// parseObjectType parses an object type literal, whose members may be
+// separated by semicolons, commas or line breaks.
+func (p *Parser) parseObjectType() *ast.ObjectType {
+	obj := &ast.ObjectType{}
+	obj.StartPos = p.nextTok().Pos
+
+	obj.Members = []*ast.PropertySignature{}
+	for !p.match(token.RIGHT_BRACE) {
+		leading := p.currTok.Comments
+		member := p.parsePropertySignature()
+		if member == nil {
+			return nil
+		}
+		if obj.Member(member.Name.Literal) != nil {
+			p.errorf(member.Name, "duplicate identifier '%s'", member.Name.Literal)
+		}
+		obj.Members = append(obj.Members, member)
+
+		separated := p.match(token.SEMICOLON, token.COMMA)
+		if separated {
+			p.nextTok()
+		}
+		p.attachComments(&member.Trivia, leading)
+		// like statements, members may be separated by line breaks
+		if !separated && !p.newlineBefore() {
+			break
+		}
+	}
+
+	rbrace, ok := p.expect(token.RIGHT_BRACE)
+	if !ok {
+		return nil
+	}
+	obj.EndPos = rbrace.End
+
+	return obj
+}
+
+func (p *Parser) parsePropertySignature() *ast.PropertySignature {
+	if !isIdentifierName(p.currTok) {
+		p.errorExpected(p.currTok, token.IDENT, "property name")
+		return nil
+	}
+	member := &ast.PropertySignature{Name: p.nextTok()}
+
+	if p.match(token.QUESTION) {
+		p.nextTok()
+		member.Optional = true
+	}
+
+	if p.match(token.LEFT_PAREN) {
+		// a method signature, as in compareTo(other: T): number, is a
+		// property of function type
+		fn := &ast.FunctionType{}
+		fn.StartPos = p.currTok.Pos
+		params, ok := p.parseParameters(true)
+		if !ok {
+			return nil
+		}
+		fn.Params = params
+		if fn.ReturnType = p.parseReturnType(); fn.ReturnType == nil {
+			return nil
+		}
+		fn.EndPos = p.prevEnd
+		member.Type = fn
+		return member
+	}
+
+	if _, ok := p.expect(token.COLON); !ok {
+		return nil
+	}
+	member.Type = p.parseType()
+	if member.Type == nil {
+		return nil
+	}
+
+	return member
+}
+
+func (p *Parser) parseInterfaceDeclaration() *ast.InterfaceDeclaration {
+	decl := &ast.InterfaceDeclaration{}
+	decl.StartPos = p.nextTok().Pos
+	defer p.endTypeParameters(len(p.typeParams))
+
+	name, ok := p.expect(token.IDENT)
+	if !ok {
+		return nil
+	}
+	decl.Name = name
+	p.declareType(name)
+
+	if decl.TypeParams, ok = p.parseTypeParameters(); !ok {
+		return nil
+	}
+
+	if !p.match(token.LEFT_BRACE) {
+		p.errorExpected(p.currTok, token.LEFT_BRACE, token.Describe(token.LEFT_BRACE))
+		return nil
+	}
+	decl.Type = p.parseObjectType()
+	if decl.Type == nil {
+		return nil
+	}
+	decl.EndPos = decl.Type.End()
+
+	return decl
+}
+
+func (p *Parser) parseTypeAliasDeclaration() *ast.TypeAliasDeclaration {
+	decl := &ast.TypeAliasDeclaration{}
+	decl.StartPos = p.nextTok().Pos
+	defer p.endTypeParameters(len(p.typeParams))
+
+	decl.Name = p.nextTok()
+	p.declareType(decl.Name)
+
+	var ok bool
+	if decl.TypeParams, ok = p.parseTypeParameters(); !ok {
+		return nil
+	}
+
+	if _, ok := p.expect(token.ASSIGN); !ok {
+		return nil
+	}
+	decl.Type = p.parseType()
+	if decl.Type == nil {
+		return nil
+	}
+	decl.EndPos = decl.Type.End()
+
+	if !p.expectSemicolon() {
+		return nil
+	}
+
+	return decl
+}
+
+// parseEnumDeclaration parses an enum, the current token being 'enum' or the
+// 'const' of a const enum. Members are separated by commas, and may be
+// named by string literals.
+func (p *Parser) parseEnumDeclaration() *ast.EnumDeclaration {
+	decl := &ast.EnumDeclaration{}
+	decl.StartPos = p.currTok.Pos
+	if p.match(token.CONST) {
+		decl.Const = true
+		p.nextTok()
+	}
+	p.nextTok()
+
+	name, ok := p.expect(token.IDENT)
+	if !ok {
+		return nil
+	}
+	decl.Name = name
+	p.declareType(name)
+
+	if _, ok := p.expect(token.LEFT_BRACE); !ok {
+		return nil
+	}
+
+	decl.Members = []*ast.EnumMember{}
+	for !p.match(token.RIGHT_BRACE) {
+		leading := p.currTok.Comments
+		if !isIdentifierName(p.currTok) && !p.match(token.STRING) {
+			p.errorExpected(p.currTok, token.IDENT, "enum member name")
+			return nil
+		}
+		member := &ast.EnumMember{Name: p.nextTok()}
+		if decl.Member(member.Name.Literal) != nil {
+			p.errorf(member.Name, "duplicate identifier '%s'", member.Name.Literal)
+		}
+		if p.match(token.ASSIGN) {
+			p.nextTok()
+			if member.Value = p.parseExpression(); member.Value == nil {
+				return nil
+			}
+		}
+		decl.Members = append(decl.Members, member)
+
+		separated := p.match(token.COMMA)
+		if separated {
+			p.nextTok()
+		}
+		p.attachComments(&member.Trivia, leading)
+		if !separated {
+			break
+		}
+	}
+
+	rbrace, ok := p.expect(token.RIGHT_BRACE)
+	if !ok {
+		return nil
+	}
+	decl.EndPos = rbrace.End
+
+	return decl
+}
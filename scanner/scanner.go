@@ -2,108 +2,244 @@ package scanner
 
 import (
 	"io"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/toyaAoi/sild/token"
 )
 
 type Scanner struct {
-	r        io.Reader
+	filename string
 	buf      []byte
-	pos      int
+	offset   int // offset of ch
+	pos      int // offset of the character after ch
+	line     int
+	col      int
 	ch       byte
+	tokStart int // offset of the first character of the current token
 	pastTok  token.Token
-	eof      bool
 	readErr  error
+
+	// templates holds, for each template literal substitution being
+	// scanned, the depth of the braces opened inside it, so that the '}'
+	// ending the substitution resumes scanning the template.
+	templates []int
 }
 
 func (s *Scanner) readChar() {
-	if s.pos >= len(s.buf) {
-		s.buf = make([]byte, 1024)
-		n, err := s.r.Read(s.buf)
-		if err != nil && err != io.EOF {
-			s.readErr = err
-			s.ch = 0
-			return
-		}
-		s.buf = s.buf[:n]
-		s.pos = 0
+	if s.pos > len(s.buf) {
+		// already past the end of input
+		return
+	}
 
-		if n == 0 {
-			s.ch = 0
-			s.eof = true
-			return
-		}
+	if s.ch == '\n' {
+		s.line++
+		s.col = 0
+	}
+
+	s.offset = s.pos
+	if s.pos == len(s.buf) {
+		s.col++
+		s.ch = 0
+		s.pos++
+		return
 	}
 
 	s.ch = s.buf[s.pos]
+	if utf8.RuneStart(s.ch) {
+		// columns count characters rather than bytes
+		s.col++
+	}
 	s.pos++
 }
 
+// position returns the position of the current character.
+func (s *Scanner) position() token.Position {
+	return token.Position{
+		Filename: s.filename,
+		Offset:   s.offset,
+		Line:     s.line,
+		Column:   s.col,
+	}
+}
+
+// Clone returns a copy of the scanner that scans independently of it, so
+// that tokens can be looked ahead at without consuming them.
+func (s *Scanner) Clone() *Scanner {
+	clone := *s
+	clone.templates = slices.Clone(s.templates)
+	return &clone
+}
+
+// Err returns the error encountered while reading the input, if any.
+func (s *Scanner) Err() error {
+	return s.readErr
+}
+
 func (s *Scanner) NextToken() token.Token {
-	s.skipWhiteSpaces()
+	comments, ok := s.skipComments()
 
 	var tok token.Token
+	pos := s.position()
+	s.tokStart = s.offset
 
 	switch s.ch {
+	case '/':
+		if !ok {
+			// an unterminated block comment runs to the end of input
+			s.advance(len(s.buf) - s.offset)
+			tok = token.Token{Type: token.ILLEGAL, Literal: string(s.buf[s.tokStart:])}
+			break
+		}
+		tok = s.newTokenWithEqual(token.DIV, token.DIV_ASSIGN)
 	case ',':
 		tok = s.newToken(token.COMMA)
+	case '.':
+		if isDigit(s.peekChar()) {
+			tok = s.numberToken()
+		} else {
+			tok = s.newToken(token.DOT)
+		}
 	case ':':
 		tok = s.newToken(token.COLON)
+	case '?':
+		switch {
+		case s.peekChar() == '?':
+			s.readChar()
+			tok = s.newTokenWithEqual(token.NULLISH, token.NULLISH_ASSIGN)
+		case s.peekChar() == '.' && (s.pos+1 >= len(s.buf) || !isDigit(s.buf[s.pos+1])):
+			// a?.5:1 is a conditional expression with a number
+			s.readChar()
+			tok = s.newToken(token.QUESTION_DOT)
+		default:
+			tok = s.newToken(token.QUESTION)
+		}
 	case ';':
 		tok = s.newToken(token.SEMICOLON)
 	case '=':
-		tok = s.newToken(token.ASSIGN)
+		if s.peekChar() == '=' {
+			s.readChar()
+			if s.peekChar() == '=' {
+				s.readChar()
+				tok = s.newToken(token.STRICT_EQUAL)
+			} else {
+				tok = s.newToken(token.EQUAL)
+			}
+		} else if s.peekChar() == '>' {
+			s.readChar()
+			tok = s.newToken(token.ARROW)
+		} else {
+			tok = s.newToken(token.ASSIGN)
+		}
 	case '+':
-		tok = s.newToken(token.PLUS)
+		if s.peekChar() == '+' {
+			s.readChar()
+			tok = s.newToken(token.PLUS_PLUS)
+		} else {
+			tok = s.newTokenWithEqual(token.PLUS, token.PLUS_ASSIGN)
+		}
 	case '-':
-		tok = s.newToken(token.MINUS)
+		if s.peekChar() == '-' {
+			s.readChar()
+			tok = s.newToken(token.MINUS_MINUS)
+		} else {
+			tok = s.newTokenWithEqual(token.MINUS, token.MINUS_ASSIGN)
+		}
 	case '*':
-		tok = s.newToken(token.MUL)
-	case '/':
-		tok = s.newToken(token.DIV)
+		tok = s.newTokenWithEqual(token.MUL, token.MUL_ASSIGN)
+	case '%':
+		tok = s.newTokenWithEqual(token.MOD, token.MOD_ASSIGN)
 	case '!':
-		tok = s.newToken(token.BANG)
+		if s.peekChar() == '=' {
+			s.readChar()
+			if s.peekChar() == '=' {
+				s.readChar()
+				tok = s.newToken(token.STRICT_NOT_EQUAL)
+			} else {
+				tok = s.newToken(token.NOT_EQUAL)
+			}
+		} else {
+			tok = s.newToken(token.BANG)
+		}
+	case '<':
+		tok = s.newTokenWithEqual(token.LESS, token.LESS_EQUAL)
+	case '>':
+		tok = s.newTokenWithEqual(token.GREATER, token.GREATER_EQUAL)
+	case '&':
+		if s.peekChar() == '&' {
+			s.readChar()
+			tok = s.newToken(token.AND)
+		} else {
+			tok = s.newToken(token.ILLEGAL)
+		}
+	case '|':
+		if s.peekChar() == '|' {
+			s.readChar()
+			tok = s.newToken(token.OR)
+		} else {
+			tok = s.newToken(token.PIPE)
+		}
 	case '(':
 		tok = s.newToken(token.LEFT_PAREN)
 	case ')':
 		tok = s.newToken(token.RIGHT_PAREN)
 	case '{':
+		if n := len(s.templates); n > 0 {
+			s.templates[n-1]++
+		}
 		tok = s.newToken(token.LEFT_BRACE)
 	case '}':
+		if n := len(s.templates); n > 0 && s.templates[n-1] == 0 {
+			s.templates = s.templates[:n-1]
+			tok = s.templateToken(token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL)
+			break
+		}
+		if n := len(s.templates); n > 0 {
+			s.templates[n-1]--
+		}
 		tok = s.newToken(token.RIGHT_BRACE)
-	case '"':
-		s.readChar()
-
-		tok.Type = token.STRING
-		tok.Literal = s.readStr()
-
-		s.readChar()
+	case '[':
+		tok = s.newToken(token.LEFT_BRACKET)
+	case ']':
+		tok = s.newToken(token.RIGHT_BRACKET)
+	case '"', '\'':
+		tok = s.stringToken(s.ch)
+	case '`':
+		tok = s.templateToken(token.TEMPLATE_HEAD, token.STRING)
 	case 0:
 		tok.Type = token.EOF
 		tok.Literal = ""
 	default:
-		if isLetter(s.ch) {
+		if r, _ := s.peekRune(); isIdentStart(r) {
 			tok.Literal = s.readIdent()
 
 			// type token - either after colon in type annotation or in variable declaration
-			if s.pastTok.Type == token.COLON {
+			if s.pastTok.Type == token.COLON || s.peakNextChar() == '=' {
 				// Check if it's a type name
-				if typ := token.LookupType(tok.Literal); typ != "" {
+				if typ := token.LookupType(tok.Literal); typ != token.IDENT {
 					tok.Type = typ
 				} else {
 					tok.Type = token.LookupIdent(tok.Literal)
 				}
-			} else if s.peakNextChar() == '=' {
-				tok.Type = token.LookupType(tok.Literal)
 			} else {
 				tok.Type = token.LookupIdent(tok.Literal)
 			}
 		} else if isDigit(s.ch) {
-			tok.Type = token.NUMBER
-			tok.Literal = s.readInt()
+			tok = s.numberToken()
+		} else {
+			// consume the whole of a character encoded in several bytes
+			_, size := s.peekRune()
+			s.advance(size - 1)
+			tok = s.newToken(token.ILLEGAL)
 		}
 	}
 
+	tok.Pos = pos
+	tok.End = s.position()
+	tok.Comments = comments
 	s.pastTok = tok
 	return tok
 }
@@ -113,76 +249,451 @@ func isWhiteSpace(ch byte) bool {
 }
 
 func (s *Scanner) skipWhiteSpaces() {
-	for isWhiteSpace(s.ch) {
-		s.readChar()
+	for {
+		if isWhiteSpace(s.ch) {
+			s.readChar()
+			continue
+		}
+		r, size := s.peekRune()
+		if !isUnicodeSpace(r) {
+			return
+		}
+		s.advance(size)
 	}
 }
 
-func (s *Scanner) peakNextChar() byte {
-	// Save current position and character
-	oldPos := s.pos
-	oldCh := s.ch
-	oldBuf := make([]byte, len(s.buf))
-	copy(oldBuf, s.buf)
-
-	// Read next non-whitespace character
-	for isWhiteSpace(s.ch) && !s.eof && s.readErr == nil {
-		s.readChar()
+// skipComments skips white space and comments, and returns the comments. It
+// reports false, leaving the scanner at the start of the comment, if a block
+// comment isn't closed.
+func (s *Scanner) skipComments() ([]token.Comment, bool) {
+	var comments []token.Comment
+	for {
+		s.skipWhiteSpaces()
+		if s.ch != '/' || s.peekChar() != '/' && s.peekChar() != '*' {
+			return comments, true
+		}
+
+		pos, start := s.position(), s.offset
+		if s.peekChar() == '/' {
+			for s.ch != '\n' && s.ch != '\r' && s.ch != 0 {
+				s.readChar()
+			}
+		} else {
+			end := strings.Index(string(s.buf[start+2:]), "*/")
+			if end < 0 {
+				return comments, false
+			}
+			s.advance(end + 4)
+		}
+		comments = append(comments, token.Comment{
+			Text: string(s.buf[start:s.offset]),
+			Pos:  pos,
+			End:  s.position(),
+		})
 	}
+}
 
-	ch := s.ch
+// isUnicodeSpace reports whether r is a white space or line terminator
+// character outside of ASCII, such as a no-break space or a byte order mark.
+func isUnicodeSpace(r rune) bool {
+	return r >= utf8.RuneSelf && (unicode.Is(unicode.Zs, r) || r == '\uFEFF' || r == '\u2028' || r == '\u2029')
+}
+
+// peekRune returns the character starting at the current byte and the number
+// of bytes encoding it.
+func (s *Scanner) peekRune() (rune, int) {
+	if s.ch < utf8.RuneSelf {
+		return rune(s.ch), 1
+	}
+	return utf8.DecodeRune(s.buf[s.offset:])
+}
+
+// advance consumes the next n bytes.
+func (s *Scanner) advance(n int) {
+	for range n {
+		s.readChar()
+	}
+}
 
-	// Restore position and character
-	s.pos = oldPos
-	s.ch = oldCh
-	s.buf = oldBuf
+func (s *Scanner) peakNextChar() byte {
+	// Read next non-whitespace character without consuming it
+	for i := s.offset; i < len(s.buf); i++ {
+		if !isWhiteSpace(s.buf[i]) {
+			return s.buf[i]
+		}
+	}
 
-	return ch
+	return 0
 }
 
+// newToken returns a token of the given type whose literal spans from the
+// start of the current token up to and including the current character.
 func (s *Scanner) newToken(tok token.TokenType) token.Token {
-	token := token.Token{Type: tok, Literal: string(s.ch)}
 	s.readChar()
-	return token
+	return token.Token{Type: tok, Literal: string(s.buf[s.tokStart:s.offset])}
+}
+
+// newTokenWithEqual returns a token of type eq if the current character is
+// followed by '=', and a token of type single otherwise.
+func (s *Scanner) newTokenWithEqual(single, eq token.TokenType) token.Token {
+	if s.peekChar() == '=' {
+		s.readChar()
+		return s.newToken(eq)
+	}
+	return s.newToken(single)
+}
+
+// peekChar returns the character after the current one without consuming it.
+func (s *Scanner) peekChar() byte {
+	if s.pos >= len(s.buf) {
+		return 0
+	}
+	return s.buf[s.pos]
 }
 
 func (s *Scanner) readIdent() string {
-	var buf []byte
-	for isLetter(s.ch) {
-		buf = append(buf, s.ch)
+	start := s.offset
+	for {
+		r, size := s.peekRune()
+		if !isIdentPart(r) {
+			break
+		}
+		s.advance(size)
+	}
+	return string(s.buf[start:s.offset])
+}
+
+// numberToken reads a numeric literal and returns it as a NUMBER token, as a
+// BIGINT token if it is an integer with an n suffix, or as an ILLEGAL token
+// if it is malformed.
+func (s *Scanner) numberToken() token.Token {
+	start := s.offset
+	tok := token.Token{Type: token.NUMBER}
+	ok := s.readNumber()
+	if s.ch == 'n' && isInteger(s.buf[start:s.offset]) {
 		s.readChar()
+		tok.Type = token.BIGINT
+	}
+	if !s.endNumber() || !ok {
+		tok.Type = token.ILLEGAL
 	}
-	return string(buf)
+	tok.Literal = string(s.buf[start:s.offset])
+	return tok
+}
+
+// isInteger reports whether the numeric literal lit has neither a fraction
+// nor an exponent.
+func isInteger(lit []byte) bool {
+	if len(lit) > 1 && lit[0] == '0' && basePrefix(lit[1]) != nil {
+		return true
+	}
+	for _, ch := range lit {
+		if ch == '.' || ch == 'e' || ch == 'E' {
+			return false
+		}
+	}
+	return true
 }
 
-func (s *Scanner) readInt() string {
-	var buf []byte
-	for isDigit(s.ch) {
-		buf = append(buf, s.ch)
+// readNumber reads a numeric literal: a decimal number with an optional
+// fraction and exponent, or a hexadecimal (0x), octal (0o) or binary (0b)
+// integer. Digits may be grouped with '_' separators. Every form is also valid
+// Go, so the literal can be emitted as written. It reports whether the
+// literal is well formed, leaving the check of what follows it to
+// endNumber.
+func (s *Scanner) readNumber() bool {
+	if s.ch == '0' {
+		if isBaseDigit := basePrefix(s.peekChar()); isBaseDigit != nil {
+			s.readChar()
+			s.readChar()
+			return s.readDigits(isBaseDigit)
+		}
+		if isDigit(s.peekChar()) || s.peekChar() == '_' {
+			// legacy octal literals such as 017 aren't allowed
+			s.readDigits(isDigit)
+			return false
+		}
+	}
+
+	ok := true
+	if s.ch != '.' {
+		ok = s.readDigits(isDigit)
+	}
+	if s.ch == '.' {
 		s.readChar()
+		if isDigit(s.ch) || s.ch == '_' {
+			ok = s.readDigits(isDigit) && ok
+		}
+	}
+	if s.ch == 'e' || s.ch == 'E' {
+		s.readChar()
+		if s.ch == '+' || s.ch == '-' {
+			s.readChar()
+		}
+		ok = s.readDigits(isDigit) && ok
 	}
-	return string(buf)
+	return ok
 }
 
-func (s *Scanner) readStr() string {
-	var buf []byte
-	for s.ch != '"' && s.ch != 0 {
-		buf = append(buf, s.ch)
+// basePrefix returns the digit predicate of the base introduced by the
+// character after a leading 0, or nil if ch doesn't start a base prefix.
+func basePrefix(ch byte) func(byte) bool {
+	switch ch {
+	case 'x', 'X':
+		return isHexDigit
+	case 'o', 'O':
+		return func(ch byte) bool { return '0' <= ch && ch <= '7' }
+	case 'b', 'B':
+		return func(ch byte) bool { return ch == '0' || ch == '1' }
+	}
+	return nil
+}
+
+// readDigits reads a run of digits accepted by isDigit. It reports whether
+// the run is non-empty and each '_' separator sits between two digits.
+func (s *Scanner) readDigits(isDigit func(byte) bool) bool {
+	ok := isDigit(s.ch)
+	for isDigit(s.ch) || s.ch == '_' {
+		if s.ch == '_' && !isDigit(s.peekChar()) {
+			ok = false
+		}
 		s.readChar()
 	}
-	return string(buf)
+	return ok
 }
 
-func isLetter(ch byte) bool {
-	return ('a' <= ch && 'z' >= 'a') || ('A' <= ch && 'Z' >= ch)
+// endNumber reports whether the numeric literal just read is followed by a
+// character that may end it. An identifier or a digit can't immediately
+// follow a numeric literal, so they are consumed as part of the malformed
+// literal.
+func (s *Scanner) endNumber() bool {
+	ok := true
+	for {
+		r, size := s.peekRune()
+		if !isIdentPart(r) {
+			break
+		}
+		ok = false
+		s.advance(size)
+	}
+	return ok
+}
+
+// stringToken reads a string literal delimited by quote and returns it as a
+// STRING token whose literal is the value of the string, with its escape
+// sequences decoded. An unterminated string, or one with a malformed escape
+// sequence, is returned as an ILLEGAL token.
+func (s *Scanner) stringToken(quote byte) token.Token {
+	s.readChar()
+	var b strings.Builder
+	ok := true
+	for s.ch != quote {
+		switch s.ch {
+		case 0, '\n', '\r':
+			// unterminated string literal
+			return token.Token{Type: token.ILLEGAL, Literal: string(s.buf[s.tokStart:s.offset])}
+		case '\\':
+			ok = s.readEscape(&b) && ok
+		default:
+			b.WriteByte(s.ch)
+			s.readChar()
+		}
+	}
+	s.readChar()
+	if !ok {
+		return token.Token{Type: token.ILLEGAL, Literal: string(s.buf[s.tokStart:s.offset])}
+	}
+	return token.Token{Type: token.STRING, Literal: b.String()}
+}
+
+// templateToken reads the text of a template literal, starting at the '`'
+// or '}' before it, up to the '${' of the next substitution or the closing
+// '`'. It returns the text with its escape sequences decoded as a token of
+// type open if a substitution follows, and of type end otherwise. An
+// unterminated template, or one with a malformed escape sequence, is
+// returned as an ILLEGAL token.
+func (s *Scanner) templateToken(open, end token.TokenType) token.Token {
+	s.readChar()
+	var b strings.Builder
+	ok := true
+	for {
+		switch {
+		case s.ch == 0 && s.pos > len(s.buf):
+			// unterminated template literal
+			return token.Token{Type: token.ILLEGAL, Literal: string(s.buf[s.tokStart:s.offset])}
+		case s.ch == '`':
+			s.readChar()
+			return s.templatePart(end, b.String(), ok)
+		case s.ch == '$' && s.peekChar() == '{':
+			s.readChar()
+			s.readChar()
+			s.templates = append(s.templates, 0)
+			return s.templatePart(open, b.String(), ok)
+		case s.ch == '\\':
+			ok = s.readEscape(&b) && ok
+		case s.ch == '\r':
+			// line terminators in templates are normalized to '\n'
+			b.WriteByte('\n')
+			s.readChar()
+			if s.ch == '\n' {
+				s.readChar()
+			}
+		default:
+			b.WriteByte(s.ch)
+			s.readChar()
+		}
+	}
+}
+
+func (s *Scanner) templatePart(typ token.TokenType, text string, ok bool) token.Token {
+	if !ok {
+		return token.Token{Type: token.ILLEGAL, Literal: string(s.buf[s.tokStart:s.offset])}
+	}
+	return token.Token{Type: typ, Literal: text}
+}
+
+// readEscape reads the escape sequence starting at the current '\\' and
+// writes the character it stands for to b. It reports whether the sequence
+// is well formed; legacy octal escapes such as \1 aren't allowed.
+func (s *Scanner) readEscape(b *strings.Builder) bool {
+	s.readChar()
+	ch := s.ch
+	s.readChar()
+	switch ch {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'v':
+		b.WriteByte('\v')
+	case '0':
+		if isDigit(s.ch) {
+			return false
+		}
+		b.WriteByte(0)
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return false
+	case 'x':
+		r, ok := s.readHex(2)
+		b.WriteRune(r)
+		return ok
+	case 'u':
+		r, ok := s.readUnicodeEscape()
+		if ok && utf16.IsSurrogate(r) && r < 0xdc00 && s.ch == '\\' && s.peekChar() == 'u' {
+			// a surrogate pair escaped as two UTF-16 code units
+			s.readChar()
+			s.readChar()
+			low, lowOK := s.readUnicodeEscape()
+			if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+				r = pair
+			} else {
+				b.WriteRune(utf8.RuneError)
+				r = low
+			}
+			ok = lowOK
+		}
+		b.WriteRune(r)
+		return ok
+	case '\r':
+		// line continuation
+		if s.ch == '\n' {
+			s.readChar()
+		}
+	case '\n':
+		// line continuation
+	case 0:
+		return false
+	default:
+		// any other escaped character stands for itself
+		b.WriteByte(ch)
+	}
+	return true
+}
+
+// readUnicodeEscape reads the code point of a \\u escape sequence after the
+// 'u', written either as four hexadecimal digits or as up to six in braces.
+func (s *Scanner) readUnicodeEscape() (rune, bool) {
+	if s.ch != '{' {
+		return s.readHex(4)
+	}
+	s.readChar()
+	var r rune
+	ok := isHexDigit(s.ch)
+	for isHexDigit(s.ch) {
+		r = r*16 + hexValue(s.ch)
+		if r > unicode.MaxRune {
+			ok = false
+			r = unicode.MaxRune
+		}
+		s.readChar()
+	}
+	if s.ch != '}' {
+		return utf8.RuneError, false
+	}
+	s.readChar()
+	return r, ok
+}
+
+// readHex reads exactly n hexadecimal digits and returns their value.
+func (s *Scanner) readHex(n int) (rune, bool) {
+	var r rune
+	for range n {
+		if !isHexDigit(s.ch) {
+			return utf8.RuneError, false
+		}
+		r = r*16 + hexValue(s.ch)
+		s.readChar()
+	}
+	return r, true
+}
+
+func hexValue(ch byte) rune {
+	switch {
+	case isDigit(ch):
+		return rune(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return rune(ch - 'a' + 10)
+	}
+	return rune(ch - 'A' + 10)
+}
+
+// isIdentStart reports whether r may start an identifier: '$', '_' or a
+// character of the Unicode ID_Start set.
+func isIdentStart(r rune) bool {
+	return r == '$' || r == '_' || unicode.IsLetter(r) || unicode.In(r, unicode.Nl, unicode.Other_ID_Start)
+}
+
+// isIdentPart reports whether r may continue an identifier: a character that
+// may start one, a character of the Unicode ID_Continue set, or one of the
+// zero-width joiners.
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) ||
+		r == '\u200C' || r == '\u200D'
 }
 
 func isDigit(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
 
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
 func New(r io.Reader) *Scanner {
-	s := &Scanner{r: r, buf: make([]byte, 0, 1024)}
+	return NewFile("", r)
+}
+
+// NewFile returns a scanner whose token positions report filename as their
+// source file.
+func NewFile(filename string, r io.Reader) *Scanner {
+	s := &Scanner{filename: filename, line: 1}
+	s.buf, s.readErr = io.ReadAll(r)
 	s.readChar()
 	return s
 }
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
@@ -165,3 +166,571 @@ func TestNextTokenSingleLine(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x: number = 42;\nlet s: string = \"hi\";"
+
+	tests := []struct {
+		expectedType token.TokenType
+		line, column int
+		offset       int
+		endColumn    int
+	}{
+		{token.LET, 1, 1, 0, 4},
+		{token.IDENT, 1, 5, 4, 6},
+		{token.COLON, 1, 6, 5, 7},
+		{token.TYPE_NUMBER, 1, 8, 7, 14},
+		{token.ASSIGN, 1, 15, 14, 16},
+		{token.NUMBER, 1, 17, 16, 19},
+		{token.SEMICOLON, 1, 19, 18, 20},
+		{token.LET, 2, 1, 20, 4},
+		{token.IDENT, 2, 5, 24, 6},
+		{token.COLON, 2, 6, 25, 7},
+		{token.TYPE_STRING, 2, 8, 27, 14},
+		{token.ASSIGN, 2, 15, 34, 16},
+		{token.STRING, 2, 17, 36, 21},
+		{token.SEMICOLON, 2, 21, 40, 22},
+		{token.EOF, 2, 22, 41, 22},
+	}
+
+	sc := NewFile("main.ts", strings.NewReader(input))
+
+	for i, tt := range tests {
+		tok := sc.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column || tok.Pos.Offset != tt.offset {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d (offset %d), got=%d:%d (offset %d)",
+				i, tt.line, tt.column, tt.offset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+
+		if tok.End.Line != tt.line || tok.End.Column != tt.endColumn {
+			t.Fatalf("tests[%d] - end position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.line, tt.endColumn, tok.End.Line, tok.End.Column)
+		}
+
+		if tok.Pos.Filename != "main.ts" {
+			t.Fatalf("tests[%d] - filename wrong. expected=%q, got=%q", i, "main.ts", tok.Pos.Filename)
+		}
+	}
+}
+
+func TestIllegalCharacter(t *testing.T) {
+	sc := New(strings.NewReader("let x: number = 4 @ 2;"))
+
+	for {
+		tok := sc.NextToken()
+		if tok.Type == token.EOF {
+			t.Fatal("expected an ILLEGAL token before EOF")
+		}
+		if tok.Type == token.ILLEGAL {
+			if tok.Literal != "@" || tok.Pos.Column != 19 {
+				t.Fatalf("unexpected illegal token %q at %s", tok.Literal, tok.Pos)
+			}
+			return
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType token.TokenType
+		literal      string
+	}{
+		{"42", token.NUMBER, "42"},
+		{"3.14", token.NUMBER, "3.14"},
+		{".5", token.NUMBER, ".5"},
+		{"1.", token.NUMBER, "1."},
+		{"1e3", token.NUMBER, "1e3"},
+		{"1.5E-3", token.NUMBER, "1.5E-3"},
+		{"2e+10", token.NUMBER, "2e+10"},
+		{"0xFF", token.NUMBER, "0xFF"},
+		{"0o17", token.NUMBER, "0o17"},
+		{"0b1010", token.NUMBER, "0b1010"},
+		{"1_000_000", token.NUMBER, "1_000_000"},
+		{"0x_FF", token.ILLEGAL, "0x_FF"},
+		{"0.000_1", token.NUMBER, "0.000_1"},
+		{"1__0", token.ILLEGAL, "1__0"},
+		{"1_", token.ILLEGAL, "1_"},
+		{"1e", token.ILLEGAL, "1e"},
+		{"0x", token.ILLEGAL, "0x"},
+		{"0b12", token.ILLEGAL, "0b12"},
+		{"017", token.ILLEGAL, "017"},
+		{"3in", token.ILLEGAL, "3in"},
+		{"3é", token.ILLEGAL, "3é"},
+		{"123n", token.BIGINT, "123n"},
+		{"0n", token.BIGINT, "0n"},
+		{"0xFFn", token.BIGINT, "0xFFn"},
+		{"1_000n", token.BIGINT, "1_000n"},
+		{"1.5n", token.ILLEGAL, "1.5n"},
+		{"1e3n", token.ILLEGAL, "1e3n"},
+		{"12nx", token.ILLEGAL, "12nx"},
+	}
+
+	for _, tt := range tests {
+		sc := New(strings.NewReader(tt.input + ";"))
+		tok := sc.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.literal {
+			t.Errorf("%s: expected %s %q, got %s %q", tt.input, tt.expectedType, tt.literal, tok.Type, tok.Literal)
+		}
+		if next := sc.NextToken(); next.Type != token.SEMICOLON {
+			t.Errorf("%s: expected ';' after the literal, got %q", tt.input, next.Literal)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType token.TokenType
+		literal      string
+	}{
+		{`"hello"`, token.STRING, "hello"},
+		{`'hello'`, token.STRING, "hello"},
+		{`'say "hi"'`, token.STRING, `say "hi"`},
+		{`"it's"`, token.STRING, "it's"},
+		{`'it\'s'`, token.STRING, "it's"},
+		{`"a\nb\tc\rd\be\ff\vg\0"`, token.STRING, "a\nb\tc\rd\be\ff\vg\x00"},
+		{`"\\ \" \q"`, token.STRING, `\ " q`},
+		{`"\x41\u00e9\u{1F600}"`, token.STRING, "Aé😀"},
+		{`"\uD83D\uDE00"`, token.STRING, "😀"},
+		{`"\uD83D"`, token.STRING, "\uFFFD"},
+		{"\"line \\\ncontinued\"", token.STRING, "line continued"},
+		{"\"héllo\"", token.STRING, "héllo"},
+		{"`plain`", token.STRING, "plain"},
+		{"`two\r\nlines \\` \\${x}`", token.STRING, "two\nlines ` ${x}"},
+		{`"\x4"`, token.ILLEGAL, `"\x4"`},
+		{`"\u{110000}"`, token.ILLEGAL, `"\u{110000}"`},
+		{`"\u{}"`, token.ILLEGAL, `"\u{}"`},
+		{`"\1"`, token.ILLEGAL, `"\1"`},
+		{`"\01"`, token.ILLEGAL, `"\01"`},
+	}
+
+	for _, tt := range tests {
+		sc := New(strings.NewReader(tt.input + ";"))
+		tok := sc.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.literal {
+			t.Errorf("%s: expected %s %q, got %s %q", tt.input, tt.expectedType, tt.literal, tok.Type, tok.Literal)
+		}
+		if next := sc.NextToken(); next.Type != token.SEMICOLON {
+			t.Errorf("%s: expected ';' after the literal, got %q", tt.input, next.Literal)
+		}
+	}
+}
+
+func TestUnterminatedStrings(t *testing.T) {
+	for _, input := range []string{`"abc`, "'abc\n'", "`abc", "`a${x}b"} {
+		sc := New(strings.NewReader(input))
+		for tok := sc.NextToken(); tok.Type != token.ILLEGAL; tok = sc.NextToken() {
+			if tok.Type == token.EOF {
+				t.Errorf("%q: expected an illegal token", input)
+				break
+			}
+		}
+	}
+}
+
+func TestTemplateLiteralTokens(t *testing.T) {
+	input := "`a${x}b${ {k: `c${y}`}.k }d`;"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TEMPLATE_HEAD, "a"},
+		{token.IDENT, "x"},
+		{token.TEMPLATE_MIDDLE, "b"},
+		{token.LEFT_BRACE, "{"},
+		{token.IDENT, "k"},
+		{token.COLON, ":"},
+		{token.TEMPLATE_HEAD, "c"},
+		{token.IDENT, "y"},
+		{token.TEMPLATE_TAIL, ""},
+		{token.RIGHT_BRACE, "}"},
+		{token.DOT, "."},
+		{token.IDENT, "k"},
+		{token.TEMPLATE_TAIL, "d"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range tests {
+		tok := sc.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	input := "café $el _a1 a$ b2c ⅻ a‿b x\u200d 日本 \u00a0§"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		column          int
+	}{
+		{token.IDENT, "café", 1},
+		{token.IDENT, "$el", 6},
+		{token.IDENT, "_a1", 10},
+		{token.IDENT, "a$", 14},
+		{token.IDENT, "b2c", 17},
+		{token.IDENT, "ⅻ", 21},
+		{token.IDENT, "a‿b", 23},
+		{token.IDENT, "x\u200d", 27},
+		{token.IDENT, "日本", 30},
+		{token.ILLEGAL, "§", 34},
+		{token.EOF, "", 35},
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range tests {
+		tok := sc.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Pos.Column != tt.column {
+			t.Errorf("tests[%d] - %q: expected column %d, got %d", i, tt.expectedLiteral, tt.column, tok.Pos.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// one\n/** two\n * lines */ a / b // three\n/=/**/c"
+
+	tests := []struct {
+		expectedType     token.TokenType
+		expectedLiteral  string
+		expectedComments []string
+	}{
+		{token.IDENT, "a", []string{"// one", "/** two\n * lines */"}},
+		{token.DIV, "/", nil},
+		{token.IDENT, "b", nil},
+		{token.DIV_ASSIGN, "/=", []string{"// three"}},
+		{token.IDENT, "c", []string{"/**/"}},
+		{token.EOF, "", nil},
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range tests {
+		tok := sc.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		var comments []string
+		for _, c := range tok.Comments {
+			comments = append(comments, c.Text)
+		}
+		if !slices.Equal(comments, tt.expectedComments) {
+			t.Fatalf("tests[%d] - expected comments %q, got %q", i, tt.expectedComments, comments)
+		}
+	}
+}
+
+func TestCommentPositions(t *testing.T) {
+	sc := New(strings.NewReader("x; // note\n  /* é */ y"))
+	sc.NextToken()
+	sc.NextToken()
+
+	tok := sc.NextToken()
+	if len(tok.Comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(tok.Comments))
+	}
+	note, block := tok.Comments[0], tok.Comments[1]
+	if note.Pos.Line != 1 || note.Pos.Column != 4 || note.End.Column != 11 {
+		t.Errorf("wrong position for %q: %v-%v", note.Text, note.Pos, note.End)
+	}
+	if block.Pos.Line != 2 || block.Pos.Column != 3 || block.End.Column != 10 {
+		t.Errorf("wrong position for %q: %v-%v", block.Text, block.Pos, block.End)
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	sc := New(strings.NewReader("a /* b\nc"))
+	sc.NextToken()
+
+	tok := sc.NextToken()
+	if tok.Type != token.ILLEGAL || tok.Literal != "/* b\nc" {
+		t.Fatalf("expected ILLEGAL %q, got %s %q", "/* b\nc", tok.Type, tok.Literal)
+	}
+	if tok := sc.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF, got %s %q", tok.Type, tok.Literal)
+	}
+}
+
+func TestNumberMemberAccess(t *testing.T) {
+	sc := New(strings.NewReader("xs[0].length"))
+
+	for _, expected := range []token.TokenType{token.IDENT, token.LEFT_BRACKET, token.NUMBER, token.RIGHT_BRACKET, token.DOT, token.IDENT, token.EOF} {
+		if tok := sc.NextToken(); tok.Type != expected {
+			t.Fatalf("expected %s, got %s %q", expected, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestOperatorTokens(t *testing.T) {
+	input := `if (a < b && c <= d || e > f) {} else if (g >= h) {} a == b; a != b; a === b; a !== b; !a = b;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IF, "if"},
+		{token.LEFT_PAREN, "("},
+		{token.IDENT, "a"},
+		{token.LESS, "<"},
+		{token.IDENT, "b"},
+		{token.AND, "&&"},
+		{token.IDENT, "c"},
+		{token.LESS_EQUAL, "<="},
+		{token.IDENT, "d"},
+		{token.OR, "||"},
+		{token.IDENT, "e"},
+		{token.GREATER, ">"},
+		{token.IDENT, "f"},
+		{token.RIGHT_PAREN, ")"},
+		{token.LEFT_BRACE, "{"},
+		{token.RIGHT_BRACE, "}"},
+		{token.ELSE, "else"},
+		{token.IF, "if"},
+		{token.LEFT_PAREN, "("},
+		{token.IDENT, "g"},
+		{token.GREATER_EQUAL, ">="},
+		{token.IDENT, "h"},
+		{token.RIGHT_PAREN, ")"},
+		{token.LEFT_BRACE, "{"},
+		{token.RIGHT_BRACE, "}"},
+		{token.IDENT, "a"},
+		{token.EQUAL, "=="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.NOT_EQUAL, "!="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.STRICT_EQUAL, "==="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.STRICT_NOT_EQUAL, "!=="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.BANG, "!"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range tests {
+		tok := sc.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUpdateOperatorTokens(t *testing.T) {
+	input := `i++; i--; ++i; a += 1; a -= 2; a *= 3; a /= 4; a %= 5; a % b - -c + +d;`
+
+	expected := []token.TokenType{
+		token.IDENT, token.PLUS_PLUS, token.SEMICOLON,
+		token.IDENT, token.MINUS_MINUS, token.SEMICOLON,
+		token.PLUS_PLUS, token.IDENT, token.SEMICOLON,
+		token.IDENT, token.PLUS_ASSIGN, token.NUMBER, token.SEMICOLON,
+		token.IDENT, token.MINUS_ASSIGN, token.NUMBER, token.SEMICOLON,
+		token.IDENT, token.MUL_ASSIGN, token.NUMBER, token.SEMICOLON,
+		token.IDENT, token.DIV_ASSIGN, token.NUMBER, token.SEMICOLON,
+		token.IDENT, token.MOD_ASSIGN, token.NUMBER, token.SEMICOLON,
+		token.IDENT, token.MOD, token.IDENT, token.MINUS, token.MINUS, token.IDENT,
+		token.PLUS, token.PLUS, token.IDENT, token.SEMICOLON,
+		token.EOF,
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range expected {
+		tok := sc.NextToken()
+
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestKeywordAfterColon(t *testing.T) {
+	sc := New(strings.NewReader("outer: while (x) {}"))
+
+	expected := []token.TokenType{token.IDENT, token.COLON, token.WHILE}
+	for i, tt := range expected {
+		if tok := sc.NextToken(); tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestDeclarationTokens(t *testing.T) {
+	sc := New(strings.NewReader("let a; const b; var c;"))
+
+	expected := []token.TokenType{
+		token.LET, token.IDENT, token.SEMICOLON,
+		token.CONST, token.IDENT, token.SEMICOLON,
+		token.VAR, token.IDENT, token.SEMICOLON,
+		token.EOF,
+	}
+	for i, tt := range expected {
+		if tok := sc.NextToken(); tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestArrayTokens(t *testing.T) {
+	input := `let xs: number[] = [1, 2]; xs[0]; xs.length;`
+
+	expected := []token.TokenType{
+		token.LET, token.IDENT, token.COLON, token.TYPE_NUMBER, token.LEFT_BRACKET, token.RIGHT_BRACKET,
+		token.ASSIGN, token.LEFT_BRACKET, token.NUMBER, token.COMMA, token.NUMBER, token.RIGHT_BRACKET, token.SEMICOLON,
+		token.IDENT, token.LEFT_BRACKET, token.NUMBER, token.RIGHT_BRACKET, token.SEMICOLON,
+		token.IDENT, token.DOT, token.IDENT, token.SEMICOLON,
+		token.EOF,
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range expected {
+		tok := sc.NextToken()
+
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestObjectTokens(t *testing.T) {
+	input := `interface P { x?: number; } type T = {};`
+
+	expected := []token.TokenType{
+		token.INTERFACE, token.IDENT, token.LEFT_BRACE, token.IDENT, token.QUESTION, token.COLON,
+		token.TYPE_NUMBER, token.SEMICOLON, token.RIGHT_BRACE,
+		token.IDENT, token.IDENT, token.ASSIGN, token.LEFT_BRACE, token.RIGHT_BRACE, token.SEMICOLON,
+		token.EOF,
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range expected {
+		tok := sc.NextToken()
+
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestClassTokens(t *testing.T) {
+	input := `class A extends B { private x: number; } new A(); this.x; super.x;`
+
+	expected := []token.TokenType{
+		token.CLASS, token.IDENT, token.EXTENDS, token.IDENT, token.LEFT_BRACE, token.IDENT, token.IDENT, token.COLON,
+		token.TYPE_NUMBER, token.SEMICOLON, token.RIGHT_BRACE,
+		token.NEW, token.IDENT, token.LEFT_PAREN, token.RIGHT_PAREN, token.SEMICOLON,
+		token.THIS, token.DOT, token.IDENT, token.SEMICOLON,
+		token.SUPER, token.DOT, token.IDENT, token.SEMICOLON,
+		token.EOF,
+	}
+
+	sc := New(strings.NewReader(input))
+
+	for i, tt := range expected {
+		tok := sc.NextToken()
+
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestArrowTokens(t *testing.T) {
+	sc := New(strings.NewReader("(a: number) => a >= 1 ==> b"))
+
+	expected := []token.TokenType{
+		token.LEFT_PAREN, token.IDENT, token.COLON, token.TYPE_NUMBER, token.RIGHT_PAREN, token.ARROW,
+		token.IDENT, token.GREATER_EQUAL, token.NUMBER, token.EQUAL, token.GREATER, token.IDENT, token.EOF,
+	}
+	for i, tt := range expected {
+		if tok := sc.NextToken(); tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestNullTokens(t *testing.T) {
+	sc := New(strings.NewReader("a?.b ?? null; c ??= undefined; d?.5:1; e!; let n: null = null;"))
+
+	expected := []token.TokenType{
+		token.IDENT, token.QUESTION_DOT, token.IDENT, token.NULLISH, token.NULL, token.SEMICOLON,
+		token.IDENT, token.NULLISH_ASSIGN, token.UNDEFINED, token.SEMICOLON,
+		token.IDENT, token.QUESTION, token.NUMBER, token.COLON, token.NUMBER, token.SEMICOLON,
+		token.IDENT, token.BANG, token.SEMICOLON,
+		token.LET, token.IDENT, token.COLON, token.NULL, token.ASSIGN, token.NULL, token.SEMICOLON,
+		token.EOF,
+	}
+	for i, tt := range expected {
+		if tok := sc.NextToken(); tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestEnumTokens(t *testing.T) {
+	sc := New(strings.NewReader("const enum E { A = 1 } let enumValue = E.A;"))
+
+	expected := []token.TokenType{
+		token.CONST, token.ENUM, token.IDENT, token.LEFT_BRACE, token.IDENT, token.ASSIGN, token.NUMBER, token.RIGHT_BRACE,
+		token.LET, token.IDENT, token.ASSIGN, token.IDENT, token.DOT, token.IDENT, token.SEMICOLON,
+		token.EOF,
+	}
+	for i, tt := range expected {
+		if tok := sc.NextToken(); tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	sc := New(strings.NewReader("a `${b}` c"))
+	sc.NextToken()
+	sc.NextToken() // the template head, which starts a substitution
+
+	clone := sc.Clone()
+	for tok := clone.NextToken(); tok.Type != token.EOF; tok = clone.NextToken() {
+	}
+
+	expected := []string{"b", "", "c"}
+	for i, lit := range expected {
+		if tok := sc.NextToken(); tok.Literal != lit {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, lit, tok.Literal)
+		}
+	}
+}
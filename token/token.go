@@ -1,55 +1,186 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+// Position describes a location in a source file. Offset is the 0-based byte
+// offset; Line and Column are 1-based.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// IsValid reports whether the position was set by the scanner.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first character
+	End     Position // position just past the last character
+
+	// Comments holds the comments between the previous token and this one.
+	Comments []Comment
+}
+
+// Comment is a // or /* */ comment. Text includes the comment markers.
+type Comment struct {
+	Text string
+	Pos  Position // position of the first character
+	End  Position // position just past the last character
 }
 
 const (
-	EOF   TokenType = "EOF"
-	IDENT TokenType = "IDENT"
+	EOF     TokenType = "EOF"
+	ILLEGAL TokenType = "ILLEGAL"
+	IDENT   TokenType = "IDENT"
 
 	NUMBER  TokenType = "NUMBER"
+	BIGINT  TokenType = "BIGINT"
 	STRING  TokenType = "STRING"
 	BOOLEAN TokenType = "BOOLEAN"
 
-	COMMA        TokenType = ","
-	COLON        TokenType = ":"
-	SEMICOLON    TokenType = ";"
-	PLUS         TokenType = "+"
-	MINUS        TokenType = "-"
-	MUL          TokenType = "*"
-	DIV          TokenType = "/"
-	BANG         TokenType = "!"
-	LEFT_PAREN   TokenType = "("
-	RIGHT_PAREN  TokenType = ")"
-	LEFT_BRACE   TokenType = "{"
-	RIGHT_BRACE  TokenType = "}"
-	ASSIGN       TokenType = "="
-	DOUBLE_QUOTE TokenType = `"`
-
-	LET      TokenType = "LET"
-	FUNCTION TokenType = "FUNCTION"
-	RETURN   TokenType = "RETURN"
+	// A template literal with substitutions is split around them into a
+	// head, any number of middles and a tail. One without substitutions is
+	// a STRING.
+	TEMPLATE_HEAD   TokenType = "TEMPLATE_HEAD"
+	TEMPLATE_MIDDLE TokenType = "TEMPLATE_MIDDLE"
+	TEMPLATE_TAIL   TokenType = "TEMPLATE_TAIL"
+
+	COMMA         TokenType = ","
+	DOT           TokenType = "."
+	COLON         TokenType = ":"
+	QUESTION      TokenType = "?"
+	QUESTION_DOT  TokenType = "?."
+	SEMICOLON     TokenType = ";"
+	PLUS          TokenType = "+"
+	MINUS         TokenType = "-"
+	MUL           TokenType = "*"
+	DIV           TokenType = "/"
+	MOD           TokenType = "%"
+	BANG          TokenType = "!"
+	LEFT_PAREN    TokenType = "("
+	RIGHT_PAREN   TokenType = ")"
+	LEFT_BRACE    TokenType = "{"
+	RIGHT_BRACE   TokenType = "}"
+	LEFT_BRACKET  TokenType = "["
+	RIGHT_BRACKET TokenType = "]"
+	ASSIGN        TokenType = "="
+	ARROW         TokenType = "=>"
+	DOUBLE_QUOTE  TokenType = `"`
+
+	EQUAL            TokenType = "=="
+	NOT_EQUAL        TokenType = "!="
+	STRICT_EQUAL     TokenType = "==="
+	STRICT_NOT_EQUAL TokenType = "!=="
+	LESS             TokenType = "<"
+	LESS_EQUAL       TokenType = "<="
+	GREATER          TokenType = ">"
+	GREATER_EQUAL    TokenType = ">="
+	AND              TokenType = "&&"
+	OR               TokenType = "||"
+	PIPE             TokenType = "|"
+	NULLISH          TokenType = "??"
+
+	PLUS_PLUS    TokenType = "++"
+	MINUS_MINUS  TokenType = "--"
+	PLUS_ASSIGN  TokenType = "+="
+	MINUS_ASSIGN TokenType = "-="
+	MUL_ASSIGN   TokenType = "*="
+	DIV_ASSIGN   TokenType = "/="
+	MOD_ASSIGN   TokenType = "%="
+
+	NULLISH_ASSIGN TokenType = "??="
+
+	LET       TokenType = "LET"
+	CONST     TokenType = "CONST"
+	VAR       TokenType = "VAR"
+	FUNCTION  TokenType = "FUNCTION"
+	RETURN    TokenType = "RETURN"
+	THROW     TokenType = "THROW"
+	IF        TokenType = "IF"
+	ELSE      TokenType = "ELSE"
+	WHILE     TokenType = "WHILE"
+	DO        TokenType = "DO"
+	FOR       TokenType = "FOR"
+	BREAK     TokenType = "BREAK"
+	CONTINUE  TokenType = "CONTINUE"
+	IN        TokenType = "IN"
+	INTERFACE TokenType = "INTERFACE"
+	CLASS     TokenType = "CLASS"
+	THIS      TokenType = "THIS"
+	NEW       TokenType = "NEW"
+	EXTENDS   TokenType = "EXTENDS"
+	SUPER     TokenType = "SUPER"
+	TYPEOF    TokenType = "TYPEOF"
+	SWITCH    TokenType = "SWITCH"
+	CASE      TokenType = "CASE"
+	DEFAULT   TokenType = "DEFAULT"
+	NULL      TokenType = "NULL"
+	UNDEFINED TokenType = "UNDEFINED"
+	ENUM      TokenType = "ENUM"
 
 	TYPE_NUMBER  TokenType = "TYPE_NUMBER"
+	TYPE_BIGINT  TokenType = "TYPE_BIGINT"
 	TYPE_STRING  TokenType = "TYPE_STRING"
 	TYPE_BOOLEAN TokenType = "TYPE_BOOLEAN"
 	TYPE_VOID    TokenType = "TYPE_VOID"
 )
 
 var keywords = map[string]TokenType{
-	"let":      LET,
-	"true":     BOOLEAN,
-	"false":    BOOLEAN,
-	"function": FUNCTION,
-	"return":   RETURN,
+	"let":       LET,
+	"const":     CONST,
+	"var":       VAR,
+	"true":      BOOLEAN,
+	"false":     BOOLEAN,
+	"function":  FUNCTION,
+	"return":    RETURN,
+	"throw":     THROW,
+	"if":        IF,
+	"else":      ELSE,
+	"while":     WHILE,
+	"do":        DO,
+	"for":       FOR,
+	"break":     BREAK,
+	"continue":  CONTINUE,
+	"in":        IN,
+	"interface": INTERFACE,
+	"class":     CLASS,
+	"this":      THIS,
+	"new":       NEW,
+	"extends":   EXTENDS,
+	"super":     SUPER,
+	"typeof":    TYPEOF,
+	"switch":    SWITCH,
+	"case":      CASE,
+	"default":   DEFAULT,
+	"null":      NULL,
+	"undefined": UNDEFINED,
+	"enum":      ENUM,
 }
 
 var types = map[string]TokenType{
 	"number":  TYPE_NUMBER,
+	"bigint":  TYPE_BIGINT,
 	"string":  TYPE_STRING,
 	"boolean": TYPE_BOOLEAN,
 	"void":    TYPE_VOID,
@@ -70,3 +201,39 @@ func LookupType(t string) TokenType {
 
 	return IDENT
 }
+
+// Describe returns a human readable name for a token type, suitable for use
+// in diagnostics such as "expected identifier".
+func Describe(t TokenType) string {
+	switch t {
+	case EOF:
+		return "end of file"
+	case ILLEGAL:
+		return "illegal character"
+	case IDENT:
+		return "identifier"
+	case NUMBER:
+		return "number literal"
+	case BIGINT:
+		return "bigint literal"
+	case STRING:
+		return "string literal"
+	case TEMPLATE_HEAD, TEMPLATE_MIDDLE, TEMPLATE_TAIL:
+		return "template literal"
+	case BOOLEAN:
+		return "boolean literal"
+	}
+
+	for word, typ := range keywords {
+		if typ == t && typ != BOOLEAN {
+			return fmt.Sprintf("'%s'", word)
+		}
+	}
+	for word, typ := range types {
+		if typ == t {
+			return fmt.Sprintf("'%s'", word)
+		}
+	}
+
+	return fmt.Sprintf("'%s'", string(t))
+}
@@ -0,0 +1,98 @@
+package types
+
+import "github.com/toyaAoi/sild/ast"
+
+// arrayMethods are the Array.prototype methods the code generator lowers.
+var arrayMethods = map[string]bool{
+	"push":     true,
+	"pop":      true,
+	"slice":    true,
+	"concat":   true,
+	"indexOf":  true,
+	"includes": true,
+	"join":     true,
+	"reverse":  true,
+	"sort":     true,
+	"map":      true,
+	"filter":   true,
+	"reduce":   true,
+	"find":     true,
+	"some":     true,
+	"every":    true,
+	"forEach":  true,
+}
+
+// arrayMethodCall checks a call of an Array.prototype method on an array of
+// type receiver and returns the type of its result.
+func (c *Checker) arrayMethodCall(call *ast.FunctionCallExpression, receiver ast.TypeExpr, method string) ast.TypeExpr {
+	elem := ast.ElementType(receiver)
+
+	// the accumulator of reduce starts with the initial value, if any
+	acc := elem
+	if method == "reduce" && len(call.Args) == 2 {
+		acc = c.expr(call.Args[1], nil)
+	}
+
+	for i, arg := range call.Args {
+		if i == 0 {
+			if callback := callbackType(method, elem, acc); callback != nil {
+				c.checkArg(arg, callback)
+				continue
+			}
+		}
+		if method == "reduce" && i == 1 {
+			continue
+		}
+		switch method {
+		case "push", "indexOf", "includes":
+			c.checkArg(arg, elem)
+		case "concat":
+			c.checkArg(arg, receiver)
+		case "slice":
+			c.checkArg(arg, primitive("number"))
+		case "join":
+			c.checkArg(arg, primitive("string"))
+		default:
+			c.expr(arg, nil)
+		}
+	}
+
+	switch method {
+	case "push", "indexOf":
+		return primitive("number")
+	case "pop", "find":
+		// undefined if the array is empty, or no element is found
+		return c.orUndefined(elem)
+	case "slice", "concat", "reverse", "sort", "filter":
+		return receiver
+	case "includes", "some", "every":
+		return primitive("boolean")
+	case "join":
+		return primitive("string")
+	case "map":
+		if len(call.Args) == 1 {
+			return &ast.ArrayType{Elem: c.callbackReturnType(call.Args[0])}
+		}
+		return &ast.ArrayType{Elem: primitive("any")}
+	case "reduce":
+		return acc
+	case "forEach":
+		return primitive("void")
+	}
+	return nil
+}
+
+func (c *Checker) checkArg(arg ast.Expression, want ast.TypeExpr) {
+	if got := c.expr(arg, want); !c.assignable(got, want) {
+		c.errorf(arg, 2345, "argument of type '%s' is not assignable to parameter of type '%s'", typeString(got), typeString(want))
+	}
+}
+
+// callbackReturnType returns the return type of a function passed as a
+// callback, or any if it isn't known.
+func (c *Checker) callbackReturnType(callback ast.Expression) ast.TypeExpr {
+	if fn, ok := c.resolve(c.info.Types[callback]).(*ast.FunctionType); ok && fn.ReturnType != nil {
+		return fn.ReturnType
+	}
+	return primitive("any")
+}
@@ -0,0 +1,890 @@
+// Package types implements the type checker, which runs between the parser
+// and the code generator. It resolves the names used in a program, checks
+// that values are assignable to the types they are given, and reports the
+// errors that tsc would report, numbered like tsc's, before any Go is
+// generated.
+package types
+
+import (
+	"fmt"
+	"go/constant"
+	"sort"
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/diag"
+	"github.com/toyaAoi/sild/token"
+)
+
+// Info holds the results of checking a program.
+type Info struct {
+	// Types maps the expressions of the program to their types. Expressions
+	// whose type isn't known map to nil.
+	Types map[ast.Expression]ast.TypeExpr
+
+	// Uses maps the identifiers of the program to the symbols they refer
+	// to. Identifiers that could not be resolved are absent.
+	Uses map[*ast.VariableExpression]*Symbol
+
+	// Assigned holds the identifiers assigned to with '=', which only write
+	// the variables they refer to.
+	Assigned map[*ast.VariableExpression]bool
+
+	// TypeArgs maps the calls of generic functions and the creations of
+	// instances of generic classes to their type arguments, given or
+	// inferred, by type parameter name.
+	TypeArgs map[ast.Expression]map[string]ast.TypeExpr
+
+	// Chains maps the links of optional chains that may short-circuit to
+	// their types when they don't, which is what the rest of the chain sees.
+	Chains map[ast.Expression]ast.TypeExpr
+
+	// Narrowings maps the nodes in which conditions narrow the types of
+	// variables to their narrowed types, by variable name: the bodies of if
+	// statements, the right operands of && and ||, and the case clauses of
+	// switch statements, which are narrowed by the cases leading to them.
+	// If statements, assignments and declarations with an initializer map
+	// to the narrowing of the statements after them.
+	Narrowings map[ast.Node]map[string]ast.TypeExpr
+
+	// Declared maps the property accesses whose types conditions narrowed
+	// to the types of the properties they access.
+	Declared map[ast.Expression]ast.TypeExpr
+
+	// Exhaustive holds the switch statements without a default clause whose
+	// cases cover all the members of the union type of a variable.
+	Exhaustive map[*ast.SwitchStatement]bool
+}
+
+// Checker type checks a program.
+type Checker struct {
+	info   *Info
+	scope  *Scope
+	global *Scope // the scope of the top level of the program
+
+	aliases  map[string]ast.TypeExpr     // interfaces and type aliases
+	generics map[string][]*ast.TypeParam // type parameters of generic interfaces and type aliases
+	classes  map[string]*ast.ClassDeclaration
+	enums    map[string]*ast.EnumDeclaration
+	bases    map[*ast.ClassDeclaration]*ast.ClassDeclaration
+	members  map[*ast.MemberExpression]*classMember // class members accessed
+	unknown  map[string]bool                        // type names that couldn't be found
+
+	enumValues map[*ast.EnumDeclaration][]constant.Value
+
+	// chains maps the links of optional chains that may short-circuit to
+	// their types when they don't
+	chains map[ast.Expression]ast.TypeExpr
+
+	typeParams map[string]*ast.TypeParam // type parameters in scope
+
+	fn       *function // the function being checked, nil at top level
+	loops    int       // number of loops enclosing the statement being checked
+	switches int       // number of switch statements enclosing it
+	labels   []string  // labels enclosing the statement being checked
+
+	// switch statements without a default clause whose cases cover every
+	// value of the discriminant
+	exhaustive map[*ast.SwitchStatement]bool
+
+	// the types of the values that compound assignments assign, which are
+	// the results of their operators
+	compound map[*ast.AssignmentStatement]ast.TypeExpr
+
+	diagnostics []diag.Diagnostic
+}
+
+// function is the context of the function or method being checked.
+type function struct {
+	scope       *Scope // the scope of the body, which holds var declarations
+	returnType  ast.TypeExpr
+	class       *ast.ClassDeclaration // the class of a method
+	static      bool
+	constructor bool
+
+	// infer is set for functions whose return type is inferred from the
+	// types of the values they return, which are collected in returns
+	infer   bool
+	returns []ast.TypeExpr
+}
+
+// Check type checks program and returns the types and symbols it resolved
+// along with the errors it found.
+func Check(program *ast.Program) (*Info, []diag.Diagnostic) {
+	c := New()
+	info := c.Check(program)
+	return info, c.Diagnostics()
+}
+
+func New() *Checker {
+	return &Checker{
+		aliases:  map[string]ast.TypeExpr{},
+		generics: map[string][]*ast.TypeParam{},
+		classes:  map[string]*ast.ClassDeclaration{},
+		enums:    map[string]*ast.EnumDeclaration{},
+		bases:    map[*ast.ClassDeclaration]*ast.ClassDeclaration{},
+		members:  map[*ast.MemberExpression]*classMember{},
+		unknown:  map[string]bool{},
+		chains:   map[ast.Expression]ast.TypeExpr{},
+
+		enumValues: map[*ast.EnumDeclaration][]constant.Value{},
+
+		exhaustive: map[*ast.SwitchStatement]bool{},
+		compound:   map[*ast.AssignmentStatement]ast.TypeExpr{},
+	}
+}
+
+// Diagnostics returns the errors found by the checker, sorted by position.
+func (c *Checker) Diagnostics() []diag.Diagnostic {
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return c.diagnostics[i].Pos.Offset < c.diagnostics[j].Pos.Offset
+	})
+	return c.diagnostics
+}
+
+// Check type checks program.
+func (c *Checker) Check(program *ast.Program) *Info {
+	c.info = &Info{
+		Types:    map[ast.Expression]ast.TypeExpr{},
+		Uses:     map[*ast.VariableExpression]*Symbol{},
+		Assigned: map[*ast.VariableExpression]bool{},
+		TypeArgs: map[ast.Expression]map[string]ast.TypeExpr{},
+		Chains:   c.chains,
+
+		Narrowings: map[ast.Node]map[string]ast.TypeExpr{},
+		Declared:   map[ast.Expression]ast.TypeExpr{},
+		Exhaustive: c.exhaustive,
+	}
+	c.global = newScope(universe())
+	c.scope = c.global
+
+	c.collectTypes(program.Statements)
+
+	// Functions and classes are hoisted, and top-level variables are
+	// visible in the functions declared before them.
+	c.declareFunctions(program.Statements)
+	for _, stmt := range program.Statements {
+		if v, ok := stmt.(*ast.VariableDeclaration); ok && v.Keyword.Type != token.VAR {
+			c.declareVar(v.Name, v.Type, v)
+		}
+	}
+	c.hoistVars(program.Statements)
+
+	// Function and class bodies are checked last, so that they see the
+	// inferred types of all top-level variables.
+	var bodies []ast.Statement
+	for _, stmt := range program.Statements {
+		switch stmt.(type) {
+		case *ast.FunctionDeclaration, *ast.ClassDeclaration:
+			bodies = append(bodies, stmt)
+		default:
+			c.checkStatement(stmt)
+		}
+	}
+	for _, stmt := range bodies {
+		c.checkStatement(stmt)
+	}
+
+	return c.info
+}
+
+// collectTypes records the interfaces, type aliases, classes and enums
+// declared at the top level of the program, the base class of each class and
+// the values of the members of each enum.
+func (c *Checker) collectTypes(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.InterfaceDeclaration:
+			c.aliases[s.Name.Literal] = s.Type
+			c.generics[s.Name.Literal] = s.TypeParams
+		case *ast.TypeAliasDeclaration:
+			c.aliases[s.Name.Literal] = s.Type
+			c.generics[s.Name.Literal] = s.TypeParams
+		case *ast.ClassDeclaration:
+			c.classes[s.Name.Literal] = s
+		case *ast.EnumDeclaration:
+			c.enums[s.Name.Literal] = s
+			c.enumValues[s] = ast.EnumValues(s)
+		}
+	}
+
+	for _, class := range c.classes {
+		if class.Extends == nil {
+			continue
+		}
+		if base, ok := c.classes[class.Extends.String()]; ok {
+			c.bases[class] = base
+			c.checkTypeArgCount(class.Extends, base.Name.Literal, base.TypeParams, 0)
+		} else if _, ok := c.aliases[class.Extends.String()]; ok {
+			c.errorf(class.Extends, 2689, "cannot extend an interface '%s'. Did you mean 'implements'?", class.Extends)
+		} else {
+			c.errorf(class.Extends, 2304, "cannot find name '%s'", class.Extends)
+		}
+	}
+
+	// break inheritance cycles, which the code generator reports, so that
+	// walking up the bases of a class terminates
+	for _, class := range c.classes {
+		seen := map[*ast.ClassDeclaration]bool{class: true}
+		for b := class; c.bases[b] != nil; b = c.bases[b] {
+			if seen[c.bases[b]] {
+				delete(c.bases, b)
+				break
+			}
+			seen[c.bases[b]] = true
+		}
+	}
+}
+
+// declareFunctions declares the functions, classes and enums among stmts in
+// the current scope, so that they can be used before they are declared.
+func (c *Checker) declareFunctions(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.FunctionDeclaration:
+			c.declare(&Symbol{Name: s.Name.Literal, Kind: Func, Decl: s}, ident(s.Name))
+		case *ast.ClassDeclaration:
+			c.declare(&Symbol{Name: s.Name.Literal, Kind: Class, Decl: s}, ident(s.Name))
+		case *ast.EnumDeclaration:
+			c.declare(&Symbol{Name: s.Name.Literal, Kind: Enum, Decl: s}, ident(s.Name))
+		}
+	}
+}
+
+func (c *Checker) declareVar(name string, t ast.TypeExpr, decl ast.Node) {
+	c.declare(&Symbol{Name: name, Kind: Var, Type: t, Decl: decl}, decl)
+}
+
+// declare adds sym to the current scope, reporting a conflict with a symbol
+// already declared there.
+func (c *Checker) declare(sym *Symbol, at ast.Node) {
+	prev := c.scope.block().insert(sym)
+	switch {
+	case prev == nil:
+	case prev.Kind == Func && sym.Kind == Func:
+		c.errorf(at, 2393, "duplicate function implementation")
+	case isVariableDeclaration(prev.Decl) && isVariableDeclaration(sym.Decl):
+		c.errorf(at, 2451, "cannot redeclare block-scoped variable '%s'", sym.Name)
+	default:
+		c.errorf(at, 2300, "duplicate identifier '%s'", sym.Name)
+	}
+}
+
+func isVariableDeclaration(decl ast.Node) bool {
+	switch decl.(type) {
+	case *ast.VariableDeclaration, *ast.ForOfStatement, *ast.ForInStatement:
+		return true
+	}
+	return false
+}
+
+// keyword returns the keyword declaring a variable: let, const or var. It
+// returns "" for parameters and for symbols that aren't variables.
+func keyword(sym *Symbol) token.TokenType {
+	switch d := sym.Decl.(type) {
+	case *ast.VariableDeclaration:
+		return d.Keyword.Type
+	case *ast.ForOfStatement:
+		return d.Keyword.Type
+	case *ast.ForInStatement:
+		return d.Keyword.Type
+	}
+	return ""
+}
+
+// hoistVars declares the var declarations of a function body or of the
+// program in the current scope, which var declarations can be used
+// throughout. A var may be declared more than once, and may redeclare a
+// parameter.
+func (c *Checker) hoistVars(stmts []ast.Statement) {
+	for _, v := range ast.VarDeclarations(stmts) {
+		if prev := c.scope.symbols[v.Name]; prev != nil {
+			if _, param := prev.Decl.(*ast.FunctionParam); param || keyword(prev) == token.VAR {
+				continue
+			}
+		}
+		c.declareVar(v.Name, v.Type, v)
+	}
+}
+
+func (c *Checker) pushScope() {
+	c.scope = newScope(c.scope)
+}
+
+func (c *Checker) popScope() {
+	c.scope = c.scope.parent
+}
+
+func (c *Checker) checkStatement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.VariableDeclaration:
+		c.checkVariableDeclaration(s)
+	case *ast.FunctionDeclaration:
+		outer := c.declareTypeParams(s.TypeParams)
+		c.checkSignature(s.Params, s.ReturnType)
+		c.checkFunction(s.Params, s.Body, &function{returnType: s.ReturnType}, ident(s.Name))
+		c.typeParams = outer
+	case *ast.ReturnStatement:
+		c.checkReturnStatement(s)
+	case *ast.ThrowStatement:
+		c.checkThrowStatement(s)
+	case *ast.ExpressionStatement:
+		c.expr(s.Expression, nil)
+	case *ast.AssignmentStatement:
+		c.checkAssignmentStatement(s)
+	case *ast.IncDecStatement:
+		c.checkArithmeticOperand(s.Target, c.current(s.Target, c.checkAssignmentTarget(s.Target)))
+	case *ast.BlockStatement:
+		c.pushScope()
+		c.checkStatements(s.Statements)
+		c.popScope()
+	case *ast.IfStatement:
+		c.checkIfStatement(s)
+	case *ast.WhileStatement:
+		c.widenAssigned(s.Body)
+		c.expr(s.Condition, nil)
+		c.checkLoopBody(s.Body)
+	case *ast.DoWhileStatement:
+		c.widenAssigned(s.Body)
+		c.checkLoopBody(s.Body)
+		c.expr(s.Condition, nil)
+	case *ast.ForStatement:
+		c.pushScope()
+		if s.Init != nil {
+			c.checkStatement(s.Init)
+		}
+		c.widenAssigned(s.Body, s.Update)
+		if s.Condition != nil {
+			c.expr(s.Condition, nil)
+		}
+		if s.Update != nil {
+			c.checkStatement(s.Update)
+		}
+		c.checkLoopBody(s.Body)
+		c.popScope()
+	case *ast.ForOfStatement:
+		c.checkForOfStatement(s)
+	case *ast.ForInStatement:
+		c.checkForInStatement(s)
+	case *ast.SwitchStatement:
+		c.checkSwitchStatement(s)
+	case *ast.BranchStatement:
+		c.checkBranchStatement(s)
+	case *ast.LabeledStatement:
+		c.labels = append(c.labels, s.Label.String())
+		c.checkStatement(s.Body)
+		c.labels = c.labels[:len(c.labels)-1]
+	case *ast.ClassDeclaration:
+		c.checkClassDeclaration(s)
+	case *ast.EnumDeclaration:
+		c.checkEnumDeclaration(s)
+	case *ast.InterfaceDeclaration:
+		outer := c.declareTypeParams(s.TypeParams)
+		c.checkType(s.Type)
+		c.typeParams = outer
+	case *ast.TypeAliasDeclaration:
+		outer := c.declareTypeParams(s.TypeParams)
+		c.checkType(s.Type)
+		c.typeParams = outer
+	}
+}
+
+// checkStatements checks the statements of a block, in the current scope,
+// and returns the types of the variables narrowed at its end. The
+// statements after an if statement see the variables narrowed to the types
+// they may have at the end of its branches, and those after an assignment
+// or a declaration with an initializer see the variable narrowed to the
+// type of its value.
+func (c *Checker) checkStatements(stmts []ast.Statement) narrowing {
+	c.declareFunctions(stmts)
+	block := c.scope.block()
+	narrowings := 0
+	for _, stmt := range stmts {
+		var n narrowing
+		switch s := stmt.(type) {
+		case *ast.IfStatement:
+			n = c.checkIfStatement(s)
+		case *ast.AssignmentStatement:
+			c.checkStatement(s)
+			n = c.narrowAssignment(s)
+		case *ast.VariableDeclaration:
+			c.checkStatement(s)
+			n = c.narrowDeclaration(s)
+		default:
+			c.checkStatement(stmt)
+		}
+		if len(n) > 0 {
+			c.pushNarrowing(stmt, n)
+			narrowings++
+		}
+	}
+	end := c.narrowed(block)
+	for range narrowings {
+		c.popScope()
+	}
+	return end
+}
+
+// checkIfStatement checks an if statement and returns the narrowing of the
+// statements after it, which are reached from the end of either branch that
+// completes normally: the variables narrowed at the end of both have the
+// union of their types there.
+func (c *Checker) checkIfStatement(s *ast.IfStatement) narrowing {
+	c.expr(s.Condition, nil)
+
+	var ends []narrowing
+	c.pushNarrowing(s.Consequence, c.narrow(s.Condition, true))
+	if end := c.checkBranch(s.Consequence); !c.exits([]ast.Statement{s.Consequence}) {
+		ends = append(ends, end)
+	}
+	c.popScope()
+	if s.Alternative != nil {
+		c.pushNarrowing(s.Alternative, c.narrow(s.Condition, false))
+		if end := c.checkBranch(s.Alternative); !c.exits([]ast.Statement{s.Alternative}) {
+			ends = append(ends, end)
+		}
+		c.popScope()
+	} else {
+		ends = append(ends, c.narrowed(nil).then(c.narrow(s.Condition, false)))
+	}
+
+	if len(ends) == 0 {
+		return nil
+	}
+	n := ends[0]
+	for _, end := range ends[1:] {
+		n = c.join(n, end)
+	}
+	// leave out the variables whose types are unchanged
+	for name, t := range n {
+		current := c.scope.lookupPath(name)
+		if sym := c.scope.Lookup(name); sym != nil {
+			current = sym.Type
+		}
+		if current != nil && c.identical(t, current) {
+			delete(n, name)
+		}
+	}
+	return n
+}
+
+// checkBranch checks a branch of an if statement in a scope of its own and
+// returns the types of the variables narrowed at its end.
+func (c *Checker) checkBranch(stmt ast.Statement) narrowing {
+	c.pushScope()
+	defer c.popScope()
+	if block, ok := stmt.(*ast.BlockStatement); ok {
+		return c.checkStatements(block.Statements)
+	}
+	return c.checkStatements([]ast.Statement{stmt})
+}
+
+// narrowed returns the types of the variables and property paths narrowed
+// in the current scope, leaving out the variables declared in block, if it
+// isn't nil, which its end goes out of.
+func (c *Checker) narrowed(block *Scope) narrowing {
+	n := narrowing{}
+	for s := c.scope; s != nil; s = s.parent {
+		for name := range s.symbols {
+			if _, ok := n[name]; ok || block != nil && block.symbols[name] != nil {
+				continue
+			}
+			if sym := c.scope.Lookup(name); sym.narrows != nil {
+				n[name] = sym.Type
+			}
+		}
+		for path := range s.paths {
+			root, _, _ := strings.Cut(path, ".")
+			if _, ok := n[path]; ok || block != nil && block.symbols[root] != nil {
+				continue
+			}
+			if t := c.scope.lookupPath(path); t != nil {
+				n[path] = t
+			}
+		}
+	}
+	return n
+}
+
+// checkNested checks the body of an if statement or a loop, which is a
+// scope of its own even if it isn't a block.
+func (c *Checker) checkNested(stmt ast.Statement) {
+	c.pushScope()
+	c.checkStatement(stmt)
+	c.popScope()
+}
+
+func (c *Checker) checkLoopBody(body ast.Statement) {
+	c.loops++
+	c.checkNested(body)
+	c.loops--
+}
+
+// checkVariableDeclaration checks the initializer of a variable against its
+// declared type, or infers the type of the variable from the initializer if
+// it has none.
+func (c *Checker) checkVariableDeclaration(v *ast.VariableDeclaration) {
+	c.checkType(v.Type)
+	t := v.Type
+	switch {
+	case v.Expr != nil && t == nil:
+		t = c.expr(v.Expr, nil)
+	case v.Expr != nil:
+		c.checkAssignable(v.Expr, c.expr(v.Expr, t), t)
+	case t == nil:
+		c.errorf(v, 7005, "variable '%s' implicitly has an 'any' type", v.Name)
+	}
+
+	switch {
+	case v.Keyword.Type == token.VAR:
+		c.defineVar(v, t)
+	case c.scope.block() != c.global:
+		c.declareVar(v.Name, t, v)
+	default:
+		// top-level variables were declared before checking the program
+		if sym := c.global.Lookup(v.Name); sym != nil && sym.Decl == v {
+			sym.Type = t
+		}
+	}
+}
+
+// defineVar records the type of a hoisted var declaration, checking that a
+// var declared more than once has the same type each time.
+func (c *Checker) defineVar(v *ast.VariableDeclaration, t ast.TypeExpr) {
+	scope := c.global
+	if c.fn != nil && c.fn.scope != nil {
+		scope = c.fn.scope
+	}
+
+	sym := scope.symbols[v.Name]
+	switch {
+	case sym == nil || keyword(sym) != token.VAR:
+	case sym.Decl == v:
+		sym.Type = t
+	case t != nil && sym.Type != nil && !c.identical(t, sym.Type):
+		c.errorf(v, 2403, "subsequent variable declarations must have the same type. Variable '%s' must be of type '%s', but here has type '%s'", v.Name, typeString(sym.Type), typeString(t))
+	}
+}
+
+// checkFunction checks the parameters and body of a function or method.
+func (c *Checker) checkFunction(params []ast.FunctionParam, body []ast.Statement, fn *function, name ast.Node) {
+	outerFn, outerLoops, outerSwitches, outerLabels := c.fn, c.loops, c.switches, c.labels
+	c.fn, c.loops, c.switches, c.labels = fn, 0, 0, nil
+	c.pushScope()
+	fn.scope = c.scope
+
+	for i := range params {
+		c.declareVar(params[i].Name.Literal, params[i].Type, &params[i])
+	}
+	c.hoistVars(body)
+	c.checkStatements(body)
+
+	if !c.assignable(primitive("undefined"), fn.returnType) && !c.terminates(body) {
+		c.errorf(name, 2366, "function lacks ending return statement and return type does not include 'undefined'")
+	}
+
+	c.popScope()
+	c.fn, c.loops, c.switches, c.labels = outerFn, outerLoops, outerSwitches, outerLabels
+}
+
+// terminates reports whether control can't reach the end of stmts, because
+// they end in a return or throw statement, an if statement whose branches both
+// terminate, a loop without a condition, or a switch statement that covers
+// every value and whose clauses terminate.
+func (c *Checker) terminates(stmts []ast.Statement) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	return c.terminatesStatement(stmts[len(stmts)-1])
+}
+
+func (c *Checker) terminatesStatement(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	case *ast.BlockStatement:
+		return c.terminates(s.Statements)
+	case *ast.IfStatement:
+		return s.Alternative != nil && c.terminatesStatement(s.Consequence) && c.terminatesStatement(s.Alternative)
+	case *ast.WhileStatement:
+		return isTrue(s.Condition)
+	case *ast.ForStatement:
+		return s.Condition == nil || isTrue(s.Condition)
+	case *ast.LabeledStatement:
+		return c.terminatesStatement(s.Body)
+	case *ast.SwitchStatement:
+		// clauses that don't terminate fall through to the next one, so
+		// only the last one has to, unless some clause breaks out
+		if len(s.Cases) == 0 || !hasDefault(s) && !c.exhaustive[s] || !c.terminates(s.Cases[len(s.Cases)-1].Body) {
+			return false
+		}
+		for _, clause := range s.Cases {
+			if breaks(clause.Body) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// exits reports whether control can't reach the end of stmts, because they
+// terminate or end in a break or continue statement.
+func (c *Checker) exits(stmts []ast.Statement) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	switch s := stmts[len(stmts)-1].(type) {
+	case *ast.BranchStatement:
+		return true
+	case *ast.BlockStatement:
+		return c.exits(s.Statements)
+	}
+	return c.terminates(stmts)
+}
+
+// breaks reports whether stmts contain a break statement without a label
+// that isn't nested in a loop or a switch statement of their own.
+func breaks(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.BranchStatement:
+			if s.Token.Type == token.BREAK && s.Label == nil {
+				return true
+			}
+		case *ast.BlockStatement:
+			if breaks(s.Statements) {
+				return true
+			}
+		case *ast.IfStatement:
+			if breaks([]ast.Statement{s.Consequence}) || s.Alternative != nil && breaks([]ast.Statement{s.Alternative}) {
+				return true
+			}
+		case *ast.LabeledStatement:
+			if breaks([]ast.Statement{s.Body}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isTrue(expr ast.Expression) bool {
+	b, ok := expr.(*ast.BooleanLiteral)
+	return ok && b.Token.Literal == "true"
+}
+
+func (c *Checker) checkReturnStatement(r *ast.ReturnStatement) {
+	if c.fn == nil {
+		c.errorf(r, 1108, "a 'return' statement can only be used within a function body")
+		if r.Value != nil {
+			c.expr(r.Value, nil)
+		}
+		return
+	}
+
+	want := c.fn.returnType
+	if c.fn.constructor {
+		want = primitive("void")
+	}
+
+	if c.fn.infer {
+		t := primitive("void")
+		if r.Value != nil {
+			t = c.expr(r.Value, nil)
+		}
+		c.fn.returns = append(c.fn.returns, t)
+		return
+	}
+
+	if r.Value == nil {
+		if !c.assignable(primitive("undefined"), want) {
+			c.errorf(r, 2322, "type 'undefined' is not assignable to type '%s'", typeString(want))
+		}
+		return
+	}
+	c.checkAssignable(r.Value, c.expr(r.Value, want), want)
+}
+
+// checkThrowStatement checks a throw statement, which may throw any value.
+// The builtin Error class is only known there, as in throw new Error("x"),
+// with an optional message.
+func (c *Checker) checkThrowStatement(t *ast.ThrowStatement) {
+	n, ok := t.Value.(*ast.NewExpression)
+	if _, declared := c.classes["Error"]; !ok || declared || n.Class.String() != "Error" || n.TypeArgs != nil {
+		c.expr(t.Value, nil)
+		return
+	}
+	if len(n.Args) > 1 {
+		c.errorf(n, 2554, "expected 0-1 arguments, but got %d", len(n.Args))
+	}
+	for _, arg := range n.Args {
+		c.checkArg(arg, primitive("string"))
+	}
+}
+
+func (c *Checker) checkAssignmentStatement(a *ast.AssignmentStatement) {
+	target := c.checkAssignmentTarget(a.Target)
+
+	if v, ok := a.Target.(*ast.VariableExpression); ok && a.Operator.Type == token.ASSIGN {
+		c.info.Assigned[v] = true
+	}
+
+	switch a.Operator.Type {
+	case token.ASSIGN, token.NULLISH_ASSIGN:
+		c.checkAssignable(a.Value, c.expr(a.Value, target), target)
+	case token.PLUS_ASSIGN:
+		value := c.expr(a.Value, nil)
+		if sum := c.plusType(a, a.Operator, c.current(a.Target, target), value); sum != nil {
+			c.checkAssignable(a.Value, sum, target)
+			c.compound[a] = sum
+		}
+	default:
+		c.compound[a] = c.arithmeticType(a, a.Operator, a.Target, c.current(a.Target, target), a.Value, c.expr(a.Value, nil))
+	}
+	c.widen(a.Target)
+}
+
+// current returns the type of the value that the target of a compound
+// assignment or an increment holds before it: its declared type, or the
+// type a condition or an assignment narrowed it to.
+func (c *Checker) current(target ast.Expression, declared ast.TypeExpr) ast.TypeExpr {
+	switch t := target.(type) {
+	case *ast.VariableExpression:
+		if sym := c.scope.Lookup(t.Token.Literal); sym != nil && sym.narrows != nil {
+			return sym.Type
+		}
+	case *ast.MemberExpression:
+		if path := propertyPath(t); path != "" {
+			if narrowed := c.scope.lookupPath(path); narrowed != nil {
+				return narrowed
+			}
+		}
+	}
+	return declared
+}
+
+// checkAssignmentTarget checks that target can be assigned to and returns
+// its type.
+func (c *Checker) checkAssignmentTarget(target ast.Expression) ast.TypeExpr {
+	switch t := target.(type) {
+	case *ast.VariableExpression:
+		if sym := c.scope.Lookup(t.Token.Literal); sym != nil {
+			switch {
+			case keyword(sym) == token.CONST:
+				c.errorf(t, 2588, "cannot assign to '%s' because it is a constant", sym.Name)
+			case sym.Kind == Func:
+				c.errorf(t, 2630, "cannot assign to '%s' because it is a function", sym.Name)
+			case sym.Kind == Class:
+				c.errorf(t, 2629, "cannot assign to '%s' because it is a class", sym.Name)
+			case sym.Kind == Builtin:
+				c.errorf(t, 2540, "cannot assign to '%s' because it is a read-only property", sym.Name)
+			}
+			if sym.narrows != nil {
+				// any value of the declared type may be assigned to a
+				// narrowed variable
+				c.expr(t, nil)
+				c.info.Types[t] = sym.narrows.Type
+				return sym.narrows.Type
+			}
+		}
+	case *ast.MemberExpression:
+		// any value of the type of the property may be assigned to it
+		typ := c.member(t)
+		c.info.Types[t] = typ
+		if m := c.members[t]; m != nil && m.field != nil && m.field.Readonly && !c.initializes(m) {
+			c.errorf(t.Property, 2540, "cannot assign to '%s' because it is a read-only property", t.Property)
+		}
+		return typ
+	}
+	return c.expr(target, nil)
+}
+
+// initializes reports whether the function being checked may assign the
+// readonly field m, which only the constructor of its class may do.
+func (c *Checker) initializes(m *classMember) bool {
+	return !m.field.Static && c.fn != nil && c.fn.constructor && c.fn.class == m.owner
+}
+
+// checkArithmeticOperand reports an error at the operand expr of ++ or -- if
+// its type t isn't a number or a bigint.
+func (c *Checker) checkArithmeticOperand(expr ast.Expression, t ast.TypeExpr) {
+	if !isAny(t) && !c.isNumeric(t) {
+		c.errorf(expr, 2356, "an arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type")
+	}
+}
+
+func (c *Checker) checkForOfStatement(f *ast.ForOfStatement) {
+	iterable := c.expr(f.Iterable, nil)
+
+	var elem ast.TypeExpr
+	switch resolved := c.resolve(iterable); {
+	case isAny(iterable):
+	case ast.ElementType(resolved) != nil:
+		elem = ast.ElementType(resolved)
+	case c.isString(resolved):
+		elem = primitive("string")
+	default:
+		c.errorf(f.Iterable, 2488, "type '%s' must have a '[Symbol.iterator]()' method that returns an iterator", typeString(iterable))
+	}
+
+	c.pushScope()
+	c.declareVar(f.Variable.String(), elem, f)
+	c.widenAssigned(f.Body)
+	c.checkLoopBody(f.Body)
+	c.popScope()
+}
+
+func (c *Checker) checkForInStatement(f *ast.ForInStatement) {
+	object := c.expr(f.Object, nil)
+	if name := typeName(c.resolve(object)); name == "number" || name == "boolean" {
+		c.errorf(f.Object, 2407, "the right-hand side of a 'for...in' statement must be of type 'any', an object type or a type parameter, but here has type '%s'", name)
+	}
+
+	c.pushScope()
+	c.declareVar(f.Variable.String(), primitive("string"), f)
+	c.widenAssigned(f.Body)
+	c.checkLoopBody(f.Body)
+	c.popScope()
+}
+
+func (c *Checker) checkBranchStatement(b *ast.BranchStatement) {
+	if b.Label != nil {
+		for _, label := range c.labels {
+			if label == b.Label.String() {
+				return
+			}
+		}
+		c.errorf(b.Label, 1116, "a '%s' statement can only jump to a label of an enclosing statement", b.Token.Literal)
+		return
+	}
+
+	switch {
+	case b.Token.Type == token.BREAK && c.loops == 0 && c.switches == 0:
+		c.errorf(b, 1105, "a 'break' statement can only be used within an enclosing iteration or switch statement")
+	case b.Token.Type == token.CONTINUE && c.loops == 0:
+		c.errorf(b, 1104, "a 'continue' statement can only be used within an enclosing iteration statement")
+	}
+}
+
+// checkAssignable reports an error at expr if a value of type source can't
+// be assigned to target.
+func (c *Checker) checkAssignable(expr ast.Expression, source, target ast.TypeExpr) {
+	if !c.assignable(source, target) {
+		c.errorf(expr, 2322, "type '%s' is not assignable to type '%s'", typeString(source), typeString(target))
+	}
+}
+
+func ident(tok token.Token) *ast.Identifier {
+	return &ast.Identifier{Token: tok}
+}
+
+func (c *Checker) errorf(node ast.Node, code int, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, diag.Diagnostic{
+		Severity: diag.Error,
+		Pos:      node.Pos(),
+		End:      node.End(),
+		Message:  fmt.Sprintf(format, args...),
+		Code:     code,
+	})
+}
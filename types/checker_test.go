@@ -0,0 +1,680 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/diag"
+	"github.com/toyaAoi/sild/parser"
+	"github.com/toyaAoi/sild/scanner"
+)
+
+func check(t *testing.T, input string) (*ast.Program, *Info, []diag.Diagnostic) {
+	t.Helper()
+	p := parser.New(scanner.New(strings.NewReader(input)))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("unexpected parse diagnostics: %v", p.Diagnostics())
+	}
+	info, diags := Check(program)
+	return program, info, diags
+}
+
+func TestCheckValidPrograms(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"hoisted function", `let x: number = add(1, 2);
+function add(a: number, b: number): number { return a + b; }`},
+		{"string concatenation", `let s: string = "n = " + 1;
+s += 2;`},
+		{"terminating if", `function sign(n: number): number {
+    if (n < 0) { return -1; } else { return 1; }
+}`},
+		{"infinite loop", `function f(): number { while (true) { return 1; } }`},
+		{"throw", `function f(n: number | null): number {
+    if (n === null) { throw new Error("null"); }
+    return n;
+}
+function g(): number { throw "unreachable"; }`},
+		{"arrays", `function double(n: number): number { return n * 2; }
+let xs: number[] = [1, 2];
+xs.push(3);
+let ys: number[] = xs.map(double);
+for (let x of ys) { console.log(x); }`},
+		{"structural object types", `interface Point { x: number; y?: number }
+let o: { x: number } = { x: 1 };
+let p: Point = o;`},
+		{"classes", `class Animal {
+    protected name: string;
+    static count: number = 0;
+    constructor(name: string) { this.name = name; Animal.count++; }
+    speak(): string { return this.name; }
+}
+class Dog extends Animal {
+    constructor() { super("dog"); }
+    speak(): string { return "woof from " + this.name + super.speak(); }
+}
+let a: Animal = new Dog();
+let s: string = a.speak();`},
+		{"numbers", `let x: number = NaN + Infinity + 0x1F + 1.5e3 + .5 + 1_000;
+let y: number = 7 % 2.5;`},
+		{"bigints", `let a: bigint = 10n * 3n + -1n;
+a += 2n;
+a++;
+let b: boolean = a > 1 && a <= 2n;`},
+		{"strings", `let name = 'Ann';
+let s: string = ` + "`${name} is ${30 + 1} ${true}`" + `;
+s += 1;
+let t: string = "n: " + 1n;`},
+		{"string methods", `let s: string = " a,b ".trim().toUpperCase();
+let parts: string[] = s.split(",");
+let i: number = s.indexOf("B") + s.length;
+let ok: boolean = s.startsWith("A") && s.includes("B", 1);
+let p: string = s.padStart(5, "-").slice(-3) + s.charAt(0) + s[1];`},
+		{"labels", `outer: for (let i: number = 0; i < 3; i++) {
+    while (true) { continue outer; }
+}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, diags := check(t, tt.input); len(diags) != 0 {
+				t.Errorf("unexpected diagnostics: %v", diags)
+			}
+		})
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let s: string = 5;`, "1:17: error TS2322: type 'number' is not assignable to type 'string'"},
+		{`let n: number = m;`, "1:17: error TS2304: cannot find name 'm'"},
+		{`let x: invalid = 42;`, "1:8: error TS2304: cannot find name 'invalid'"},
+		{`let xs: Foo[] = [];`, "1:9: error TS2304: cannot find name 'Foo'"},
+		{`function f(x: number): Foo { return x; }`, "1:24: error TS2304: cannot find name 'Foo'"},
+		{`let n: number = m; let m: number = 1;`, "1:17: error TS2448: block-scoped variable 'm' used before its declaration"},
+		{`let x: number = 1; let x: number = 2;`, "1:20: error TS2451: cannot redeclare block-scoped variable 'x'"},
+		{`function f(a: number): void {} f();`, "1:32: error TS2554: expected 1 arguments, but got 0"},
+		{`throw new Error(1);`, "1:17: error TS2345: argument of type 'number' is not assignable to parameter of type 'string'"},
+		{`function f(a: number): void {} f("a");`, "1:34: error TS2345: argument of type 'string' is not assignable to parameter of type 'number'"},
+		{`function f(): number { return "a"; }`, "1:31: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`function f(): number { return; }`, "1:24: error TS2322: type 'undefined' is not assignable to type 'number'"},
+		{`function f(): void { return 1; }`, "1:29: error TS2322: type 'number' is not assignable to type 'void'"},
+		{`function f(): number { if (true) { return 1; } }`, "1:10: error TS2366: function lacks ending return statement and return type does not include 'undefined'"},
+		{`function f(): void {} f = 1;`, "1:23: error TS2630: cannot assign to 'f' because it is a function"},
+		{`let n: number = 1; n();`, "1:20: error TS2349: this expression is not callable. Type 'number' has no call signatures"},
+		{`Infinity();`, "1:1: error TS2349: this expression is not callable. Type 'number' has no call signatures"},
+		{`NaN = 1;`, "1:1: error TS2540: cannot assign to 'NaN' because it is a read-only property"},
+		{`print(1);`, "1:1: error TS2304: cannot find name 'print'"},
+		{`console.warn(1);`, "1:9: error TS2339: property 'warn' does not exist on type 'Console'"},
+		{`console(1);`, "1:1: error TS2349: this expression is not callable. Type 'Console' has no call signatures"},
+		{`return;`, "1:1: error TS1108: a 'return' statement can only be used within a function body"},
+		{`break;`, "1:1: error TS1105: a 'break' statement can only be used within an enclosing iteration or switch statement"},
+		{`while (true) { continue outer; }`, "1:25: error TS1116: a 'continue' statement can only jump to a label of an enclosing statement"},
+		{`let b: number = true - 1;`, "1:17: error TS2362: the left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type"},
+		{`let b: boolean = true + 1;`, "1:18: error TS2365: operator '+' cannot be applied to types 'boolean' and 'number'"},
+		{`let b: boolean = "a" < 1;`, "1:18: error TS2365: operator '<' cannot be applied to types 'string' and 'number'"},
+		{`let b: boolean = "a" === 1;`, "1:18: error TS2367: this comparison appears to be unintentional because the types 'string' and 'number' have no overlap"},
+		{`let s: string = "a"; s++;`, "1:22: error TS2356: an arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type"},
+		{`let a: bigint = 1n + 1;`, "1:17: error TS2365: operator '+' cannot be applied to types 'bigint' and 'number'"},
+		{`let a: bigint = 1n; a -= 1;`, "1:21: error TS2365: operator '-=' cannot be applied to types 'bigint' and 'number'"},
+		{`let n: number = 1n;`, "1:17: error TS2322: type 'bigint' is not assignable to type 'number'"},
+		{`let b: boolean = 1n === 1;`, "1:18: error TS2367: this comparison appears to be unintentional because the types 'bigint' and 'number' have no overlap"},
+		{"let s: number = `${1}`;", "1:17: error TS2322: type 'string' is not assignable to type 'number'"},
+		{"let s: string = `${x}`;", "1:20: error TS2304: cannot find name 'x'"},
+		{`let n: number = 1; n += "a";`, "1:25: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`for (let c of 5) {}`, "1:15: error TS2488: type 'number' must have a '[Symbol.iterator]()' method that returns an iterator"},
+		{`let xs: number[] = [1, "a"];`, "1:24: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`let xs: number[] = [1]; xs.push("a");`, "1:33: error TS2345: argument of type 'string' is not assignable to parameter of type 'number'"},
+		{`let xs: number[] = [1]; xs.shift();`, "1:28: error TS2339: property 'shift' does not exist on type 'number[]'"},
+		{`let s: string = "a"; s.shift();`, "1:24: error TS2339: property 'shift' does not exist on type 'string'"},
+		{`let s: string = "a".repeat("2");`, "1:28: error TS2345: argument of type 'string' is not assignable to parameter of type 'number'"},
+		{`let s: string = "a".replace("a");`, "1:17: error TS2554: expected 2 arguments, but got 1"},
+		{`let s: string = "a".slice(1, 2, 3);`, "1:17: error TS2554: expected 0-2 arguments, but got 3"},
+		{`let n: number = "a".charAt(0);`, "1:17: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`interface P { x: number } let p: P = { x: 1, y: 2 };`, "1:46: error TS2353: object literal may only specify known properties, and 'y' does not exist in type 'P'"},
+		{`interface P { x: number } let p: P = {};`, "1:38: error TS2741: property 'x' is missing in type '{}' but required in type 'P'"},
+		{`interface P { x: number } let p: P = { x: 1 }; let y: number = p.y;`, "1:66: error TS2339: property 'y' does not exist on type 'P'"},
+		{`interface P { x: number } let q: { y: number } = { y: 1 }; let p: P = q;`, "1:71: error TS2322: type '{ y: number }' is not assignable to type 'P'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckClassErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`class C { private x: number = 1; } let c: C = new C(); let n: number = c.x;`, "1:74: error TS2341: property 'x' is private and only accessible within class 'C'"},
+		{`class C { protected x: number = 1; } let c: C = new C(); let n: number = c.x;`, "1:76: error TS2445: property 'x' is protected and only accessible within class 'C' and its subclasses"},
+		{`class C { readonly x: number = 1; m(): void { this.x = 2; } }`, "1:52: error TS2540: cannot assign to 'x' because it is a read-only property"},
+		{`class C { static n: number = 0; } let c: C = new C(); let n: number = c.n;`, "1:73: error TS2576: property 'n' does not exist on type 'C'. Did you mean to access the static member 'C.n' instead?"},
+		{`class C { m(): void {} } C.m();`, "1:28: error TS2339: property 'm' does not exist on type 'typeof C'"},
+		{`class C { constructor(x: number) {} } let c: C = new C();`, "1:50: error TS2554: expected 1 arguments, but got 0"},
+		{`class C { m(): number { return this.n; } }`, "1:37: error TS2339: property 'n' does not exist on type 'C'"},
+		{`class A {} class B extends A {} let b: B = new A();`, "1:44: error TS2322: type 'A' is not assignable to type 'B'"},
+		{`class A { constructor(x: number) {} } class B extends A { constructor() { super("x"); } }`, "1:81: error TS2345: argument of type 'string' is not assignable to parameter of type 'number'"},
+		{`class Dog extends Animal {}`, "1:19: error TS2304: cannot find name 'Animal'"},
+		{`class A {} class B extends A { constructor() {} }`, "1:32: error TS2377: constructors for derived classes must contain a 'super' call"},
+		{`class A { m(): void { super.m(); } }`, "1:23: error TS2335: 'super' can only be referenced in a derived class"},
+		{`class A {} class B extends A { m(): void { super(); } }`, "1:44: error TS2337: super calls are not permitted outside constructors or in nested functions inside constructors"},
+		{`class C {} let c: C = C();`, "1:23: error TS2348: value of type 'typeof C' is not callable. Did you mean to include 'new'?"},
+		{`interface I {} let i: I = new I();`, "1:31: error TS2693: 'I' only refers to a type, but is being used as a value here"},
+		{`let n: number = this.x;`, "1:17: error TS2683: 'this' implicitly has type 'any' because it does not have a type annotation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckInference(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let n = 1; n = "a";`, "1:16: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`function f(): string { return "a"; } const s = f(); let n: number = s;`, "1:69: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`let xs = [1, 2]; xs.push(true);`, "1:26: error TS2345: argument of type 'boolean' is not assignable to parameter of type 'number'"},
+		{`let p = { x: 1 }; let y: number = p.y;`, "1:37: error TS2339: property 'y' does not exist on type '{ x: number }'"},
+		{`function f(): number { return n; } let n = "a";`, "1:31: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`let x;`, "1:1: error TS7005: variable 'x' implicitly has an 'any' type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckDeclarations(t *testing.T) {
+	valid := `function f(a: number): number {
+    if (a > 0) { var r = 1; } else { var r = 2; }
+    var a = 3;
+    return r + a + later;
+}
+var later = 1;
+let xs: number[] = [1];
+xs[0] = 2;
+xs[0] += 1;`
+	if _, _, diags := check(t, valid); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const n = 1; n = 2;`, "1:14: error TS2588: cannot assign to 'n' because it is a constant"},
+		{`const n = 1; n++;`, "1:14: error TS2588: cannot assign to 'n' because it is a constant"},
+		{`for (const x of [1]) { x += 1; }`, "1:24: error TS2588: cannot assign to 'x' because it is a constant"},
+		{`var v = 1; var v = "a";`, "1:12: error TS2403: subsequent variable declarations must have the same type. Variable 'v' must be of type 'number', but here has type 'string'"},
+		{`let v = 1; { var v = 2; }`, "1:14: error TS2451: cannot redeclare block-scoped variable 'v'"},
+		{`let xs: string[] = ["a"]; xs[0] = 1;`, "1:35: error TS2322: type 'number' is not assignable to type 'string'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckResolution(t *testing.T) {
+	input := `let x: number = 1;
+function f(x: string): string { return x; }
+let y: string = f("a");`
+
+	program, info, diags := check(t, input)
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	fn := program.Statements[1].(*ast.FunctionDeclaration)
+	ret := fn.Body[0].(*ast.ReturnStatement).Value.(*ast.VariableExpression)
+	sym := info.Uses[ret]
+	if sym == nil || sym.Kind != Var || sym.Decl != &fn.Params[0] {
+		t.Errorf("expected x in f to refer to the parameter, got %+v", sym)
+	}
+
+	call := program.Statements[2].(*ast.VariableDeclaration).Expr
+	if got := typeString(info.Types[call]); got != "string" {
+		t.Errorf("expected call to have type string, got %s", got)
+	}
+	callee := call.(*ast.FunctionCallExpression).Callee.(*ast.VariableExpression)
+	if sym := info.Uses[callee]; sym == nil || sym.Kind != Func || sym.Decl != fn {
+		t.Errorf("expected f to refer to the function, got %+v", sym)
+	}
+}
+
+func TestCheckAssigned(t *testing.T) {
+	input := `let x = 1;
+x = 2;
+x += 3;`
+
+	program, info, diags := check(t, input)
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	for i, assigned := range []bool{true, false} {
+		target := program.Statements[i+1].(*ast.AssignmentStatement).Target.(*ast.VariableExpression)
+		if info.Uses[target] == nil {
+			t.Errorf("statement %d: expected x to be resolved", i+1)
+		}
+		if info.Assigned[target] != assigned {
+			t.Errorf("statement %d: expected Assigned to be %v", i+1, assigned)
+		}
+	}
+}
+
+func TestCheckFunctionExpressions(t *testing.T) {
+	valid := `function twice(f: (x: number) => number, x: number): number { return f(f(x)); }
+let double = (a: number) => a * 2;
+let n: number = twice(double, 1) + twice(x => x + 1, 2);
+let add = function (a: number, b: number): number { return a + b; };
+let adder = (a: number) => { return (b: number) => add(a, b); };
+let m: number = adder(1)(2);
+let log: (s: string) => void = s => console.log(s);
+let xs: number[] = [1, 2, 3];
+let names: string[] = xs.map(x => "n" + x);
+let evens: number[] = xs.filter(x => x % 2 === 0);
+let sum: number = xs.reduce((acc, x) => acc + x, 0);
+xs.forEach(x => xs.push(x));
+xs.sort((a, b) => b - a);
+let indices: number[] = xs.map((x: number, i: number) => i);
+let firstTwo: number[] = xs.filter((x, i) => i < 2);
+let ordered: boolean = xs.every((x, i) => i === 0 || xs[i - 1] <= x) && !xs.some((x, i) => x === i);
+xs.forEach((x, i) => console.log(x * i));
+class Counter {
+    count: number = 0;
+    increment(): () => number {
+        return () => this.count + 1;
+    }
+}`
+	if _, _, diags := check(t, valid); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = x => x;`, "1:9: error TS7006: parameter 'x' implicitly has an 'any' type"},
+		{`let f = (x: number) => x; f("a");`, "1:29: error TS2345: argument of type 'string' is not assignable to parameter of type 'number'"},
+		{`let f = (x: number) => x; f();`, "1:27: error TS2554: expected 1 arguments, but got 0"},
+		{`let f = (x: number) => x; let s: string = f(1);`, "1:43: error TS2322: type 'number' is not assignable to type 'string'"},
+		{`let f: (x: number) => string = x => x * 2;`, "1:32: error TS2322: type '(x: number) => number' is not assignable to type '(x: number) => string'"},
+		{`let f: (x: number) => number = (x: string) => 1;`, "1:32: error TS2322: type '(x: string) => number' is not assignable to type '(x: number) => number'"},
+		{`let f: () => number = function (): number { return "a"; };`, "1:52: error TS2322: type 'string' is not assignable to type 'number'"},
+		{`let xs: number[] = [1]; let ys: number[] = xs.map(x => "a" + x);`, "1:44: error TS2322: type 'string[]' is not assignable to type 'number[]'"},
+		{`let xs: number[] = [1]; xs.map((x, i, all) => x);`, "1:32: error TS2345: argument of type '(x: number, i: number, all: any) => number' is not assignable to parameter of type '(value: number, index: number) => any'"},
+		{`let f = function (): number { return this.x; };`, "1:38: error TS2683: 'this' implicitly has type 'any' because it does not have a type annotation"},
+		{`let f = (x: number) => { if (x > 1) { return "a"; } return 1; }; let s: string = f(0);`, "1:82: error TS2322: type 'string | number' is not assignable to type 'string'"},
+		{`let f = (x: number) => { if (x > 1) { return "a"; } }; let s: string = f(0);`, "1:72: error TS2322: type 'string | undefined' is not assignable to type 'string'"},
+		{`let f = (x: number) => { if (x > 1) { return; } return "a"; }; let s: string = f(0);`, "1:80: error TS2322: type 'string | undefined' is not assignable to type 'string'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckGenerics(t *testing.T) {
+	valid := `interface Comparable<T> { compareTo(other: T): number; }
+interface Box<T> { value: T }
+type Pair<A, B> = { first: A; second: B };
+function first<T>(xs: T[]): T { return xs[0]; }
+function max<T extends Comparable<T>>(a: T, b: T): T { if (a.compareTo(b) > 0) { return a; } return b; }
+function pair<A, B>(a: A, b: B): Pair<A, B> { return { first: a, second: b }; }
+function apply<T, U>(x: T, f: (x: T) => U): U { return f(x); }
+class Version {
+    major: number = 0;
+    compareTo(other: Version): number { return this.major - other.major; }
+}
+class Stack<T> {
+    items: T[] = [];
+    push(x: T): void { this.items.push(x); }
+    pop(): T { return this.items.pop()!; }
+}
+let n: number = first([1, 2]) + first<number>([3]);
+let v: Version = max(new Version(), new Version());
+let p: Pair<string, number> = pair("a", 1);
+const makePair = pair;
+makePair("b", 2);
+let len: number = apply("ab", s => s.length);
+let b: Box<string> = { value: "x" };
+let s = new Stack<number>();
+s.push(1);
+let m: number = s.pop();
+let t: Stack<string> = new Stack();
+t.push("a");`
+	if _, _, diags := check(t, valid); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`function f<T>(x: T): T { return x; } let s: string = f(1);`, "1:54: error TS2322: type 'number' is not assignable to type 'string'"},
+		{`function f<T>(x: T): T { return x; } f<number, string>(1);`, "1:38: error TS2558: expected 1 type arguments, but got 2"},
+		{`function f<T extends number>(x: T): T { return x; } f<string>("a");`, "1:55: error TS2344: type 'string' does not satisfy the constraint 'number'"},
+		{`function f<T extends number>(x: T): T { return x; } f("a");`, "1:55: error TS2345: argument of type 'string' is not assignable to parameter of type 'number'"},
+		{`function f<T>(x: T): T { return 1; }`, "1:33: error TS2322: type 'number' is not assignable to type 'T'"},
+		{`class Stack<T> { items: T[] = []; } let s: Stack<number> = new Stack<string>();`, "1:60: error TS2322: type 'Stack<string>' is not assignable to type 'Stack<number>'"},
+		{`class Stack<T> { items: T[] = []; } let s = new Stack<number>(); s.items.push("a");`, "1:79: error TS2345: argument of type 'string' is not assignable to parameter of type 'number'"},
+		{`class C<T> { static x: T; }`, "1:24: error TS2302: static members cannot reference class type parameters"},
+		{`interface Box<T> { value: T } let b: Box;`, "1:38: error TS2314: generic type 'Box<T>' requires 1 type argument(s)"},
+		{`interface Pair<A, B> { a: A } let p: Pair<number>;`, "1:38: error TS2314: generic type 'Pair<A, B>' requires 2 type argument(s)"},
+		{`class Base<T> {} class C extends Base {}`, "1:34: error TS2314: generic type 'Base<T>' requires 1 type argument(s)"},
+		{`interface P { x: number } let p: P<number>;`, "1:34: error TS2315: type 'P' is not generic"},
+		{`function f<T extends Missing>(x: T): void {}`, "1:22: error TS2304: cannot find name 'Missing'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckUnions(t *testing.T) {
+	valid := `interface Circle { kind: "circle"; r: number }
+interface Square { kind: "square"; side: number }
+type Shape = Circle | Square | { kind: "tri"; a: number };
+type Dir = "left" | "right";
+function area(s: Shape): number {
+    switch (s.kind) {
+        case "circle":
+            return s.r * s.r;
+        case "square":
+            return s.side * s.side;
+        case "tri":
+            return s.a;
+    }
+}
+function size(s: Shape): number {
+    if (s.kind === "circle" && s.r > 1) { return s.r; }
+    if (s.kind !== "tri") { return 0; }
+    return s.a;
+}
+function grouped(s: Shape): number {
+    switch (s.kind) {
+        case "circle":
+        case "square":
+            if ("r" in s) { return s.r; }
+            return s.side;
+        default:
+            return s.a;
+    }
+}
+function show(x: string | number): string {
+    if (typeof x === "number") { return "n" + (x + 1); }
+    return x.toUpperCase();
+}
+let d: Dir = "left";
+let s: Shape = { kind: "circle", r: 1 };
+let k: string = s.kind;
+let n: number = area({ kind: "tri", a: 2 }) + size(s) + grouped(s);`
+	if _, _, diags := check(t, valid); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let d: "left" | "right" = "up";`, "1:27: error TS2322: type '\"up\"' is not assignable to type '\"left\" | \"right\"'"},
+		{`let x: string | number = true;`, "1:26: error TS2322: type 'boolean' is not assignable to type 'string | number'"},
+		{`type S = { kind: "a"; x: number } | { kind: "b" }; function f(s: S): number { return s.x; }`, "1:88: error TS2339: property 'x' does not exist on type 'S'"},
+		{`type S = { kind: "a" } | { kind: "b" }; function f(s: S): void { if (s.kind === "c") {} }`, "1:70: error TS2367: this comparison appears to be unintentional because the types '\"a\" | \"b\"' and '\"c\"' have no overlap"},
+		{`type S = { kind: "a" } | { kind: "b" }; function f(s: S): number { switch (s.kind) { case "a": return 1; } }`, "1:50: error TS2366: function lacks ending return statement and return type does not include 'undefined'"},
+		{`type S = { kind: "a" } | { kind: "b" }; function f(s: S): void { switch (s.kind) { case "c": } }`, "1:89: error TS2678: type '\"c\"' is not comparable to type '\"a\" | \"b\"'"},
+		{`let b = true in { x: 1 };`, "1:9: error TS2360: the left-hand side of an 'in' expression must be a private identifier or of type 'any', 'string', 'number', or 'symbol'"},
+		{`let b = "x" in 1;`, "1:16: error TS2361: the right-hand side of an 'in' expression must not be a primitive"},
+		{`let x: string | number = 1; let n: number = x;`, "1:45: error TS2322: type 'string | number' is not assignable to type 'number'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckNulls(t *testing.T) {
+	valid := `interface Address { city: string; zip?: string }
+interface User { name: string; address?: Address }
+class Node {
+    value: number;
+    next?: Node;
+    constructor(value: number) { this.value = value; }
+    last(): Node { return this.next?.last() ?? this; }
+}
+function find(users: User[], name: string): User | null {
+    for (const u of users) {
+        if (u.name === name) { return u; }
+    }
+    return null;
+}
+function zip(u: User | undefined): string {
+    return u?.address?.zip ?? "none";
+}
+function city(u: User | null): number {
+    let n: number | undefined = u?.address?.city.length;
+    return n ?? 0;
+}
+function label(u: User | null): string {
+    if (!u) { return "nobody"; }
+    return u.name;
+}
+function size(s: string | null | undefined): number {
+    if (s == null) { return 0; }
+    return s.length;
+}
+function maybe(): number | undefined {
+    return;
+}
+function first(xs: number[]): number {
+    return xs.find((x) => x > 1) ?? -1;
+}
+let popped: number = [].pop() ?? -5;
+let unset: Address = { city: "x", zip: undefined };
+let copied: Address = { city: "y", zip: unset.zip };
+function pick(a: string | null): number {
+    let x: string | null = a;
+    x = "q";
+    return x.length;
+}
+function fill(a: number | undefined): number {
+    let x = a;
+    x ??= 7;
+    return x;
+}
+function initialized(): number {
+    let x: number | undefined = 1;
+    return x * 10;
+}
+function incremented(): number {
+    let x: number | undefined;
+    x = 3;
+    x += 1;
+    x++;
+    return x;
+}
+function defaulted(s: string | null): number {
+    if (s == null) { s = ""; }
+    return s.length;
+}
+function either(flag: boolean): number {
+    let x: number | string | undefined;
+    if (flag) { x = 1; } else { x = 2; }
+    return x * 2;
+}
+interface Tagged { label?: string; count: number | null }
+function describe(t: Tagged): string {
+    if (t.label !== undefined) { return t.label; }
+    if (t.count === null) { return "none"; }
+    return "" + (t.count + 1);
+}
+function shout(t: Tagged): string {
+    if (!t.label) { t.label = "unnamed"; }
+    if (t.label) { return t.label.toUpperCase(); }
+    return "";
+}
+let s: string | null = null;
+s ??= "set";
+let n: number = s!.length;
+let f: ((x: number) => number) | undefined = undefined;
+let r = f?.(1);
+let b = s !== null && s.length > 0;`
+	if _, _, diags := check(t, valid); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`function f(s: string | null): number { return s.length; }`, "1:47: error TS18047: 's' is possibly 'null'"},
+		{`function f(s: string | undefined): number { return s.length; }`, "1:52: error TS18048: 's' is possibly 'undefined'"},
+		{`let o: { x: number } | null | undefined = null; let n = o.x;`, "1:57: error TS18049: 'o' is possibly 'null' or 'undefined'"},
+		{`function f(x: string | null): number { return (x ?? null).length; }`, "1:48: error TS2531: object is possibly 'null'"},
+		{`function f(g: (() => void) | undefined): void { g(); }`, "1:49: error TS2722: cannot invoke an object which is possibly 'undefined'"},
+		{`function f(n: number | null): number { return n * 2; }`, "1:47: error TS18047: 'n' is possibly 'null'"},
+		{`let s: string = null;`, "1:17: error TS2322: type 'null' is not assignable to type 'string'"},
+		{`interface P { name?: string } function f(p: P): string { return p.name; }`, "1:65: error TS2322: type 'string | undefined' is not assignable to type 'string'"},
+		{`function f(x: string[] | null): number { return x?.[0].length; }`, "1:49: error TS2322: type 'number | undefined' is not assignable to type 'number'"},
+		{`function f(c: boolean): void { let x: string | null = "a"; x = "b"; while (c) { console.log(x.length); x = null; } }`, "1:93: error TS18047: 'x' is possibly 'null'"},
+		{`function f(c: boolean): number { let x: string | null = "a"; x = "b"; if (c) { x = null; } return x.length; }`, "1:99: error TS18047: 'x' is possibly 'null'"},
+		{`function f(c: boolean): number { let x: number | undefined = undefined; if (c) { x = 1; } return x * 2; }`, "1:98: error TS18048: 'x' is possibly 'undefined'"},
+		{`function f(): number { let x: number | undefined; x += 1; return 0; }`, "1:51: error TS2365: operator '+=' cannot be applied to types 'number | undefined' and 'number'"},
+		{`function f(xs: number[]): number { return xs.pop(); }`, "1:43: error TS2322: type 'number | undefined' is not assignable to type 'number'"},
+		{`interface P { label?: string } function f(p: P): string { if (p.label !== undefined) { p.label = undefined; return p.label; } return ""; }`, "1:116: error TS2322: type 'undefined' is not assignable to type 'string'"},
+		{`interface P { label?: string } function f(p: P, q: P): number { if (q.label !== undefined) { return p.label.length; } return 0; }`, "1:101: error TS18048: 'p.label' is possibly 'undefined'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckEnums(t *testing.T) {
+	valid := `enum Color { Red, Green = 4, Blue }
+enum Status { Ok = "ok", Fail = "fail" }
+const enum Flag { A = 1, B = (A + 1) % 3, C = Flag.A * 4 }
+function paint(c: Color): string {
+    switch (c) {
+        case Color.Red: return "red";
+        case Color.Green: return "green";
+    }
+    return Color[c];
+}
+let c: Color = Color.Blue;
+let n: number = c * 2 + Flag.C;
+let back: Color = n;
+c++;
+let name: string = Color[0];
+let s: Status = Status["Fail"];
+let text: string = s + "!";
+let ok = s === Status.Ok && c !== Color.Red && typeof s === "string";
+let items = ["a", "b"];
+let first = items[Color.Red];`
+	if _, _, diags := check(t, valid); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`enum Color { Red } let c = Color.Blue;`, "1:34: error TS2339: property 'Blue' does not exist on type 'typeof Color'"},
+		{`enum Status { Ok = "ok" } let s: Status = "ok";`, "1:43: error TS2322: type 'string' is not assignable to type 'Status'"},
+		{`enum Status { Ok = "ok", Fail } `, "1:26: error TS1061: enum member must have initializer"},
+		{`const enum Dir { Up } let d = Dir[0];`, "1:35: error TS2476: a const enum member can only be accessed using a string literal"},
+		{`const enum Dir { Up } let d = Dir;`, "1:31: error TS2475: 'const' enums can only be used in property or index access expressions or the right hand side of an import declaration or export assignment or type query"},
+		{`const enum Dir { Up = "up".length }`, "1:23: error TS2474: const enum member initializers must be constant expressions"},
+		{`enum E { A = true }`, "1:14: error TS18033: type 'boolean' is not assignable to type 'number' as required for computed enum member values"},
+		{`enum Status { Ok = "ok" } let s = Status[0];`, "1:42: error TS7053: element implicitly has an 'any' type because expression of type 'number' can't be used to index type 'typeof Status'"},
+		{`enum A { X } enum B { Y } let same = A.X === B.Y;`, "1:38: error TS2367: this comparison appears to be unintentional because the types 'A' and 'B' have no overlap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, diags := check(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostics for %q", tt.input)
+			}
+			if got := diags[0].Error(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package types
+
+import (
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// classMember is a field or method of owner, possibly inherited by the class
+// it was looked up in. args are the type arguments of the instance of a
+// generic class it was looked up on.
+type classMember struct {
+	owner  *ast.ClassDeclaration
+	field  *ast.FieldDeclaration
+	method *ast.MethodDeclaration
+	args   map[string]ast.TypeExpr
+}
+
+func (m *classMember) modifiers() ast.Modifiers {
+	if m.field != nil {
+		return m.field.Modifiers
+	}
+	return m.method.Modifiers
+}
+
+// typ returns the type of a field, or the function type of a method, with
+// the type arguments of the instance substituted.
+func (m *classMember) typ() ast.TypeExpr {
+	if m.field != nil {
+		return ast.Substitute(m.field.Type, m.args)
+	}
+	return ast.Substitute(&ast.FunctionType{Params: m.method.Params, ReturnType: m.method.ReturnType}, m.args)
+}
+
+func (c *Checker) checkClassDeclaration(class *ast.ClassDeclaration) {
+	outerParams := c.declareTypeParams(class.TypeParams)
+	defer func() { c.typeParams = outerParams }()
+	c.checkStaticMembers(class)
+
+	outer := c.fn
+	for _, f := range class.Fields {
+		c.checkType(f.Type)
+		if f.Value == nil {
+			continue
+		}
+		c.fn = &function{class: class, static: f.Static}
+		c.checkAssignable(f.Value, c.expr(f.Value, f.Type), f.Type)
+	}
+	c.fn = outer
+
+	if ctor := class.Constructor; ctor != nil {
+		c.checkSignature(ctor.Params, nil)
+		c.checkFunction(ctor.Params, ctor.Body, &function{class: class, constructor: true}, ident(ctor.Name))
+		if c.bases[class] != nil && !callsSuper(ctor.Body) {
+			c.errorf(ident(ctor.Name), 2377, "constructors for derived classes must contain a 'super' call")
+		}
+	}
+
+	for _, m := range class.Methods {
+		outer := c.declareTypeParams(m.TypeParams)
+		c.checkSignature(m.Params, m.ReturnType)
+		c.checkFunction(m.Params, m.Body, &function{returnType: m.ReturnType, class: class, static: m.Static}, ident(m.Name))
+		c.typeParams = outer
+	}
+}
+
+// callsSuper reports whether body calls the base class constructor as a
+// statement of its own.
+func callsSuper(body []ast.Statement) bool {
+	for _, stmt := range body {
+		if s, ok := stmt.(*ast.ExpressionStatement); ok {
+			if call, ok := s.Expression.(*ast.FunctionCallExpression); ok {
+				if _, ok := call.Callee.(*ast.SuperExpression); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// receiver checks the object of a property access. If the object is a class
+// name, receiver returns the class with static set; if it is an instance of
+// a class, including super, it returns the class. Otherwise class is nil and
+// t is the type of the object.
+func (c *Checker) receiver(object ast.Expression) (class *ast.ClassDeclaration, static bool, t ast.TypeExpr) {
+	switch o := object.(type) {
+	case *ast.VariableExpression:
+		if sym := c.scope.Lookup(o.Token.Literal); sym != nil && sym.Kind == Class {
+			c.info.Uses[o] = sym
+			c.info.Types[o] = nil
+			return sym.Decl.(*ast.ClassDeclaration), true, nil
+		}
+	case *ast.SuperExpression:
+		c.info.Types[o] = nil
+		if c.fn == nil || c.fn.class == nil || c.bases[c.fn.class] == nil {
+			c.errorf(o, 2335, "'super' can only be referenced in a derived class")
+			return nil, false, nil
+		}
+		base := c.bases[c.fn.class]
+		if !c.fn.static {
+			c.info.Types[o] = classType(base)
+		}
+		return base, c.fn.static, nil
+	}
+
+	t = c.expr(object, nil)
+	return c.classOf(t), false, t
+}
+
+// lookupMember looks up the property of m in class and its bases, reporting
+// an error if there is no such member or if it can't be accessed from the
+// function being checked.
+func (c *Checker) lookupMember(m *ast.MemberExpression, class *ast.ClassDeclaration, static bool) *classMember {
+	name := m.Property.String()
+	typ := class.Name.Literal
+	if static {
+		typ = "typeof " + typ
+	}
+
+	member := c.findMember(class, name)
+	switch {
+	case member == nil || (static && !member.modifiers().Static):
+		c.errorf(m.Property, 2339, "property '%s' does not exist on type '%s'", name, typ)
+		return nil
+	case !static && member.modifiers().Static:
+		c.errorf(m.Property, 2576, "property '%s' does not exist on type '%s'. Did you mean to access the static member '%s.%s' instead?", name, typ, member.owner.Name.Literal, name)
+		return nil
+	}
+
+	switch member.modifiers().Access {
+	case "private":
+		if c.fn == nil || c.fn.class != member.owner {
+			c.errorf(m.Property, 2341, "property '%s' is private and only accessible within class '%s'", name, member.owner.Name.Literal)
+		}
+	case "protected":
+		if c.fn == nil || c.fn.class == nil || !c.isSubclass(c.fn.class, member.owner) {
+			c.errorf(m.Property, 2445, "property '%s' is protected and only accessible within class '%s' and its subclasses", name, member.owner.Name.Literal)
+		}
+	}
+
+	return member
+}
+
+// findMember returns the member of class or of its nearest base class called
+// name, or nil if there is none.
+func (c *Checker) findMember(class *ast.ClassDeclaration, name string) *classMember {
+	for ; class != nil; class = c.bases[class] {
+		if f := class.Field(name); f != nil {
+			return &classMember{owner: class, field: f}
+		}
+		if m := class.Method(name); m != nil {
+			return &classMember{owner: class, method: m}
+		}
+	}
+	return nil
+}
+
+// superCall checks a call of the base class constructor.
+func (c *Checker) superCall(call *ast.FunctionCallExpression) {
+	switch {
+	case c.fn == nil || c.fn.class == nil || c.bases[c.fn.class] == nil:
+		c.errorf(call.Callee, 2335, "'super' can only be referenced in a derived class")
+	case !c.fn.constructor:
+		c.errorf(call.Callee, 2337, "super calls are not permitted outside constructors or in nested functions inside constructors")
+	default:
+		c.checkArgs(call, c.constructorParams(c.bases[c.fn.class]), call.Args)
+		return
+	}
+	c.checkArgs(call, nil, call.Args)
+}
+
+// constructorParams returns the parameters of the constructor of class,
+// which it inherits from its base class if it has none of its own.
+func (c *Checker) constructorParams(class *ast.ClassDeclaration) []ast.FunctionParam {
+	for ; class != nil; class = c.bases[class] {
+		if class.Constructor != nil {
+			return class.Constructor.Params
+		}
+	}
+	return []ast.FunctionParam{}
+}
+
+// classOf returns the class t is an instance of, or nil.
+func (c *Checker) classOf(t ast.TypeExpr) *ast.ClassDeclaration {
+	if ref, ok := c.resolve(t).(*ast.TypeReference); ok {
+		return c.classes[ref.Name.Literal]
+	}
+	return nil
+}
+
+// isSubclass reports whether class is base or derives from it.
+func (c *Checker) isSubclass(class, base *ast.ClassDeclaration) bool {
+	for ; class != nil; class = c.bases[class] {
+		if class == base {
+			return true
+		}
+	}
+	return false
+}
+
+func classType(class *ast.ClassDeclaration) ast.TypeExpr {
+	return &ast.TypeReference{Name: token.Token{Type: token.IDENT, Literal: class.Name.Literal}}
+}
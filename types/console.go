@@ -0,0 +1,24 @@
+package types
+
+import "github.com/toyaAoi/sild/ast"
+
+// isConsole reports whether expr refers to the builtin console object,
+// rather than to a variable of the same name.
+func (c *Checker) isConsole(expr ast.Expression) bool {
+	v, ok := expr.(*ast.VariableExpression)
+	if !ok {
+		return false
+	}
+	sym := c.scope.Lookup(v.Token.Literal)
+	return sym != nil && sym.Kind == Builtin && sym.Name == "console"
+}
+
+// consoleMember checks an access to a property of console, of which only
+// log is supported.
+func (c *Checker) consoleMember(m *ast.MemberExpression) ast.TypeExpr {
+	c.expr(m.Object, nil)
+	if m.Property.String() != "log" {
+		c.errorf(m.Property, 2339, "property '%s' does not exist on type 'Console'", m.Property.String())
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"go/constant"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// checkEnumDeclaration checks the initializers of the members of an enum,
+// in a scope where the members before them are declared.
+func (c *Checker) checkEnumDeclaration(e *ast.EnumDeclaration) {
+	values := c.enumValues[e]
+	if values == nil {
+		values = ast.EnumValues(e)
+	}
+
+	c.pushScope()
+	for i, m := range e.Members {
+		switch {
+		case m.Value != nil:
+			t := c.expr(m.Value, nil)
+			if !isAny(t) && !c.isNumber(t) && !c.isString(t) {
+				c.errorf(m.Value, 18033, "type '%s' is not assignable to type 'number' as required for computed enum member values", typeString(t))
+			} else if e.Const && values[i].Kind() == constant.Unknown {
+				c.errorf(m.Value, 2474, "const enum member initializers must be constant expressions")
+			}
+		case values[i].Kind() == constant.Unknown:
+			c.errorf(ident(m.Name), 1061, "enum member must have initializer")
+		}
+		c.declare(&Symbol{Name: m.Name.Literal, Kind: Var, Type: enumType(e), Decl: m}, ident(m.Name))
+	}
+	c.popScope()
+}
+
+// enumOf returns the enum t is the type of, or nil.
+func (c *Checker) enumOf(t ast.TypeExpr) *ast.EnumDeclaration {
+	return c.enums[typeName(c.resolve(t))]
+}
+
+// isNumericEnum reports whether t is an enum whose members are all numbers.
+// Numbers and the values of numeric enums are assignable to each other.
+func (c *Checker) isNumericEnum(t ast.TypeExpr) bool {
+	return c.enumKind(t) == constant.Int
+}
+
+// isStringEnum reports whether t is an enum whose members are all strings.
+func (c *Checker) isStringEnum(t ast.TypeExpr) bool {
+	return c.enumKind(t) == constant.String
+}
+
+// enumKind returns Int for numeric enums, String for string enums and
+// Unknown for enums that mix numbers and strings and for other types.
+func (c *Checker) enumKind(t ast.TypeExpr) constant.Kind {
+	e := c.enumOf(t)
+	if e == nil {
+		return constant.Unknown
+	}
+	kind := constant.Int
+	for i, v := range c.enumValues[e] {
+		k := v.Kind()
+		if k == constant.Float || k == constant.Unknown && e.Members[i].Value != nil {
+			// computed members are numbers
+			k = constant.Int
+		}
+		if i == 0 {
+			kind = k
+		} else if k != kind {
+			return constant.Unknown
+		}
+	}
+	return kind
+}
+
+// enumName returns the enum whose name object is, or nil.
+func (c *Checker) enumName(object ast.Expression) *ast.EnumDeclaration {
+	v, ok := object.(*ast.VariableExpression)
+	if !ok {
+		return nil
+	}
+	sym := c.scope.Lookup(v.Token.Literal)
+	if sym == nil || sym.Kind != Enum {
+		return nil
+	}
+	c.info.Uses[v] = sym
+	c.info.Types[v] = nil
+	return sym.Decl.(*ast.EnumDeclaration)
+}
+
+// enumMember checks an access to a member of an enum, as in Color.Red, and
+// returns the type of the enum.
+func (c *Checker) enumMember(m *ast.MemberExpression, e *ast.EnumDeclaration) ast.TypeExpr {
+	if e.Member(m.Property.String()) == nil {
+		c.errorf(m.Property, 2339, "property '%s' does not exist on type 'typeof %s'", m.Property, e.Name.Literal)
+		return nil
+	}
+	return enumType(e)
+}
+
+// enumIndex checks an index into an enum. A number looks up the name of the
+// member of a numeric enum with that value, and a string literal looks up a
+// member, which is all a const enum allows.
+func (c *Checker) enumIndex(i *ast.IndexExpression, e *ast.EnumDeclaration) ast.TypeExpr {
+	index := c.expr(i.Index, nil)
+	if key, ok := i.Index.(*ast.StringLiteral); ok {
+		if e.Member(key.Token.Literal) == nil {
+			c.errorf(i.Index, 2339, "property '%s' does not exist on type 'typeof %s'", key.Token.Literal, e.Name.Literal)
+			return nil
+		}
+		return enumType(e)
+	}
+
+	switch {
+	case e.Const:
+		c.errorf(i.Index, 2476, "a const enum member can only be accessed using a string literal")
+	case isAny(index):
+	case !c.isNumber(index):
+		c.errorf(i.Index, 2538, "type '%s' cannot be used as an index type", typeString(index))
+	case !c.isNumericEnum(enumType(e)):
+		c.errorf(i.Index, 7053, "element implicitly has an 'any' type because expression of type '%s' can't be used to index type 'typeof %s'", typeString(index), e.Name.Literal)
+	default:
+		return primitive("string")
+	}
+	return nil
+}
+
+func enumType(e *ast.EnumDeclaration) ast.TypeExpr {
+	return &ast.TypeReference{Name: token.Token{Type: token.IDENT, Literal: e.Name.Literal}}
+}
@@ -0,0 +1,648 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// expr checks expr and returns its type, or nil if it isn't known. expected
+// is the type the context of expr expects, or nil; it types empty array
+// literals and lets object literals be checked for excess properties.
+func (c *Checker) expr(expr ast.Expression, expected ast.TypeExpr) ast.TypeExpr {
+	t := c.exprType(expr, expected)
+	c.info.Types[expr] = t
+	return t
+}
+
+func (c *Checker) exprType(expr ast.Expression, expected ast.TypeExpr) ast.TypeExpr {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		return primitive("number")
+	case *ast.BigIntLiteral:
+		return primitive("bigint")
+	case *ast.StringLiteral:
+		return c.stringLiteral(e, expected)
+	case *ast.TemplateLiteral:
+		for _, sub := range e.Expressions {
+			c.expr(sub, nil)
+		}
+		return primitive("string")
+	case *ast.BooleanLiteral:
+		return primitive("boolean")
+	case *ast.NullLiteral:
+		return primitive(e.Token.Literal)
+	case *ast.NonNullExpression:
+		t := c.expr(e.Expression, expected)
+		if u, ok := c.chains[e.Expression]; ok {
+			c.chains[e] = c.nonNull(u)
+		}
+		return c.nonNull(t)
+	case *ast.ParenthesizedExpression:
+		return c.expr(e.Expression, expected)
+	case *ast.VariableExpression:
+		return c.variable(e)
+	case *ast.UnaryExpression:
+		operand := c.expr(e.Right, nil)
+		switch e.Operator.Type {
+		case token.BANG:
+			return primitive("boolean")
+		case token.TYPEOF:
+			return typeofType()
+		}
+		if c.isBigInt(operand) {
+			return primitive("bigint")
+		}
+		return primitive("number")
+	case *ast.BinaryExpression:
+		return c.binary(e)
+	case *ast.ArrayLiteral:
+		return c.arrayLiteral(e, expected)
+	case *ast.ObjectLiteral:
+		return c.objectLiteral(e, expected)
+	case *ast.IndexExpression:
+		return c.index(e)
+	case *ast.MemberExpression:
+		return c.narrowedMember(e, c.member(e))
+	case *ast.FunctionCallExpression:
+		return c.call(e)
+	case *ast.ThisExpression:
+		return c.this(e)
+	case *ast.NewExpression:
+		return c.new(e, expected)
+	case *ast.FunctionExpression:
+		return c.functionExpression(e, expected)
+	}
+	return nil
+}
+
+func (c *Checker) variable(v *ast.VariableExpression) ast.TypeExpr {
+	sym := c.scope.Lookup(v.Token.Literal)
+	if sym == nil {
+		c.errorf(v, 2304, "cannot find name '%s'", v.Token.Literal)
+		return nil
+	}
+	c.info.Uses[v] = sym.declared()
+
+	if decl, ok := sym.Decl.(*ast.VariableDeclaration); ok && decl.Keyword.Type != token.VAR && c.scope == c.global && v.Pos().Offset < decl.Pos().Offset {
+		c.errorf(v, 2448, "block-scoped variable '%s' used before its declaration", sym.Name)
+	}
+
+	switch sym.Kind {
+	case Func:
+		fn := sym.Decl.(*ast.FunctionDeclaration)
+		if len(fn.TypeParams) > 0 {
+			// type parameters are only bound where the function is called,
+			// so its value has no type the checker knows
+			return nil
+		}
+		return functionType(fn)
+	case Enum:
+		if sym.Decl.(*ast.EnumDeclaration).Const {
+			c.errorf(v, 2475, "'const' enums can only be used in property or index access expressions or the right hand side of an import declaration or export assignment or type query")
+		}
+	}
+	return sym.Type
+}
+
+// checkType reports the names in a type annotation that aren't declared
+// types, and the references to generic types with the wrong number of type
+// arguments. A name that can't be found stands for any from then on, so
+// that the values given its type aren't reported too.
+func (c *Checker) checkType(t ast.TypeExpr) {
+	switch t := t.(type) {
+	case *ast.TypeReference:
+		for _, arg := range t.Args {
+			c.checkType(arg)
+		}
+		if t.Name.Type != token.IDENT || t.Name.Literal == "Array" || c.typeParam(t) != nil {
+			return
+		}
+		params, ok := c.declaredTypeParams(t.Name.Literal)
+		if !ok {
+			c.errorf(t, 2304, "cannot find name '%s'", t.Name.Literal)
+			c.unknown[t.Name.Literal] = true
+			return
+		}
+		c.checkTypeArgCount(t, t.Name.Literal, params, len(t.Args))
+	case *ast.ArrayType:
+		c.checkType(t.Elem)
+	case *ast.UnionType:
+		for _, m := range t.Types {
+			c.checkType(m)
+		}
+	case *ast.ObjectType:
+		for _, m := range t.Members {
+			c.checkType(m.Type)
+		}
+	case *ast.FunctionType:
+		c.checkSignature(t.Params, t.ReturnType)
+	}
+}
+
+// checkSignature checks the types of the parameters and the return type of a
+// function, those that are given.
+func (c *Checker) checkSignature(params []ast.FunctionParam, ret ast.TypeExpr) {
+	for _, p := range params {
+		c.checkType(p.Type)
+	}
+	c.checkType(ret)
+}
+
+// declaredTypeParams returns the type parameters of the interface, type
+// alias, class or enum called name, and whether there is one.
+func (c *Checker) declaredTypeParams(name string) ([]*ast.TypeParam, bool) {
+	if _, ok := c.aliases[name]; ok {
+		return c.generics[name], true
+	}
+	if class, ok := c.classes[name]; ok {
+		return class.TypeParams, true
+	}
+	_, ok := c.enums[name]
+	return nil, ok
+}
+
+// checkTypeArgCount reports a reference to the type called name, with the
+// given type parameters, that gives it n type arguments when it needs a
+// different number.
+func (c *Checker) checkTypeArgCount(node ast.Node, name string, params []*ast.TypeParam, n int) {
+	switch {
+	case len(params) == 0 && n > 0:
+		c.errorf(node, 2315, "type '%s' is not generic", name)
+	case len(params) != n:
+		names := make([]string, len(params))
+		for i, p := range params {
+			names[i] = p.Name.Literal
+		}
+		c.errorf(node, 2314, "generic type '%s<%s>' requires %d type argument(s)", name, strings.Join(names, ", "), len(params))
+	}
+}
+
+func (c *Checker) binary(e *ast.BinaryExpression) ast.TypeExpr {
+	left := c.expr(e.Left, nil)
+
+	var right ast.TypeExpr
+	switch e.Operator.Type {
+	case token.AND, token.OR:
+		// the right operand is only evaluated if the left one is true, or
+		// false, which narrows the types of the variables it tests
+		c.pushNarrowing(e.Right, c.narrow(e.Left, e.Operator.Type == token.AND))
+		right = c.expr(e.Right, nil)
+		c.popScope()
+	case token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+		// a string compared with a union of literals is a literal too
+		right = c.expr(e.Right, left)
+	case token.NULLISH:
+		right = c.expr(e.Right, c.nonNull(left))
+	default:
+		right = c.expr(e.Right, nil)
+	}
+
+	// operands may only be null or undefined where strings are concatenated
+	switch e.Operator.Type {
+	case token.PLUS:
+		if c.isString(left) || c.isString(right) {
+			break
+		}
+		fallthrough
+	case token.MINUS, token.MUL, token.DIV, token.MOD, token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL:
+		left, right = c.checkNonNull(e.Left, left), c.checkNonNull(e.Right, right)
+	}
+
+	switch e.Operator.Type {
+	case token.PLUS:
+		return c.plusType(e, e.Operator, left, right)
+	case token.MINUS, token.MUL, token.DIV, token.MOD:
+		return c.arithmeticType(e, e.Operator, e.Left, left, e.Right, right)
+	case token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL:
+		// unlike arithmetic, comparisons may mix numbers and bigints
+		if !isAny(left) && !isAny(right) && !(c.isNumeric(left) && c.isNumeric(right)) && !(c.isString(left) && c.isString(right)) {
+			c.errorf(e, 2365, "operator '%s' cannot be applied to types '%s' and '%s'", e.Operator.Literal, typeString(left), typeString(right))
+		}
+		return primitive("boolean")
+	case token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+		// anything may be compared with null and undefined
+		if !c.isNullish(left) && !c.isNullish(right) && !c.assignable(left, right) && !c.assignable(right, left) {
+			c.errorf(e, 2367, "this comparison appears to be unintentional because the types '%s' and '%s' have no overlap", typeString(left), typeString(right))
+		}
+		return primitive("boolean")
+	case token.IN:
+		return c.in(e, left, right)
+	case token.NULLISH:
+		return c.nullish(left, right)
+	case token.AND, token.OR:
+		if c.identical(left, right) {
+			return left
+		}
+		return nil
+	}
+	return nil
+}
+
+// plusType returns the type of adding values of types left and right with
+// op, + or +=, reporting an error at node if they can't be added. It returns
+// nil if the type isn't known.
+func (c *Checker) plusType(node ast.Node, op token.Token, left, right ast.TypeExpr) ast.TypeExpr {
+	switch {
+	case c.isString(left) || c.isString(right):
+		return primitive("string")
+	case isAny(left) || isAny(right):
+		return nil
+	case c.isNumber(left) && c.isNumber(right):
+		return primitive("number")
+	case c.isBigInt(left) && c.isBigInt(right):
+		return primitive("bigint")
+	}
+	c.errorf(node, 2365, "operator '%s' cannot be applied to types '%s' and '%s'", op.Literal, typeString(left), typeString(right))
+	return nil
+}
+
+// arithmeticType returns the type of applying the arithmetic operator op,
+// other than +, to operands of types left and right. Both must be numbers or
+// both bigints.
+func (c *Checker) arithmeticType(node ast.Node, op token.Token, leftExpr ast.Expression, left ast.TypeExpr, rightExpr ast.Expression, right ast.TypeExpr) ast.TypeExpr {
+	if !isAny(left) && !c.isNumeric(left) {
+		c.errorf(leftExpr, 2362, "the left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type")
+	}
+	if !isAny(right) && !c.isNumeric(right) {
+		c.errorf(rightExpr, 2363, "the right-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type")
+	}
+
+	switch {
+	case c.isBigInt(left) && c.isBigInt(right):
+		return primitive("bigint")
+	case c.isBigInt(left) && c.isNumber(right), c.isNumber(left) && c.isBigInt(right):
+		c.errorf(node, 2365, "operator '%s' cannot be applied to types '%s' and '%s'", op.Literal, typeString(left), typeString(right))
+		return nil
+	case c.isBigInt(left) && isAny(right), isAny(left) && c.isBigInt(right):
+		return nil
+	}
+	return primitive("number")
+}
+
+// arrayLiteral checks the elements of an array literal against the element
+// type of expected. Without an expected array type, the literal has the type
+// of its elements if they all have the same type.
+func (c *Checker) arrayLiteral(a *ast.ArrayLiteral, expected ast.TypeExpr) ast.TypeExpr {
+	if elem := ast.ElementType(c.resolve(expected)); elem != nil {
+		for _, e := range a.Elements {
+			c.checkAssignable(e, c.expr(e, elem), elem)
+		}
+		return expected
+	}
+
+	// the element type is the union of the types of the elements, and any
+	// for an empty array
+	elems := make([]ast.TypeExpr, len(a.Elements))
+	known := true
+	for i, e := range a.Elements {
+		elems[i] = c.expr(e, nil)
+		known = known && elems[i] != nil
+	}
+	switch {
+	case len(elems) == 0:
+		return &ast.ArrayType{Elem: primitive("any")}
+	case !known:
+		return nil
+	}
+	return &ast.ArrayType{Elem: c.union(elems)}
+}
+
+// objectLiteral checks an object literal against expected if it is an object
+// type, reporting unknown and missing properties. Otherwise the literal has
+// an object type with the types of its properties.
+func (c *Checker) objectLiteral(o *ast.ObjectLiteral, expected ast.TypeExpr) ast.TypeExpr {
+	if variant := c.variant(o, expected); variant != nil {
+		return c.objectLiteral(o, variant)
+	}
+	obj, checked := c.resolve(expected).(*ast.ObjectType)
+
+	literal := &ast.ObjectType{}
+	for _, p := range o.Properties {
+		var want ast.TypeExpr
+		if checked {
+			if m := obj.Member(p.Key.Literal); m != nil {
+				want = m.Type
+				if m.Optional {
+					want = c.orUndefined(m.Type)
+				}
+			} else {
+				c.errorf(p, 2353, "object literal may only specify known properties, and '%s' does not exist in type '%s'", p.Key.Literal, typeString(expected))
+			}
+		}
+
+		t := c.expr(p.Value, want)
+		if want != nil {
+			c.checkAssignable(p.Value, t, want)
+		}
+		if t == nil {
+			t = primitive("any")
+		}
+		literal.Members = append(literal.Members, &ast.PropertySignature{Name: p.Key, Type: t})
+	}
+
+	if !checked {
+		return literal
+	}
+	for _, m := range obj.Members {
+		if !m.Optional && literal.Member(m.Name.Literal) == nil {
+			c.errorf(o, 2741, "property '%s' is missing in type '%s' but required in type '%s'", m.Name.Literal, literal, typeString(expected))
+		}
+	}
+	return expected
+}
+
+func (c *Checker) index(i *ast.IndexExpression) ast.TypeExpr {
+	if e := c.enumName(i.Left); e != nil {
+		return c.enumIndex(i, e)
+	}
+	left, short := c.link(i.Left, c.expr(i.Left, nil), i.Optional)
+	return c.shortCircuit(i, c.indexType(i, c.resolve(left)), short)
+}
+
+func (c *Checker) indexType(i *ast.IndexExpression, left ast.TypeExpr) ast.TypeExpr {
+	index := c.expr(i.Index, nil)
+
+	switch {
+	case ast.ElementType(left) != nil || c.isString(left):
+		if !isAny(index) && !c.isNumber(index) {
+			c.errorf(i.Index, 2538, "type '%s' cannot be used as an index type", typeString(index))
+		}
+		if c.isString(left) {
+			return primitive("string")
+		}
+		return ast.ElementType(left)
+	}
+
+	if obj, ok := left.(*ast.ObjectType); ok {
+		if key, ok := i.Index.(*ast.StringLiteral); ok {
+			if m := obj.Member(key.Token.Literal); m != nil {
+				return m.Type
+			}
+		}
+	}
+	return nil
+}
+
+// member returns the type of a property access. Methods are only typed
+// when called, so accessing one yields nil. Optional properties may be
+// undefined.
+func (c *Checker) member(m *ast.MemberExpression) ast.TypeExpr {
+	if e := c.enumName(m.Object); e != nil {
+		return c.enumMember(m, e)
+	}
+	if c.isConsole(m.Object) {
+		return c.consoleMember(m)
+	}
+	class, static, object := c.receiver(m.Object)
+	object, short := c.link(m.Object, object, m.Optional)
+	if class == nil {
+		class = c.classOf(object)
+	}
+	return c.shortCircuit(m, c.memberType(m, class, static, object), short)
+}
+
+// narrowedMember returns the type a condition narrowed the property path m
+// to, if it did, or else t, the type of the property.
+func (c *Checker) narrowedMember(m *ast.MemberExpression, t ast.TypeExpr) ast.TypeExpr {
+	path := propertyPath(m)
+	if path == "" {
+		return t
+	}
+	narrowed := c.scope.lookupPath(path)
+	if narrowed == nil {
+		return t
+	}
+	c.info.Declared[m] = t
+	return narrowed
+}
+
+// propertyPath returns the path of the properties expr accesses from a
+// variable or this without optional chaining, like p.label, or "" if it
+// isn't one.
+func propertyPath(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.VariableExpression:
+		return e.Token.Literal
+	case *ast.ThisExpression:
+		return "this"
+	case *ast.MemberExpression:
+		if object := propertyPath(e.Object); object != "" && !e.Optional {
+			return object + "." + e.Property.String()
+		}
+	}
+	return ""
+}
+
+func (c *Checker) memberType(m *ast.MemberExpression, class *ast.ClassDeclaration, static bool, object ast.TypeExpr) ast.TypeExpr {
+	if class != nil {
+		cm := c.lookupMember(m, class, static)
+		if cm == nil {
+			return nil
+		}
+		cm.args = c.classArgs(object)
+		c.members[m] = cm
+		switch {
+		case cm.field == nil:
+			return nil
+		case cm.field.Optional:
+			return c.orUndefined(cm.typ())
+		}
+		return cm.typ()
+	}
+
+	name := m.Property.String()
+	switch resolved := c.resolve(object); {
+	case isAny(object):
+		return nil
+	case ast.ElementType(resolved) != nil:
+		if name == "length" {
+			return primitive("number")
+		}
+		if arrayMethods[name] {
+			return nil
+		}
+	case c.isString(resolved):
+		if name == "length" {
+			return primitive("number")
+		}
+		if _, ok := stringMethods[name]; ok {
+			return nil
+		}
+	case isUnion(resolved):
+		if t, ok := c.unionMember(object, name); ok {
+			return t
+		}
+	default:
+		if obj, ok := resolved.(*ast.ObjectType); ok {
+			if p := obj.Member(name); p != nil && p.Optional {
+				return c.orUndefined(p.Type)
+			} else if p != nil {
+				return p.Type
+			}
+		}
+	}
+
+	c.errorf(m.Property, 2339, "property '%s' does not exist on type '%s'", name, typeString(object))
+	return nil
+}
+
+// call checks a call and returns the type of its result, which is undefined
+// if the call is optional and the function null or undefined.
+func (c *Checker) call(call *ast.FunctionCallExpression) ast.TypeExpr {
+	if call.Optional {
+		callee, short := c.link(call.Callee, c.expr(call.Callee, nil), true)
+		ret, ok := c.callFunctionType(call, callee)
+		if !ok {
+			if !isAny(callee) {
+				c.errorf(call.Callee, 2349, "this expression is not callable. Type '%s' has no call signatures", typeString(callee))
+			}
+			c.checkArgs(call, nil, call.Args)
+		}
+		return c.shortCircuit(call, ret, short)
+	}
+	_, short := c.chains[call.Callee]
+	return c.shortCircuit(call, c.callType(call), short)
+}
+
+func (c *Checker) callType(call *ast.FunctionCallExpression) ast.TypeExpr {
+	switch callee := call.Callee.(type) {
+	case *ast.VariableExpression:
+		sym := c.scope.Lookup(callee.Token.Literal)
+		c.expr(callee, nil)
+		switch {
+		case sym == nil:
+		case sym.Kind == Func:
+			fn := sym.Decl.(*ast.FunctionDeclaration)
+			return c.checkCall(call, fn.TypeParams, fn.Params, fn.ReturnType)
+		case sym.Kind == Builtin && sym.Type == nil:
+			c.errorf(callee, 2349, "this expression is not callable. Type 'Console' has no call signatures")
+		case sym.Kind == Class:
+			c.errorf(callee, 2348, "value of type 'typeof %s' is not callable. Did you mean to include 'new'?", sym.Name)
+		case !isAny(sym.Type):
+			if ret, ok := c.callFunctionType(call, c.checkCallee(callee, c.info.Types[callee])); ok {
+				return ret
+			}
+			c.errorf(callee, 2349, "this expression is not callable. Type '%s' has no call signatures", typeString(sym.Type))
+		}
+		c.checkArgs(call, nil, call.Args)
+		return nil
+	case *ast.SuperExpression:
+		c.superCall(call)
+		return primitive("void")
+	case *ast.MemberExpression:
+		c.expr(callee, nil)
+		if c.isConsole(callee.Object) && callee.Property.String() == "log" {
+			// console.log takes any number of arguments of any type
+			c.checkArgs(call, nil, call.Args)
+			return primitive("void")
+		}
+		if m := c.members[callee]; m != nil && m.method != nil {
+			fn := m.typ().(*ast.FunctionType)
+			return c.checkCall(call, m.method.TypeParams, fn.Params, fn.ReturnType)
+		}
+		receiver := c.objectType(callee)
+		if r := c.resolve(receiver); ast.ElementType(r) != nil && arrayMethods[callee.Property.String()] {
+			return c.arrayMethodCall(call, r, callee.Property.String())
+		}
+		if method, ok := stringMethods[callee.Property.String()]; ok && c.isString(receiver) {
+			return c.stringMethodCall(call, method)
+		}
+		if ret, ok := c.callFunctionType(call, c.checkCallee(callee, c.linked(callee))); ok {
+			return ret
+		}
+	default:
+		if ret, ok := c.callFunctionType(call, c.expr(callee, nil)); ok {
+			return ret
+		}
+	}
+
+	c.checkArgs(call, nil, call.Args)
+	return nil
+}
+
+// checkCall checks the arguments of a call of a function with the given
+// type parameters and parameters, and returns the type of its result.
+func (c *Checker) checkCall(call *ast.FunctionCallExpression, typeParams []*ast.TypeParam, params []ast.FunctionParam, ret ast.TypeExpr) ast.TypeExpr {
+	for _, arg := range call.TypeArgs {
+		c.checkType(arg)
+	}
+	if typeParams == nil && call.TypeArgs == nil {
+		c.checkArgs(call, params, call.Args)
+		return ret
+	}
+	args := c.checkGenericArgs(call, call.TypeArgs, typeParams, params, call.Args)
+	c.info.TypeArgs[call] = args
+	return ast.Substitute(ret, args)
+}
+
+// checkArgs checks the arguments of a call against the parameters of the
+// function called. A nil params only checks the arguments themselves.
+func (c *Checker) checkArgs(call ast.Node, params []ast.FunctionParam, args []ast.Expression) {
+	for i, arg := range args {
+		if params == nil || i >= len(params) {
+			c.expr(arg, nil)
+			continue
+		}
+		c.checkArg(arg, params[i].Type)
+	}
+
+	if params != nil && len(args) != len(params) {
+		c.errorf(call, 2554, "expected %d arguments, but got %d", len(params), len(args))
+	}
+}
+
+func (c *Checker) this(t *ast.ThisExpression) ast.TypeExpr {
+	switch {
+	case c.fn == nil || c.fn.class == nil:
+		c.errorf(t, 2683, "'this' implicitly has type 'any' because it does not have a type annotation")
+		return nil
+	case c.fn.static:
+		return nil
+	}
+	// inside a generic class, this is an instance for its own type
+	// parameters
+	ref := classType(c.fn.class).(*ast.TypeReference)
+	for _, p := range c.fn.class.TypeParams {
+		ref.Args = append(ref.Args, &ast.TypeReference{Name: p.Name})
+	}
+	return ref
+}
+
+// new checks the creation of an instance of a class. The type arguments of
+// a generic class are inferred from the arguments of the constructor, or
+// taken from the type expected, unless they are given.
+func (c *Checker) new(n *ast.NewExpression, expected ast.TypeExpr) ast.TypeExpr {
+	for _, arg := range n.TypeArgs {
+		c.checkType(arg)
+	}
+	class, ok := c.classes[n.Class.String()]
+	if !ok {
+		if _, ok := c.aliases[n.Class.String()]; ok {
+			c.errorf(n.Class, 2693, "'%s' only refers to a type, but is being used as a value here", n.Class)
+		} else {
+			c.errorf(n.Class, 2304, "cannot find name '%s'", n.Class)
+		}
+		c.checkArgs(n, nil, n.Args)
+		return nil
+	}
+
+	if class.TypeParams == nil && n.TypeArgs == nil {
+		c.checkArgs(n, c.constructorParams(class), n.Args)
+		return classType(class)
+	}
+
+	explicit := n.TypeArgs
+	if ref, ok := c.resolve(expected).(*ast.TypeReference); ok && explicit == nil && ref.Name.Literal == class.Name.Literal {
+		explicit = ref.Args
+	}
+	args := c.checkGenericArgs(n, explicit, class.TypeParams, c.constructorParams(class), n.Args)
+	c.info.TypeArgs[n] = args
+
+	t := classType(class).(*ast.TypeReference)
+	for _, p := range class.TypeParams {
+		t.Args = append(t.Args, args[p.Name.Literal])
+	}
+	return t
+}
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// functionType returns the type of a declared function.
+func functionType(fn *ast.FunctionDeclaration) *ast.FunctionType {
+	return &ast.FunctionType{Params: fn.Params, ReturnType: fn.ReturnType}
+}
+
+// functionExpression checks an arrow function or function expression and
+// returns its type. Parameters without a type annotation take the type of
+// the corresponding parameter of expected, if it is a function type, and the
+// return type is inferred from the return statements if it isn't declared.
+func (c *Checker) functionExpression(f *ast.FunctionExpression, expected ast.TypeExpr) ast.TypeExpr {
+	want, _ := c.resolve(expected).(*ast.FunctionType)
+	c.checkSignature(f.Params, f.ReturnType)
+
+	t := &ast.FunctionType{Params: make([]ast.FunctionParam, len(f.Params)), ReturnType: f.ReturnType}
+	for i, p := range f.Params {
+		if p.Type == nil {
+			if want != nil && i < len(want.Params) {
+				p.Type = want.Params[i].Type
+			} else {
+				c.errorf(ident(p.Name), 7006, "parameter '%s' implicitly has an 'any' type", p.Name.Literal)
+				p.Type = primitive("any")
+			}
+		}
+		t.Params[i] = p
+	}
+
+	fn := &function{returnType: f.ReturnType, infer: f.ReturnType == nil}
+	if f.Arrow && c.fn != nil {
+		// arrow functions don't have a this of their own
+		fn.class, fn.static = c.fn.class, c.fn.static
+	}
+
+	body := f.Body
+	if f.Expr != nil {
+		body = []ast.Statement{&ast.ReturnStatement{Loc: ast.Loc{StartPos: f.Expr.Pos(), EndPos: f.Expr.End()}, Value: f.Expr}}
+	}
+	c.checkFunction(t.Params, body, fn, f)
+
+	if t.ReturnType == nil {
+		t.ReturnType = c.inferredReturnType(fn.returns, !c.terminates(body))
+	}
+	return t
+}
+
+// inferredReturnType returns the type of the values returned by a function
+// whose return type isn't declared: void if it returns none, and otherwise
+// their union, with undefined if the function may also return without a
+// value, by a bare return statement or by reaching the end of its body.
+func (c *Checker) inferredReturnType(returns []ast.TypeExpr, falls bool) ast.TypeExpr {
+	var values []ast.TypeExpr
+	for _, t := range returns {
+		switch {
+		case t == nil:
+			return primitive("any")
+		case typeName(t) == "void":
+			falls = true
+		default:
+			values = append(values, t)
+		}
+	}
+	if len(values) == 0 {
+		return primitive("void")
+	}
+	if falls {
+		values = append(values, primitive("undefined"))
+	}
+	return c.union(values)
+}
+
+// callFunctionType checks a call of a value of function type t and returns
+// the type of its result, or reports false if t isn't a function type.
+func (c *Checker) callFunctionType(call *ast.FunctionCallExpression, t ast.TypeExpr) (ast.TypeExpr, bool) {
+	fn, ok := c.resolve(t).(*ast.FunctionType)
+	if !ok {
+		return nil, false
+	}
+	return c.checkCall(call, nil, fn.Params, fn.ReturnType), true
+}
+
+// callbackType returns the type of the callback passed to an array method
+// on an array of elem, or nil if the method doesn't take one. acc is the
+// type of the accumulator of reduce. The callbacks of the iteration methods
+// may also take the index of the element.
+func callbackType(method string, elem, acc ast.TypeExpr) ast.TypeExpr {
+	param := func(name string, t ast.TypeExpr) ast.FunctionParam {
+		return ast.FunctionParam{Name: token.Token{Type: token.IDENT, Literal: name}, Type: t}
+	}
+
+	switch method {
+	case "map", "filter", "find", "some", "every":
+		return &ast.FunctionType{Params: []ast.FunctionParam{param("value", elem), param("index", primitive("number"))}, ReturnType: primitive("any")}
+	case "forEach":
+		return &ast.FunctionType{Params: []ast.FunctionParam{param("value", elem), param("index", primitive("number"))}, ReturnType: primitive("void")}
+	case "reduce":
+		return &ast.FunctionType{Params: []ast.FunctionParam{param("acc", acc), param("value", elem)}, ReturnType: acc}
+	case "sort":
+		return &ast.FunctionType{Params: []ast.FunctionParam{param("a", elem), param("b", elem)}, ReturnType: primitive("number")}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package types
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// declareTypeParams brings the type parameters of a generic declaration
+// into scope, where they shadow the types of the same name. It returns the
+// type parameters that were in scope before, which the caller restores at
+// the end of the declaration.
+func (c *Checker) declareTypeParams(params []*ast.TypeParam) map[string]*ast.TypeParam {
+	outer := c.typeParams
+	if len(params) == 0 {
+		return outer
+	}
+	c.typeParams = maps.Clone(outer)
+	if c.typeParams == nil {
+		c.typeParams = map[string]*ast.TypeParam{}
+	}
+	for _, p := range params {
+		c.typeParams[p.Name.Literal] = p
+	}
+	for _, p := range params {
+		c.checkType(p.Constraint)
+	}
+	return outer
+}
+
+// typeParam returns the type parameter in scope that t refers to, or nil if
+// t isn't a type parameter.
+func (c *Checker) typeParam(t ast.TypeExpr) *ast.TypeParam {
+	if ref, ok := t.(*ast.TypeReference); ok && len(ref.Args) == 0 {
+		return c.typeParams[ref.Name.Literal]
+	}
+	return nil
+}
+
+// constraint returns the constraint of a type parameter, which is the type
+// its values are used as. Without one, the values have no properties.
+func constraint(p *ast.TypeParam) ast.TypeExpr {
+	if p.Constraint == nil {
+		return &ast.ObjectType{}
+	}
+	return p.Constraint
+}
+
+// classArgs returns the type arguments of t, an instance of a generic class,
+// by the name of the type parameters they are for.
+func (c *Checker) classArgs(t ast.TypeExpr) map[string]ast.TypeExpr {
+	ref, ok := c.resolve(t).(*ast.TypeReference)
+	if !ok || len(ref.Args) == 0 {
+		return nil
+	}
+	if class := c.classes[ref.Name.Literal]; class != nil {
+		return ast.TypeArgs(class.TypeParams, ref.Args)
+	}
+	return nil
+}
+
+// sameTypeArgs reports whether source and target, instances of the same
+// generic class, have identical type arguments. An instance whose type
+// arguments aren't known, such as this in the methods of the class, has
+// the same type arguments as any other.
+func (c *Checker) sameTypeArgs(source, target ast.TypeExpr, depth int) bool {
+	s, _ := source.(*ast.TypeReference)
+	t, _ := target.(*ast.TypeReference)
+	if s == nil || t == nil || len(s.Args) != len(t.Args) {
+		return true
+	}
+	for i := range s.Args {
+		if !c.assignableDepth(s.Args[i], t.Args[i], depth+1) || !c.assignableDepth(t.Args[i], s.Args[i], depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkGenericArgs checks the arguments of a call of a generic function or
+// method, or of the constructor of a generic class, and returns the type
+// arguments of the call. explicit are the type arguments the call gives,
+// if any; otherwise they are inferred from the types of the arguments, and
+// as in tsc, a type argument that doesn't satisfy its constraint is
+// replaced by the constraint, against which the argument then fails to
+// check. Type parameters that nothing is inferred for are any.
+func (c *Checker) checkGenericArgs(node ast.Node, explicit []ast.TypeExpr, typeParams []*ast.TypeParam, params []ast.FunctionParam, args []ast.Expression) map[string]ast.TypeExpr {
+	if explicit != nil {
+		if len(explicit) != len(typeParams) {
+			c.errorf(node, 2558, "expected %d type arguments, but got %d", len(typeParams), len(explicit))
+		}
+		bound := ast.TypeArgs(typeParams, explicit)
+		for i, p := range typeParams {
+			if i >= len(explicit) {
+				bound[p.Name.Literal] = primitive("any")
+				continue
+			}
+			if want := ast.Substitute(p.Constraint, bound); want != nil && !c.assignable(explicit[i], want) {
+				c.errorf(explicit[i], 2344, "type '%s' does not satisfy the constraint '%s'", typeString(explicit[i]), typeString(want))
+			}
+		}
+		c.checkArgs(node, ast.SubstituteParams(params, bound), args)
+		return bound
+	}
+
+	bound := map[string]ast.TypeExpr{}
+	types := make([]ast.TypeExpr, len(args))
+	// function expressions come last, so that the types of their parameters
+	// can be taken from the type arguments inferred from the other arguments
+	for _, functions := range []bool{false, true} {
+		for i, arg := range args {
+			if _, ok := arg.(*ast.FunctionExpression); ok != functions {
+				continue
+			}
+			if i >= len(params) {
+				c.expr(arg, nil)
+				continue
+			}
+			expected := ast.Substitute(params[i].Type, withAny(typeParams, bound))
+			if !functions && slices.ContainsFunc(typeParams, func(p *ast.TypeParam) bool {
+				_, ok := bound[p.Name.Literal]
+				return !ok && ast.Mentions(params[i].Type, p.Name.Literal)
+			}) {
+				// the argument has its own type, from which the type
+				// arguments are inferred
+				expected = nil
+			}
+			types[i] = c.expr(arg, expected)
+			ast.InferTypeArgs(params[i].Type, types[i], bound, typeParams, c.resolve)
+		}
+	}
+
+	for _, p := range typeParams {
+		t, ok := bound[p.Name.Literal]
+		if !ok {
+			bound[p.Name.Literal] = primitive("any")
+			continue
+		}
+		if want := ast.Substitute(p.Constraint, bound); want != nil && !c.assignable(t, want) {
+			bound[p.Name.Literal] = want
+		}
+	}
+
+	for i, arg := range args {
+		if i >= len(params) {
+			break
+		}
+		if want := ast.Substitute(params[i].Type, bound); !c.assignable(types[i], want) {
+			c.errorf(arg, 2345, "argument of type '%s' is not assignable to parameter of type '%s'", typeString(types[i]), typeString(want))
+		}
+	}
+	if len(args) != len(params) {
+		c.errorf(node, 2554, "expected %d arguments, but got %d", len(params), len(args))
+	}
+	return bound
+}
+
+// withAny returns the type arguments bound, extended with any for the type
+// parameters that have none yet.
+func withAny(params []*ast.TypeParam, bound map[string]ast.TypeExpr) map[string]ast.TypeExpr {
+	args := maps.Clone(bound)
+	for _, p := range params {
+		if _, ok := args[p.Name.Literal]; !ok {
+			args[p.Name.Literal] = primitive("any")
+		}
+	}
+	return args
+}
+
+// checkStaticMembers reports the static members of a generic class whose
+// types refer to the type parameters of the class, which only its instances
+// have.
+func (c *Checker) checkStaticMembers(class *ast.ClassDeclaration) {
+	check := func(t ast.TypeExpr) {
+		for _, p := range class.TypeParams {
+			if ast.Mentions(t, p.Name.Literal) {
+				c.errorf(t, 2302, "static members cannot reference class type parameters")
+				return
+			}
+		}
+	}
+
+	for _, f := range class.Fields {
+		if f.Static {
+			check(f.Type)
+		}
+	}
+	for _, m := range class.Methods {
+		if !m.Static {
+			continue
+		}
+		for _, p := range m.Params {
+			check(p.Type)
+		}
+		check(m.ReturnType)
+	}
+}
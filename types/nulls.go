@@ -0,0 +1,179 @@
+package types
+
+import (
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// isNullish reports whether t is null or undefined.
+func (c *Checker) isNullish(t ast.TypeExpr) bool {
+	switch typeName(c.resolve(t)) {
+	case "null", "undefined":
+		return true
+	}
+	return false
+}
+
+// isNullable reports whether t is a union with null or undefined among its
+// members, whose values must be checked before they are used.
+func (c *Checker) isNullable(t ast.TypeExpr) bool {
+	for _, m := range c.unionMembers(t) {
+		if c.isNullish(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNull returns t without null and undefined.
+func (c *Checker) nonNull(t ast.TypeExpr) ast.TypeExpr {
+	if !c.isNullable(t) {
+		return t
+	}
+	var members []ast.TypeExpr
+	for _, m := range c.unionMembers(t) {
+		if !c.isNullish(m) {
+			members = append(members, m)
+		}
+	}
+	return c.union(members)
+}
+
+// orUndefined returns the union of t and undefined, the type of an optional
+// property or of an optional chain that may short-circuit.
+func (c *Checker) orUndefined(t ast.TypeExpr) ast.TypeExpr {
+	if isAny(t) {
+		return t
+	}
+	return c.union(append(c.unionMembers(t), primitive("undefined")))
+}
+
+// nullishNames returns how messages name the nullish members of t: 'null',
+// 'undefined' or both, and 0, 1 or 2 to offset the codes of the messages.
+func (c *Checker) nullishNames(t ast.TypeExpr) (string, int) {
+	null, undefined := false, false
+	for _, m := range c.unionMembers(t) {
+		switch typeName(c.resolve(m)) {
+		case "null":
+			null = true
+		case "undefined":
+			undefined = true
+		}
+	}
+	switch {
+	case null && undefined:
+		return "'null' or 'undefined'", 2
+	case undefined:
+		return "'undefined'", 1
+	}
+	return "'null'", 0
+}
+
+// checkNonNull reports an error if expr, of type t, may be null or undefined
+// where it is used as an object or an operand, and returns t without them.
+func (c *Checker) checkNonNull(expr ast.Expression, t ast.TypeExpr) ast.TypeExpr {
+	if !c.isNullable(t) {
+		return t
+	}
+	names, offset := c.nullishNames(t)
+	switch expr.(type) {
+	case *ast.VariableExpression, *ast.MemberExpression, *ast.ThisExpression:
+		c.errorf(expr, 18047+offset, "'%s' is possibly %s", expr, names)
+	default:
+		c.errorf(expr, 2531+offset, "object is possibly %s", names)
+	}
+	return c.nonNull(t)
+}
+
+// link returns the type of the object of a property access, element access
+// or call, of type t: without null and undefined if the access is optional,
+// and the type it has when the chain it belongs to doesn't short-circuit.
+// short reports whether the chain may short-circuit before the access.
+func (c *Checker) link(object ast.Expression, t ast.TypeExpr, optional bool) (_ ast.TypeExpr, short bool) {
+	if u, ok := c.chains[object]; ok {
+		t, short = u, true
+	}
+	switch {
+	case optional && c.isNullable(t):
+		return c.nonNull(t), true
+	case !optional:
+		t = c.checkNonNull(object, t)
+	}
+	return t, short
+}
+
+// linked returns the type of expr when the optional chain it may belong to
+// doesn't short-circuit.
+func (c *Checker) linked(expr ast.Expression) ast.TypeExpr {
+	if t, ok := c.chains[expr]; ok {
+		return t
+	}
+	return c.info.Types[expr]
+}
+
+// objectType returns the type of the object of m where the property is
+// accessed, which isn't null or undefined.
+func (c *Checker) objectType(m *ast.MemberExpression) ast.TypeExpr {
+	return c.nonNull(c.linked(m.Object))
+}
+
+// checkCallee reports an error if the function called by a call, of type t,
+// may be null or undefined, and returns t without them.
+func (c *Checker) checkCallee(callee ast.Expression, t ast.TypeExpr) ast.TypeExpr {
+	if !c.isNullable(t) {
+		return t
+	}
+	names, offset := c.nullishNames(t)
+	c.errorf(callee, 2721+offset, "cannot invoke an object which is possibly %s", names)
+	return c.nonNull(t)
+}
+
+// shortCircuit returns the type of a link of an optional chain, whose value
+// is t when the chain doesn't short-circuit, and undefined when it does.
+func (c *Checker) shortCircuit(link ast.Expression, t ast.TypeExpr, short bool) ast.TypeExpr {
+	if !short {
+		return t
+	}
+	c.chains[link] = t
+	return c.orUndefined(t)
+}
+
+// nullish returns the type of a ?? b: a without null and undefined, or b.
+func (c *Checker) nullish(left, right ast.TypeExpr) ast.TypeExpr {
+	switch {
+	case left == nil || right == nil:
+		return nil
+	case isAny(left) || isAny(right):
+		return primitive("any")
+	}
+	return c.union(append(c.unionMembers(c.nonNull(left)), c.unionMembers(right)...))
+}
+
+// narrowNull narrows with the comparison of a variable with null or
+// undefined, which == and != also consider equal to each other.
+func (c *Checker) narrowNull(e *ast.BinaryExpression, ref ast.Expression, null *ast.NullLiteral, equal bool) narrowing {
+	loose := e.Operator.Type == token.EQUAL || e.Operator.Type == token.NOT_EQUAL
+	return c.filter(ref, func(t ast.TypeExpr) bool {
+		matches := c.isNullish(t)
+		if !loose {
+			matches = typeName(c.resolve(t)) == null.Token.Literal
+		}
+		return matches == equal
+	})
+}
+
+// narrowTruthy narrows with a variable or a property path used as a
+// condition: null and undefined are false, and so may be the values of
+// primitive types.
+func (c *Checker) narrowTruthy(ref ast.Expression, assume bool) narrowing {
+	return c.filter(ref, func(t ast.TypeExpr) bool {
+		if assume {
+			return !c.isNullish(t)
+		}
+		switch c.typeofName(t) {
+		case "object", "function":
+			return c.isNullish(t)
+		}
+		return true
+	})
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+)
+
+// SymbolKind tells what a name declared in a scope refers to.
+type SymbolKind int
+
+const (
+	Var SymbolKind = iota
+	Func
+	Class
+	Enum
+	Builtin
+)
+
+// Symbol is a declared name. Type is the declared type of a variable or a
+// builtin constant, and nil for functions, classes and enums or when the type
+// isn't known. Decl is the declaration of functions, classes and enums, the
+// declaring node of variables and parameters, and nil for builtins.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	Type ast.TypeExpr
+	Decl ast.Node
+
+	// narrows is the symbol of the variable whose type this one narrows,
+	// or nil if it isn't a narrowed copy of another symbol
+	narrows *Symbol
+}
+
+// declared returns the symbol of the variable declared, rather than of its
+// narrowed type.
+func (s *Symbol) declared() *Symbol {
+	if s.narrows != nil {
+		return s.narrows
+	}
+	return s
+}
+
+// Scope is a lexical scope: the program, a function body, a block or the
+// header of a for loop. A narrowing scope only holds the variables of the
+// enclosing scope whose type a condition narrows, and the declarations made
+// in it go to the enclosing scope.
+type Scope struct {
+	parent    *Scope
+	symbols   map[string]*Symbol
+	narrowing bool
+
+	// paths maps the property paths, like p.label, that a condition
+	// narrows in a narrowing scope to their narrowed types, or to nil where
+	// an assignment widened them again
+	paths map[string]ast.TypeExpr
+}
+
+// universe returns the scope enclosing the program, which declares the
+// console object and the global number constants.
+func universe() *Scope {
+	s := newScope(nil)
+	s.insert(&Symbol{Name: "console", Kind: Builtin})
+	for _, name := range []string{"NaN", "Infinity"} {
+		s.insert(&Symbol{Name: name, Kind: Builtin, Type: primitive("number")})
+	}
+	return s
+}
+
+func newScope(parent *Scope) *Scope {
+	return &Scope{parent: parent, symbols: map[string]*Symbol{}, paths: map[string]ast.TypeExpr{}}
+}
+
+// Lookup returns the symbol called name in s or the innermost enclosing
+// scope that declares it, or nil if there is none.
+func (s *Scope) Lookup(name string) *Symbol {
+	for ; s != nil; s = s.parent {
+		if sym, ok := s.symbols[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// lookupPath returns the type a condition narrowed the property path to in
+// s or the scopes enclosing it, or nil if it isn't narrowed. The scope
+// declaring the variable the path starts from ends the search.
+func (s *Scope) lookupPath(path string) ast.TypeExpr {
+	root, _, _ := strings.Cut(path, ".")
+	for ; s != nil; s = s.parent {
+		if t, ok := s.paths[path]; ok {
+			return t
+		}
+		if sym := s.symbols[root]; sym != nil && sym.narrows == nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// block returns s, or the scope enclosing it if s is a narrowing scope.
+func (s *Scope) block() *Scope {
+	for s.narrowing {
+		s = s.parent
+	}
+	return s
+}
+
+// insert declares sym in s. It returns the symbol already declared under the
+// same name in s, if any, and leaves s unchanged in that case.
+func (s *Scope) insert(sym *Symbol) *Symbol {
+	if prev, ok := s.symbols[sym.Name]; ok {
+		return prev
+	}
+	s.symbols[sym.Name] = sym
+	return nil
+}
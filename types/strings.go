@@ -0,0 +1,57 @@
+package types
+
+import "github.com/toyaAoi/sild/ast"
+
+// stringMethod is the signature of a String.prototype method: the types of
+// its parameters, of which only the first required must be passed, and the
+// type of its result.
+type stringMethod struct {
+	params   []string
+	required int
+	result   ast.TypeExpr
+}
+
+// stringMethods are the String.prototype methods the code generator lowers.
+var stringMethods = map[string]stringMethod{
+	"toUpperCase": {nil, 0, primitive("string")},
+	"toLowerCase": {nil, 0, primitive("string")},
+	"trim":        {nil, 0, primitive("string")},
+	"trimStart":   {nil, 0, primitive("string")},
+	"trimEnd":     {nil, 0, primitive("string")},
+	"split":       {[]string{"string"}, 0, &ast.ArrayType{Elem: primitive("string")}},
+	"startsWith":  {[]string{"string", "number"}, 1, primitive("boolean")},
+	"endsWith":    {[]string{"string", "number"}, 1, primitive("boolean")},
+	"includes":    {[]string{"string", "number"}, 1, primitive("boolean")},
+	"indexOf":     {[]string{"string", "number"}, 1, primitive("number")},
+	"lastIndexOf": {[]string{"string"}, 1, primitive("number")},
+	"replace":     {[]string{"string", "string"}, 2, primitive("string")},
+	"replaceAll":  {[]string{"string", "string"}, 2, primitive("string")},
+	"padStart":    {[]string{"number", "string"}, 1, primitive("string")},
+	"padEnd":      {[]string{"number", "string"}, 1, primitive("string")},
+	"repeat":      {[]string{"number"}, 1, primitive("string")},
+	"charAt":      {[]string{"number"}, 0, primitive("string")},
+	"charCodeAt":  {[]string{"number"}, 0, primitive("number")},
+	"substring":   {[]string{"number", "number"}, 1, primitive("string")},
+	"slice":       {[]string{"number", "number"}, 0, primitive("string")},
+}
+
+// stringMethodCall checks a call of a String.prototype method and returns
+// the type of its result.
+func (c *Checker) stringMethodCall(call *ast.FunctionCallExpression, method stringMethod) ast.TypeExpr {
+	for i, arg := range call.Args {
+		if i >= len(method.params) {
+			c.expr(arg, nil)
+			continue
+		}
+		c.checkArg(arg, primitive(method.params[i]))
+	}
+
+	switch n := len(call.Args); {
+	case n >= method.required && n <= len(method.params):
+	case method.required == len(method.params):
+		c.errorf(call, 2554, "expected %d arguments, but got %d", method.required, n)
+	default:
+		c.errorf(call, 2554, "expected %d-%d arguments, but got %d", method.required, len(method.params), n)
+	}
+	return method.result
+}
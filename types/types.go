@@ -0,0 +1,233 @@
+package types
+
+import (
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// maxDepth bounds the recursion of structural comparisons, which would
+// otherwise not terminate for mutually recursive object types.
+const maxDepth = 32
+
+func primitive(name string) ast.TypeExpr {
+	return &ast.TypeReference{Name: token.Token{Type: token.LookupType(name), Literal: name}}
+}
+
+// typeName returns the name of a type reference without type arguments, or
+// "" for other types.
+func typeName(t ast.TypeExpr) string {
+	if ref, ok := t.(*ast.TypeReference); ok {
+		return ref.Name.Literal
+	}
+	return ""
+}
+
+// typeString formats t for a message. Unknown types are any.
+func typeString(t ast.TypeExpr) string {
+	if t == nil {
+		return "any"
+	}
+	return t.String()
+}
+
+// isAny reports whether t is unknown, and so compatible with every type.
+func isAny(t ast.TypeExpr) bool {
+	return t == nil || typeName(t) == "any"
+}
+
+func isVoid(t ast.TypeExpr) bool {
+	return typeName(t) == "void"
+}
+
+// isNumber reports whether t is number or a numeric enum.
+func (c *Checker) isNumber(t ast.TypeExpr) bool {
+	return typeName(c.resolve(t)) == "number" || c.isNumericEnum(t)
+}
+
+func (c *Checker) isBigInt(t ast.TypeExpr) bool {
+	return typeName(c.resolve(t)) == "bigint"
+}
+
+// isNumeric reports whether t is number or bigint, the types arithmetic
+// operators apply to.
+func (c *Checker) isNumeric(t ast.TypeExpr) bool {
+	return c.isNumber(t) || c.isBigInt(t)
+}
+
+// isString reports whether t is string, a string literal type or a union of
+// them.
+func (c *Checker) isString(t ast.TypeExpr) bool {
+	for _, m := range c.unionMembers(t) {
+		r := c.resolve(m)
+		if _, ok := r.(*ast.LiteralType); !ok && typeName(r) != "string" {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve replaces the name of an interface or type alias with the type it
+// stands for, instantiated with the type arguments of generic ones, and a
+// type parameter with its constraint.
+func (c *Checker) resolve(t ast.TypeExpr) ast.TypeExpr {
+	for range len(c.aliases) + len(c.typeParams) + 1 {
+		ref, ok := t.(*ast.TypeReference)
+		if !ok {
+			break
+		}
+		if p := c.typeParam(ref); p != nil {
+			t = constraint(p)
+			continue
+		}
+		if c.unknown[ref.Name.Literal] {
+			return nil
+		}
+		alias, ok := c.aliases[ref.Name.Literal]
+		if !ok {
+			break
+		}
+		t = ast.Substitute(alias, ast.TypeArgs(c.generics[ref.Name.Literal], ref.Args))
+	}
+	return t
+}
+
+// assignable reports whether a value of type source can be assigned to a
+// variable of type target. Object types are compared structurally, with
+// optional properties allowed to be missing, while classes are compared by
+// name, an instance of a class being assignable to its base classes. A union
+// is assignable if all of its members are, and a type is assignable to a
+// union if it is assignable to one of its members. String literal types are
+// assignable to string, string enums too, and numbers and numeric enums are
+// assignable to each other.
+func (c *Checker) assignable(source, target ast.TypeExpr) bool {
+	return c.assignableDepth(source, target, 0)
+}
+
+func (c *Checker) assignableDepth(source, target ast.TypeExpr, depth int) bool {
+	if isAny(source) || isAny(target) || depth > maxDepth {
+		return true
+	}
+	if p := c.typeParam(target); p != nil {
+		// a type parameter may stand for any type satisfying its
+		// constraint, so only its own values can be assigned to it
+		return c.typeParam(source) == p
+	}
+
+	s, t := c.resolve(source), c.resolve(target)
+	if s == t || isAny(s) || isAny(t) || isNever(s) {
+		return true
+	}
+	// a function returning void may return undefined
+	if typeName(s) == "undefined" && isVoid(t) {
+		return true
+	}
+
+	if u, ok := s.(*ast.UnionType); ok {
+		for _, m := range u.Types {
+			if !c.assignableDepth(m, target, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+	if u, ok := t.(*ast.UnionType); ok {
+		for _, m := range u.Types {
+			if c.assignableDepth(source, m, depth+1) {
+				return true
+			}
+		}
+		return false
+	}
+	if lit, ok := t.(*ast.LiteralType); ok {
+		s, ok := s.(*ast.LiteralType)
+		return ok && s.Token.Literal == lit.Token.Literal
+	}
+	if _, ok := s.(*ast.LiteralType); ok {
+		return typeName(t) == "string"
+	}
+
+	switch {
+	case c.isNumericEnum(s) && typeName(t) == "number", typeName(s) == "number" && c.isNumericEnum(t):
+		return true
+	case c.isStringEnum(s) && typeName(t) == "string":
+		return true
+	}
+
+	if te := ast.ElementType(t); te != nil {
+		se := ast.ElementType(s)
+		return se != nil && c.assignableDepth(se, te, depth+1)
+	}
+	if tc := c.classOf(t); tc != nil {
+		sc := c.classOf(s)
+		return sc != nil && c.isSubclass(sc, tc) && c.sameTypeArgs(s, t, depth)
+	}
+	if obj, ok := t.(*ast.ObjectType); ok {
+		return c.hasMembers(s, obj, depth)
+	}
+	if fn, ok := t.(*ast.FunctionType); ok {
+		return c.assignableFunction(s, fn, depth)
+	}
+
+	return typeName(s) != "" && typeName(s) == typeName(t)
+}
+
+// hasMembers reports whether source, an object type or a class, has the
+// members of target. The public methods of a class count as members of
+// function type.
+func (c *Checker) hasMembers(source ast.TypeExpr, target *ast.ObjectType, depth int) bool {
+	obj, _ := source.(*ast.ObjectType)
+	class, args := c.classOf(source), c.classArgs(source)
+	if obj == nil && class == nil {
+		return false
+	}
+
+	for _, m := range target.Members {
+		var t ast.TypeExpr
+		found := false
+		if obj != nil {
+			if p := obj.Member(m.Name.Literal); p != nil {
+				t, found = p.Type, true
+			}
+		} else if cm := c.findMember(class, m.Name.Literal); cm != nil && cm.modifiers().IsPublic() && !cm.modifiers().Static {
+			cm.args = args
+			t, found = cm.typ(), true
+		}
+
+		if !found {
+			if !m.Optional {
+				return false
+			}
+			continue
+		}
+		if !c.assignableDepth(t, m.Type, depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// assignableFunction reports whether source is a function type that can be
+// used as target: it may take fewer parameters, which must accept the
+// arguments target is called with, and its result is ignored if target
+// returns void.
+func (c *Checker) assignableFunction(source ast.TypeExpr, target *ast.FunctionType, depth int) bool {
+	fn, ok := source.(*ast.FunctionType)
+	if !ok || len(fn.Params) > len(target.Params) {
+		return false
+	}
+	for i, p := range fn.Params {
+		if !c.assignableDepth(target.Params[i].Type, p.Type, depth+1) {
+			return false
+		}
+	}
+	return isVoid(target.ReturnType) || c.assignableDepth(fn.ReturnType, target.ReturnType, depth+1)
+}
+
+// identical reports whether a and b are the same type. Unknown types are
+// only identical to each other.
+func (c *Checker) identical(a, b ast.TypeExpr) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return c.assignable(a, b) && c.assignable(b, a)
+}
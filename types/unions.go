@@ -0,0 +1,636 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/toyaAoi/sild/ast"
+	"github.com/toyaAoi/sild/token"
+)
+
+// unionMembers returns the types t is a union of, following type aliases, or t
+// itself if it isn't a union. Members that are aliases of other types are
+// left unresolved.
+func (c *Checker) unionMembers(t ast.TypeExpr) []ast.TypeExpr {
+	return c.unionMembersDepth(t, 0)
+}
+
+func (c *Checker) unionMembersDepth(t ast.TypeExpr, depth int) []ast.TypeExpr {
+	u, ok := c.resolve(t).(*ast.UnionType)
+	if !ok || depth > maxDepth {
+		return []ast.TypeExpr{t}
+	}
+	var types []ast.TypeExpr
+	for _, m := range u.Types {
+		types = append(types, c.unionMembersDepth(m, depth+1)...)
+	}
+	return types
+}
+
+// union returns the union of types, leaving out those identical to an
+// earlier one. It returns never for no types, and the type itself for one.
+func (c *Checker) union(types []ast.TypeExpr) ast.TypeExpr {
+	var distinct []ast.TypeExpr
+	for _, t := range types {
+		if isNever(t) {
+			continue
+		}
+		dup := false
+		for _, d := range distinct {
+			dup = dup || c.identical(d, t)
+		}
+		if !dup {
+			distinct = append(distinct, t)
+		}
+	}
+
+	switch len(distinct) {
+	case 0:
+		return primitive("never")
+	case 1:
+		return distinct[0]
+	}
+	return &ast.UnionType{Types: distinct}
+}
+
+func isUnion(t ast.TypeExpr) bool {
+	_, ok := t.(*ast.UnionType)
+	return ok
+}
+
+// isNever reports whether t is the type of values that can't exist, such as
+// a variable narrowed by conditions that can't all be true.
+func isNever(t ast.TypeExpr) bool {
+	return typeName(t) == "never"
+}
+
+// stringLiteral returns the type of a string literal: its literal type
+// where a literal type is expected, so that "circle" can be assigned to a
+// union of literals, and string elsewhere.
+func (c *Checker) stringLiteral(s *ast.StringLiteral, expected ast.TypeExpr) ast.TypeExpr {
+	for _, t := range c.unionMembers(expected) {
+		if _, ok := c.resolve(t).(*ast.LiteralType); ok {
+			return &ast.LiteralType{Token: s.Token}
+		}
+	}
+	return primitive("string")
+}
+
+// typeofNames are the strings the typeof operator evaluates to.
+var typeofNames = []string{"string", "number", "bigint", "boolean", "symbol", "undefined", "object", "function"}
+
+// typeofType returns the type of a typeof expression, the union of the
+// strings it may evaluate to.
+func typeofType() ast.TypeExpr {
+	u := &ast.UnionType{}
+	for _, name := range typeofNames {
+		u.Types = append(u.Types, &ast.LiteralType{Token: token.Token{Type: token.STRING, Literal: name}})
+	}
+	return u
+}
+
+// typeofName returns what typeof evaluates to for values of type t, or ""
+// if it isn't known.
+func (c *Checker) typeofName(t ast.TypeExpr) string {
+	switch r := c.resolve(t); {
+	case isAny(r):
+		return ""
+	case c.isString(r), c.isStringEnum(r):
+		return "string"
+	case c.isNumber(r):
+		return "number"
+	case c.isBigInt(r):
+		return "bigint"
+	case typeName(r) == "boolean":
+		return "boolean"
+	case isVoid(r), typeName(r) == "undefined":
+		return "undefined"
+	default:
+		if _, ok := r.(*ast.FunctionType); ok {
+			return "function"
+		}
+	}
+	return "object"
+}
+
+// in checks an in expression, which tests whether an object has a property.
+func (c *Checker) in(e *ast.BinaryExpression, left, right ast.TypeExpr) ast.TypeExpr {
+	if !isAny(left) && !c.isString(left) && !c.isNumber(left) {
+		c.errorf(e.Left, 2360, "the left-hand side of an 'in' expression must be a private identifier or of type 'any', 'string', 'number', or 'symbol'")
+	}
+	for _, m := range c.unionMembers(right) {
+		if name := c.typeofName(m); name != "" && name != "object" && name != "function" {
+			c.errorf(e.Right, 2361, "the right-hand side of an 'in' expression must not be a primitive")
+			break
+		}
+	}
+	return primitive("boolean")
+}
+
+// unionMember returns the type of a property of a union, which each of its
+// members must have, or reports false if one of them doesn't.
+func (c *Checker) unionMember(t ast.TypeExpr, name string) (ast.TypeExpr, bool) {
+	var types []ast.TypeExpr
+	for _, m := range c.unionMembers(t) {
+		p, ok := c.property(m, name)
+		if !ok {
+			return nil, false
+		}
+		types = append(types, p)
+	}
+	return c.union(types), true
+}
+
+// property returns the type of a property of a value of type t other than
+// a method, or reports false if t doesn't have it.
+func (c *Checker) property(t ast.TypeExpr, name string) (ast.TypeExpr, bool) {
+	switch r := c.resolve(t); {
+	case ast.ElementType(r) != nil || c.isString(r):
+		return primitive("number"), name == "length"
+	case c.classOf(r) != nil:
+		if m := c.findMember(c.classOf(r), name); m != nil && m.field != nil && m.modifiers().IsPublic() && !m.modifiers().Static {
+			m.args = c.classArgs(r)
+			return m.typ(), true
+		}
+	default:
+		if obj, ok := r.(*ast.ObjectType); ok {
+			if m := obj.Member(name); m != nil {
+				return m.Type, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// variant returns the member of the union expected that an object literal
+// is written for, or nil if there isn't exactly one. It is the object type
+// whose properties of string literal types, like the kind of
+// { kind: "circle"; r: number }, match the values of the literal.
+func (c *Checker) variant(o *ast.ObjectLiteral, expected ast.TypeExpr) ast.TypeExpr {
+	if !isUnion(c.resolve(expected)) {
+		return nil
+	}
+
+	var variant ast.TypeExpr
+	for _, m := range c.unionMembers(expected) {
+		obj, ok := c.resolve(m).(*ast.ObjectType)
+		if !ok || !c.matchesLiterals(o, obj) {
+			continue
+		}
+		if variant != nil {
+			return nil
+		}
+		variant = m
+	}
+	return variant
+}
+
+func (c *Checker) matchesLiterals(o *ast.ObjectLiteral, obj *ast.ObjectType) bool {
+	for _, m := range obj.Members {
+		lit, ok := c.resolve(m.Type).(*ast.LiteralType)
+		if !ok {
+			continue
+		}
+		value := objectProperty(o, m.Name.Literal)
+		if s, ok := value.(*ast.StringLiteral); !ok || s.Token.Literal != lit.Token.Literal {
+			return false
+		}
+	}
+	return true
+}
+
+// objectProperty returns the value of the property of o called name, or nil
+// if it has none.
+func objectProperty(o *ast.ObjectLiteral, name string) ast.Expression {
+	for _, p := range o.Properties {
+		if p.Key.Literal == name {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+// narrowing maps the variables whose type a condition narrows to their
+// narrowed types.
+type narrowing map[string]ast.TypeExpr
+
+// narrow returns the types of the variables that cond narrows when it has
+// the truth value assume. Comparing a property of a union of object types
+// with a string literal narrows the union to the members whose property
+// may have that value, and so do comparing the typeof of a variable with a
+// string and testing whether a property is in it. Comparing a variable with
+// null or undefined, or testing it as a condition, narrows it to the members
+// that may hold the values it is then known to have.
+func (c *Checker) narrow(cond ast.Expression, assume bool) narrowing {
+	switch e := cond.(type) {
+	case *ast.ParenthesizedExpression:
+		return c.narrow(e.Expression, assume)
+	case *ast.VariableExpression, *ast.MemberExpression:
+		return c.narrowTruthy(e, assume)
+	case *ast.UnaryExpression:
+		if e.Operator.Type == token.BANG {
+			return c.narrow(e.Right, !assume)
+		}
+	case *ast.BinaryExpression:
+		switch e.Operator.Type {
+		case token.AND, token.OR:
+			return c.narrowLogical(e, assume)
+		case token.EQUAL, token.STRICT_EQUAL:
+			return c.narrowEquality(e, assume)
+		case token.NOT_EQUAL, token.STRICT_NOT_EQUAL:
+			return c.narrowEquality(e, !assume)
+		case token.IN:
+			return c.narrowIn(e, assume)
+		}
+	}
+	return nil
+}
+
+// narrowLogical narrows with a && b or a || b. The right operand is only
+// evaluated when the left one didn't decide the result, and narrows the
+// types the left one narrowed.
+func (c *Checker) narrowLogical(e *ast.BinaryExpression, assume bool) narrowing {
+	// a && b being true and a || b being false need both operands to agree
+	both := (e.Operator.Type == token.AND) == assume
+
+	left := c.narrow(e.Left, assume)
+	if both {
+		c.pushNarrowing(e.Right, left)
+		right := c.narrow(e.Right, assume)
+		c.popScope()
+		return left.then(right)
+	}
+
+	// otherwise either the left operand decided, or it didn't and the right
+	// one did
+	undecided := c.narrow(e.Left, !assume)
+	c.pushNarrowing(e.Right, undecided)
+	right := c.narrow(e.Right, assume)
+	c.popScope()
+	return c.join(left, undecided.then(right))
+}
+
+// then returns the narrowing of n followed by that of next.
+func (n narrowing) then(next narrowing) narrowing {
+	m := narrowing{}
+	for name, t := range n {
+		m[name] = t
+	}
+	for name, t := range next {
+		m[name] = t
+	}
+	return m
+}
+
+// join returns the narrowing that holds when either a or b does: variables
+// narrowed by both have the union of their narrowed types.
+func (c *Checker) join(a, b narrowing) narrowing {
+	m := narrowing{}
+	for name, t := range a {
+		if u, ok := b[name]; ok {
+			m[name] = c.union(append(c.unionMembers(t), c.unionMembers(u)...))
+		}
+	}
+	return m
+}
+
+// narrowEquality narrows with the comparison of a string literal with a
+// property of a variable, the typeof of a variable or a variable itself, or
+// of null or undefined with a variable, being equal if equal is set.
+func (c *Checker) narrowEquality(e *ast.BinaryExpression, equal bool) narrowing {
+	ref, value := e.Left, e.Right
+	switch ref.(type) {
+	case *ast.StringLiteral, *ast.NullLiteral:
+		ref, value = value, ref
+	}
+	if null, ok := value.(*ast.NullLiteral); ok {
+		return c.narrowNull(e, ref, null, equal)
+	}
+	lit, ok := value.(*ast.StringLiteral)
+	if !ok {
+		return nil
+	}
+
+	switch r := ref.(type) {
+	case *ast.MemberExpression:
+		name := r.Property.String()
+		return c.filter(r.Object, func(t ast.TypeExpr) bool {
+			p, ok := c.property(t, name)
+			return !ok || c.matchesLiteral(p, lit.Token.Literal, equal)
+		})
+	case *ast.UnaryExpression:
+		if r.Operator.Type != token.TYPEOF {
+			return nil
+		}
+		return c.filter(r.Right, func(t ast.TypeExpr) bool {
+			name := c.typeofName(t)
+			return name == "" || (name == lit.Token.Literal) == equal
+		})
+	case *ast.VariableExpression:
+		return c.filter(r, func(t ast.TypeExpr) bool {
+			return c.matchesLiteral(t, lit.Token.Literal, equal)
+		})
+	}
+	return nil
+}
+
+// matchesLiteral reports whether a value of type t may be equal to the
+// string value, or may differ from it if equal isn't set.
+func (c *Checker) matchesLiteral(t ast.TypeExpr, value string, equal bool) bool {
+	for _, m := range c.unionMembers(t) {
+		lit, ok := c.resolve(m).(*ast.LiteralType)
+		if !ok || (lit.Token.Literal == value) == equal {
+			return true
+		}
+	}
+	return false
+}
+
+// narrowIn narrows with a test of whether an object has a property.
+func (c *Checker) narrowIn(e *ast.BinaryExpression, has bool) narrowing {
+	key, ok := e.Left.(*ast.StringLiteral)
+	if !ok {
+		return nil
+	}
+	return c.filter(e.Right, func(t ast.TypeExpr) bool {
+		if obj, ok := c.resolve(t).(*ast.ObjectType); ok {
+			m := obj.Member(key.Token.Literal)
+			return has && m != nil || !has && (m == nil || m.Optional)
+		}
+		_, ok := c.property(t, key.Token.Literal)
+		return ok == has
+	})
+}
+
+// filter narrows the type of the variable or the property path expr to the
+// members keep returns true for. Only variables declared with a union type
+// are narrowed.
+func (c *Checker) filter(expr ast.Expression, keep func(ast.TypeExpr) bool) narrowing {
+	var name string
+	var t ast.TypeExpr
+	switch e := expr.(type) {
+	case *ast.VariableExpression:
+		sym := c.scope.Lookup(e.Token.Literal)
+		if sym == nil || sym.Kind != Var || !isUnion(c.resolve(sym.declared().Type)) {
+			return nil
+		}
+		name, t = sym.Name, sym.Type
+	case *ast.MemberExpression:
+		name, t = propertyPath(e), c.info.Types[e]
+		if name == "" || !isUnion(c.resolve(t)) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	members := c.unionMembers(t)
+	var kept []ast.TypeExpr
+	for _, m := range members {
+		if keep(m) {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == len(members) {
+		return narrowing{name: t}
+	}
+	return narrowing{name: c.union(kept)}
+}
+
+// pushNarrowing opens a narrowing scope in which the variables of n have
+// their narrowed types, and records n as the narrowing of node.
+func (c *Checker) pushNarrowing(node ast.Node, n narrowing) {
+	c.info.Narrowings[node] = n
+	c.pushScope()
+	c.scope.narrowing = true
+	for name, t := range n {
+		if strings.Contains(name, ".") {
+			c.scope.paths[name] = t
+			continue
+		}
+		sym := c.scope.Lookup(name)
+		if sym == nil {
+			continue
+		}
+		narrowed := *sym
+		narrowed.Type = t
+		narrowed.narrows = sym.declared()
+		c.scope.symbols[name] = &narrowed
+	}
+}
+
+// narrowAssignment returns the narrowing of the statements after a, which
+// assigns a value to a variable declared with a union type or to a property
+// path of a union type: it holds the members of its type that the value
+// may be, and after a ??= b also the members that aren't null or undefined.
+// A compound assignment assigns the result of its operator.
+func (c *Checker) narrowAssignment(a *ast.AssignmentStatement) narrowing {
+	var name string
+	var declared ast.TypeExpr
+	switch t := a.Target.(type) {
+	case *ast.VariableExpression:
+		sym := c.scope.Lookup(t.Token.Literal)
+		if sym == nil || sym.Kind != Var {
+			return nil
+		}
+		name, declared = sym.Name, sym.declared().Type
+	case *ast.MemberExpression:
+		name, declared = propertyPath(t), c.info.Types[t]
+	}
+
+	value := c.info.Types[a.Value]
+	if a.Operator.Type != token.ASSIGN && a.Operator.Type != token.NULLISH_ASSIGN {
+		value = c.compound[a]
+	}
+	return c.narrowAssigned(name, declared, value, a.Operator.Type == token.NULLISH_ASSIGN)
+}
+
+// narrowDeclaration returns the narrowing of the statements after v, which
+// declares a variable of a union type with an initializer.
+func (c *Checker) narrowDeclaration(v *ast.VariableDeclaration) narrowing {
+	if v.Type == nil || v.Expr == nil {
+		return nil
+	}
+	return c.narrowAssigned(v.Name, v.Type, c.info.Types[v.Expr], false)
+}
+
+// narrowAssigned narrows the variable or property path name, declared with
+// type declared, to the members a value of type value may be, and to those
+// that aren't null or undefined too if nullish is set.
+func (c *Checker) narrowAssigned(name string, declared, value ast.TypeExpr, nullish bool) narrowing {
+	if name == "" || value == nil || !isUnion(c.resolve(declared)) || isAny(value) {
+		return nil
+	}
+
+	var kept []ast.TypeExpr
+	for _, m := range c.unionMembers(declared) {
+		keep := nullish && !c.isNullish(m)
+		for _, v := range c.unionMembers(value) {
+			keep = keep || c.assignable(v, m)
+		}
+		if keep {
+			kept = append(kept, m)
+		}
+	}
+	return narrowing{name: c.union(kept)}
+}
+
+// widen gives the variable or the property path target, which is assigned,
+// its declared type in the narrowing scopes enclosing the assignment, whose
+// narrowings no longer hold, and widens the paths of the properties of
+// target.
+func (c *Checker) widen(target ast.Expression) {
+	path := propertyPath(target)
+	if path == "" {
+		return
+	}
+	root, _, _ := strings.Cut(path, ".")
+	for s := c.scope; s != nil; s = s.parent {
+		for p := range s.paths {
+			if p == path || strings.HasPrefix(p, path+".") {
+				s.paths[p] = nil
+			}
+		}
+		sym := s.symbols[root]
+		if sym == nil {
+			continue
+		}
+		if sym.narrows == nil {
+			return
+		}
+		if root == path {
+			widened := *sym
+			widened.Type = sym.narrows.Type
+			s.symbols[sym.Name] = &widened
+		}
+	}
+}
+
+// widenAssigned widens the variables assigned in the body of a loop before
+// it is checked: each iteration may see the values assigned by the previous
+// one.
+func (c *Checker) widenAssigned(stmts ...ast.Statement) {
+	var walk func(stmt ast.Statement)
+	walk = func(stmt ast.Statement) {
+		switch s := stmt.(type) {
+		case *ast.AssignmentStatement:
+			c.widen(s.Target)
+		case *ast.BlockStatement:
+			for _, stmt := range s.Statements {
+				walk(stmt)
+			}
+		case *ast.IfStatement:
+			walk(s.Consequence)
+			walk(s.Alternative)
+		case *ast.WhileStatement:
+			walk(s.Body)
+		case *ast.DoWhileStatement:
+			walk(s.Body)
+		case *ast.ForStatement:
+			walk(s.Init)
+			walk(s.Update)
+			walk(s.Body)
+		case *ast.ForOfStatement:
+			walk(s.Body)
+		case *ast.ForInStatement:
+			walk(s.Body)
+		case *ast.LabeledStatement:
+			walk(s.Body)
+		case *ast.SwitchStatement:
+			for _, clause := range s.Cases {
+				for _, stmt := range clause.Body {
+					walk(stmt)
+				}
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		walk(stmt)
+	}
+}
+
+// checkSwitchStatement checks a switch statement. The case clauses share a
+// scope, and each is narrowed by the cases that lead to it: its own, and
+// those of the clauses falling through to it. The default clause is
+// narrowed by all cases being false.
+func (c *Checker) checkSwitchStatement(s *ast.SwitchStatement) {
+	discriminant := c.expr(s.Discriminant, nil)
+	for _, clause := range s.Cases {
+		if clause.Test == nil {
+			continue
+		}
+		t := c.expr(clause.Test, discriminant)
+		if !c.assignable(t, discriminant) && !c.assignable(discriminant, t) {
+			c.errorf(clause.Test, 2678, "type '%s' is not comparable to type '%s'", typeString(t), typeString(discriminant))
+		}
+	}
+
+	if !hasDefault(s) {
+		for _, t := range c.narrow(c.clauseCondition(s, nil, true), true) {
+			if isNever(t) {
+				c.exhaustive[s] = true
+			}
+		}
+	}
+
+	c.switches++
+	c.pushScope()
+	for i, clause := range s.Cases {
+		first := i
+		for first > 0 && !c.exits(s.Cases[first-1].Body) {
+			first--
+		}
+		c.pushNarrowing(clause, c.narrow(c.clauseCondition(s, s.Cases[first:i+1], false), true))
+		c.checkStatements(clause.Body)
+		c.popScope()
+	}
+	c.popScope()
+	c.switches--
+}
+
+// clauseCondition returns the condition under which control enters one of
+// clauses, as a comparison of the discriminant of s with their cases. If
+// one of them is the default clause, or if otherwise is set, control also
+// enters when no case of s matches.
+func (c *Checker) clauseCondition(s *ast.SwitchStatement, clauses []*ast.CaseClause, otherwise bool) ast.Expression {
+	or := func(a, b ast.Expression) ast.Expression {
+		if a == nil {
+			return b
+		}
+		return &ast.BinaryExpression{Left: a, Operator: token.Token{Type: token.OR, Literal: "||"}, Right: b}
+	}
+	matches := func(clause *ast.CaseClause) ast.Expression {
+		return &ast.BinaryExpression{Left: s.Discriminant, Operator: token.Token{Type: token.STRICT_EQUAL, Literal: "==="}, Right: clause.Test}
+	}
+
+	var cond ast.Expression
+	for _, clause := range clauses {
+		if clause.Test == nil {
+			otherwise = true
+		} else {
+			cond = or(cond, matches(clause))
+		}
+	}
+	if !otherwise {
+		return cond
+	}
+
+	var matched ast.Expression
+	for _, clause := range s.Cases {
+		if clause.Test != nil {
+			matched = or(matched, matches(clause))
+		}
+	}
+	if matched == nil {
+		return &ast.BooleanLiteral{Token: token.Token{Type: token.BOOLEAN, Literal: "true"}}
+	}
+	return or(cond, &ast.UnaryExpression{Operator: token.Token{Type: token.BANG, Literal: "!"}, Right: matched})
+}
+
+func hasDefault(s *ast.SwitchStatement) bool {
+	for _, clause := range s.Cases {
+		if clause.Test == nil {
+			return true
+		}
+	}
+	return false
+}